@@ -0,0 +1,303 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// AckStore persists the acknowledgments of a consumer, so that the messages acknowledged before a crash of the
+// application are not processed again when they are redelivered, because the broker did not persist their
+// acknowledgment yet. See ConsumerOptions.AckStore.
+//
+// The messages are identified by their topic (the partition for a partitioned topic), subscription, ledger,
+// entry and batch index.
+type AckStore interface {
+	// Ack persists the acknowledgment of a message
+	Ack(topic, subscription string, id MessageID) error
+
+	// AckCumulative persists the acknowledgment of all the messages up to and including the given message
+	AckCumulative(topic, subscription string, id MessageID) error
+
+	// IsAcked returns whether the acknowledgment of the message was persisted
+	IsAcked(topic, subscription string, id MessageID) bool
+}
+
+// messageKey identifies a message within a topic partition, and can be used as a map key
+type messageKey struct {
+	ledgerID int64
+	entryID  int64
+	batchIdx int32
+}
+
+func newMessageKey(id MessageID) messageKey {
+	return messageKey{ledgerID: id.LedgerID(), entryID: id.EntryID(), batchIdx: id.BatchIdx()}
+}
+
+func (k messageKey) messageID() *messageID {
+	return &messageID{ledgerID: k.ledgerID, entryID: k.entryID, batchIdx: k.batchIdx}
+}
+
+func (k messageKey) String() string {
+	return fmt.Sprintf("%d:%d:%d", k.ledgerID, k.entryID, k.batchIdx)
+}
+
+type ackStoreSubscription struct {
+	cumulative *messageID
+	acked      map[messageKey]struct{}
+}
+
+func (s *ackStoreSubscription) isAcked(key messageKey) bool {
+	if s.cumulative != nil && messageIDCompare(key.messageID(), s.cumulative) <= 0 {
+		return true
+	}
+	_, ok := s.acked[key]
+	return ok
+}
+
+// ackCumulative advances the cumulative position and drops the individual acks it covers
+func (s *ackStoreSubscription) ackCumulative(key messageKey) {
+	id := key.messageID()
+	if s.cumulative != nil && messageIDCompare(id, s.cumulative) <= 0 {
+		return
+	}
+	s.cumulative = id
+	for k := range s.acked {
+		if messageIDCompare(k.messageID(), id) <= 0 {
+			delete(s.acked, k)
+		}
+	}
+}
+
+const (
+	fileAckStoreIndividual = "A"
+	fileAckStoreCumulative = "C"
+
+	// fileAckStoreMinCompactionLines is the number of lines of the file below which it is not compacted
+	fileAckStoreMinCompactionLines = 10000
+)
+
+// FileAckStore is an AckStore appending the acknowledgments to a local file. The file is compacted when it is
+// reopened, and while running once it holds twice as many lines as acknowledgments: the individual
+// acknowledgments covered by a cumulative acknowledgment are dropped.
+//
+// The individual acknowledgments are kept, in memory and in the file, until a cumulative acknowledgment covers
+// them, as the store can not tell when the broker persisted them. The store thus grows without bound for the
+// subscriptions that are never acknowledged cumulatively (e.g. Shared ones), unless the application calls
+// AckCumulative on the store itself with a position up to which all the messages are known to be acknowledged,
+// such as the mark delete position of the subscription.
+//
+// The file is not synced after each acknowledgment, so the acknowledgments survive a crash of the application,
+// but not of the machine.
+type FileAckStore struct {
+	sync.Mutex
+	path          string
+	file          *os.File
+	subscriptions map[string]*ackStoreSubscription
+	// lines is the number of lines of the file, which is compacted once it reaches compactAt
+	lines     int
+	compactAt int
+}
+
+// NewFileAckStore opens the FileAckStore persisted at the given path, creating it if needed
+func NewFileAckStore(path string) (*FileAckStore, error) {
+	s := &FileAckStore{
+		path:          path,
+		subscriptions: make(map[string]*ackStoreSubscription),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileAckStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 {
+			// ignore a line partially written before a crash
+			continue
+		}
+		var key messageKey
+		if _, err := fmt.Sscanf(fields[3], "%d:%d:%d", &key.ledgerID, &key.entryID, &key.batchIdx); err != nil {
+			continue
+		}
+		sub := s.subscription(fields[1], fields[2])
+		switch fields[0] {
+		case fileAckStoreIndividual:
+			if !sub.isAcked(key) {
+				sub.acked[key] = struct{}{}
+			}
+		case fileAckStoreCumulative:
+			sub.ackCumulative(key)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the file with the current acknowledgments, and opens it for appending
+func (s *FileAckStore) compact() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	lines := 0
+	for name, sub := range s.subscriptions {
+		topic, subscription, _ := strings.Cut(name, "\t")
+		if sub.cumulative != nil {
+			key := newMessageKey(sub.cumulative)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", fileAckStoreCumulative, topic, subscription, key)
+			lines++
+		}
+		for key := range sub.acked {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", fileAckStoreIndividual, topic, subscription, key)
+			lines++
+		}
+	}
+	if err = w.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	if s.file != nil {
+		// the file was replaced, append to the compacted one
+		s.file.Close()
+	}
+
+	s.lines = lines
+	s.compactAt = 2 * lines
+	if s.compactAt < fileAckStoreMinCompactionLines {
+		s.compactAt = fileAckStoreMinCompactionLines
+	}
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
+
+func (s *FileAckStore) subscription(topic, subscription string) *ackStoreSubscription {
+	name := topic + "\t" + subscription
+	sub, ok := s.subscriptions[name]
+	if !ok {
+		sub = &ackStoreSubscription{acked: make(map[messageKey]struct{})}
+		s.subscriptions[name] = sub
+	}
+	return sub
+}
+
+func (s *FileAckStore) append(op, topic, subscription string, key messageKey) error {
+	if s.file == nil {
+		return newError(ConsumerClosed, "ack store is closed")
+	}
+	_, err := fmt.Fprintf(s.file, "%s\t%s\t%s\t%s\n", op, topic, subscription, key)
+	if err != nil {
+		return err
+	}
+	s.lines++
+	return nil
+}
+
+// compactIfNeeded compacts the file once it holds too many lines that are no longer needed
+func (s *FileAckStore) compactIfNeeded() error {
+	if s.lines < s.compactAt {
+		return nil
+	}
+	if err := s.compact(); err != nil {
+		return fmt.Errorf("failed to compact the ack store: %w", err)
+	}
+	return nil
+}
+
+// Ack persists the acknowledgment of a message
+func (s *FileAckStore) Ack(topic, subscription string, id MessageID) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := newMessageKey(id)
+	sub := s.subscription(topic, subscription)
+	if sub.isAcked(key) {
+		return nil
+	}
+	if err := s.append(fileAckStoreIndividual, topic, subscription, key); err != nil {
+		return err
+	}
+	sub.acked[key] = struct{}{}
+	return s.compactIfNeeded()
+}
+
+// AckCumulative persists the acknowledgment of all the messages up to and including the given message
+func (s *FileAckStore) AckCumulative(topic, subscription string, id MessageID) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := newMessageKey(id)
+	sub := s.subscription(topic, subscription)
+	if sub.cumulative != nil && messageIDCompare(key.messageID(), sub.cumulative) <= 0 {
+		return nil
+	}
+	if err := s.append(fileAckStoreCumulative, topic, subscription, key); err != nil {
+		return err
+	}
+	sub.ackCumulative(key)
+	return s.compactIfNeeded()
+}
+
+// IsAcked returns whether the acknowledgment of the message was persisted
+func (s *FileAckStore) IsAcked(topic, subscription string, id MessageID) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	sub, ok := s.subscriptions[topic+"\t"+subscription]
+	return ok && sub.isAcked(newMessageKey(id))
+}
+
+// Close closes the file of the store
+func (s *FileAckStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
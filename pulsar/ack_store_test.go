@@ -0,0 +1,99 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAckStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acks")
+	store, err := NewFileAckStore(path)
+	assert.Nil(t, err)
+
+	topic := "persistent://public/default/my-topic"
+	assert.False(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 1, -1, 0)))
+
+	assert.Nil(t, store.Ack(topic, "my-sub", NewMessageID(1, 1, -1, 0)))
+	assert.Nil(t, store.Ack(topic, "my-sub", NewMessageID(1, 2, 0, 0)))
+	assert.Nil(t, store.Ack(topic, "my-sub", NewMessageID(1, 5, -1, 0)))
+	assert.Nil(t, store.Ack(topic, "my-sub", NewMessageID(1, 5, -1, 0)))
+	assert.True(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 1, -1, 0)))
+	assert.True(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 2, 0, 0)))
+	assert.False(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 2, 1, 0)))
+	assert.False(t, store.IsAcked(topic, "other-sub", NewMessageID(1, 1, -1, 0)))
+
+	// a cumulative ack covers all the previous messages, and the batched messages of the same entry
+	assert.Nil(t, store.AckCumulative(topic, "my-sub", NewMessageID(1, 3, -1, 0)))
+	assert.True(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 2, 1, 0)))
+	assert.True(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 3, 4, 0)))
+	assert.False(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 4, -1, 0)))
+	assert.Nil(t, store.Close())
+	assert.NotNil(t, store.Ack(topic, "my-sub", NewMessageID(1, 6, -1, 0)))
+
+	// the acks are replayed and compacted when the store is reopened
+	store, err = NewFileAckStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	assert.True(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 2, 1, 0)))
+	assert.True(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 5, -1, 0)))
+	assert.False(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 4, -1, 0)))
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+}
+
+func TestFileAckStoreCompactsWhileRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acks")
+	store, err := NewFileAckStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	topic := "persistent://public/default/my-topic"
+	for i := 0; i < fileAckStoreMinCompactionLines; i++ {
+		assert.Nil(t, store.Ack(topic, "my-sub", NewMessageID(1, int64(i), -1, 0)))
+	}
+	// the file was compacted once it reached the minimum number of lines, and holds the individual acks
+	assert.Equal(t, fileAckStoreMinCompactionLines, store.lines)
+	assert.Equal(t, 2*fileAckStoreMinCompactionLines, store.compactAt)
+
+	// a cumulative ack drops the individual acks it covers, from memory and then from the file once it is compacted
+	assert.Nil(t, store.AckCumulative(topic, "my-sub", NewMessageID(1, fileAckStoreMinCompactionLines, -1, 0)))
+	for i := 0; i < fileAckStoreMinCompactionLines-1; i++ {
+		assert.Nil(t, store.Ack(topic, "my-sub", NewMessageID(2, int64(i), -1, 0)))
+	}
+	assert.Equal(t, fileAckStoreMinCompactionLines, store.lines)
+	assert.True(t, store.IsAcked(topic, "my-sub", NewMessageID(1, 10, -1, 0)))
+	assert.True(t, store.IsAcked(topic, "my-sub", NewMessageID(2, 10, -1, 0)))
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, fileAckStoreMinCompactionLines, strings.Count(string(data), "\n"))
+
+	// the acks after the compaction are appended to the compacted file
+	assert.Nil(t, store.Ack(topic, "my-sub", NewMessageID(3, 1, -1, 0)))
+	data, err = os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, fileAckStoreMinCompactionLines+1, strings.Count(string(data), "\n"))
+}
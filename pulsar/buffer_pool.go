@@ -0,0 +1,86 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"sync"
+
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+)
+
+// BufferPool provides the byte slices in which the producers encode the frames sent to the brokers, and takes
+// them back once the broker has acknowledged the frame, so that they can be reused by the next frames instead of
+// being garbage collected. See ClientOptions.BufferPool.
+//
+// A BufferPool must be safe for concurrent use.
+type BufferPool interface {
+	// Get returns a byte slice whose capacity can be used to encode a frame, or nil to let the client allocate a
+	// new one. Its content is overwritten.
+	Get() []byte
+
+	// Put gives back a byte slice that is no longer used by the client.
+	Put([]byte)
+}
+
+// NewBufferPool returns a BufferPool backed by a sync.Pool, which releases the unused byte slices over the
+// garbage collections.
+func NewBufferPool() BufferPool {
+	return &syncBufferPool{}
+}
+
+// defaultBufferPool is shared by all the clients which do not set ClientOptions.BufferPool
+var defaultBufferPool = NewBufferPool()
+
+type syncBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *syncBufferPool) Get() []byte {
+	b, ok := p.pool.Get().(*[]byte)
+	if !ok {
+		return nil
+	}
+	return *b
+}
+
+func (p *syncBufferPool) Put(b []byte) {
+	p.pool.Put(&b)
+}
+
+// getPooledBuffer returns an empty buffer backed by a byte slice of the pool, or nil if the pool has none
+func getPooledBuffer(pool BufferPool) internal.Buffer {
+	if pool == nil {
+		return nil
+	}
+	b := pool.Get()
+	if b == nil {
+		return nil
+	}
+	buffer := internal.NewBufferWrapper(b[:cap(b)])
+	buffer.Clear()
+	return buffer
+}
+
+// releasePooledBuffer gives back the byte slice of the buffer to the pool
+func releasePooledBuffer(pool BufferPool, buffer internal.Buffer) {
+	if pool == nil || buffer == nil {
+		return
+	}
+	buffer.Clear()
+	pool.Put(buffer.WritableSlice()[:0])
+}
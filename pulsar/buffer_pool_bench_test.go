@@ -0,0 +1,61 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+	"github.com/apache/pulsar-client-go/pulsar/internal/crypto"
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// benchmarkFrameEncoding encodes frames of 64KB like a producer does, with the buffers obtained from the pool,
+// and released once "acknowledged" by the broker.
+func benchmarkFrameEncoding(b *testing.B, pool BufferPool) {
+	payload := internal.NewBufferWrapper(make([]byte, 64*1024))
+	metadata := &pb.MessageMetadata{
+		ProducerName: proto.String("bench"),
+		SequenceId:   proto.Uint64(0),
+		PublishTime:  proto.Uint64(0),
+	}
+	encryptor := crypto.NewNoopEncryptor()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buffer := getPooledBuffer(pool)
+		if buffer == nil {
+			buffer = internal.NewBuffer(int(payload.ReadableBytes() * 3 / 2))
+		}
+		if err := internal.SingleSend(buffer, 1, uint64(i), metadata, payload, encryptor,
+			internal.MaxMessageSize, false, 0, 0); err != nil {
+			b.Fatal(err)
+		}
+		releasePooledBuffer(pool, buffer)
+	}
+}
+
+func BenchmarkFrameEncodingWithoutPool(b *testing.B) {
+	benchmarkFrameEncoding(b, nil)
+}
+
+func BenchmarkFrameEncodingWithPool(b *testing.B) {
+	benchmarkFrameEncoding(b, NewBufferPool())
+}
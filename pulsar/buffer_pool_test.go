@@ -0,0 +1,54 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferPool(t *testing.T) {
+	pool := NewBufferPool()
+	assert.Nil(t, getPooledBuffer(pool))
+	assert.Nil(t, getPooledBuffer(nil))
+
+	buffer := getPooledBuffer(&fixedBufferPool{})
+	assert.NotNil(t, buffer)
+	assert.Equal(t, uint32(0), buffer.ReadableBytes())
+	assert.Equal(t, uint32(1024), buffer.WritableBytes())
+
+	buffer.Write([]byte("hello"))
+	releasePooledBuffer(pool, buffer)
+
+	// the byte slice is reused, with its whole capacity
+	b := pool.Get()
+	if b != nil {
+		assert.Equal(t, 0, len(b))
+		assert.Equal(t, 1024, cap(b))
+	}
+	releasePooledBuffer(nil, buffer)
+}
+
+type fixedBufferPool struct{}
+
+func (p *fixedBufferPool) Get() []byte {
+	return make([]byte, 10, 1024)
+}
+
+func (p *fixedBufferPool) Put([]byte) {}
@@ -18,6 +18,7 @@
 package pulsar
 
 import (
+	"context"
 	"crypto/tls"
 	"time"
 
@@ -28,7 +29,30 @@ import (
 
 // NewClient Creates a pulsar client instance
 func NewClient(options ClientOptions) (Client, error) {
-	return newClient(options)
+	return newClient(options, nil)
+}
+
+// NewClientWithSharedConnections Creates a pulsar client instance that shares the broker connections of an
+// existing client, instead of opening its own.
+//
+// The connection-level settings (TLS and authentication, ConnectionTimeout, KeepAliveInterval,
+// MaxConnectionsPerBroker and ConnectionMaxIdleTime) are inherited from the existing client, and the ones
+// in options are ignored. The service URL of both clients must use the same TLS mode.
+// The shared connections are only closed once every client using them has been closed.
+func NewClientWithSharedConnections(options ClientOptions, other Client) (Client, error) {
+	c, ok := other.(*client)
+	if !ok {
+		return nil, newError(InvalidConfiguration, "connections can only be shared with a client created by NewClient")
+	}
+	return newClient(options, c)
+}
+
+// ValidateOptions checks the given ClientOptions for missing or contradictory settings (e.g. TLS files
+// configured for a plaintext service URL) without creating a client.
+// It returns an *Error with the InvalidConfiguration or AuthenticationError result describing the first
+// problem found, or nil if the options look consistent.
+func ValidateOptions(options ClientOptions) error {
+	return validateClientOptions(&options)
 }
 
 // Authentication Opaque interface that represents the authentication credentials
@@ -98,6 +122,10 @@ type ClientOptions struct {
 	// operation will be marked as failed
 	OperationTimeout time.Duration
 
+	// OperationTimeouts overrides OperationTimeout for specific operations, e.g. to keep acks snappy while
+	// tolerating a slower producer creation during broker rebalances.
+	OperationTimeouts OperationTimeouts
+
 	// Configure the ping send and check interval, default to 30 seconds.
 	KeepAliveInterval time.Duration
 
@@ -114,6 +142,17 @@ type ClientOptions struct {
 	// Set the path to the trusted TLS certificate file
 	TLSTrustCertsFilePath string
 
+	// TLSCertPEM is the PEM encoded TLS certificate, for the TLS material available in memory rather than on disk
+	// (e.g. a secret passed through an environment variable). It requires TLSKeyPEM and can not be used with
+	// TLSCertificateFile or TLSKeyFilePath.
+	TLSCertPEM []byte
+
+	// TLSKeyPEM is the PEM encoded TLS key matching TLSCertPEM
+	TLSKeyPEM []byte
+
+	// TLSTrustCertsPEM is the PEM encoded trusted TLS certificates, it can not be used with TLSTrustCertsFilePath
+	TLSTrustCertsPEM []byte
+
 	// Configure whether the Pulsar client accept untrusted TLS certificate from broker (default: false)
 	TLSAllowInsecureConnection bool
 
@@ -133,11 +172,35 @@ type ClientOptions struct {
 	ListenerName string
 
 	// Max number of connections to a single broker that will kept in the pool. (Default: 1 connection)
+	//
+	// The producers, consumers and readers on a broker are spread round-robin over its connections, so that a
+	// high-throughput one does not hold back the others. Each of them keeps its connection until it reconnects,
+	// which preserves the order of the messages it sends or receives, but the producers of a same topic may use
+	// different connections: there is no ordering between the messages of different producers. More connections
+	// also mean more sockets and handshakes on the brokers.
 	MaxConnectionsPerBroker int
 
+	// MaxConcurrentLookupRequests is the number of lookup and partitioned topic metadata requests that can be in
+	// flight at the same time on a broker connection, the next ones waiting for one of them to complete. It
+	// smooths the burst of lookups sent to the brokers when many producers and consumers reconnect at once.
+	// (Default: 5000)
+	MaxConcurrentLookupRequests int
+
+	// MaxLookupRequestsPerConnection is the number of lookup requests that can be in flight or waiting on a
+	// broker connection, the next ones being rejected until some complete. The rejected lookups are retried
+	// until the operation timeout when they are not specific to a broker. (Default: 50000)
+	MaxLookupRequestsPerConnection int
+
+	// SchemaCacheSize is the number of schema versions, resolved from the brokers to decode the messages, that are
+	// cached by the client for all its consumers. The least recently used ones are evicted first. (Default: 128)
+	SchemaCacheSize int
+
 	// Configure the logger used by the client.
 	// By default, a wrapped logrus.StandardLogger will be used, namely,
 	// log.NewLoggerWithLogrus(logrus.StandardLogger())
+	// Use log.DefaultNopLogger() to discard all the logs of the client.
+	// The client attaches the topic, partition and subscription of its producers and consumers to their logs as
+	// structured fields, with Logger.SubLogger and Logger.WithFields, rather than in the log messages.
 	// FIXME: use `logger` as internal field name instead of `log` as it's more idiomatic
 	Logger log.Logger
 
@@ -152,6 +215,14 @@ type ClientOptions struct {
 	// Default prometheus.DefaultRegisterer
 	MetricsRegisterer prometheus.Registerer
 
+	// MetricsSink, if set, is notified of the latency of every message sent by the producers of the client,
+	// in addition to the built-in metrics.
+	MetricsSink MetricsSink
+
+	// CompressionProviders decompress the payloads of their Type received by the consumers and readers of the
+	// client, instead of the built-in codecs, see ProducerOptions.CompressionProvider.
+	CompressionProviders []CompressionProvider
+
 	// Release the connection if it is not used for more than ConnectionMaxIdleTime.
 	// Default is 180 seconds, minimum is 60 seconds. Negative such as -1 to disable.
 	ConnectionMaxIdleTime time.Duration
@@ -161,6 +232,27 @@ type ClientOptions struct {
 	// Limit of client memory usage (in byte). The 64M default can guarantee a high producer throughput.
 	// Config less than 0 indicates off memory limit.
 	MemoryLimitBytes int64
+
+	// BufferPool provides the buffers in which the producers encode the messages sent to the brokers, which are
+	// reused once the brokers have acknowledged them to reduce the allocations and the GC pressure.
+	// Default: a pool backed by a sync.Pool, shared by the clients. See NewBufferPool.
+	BufferPool BufferPool
+}
+
+// OperationTimeouts holds per-operation overrides of ClientOptions.OperationTimeout.
+// A zero value keeps ClientOptions.OperationTimeout for the corresponding operation.
+type OperationTimeouts struct {
+	// Lookup applies to topic lookups and partitioned topic metadata requests
+	Lookup time.Duration
+
+	// CreateProducer applies to the registration of a producer on the broker
+	CreateProducer time.Duration
+
+	// Subscribe applies to the registration of a consumer or a reader on the broker
+	Subscribe time.Duration
+
+	// Ack applies to the acknowledgments waiting for a broker response (see ConsumerOptions.AckWithResponse)
+	Ack time.Duration
 }
 
 // Client represents a pulsar client
@@ -169,16 +261,52 @@ type Client interface {
 	// This method will block until the producer is created successfully
 	CreateProducer(ProducerOptions) (Producer, error)
 
+	// ValidateSchema checks, without creating a producer, that a producer with the schema can be created on the
+	// topic given the schema registered on it, if any. It returns a *SchemaIncompatibleError naming the
+	// incompatible field otherwise. The check approximates the default FULL compatibility strategy of the
+	// brokers, which remain the authority on the schemas they accept.
+	ValidateSchema(topic string, schema Schema) error
+
 	// Subscribe Creates a `Consumer` by subscribing to a topic.
 	//
 	// If the subscription does not exist, a new subscription will be created and all messages published after the
 	// creation will be retained until acknowledged, even if the consumer is not connected
 	Subscribe(ConsumerOptions) (Consumer, error)
 
+	// CreateSubscription creates the durable subscription subName on the topic, positioned on pos, without
+	// consuming from it: the first consumer of the subscription starts from pos rather than from its
+	// SubscriptionInitialPosition. pos is either EarliestMessageID(), LatestMessageID() or the id of a message,
+	// in which case the subscription starts with this message.
+	//
+	// If the subscription already exists, it is moved to pos. It fails if a consumer is connected to the
+	// subscription. The position of a subscription on a partitioned topic can only be the earliest or the
+	// latest message, the other positions must be set on each partition topic.
+	CreateSubscription(topic, subName string, pos MessageID) error
+
 	// CreateReader Creates a Reader instance.
 	// This method will block until the reader is created successfully.
 	CreateReader(ReaderOptions) (Reader, error)
 
+	// CreateReaderAtTime Creates a Reader returning a consistent snapshot of the topic at the given time: on every
+	// partition, it reads from ReaderOptions.StartMessageID (by default the earliest message) up to the last
+	// message published at or before that time, after which Next returns a StopMessageIDReached error and
+	// HasNext returns false.
+	//
+	// Only the messages already published when the reader is created are part of the snapshot. The partitions
+	// are bounded by the publish time of their messages, which is assumed to increase within a partition.
+	CreateReaderAtTime(options ReaderOptions, t time.Time) (Reader, error)
+
+	// RestoreReader creates a Reader resuming from a state saved by Reader.SaveState, which returns the messages
+	// that follow the ones returned by the reader that saved it. The topic of the options can be left empty, and
+	// the StartMessageID is only used for the partitions added to the topic since the state was saved
+	// (default: EarliestMessageID).
+	RestoreReader(options ReaderOptions, state []byte) (Reader, error)
+
+	// CreateDLQReader creates a Reader on the dead letter topic of a consumer created with the options, as
+	// returned by DLQTopicName, positioned at the earliest message. Unlike DLQTopicName, it resolves the
+	// namespace-level dead letter topic of older clients when it exists.
+	CreateDLQReader(options ConsumerOptions) (Reader, error)
+
 	// CreateTableView creates a table view instance.
 	// This method will block until the table view is created successfully.
 	CreateTableView(TableViewOptions) (TableView, error)
@@ -204,10 +332,55 @@ type Client interface {
 	// be returned.
 	NewTransaction(duration time.Duration) (Transaction, error)
 
+	// MetricsSnapshot returns the current value of the client metrics in the OpenMetrics text format.
+	//
+	// The metrics are gathered from ClientOptions.MetricsRegisterer, which must also implement
+	// prometheus.Gatherer (as the default registerer does), and include the metrics of all the clients
+	// registered with it.
+	MetricsSnapshot() (string, error)
+
+	// TopicStats returns the statistics of a topic, as reported by the broker over the binary protocol for the
+	// subscription of a consumer or a reader of this client on the topic. The statistics of all the partitions
+	// consumed are summed up.
+	//
+	// An OperationNotSupported error is returned if there is no consumer or reader on the topic, since the
+	// protocol only reports the statistics of a consumer. The publish rates are not available.
+	TopicStats(topic string) (TopicStats, error)
+
+	// NewResourceGroup returns a group creating producers, consumers and readers with this client, which are
+	// all closed together by ResourceGroup.Close.
+	NewResourceGroup() ResourceGroup
+
+	// CloseIdleConnections closes the broker connections that have not been used by any producer, consumer or
+	// pending request for at least a second, and returns how many were closed. A later operation on the same
+	// broker transparently opens a new connection.
+	//
+	// Idle connections are also closed automatically after ClientOptions.ConnectionMaxIdleTime.
+	CloseIdleConnections() int
+
+	// ConnectionStats returns the number of live broker connections, in total and by broker, and the number of
+	// reconnections since the client was created. The counters are updated as the connections open and close,
+	// so that it is cheap to call frequently. The clients sharing their connections report the same statistics.
+	ConnectionStats() ConnectionStats
+
+	// HealthCheck checks that a broker of the service URL is reachable, without creating any topic: it pings a
+	// broker over a connection of the pool, reusing an open one when possible, and fails if the broker does not
+	// answer before the context is done. With an http(s) service URL, it requests the readiness endpoint of a
+	// broker instead.
+	HealthCheck(ctx context.Context) error
+
 	// Close Closes the Client and free associated resources
 	Close()
 }
 
+// MetricsSink receives the measures of the client, to feed custom metrics collectors.
+type MetricsSink interface {
+	// ObserveSendLatency is called once the send of a message completes, successfully or not, with the
+	// time elapsed since the message was handed to Send or SendAsync.
+	// It runs on the goroutine completing the send, before the SendAsync callback, and must not block.
+	ObserveSendLatency(topic string, d time.Duration, err error)
+}
+
 // MetricsCardinality represents the specificty of labels on a per-metric basis
 type MetricsCardinality int
 
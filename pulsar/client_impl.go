@@ -18,15 +18,22 @@
 package pulsar
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar/auth"
 	"github.com/apache/pulsar-client-go/pulsar/internal"
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
 	"github.com/apache/pulsar-client-go/pulsar/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
 	"github.com/sirupsen/logrus"
 )
 
@@ -36,7 +43,11 @@ const (
 	defaultMemoryLimitBytes            = 64 * 1024 * 1024
 	defaultMemoryLimitTriggerThreshold = 0.95
 	defaultConnMaxIdleTime             = 180 * time.Second
+	defaultMaxConcurrentLookups        = 5000
+	defaultMaxLookupsPerConnection     = 50000
+	defaultSchemaCacheSize             = 128
 	minConnMaxIdleTime                 = 60 * time.Second
+	metricsNamePrefix                  = "pulsar_client_"
 )
 
 type client struct {
@@ -45,16 +56,25 @@ type client struct {
 	handlers         internal.ClientHandlers
 	lookupService    internal.LookupService
 	metrics          *internal.Metrics
+	metricsGatherer  prometheus.Gatherer
 	tcClient         *transactionCoordinatorClient
 	memLimit         internal.MemoryLimitController
+	bufferPool       BufferPool
 	closeOnce        sync.Once
 	operationTimeout time.Duration
 	tlsEnabled       bool
+	schemaCache      *schemaVersionCache
+	metricsSink      MetricsSink
+	// compressionProviders are the ClientOptions.CompressionProviders by type
+	compressionProviders map[CompressionType]CompressionProvider
+	// serviceNameResolver resolves the hosts of the service URL, httpClient is only set for an http(s) one
+	serviceNameResolver internal.ServiceNameResolver
+	httpClient          internal.HTTPClient
 
 	log log.Logger
 }
 
-func newClient(options ClientOptions) (Client, error) {
+func newClient(options ClientOptions, shared *client) (Client, error) {
 	var logger log.Logger
 	if options.Logger != nil {
 		logger = options.Logger
@@ -92,6 +112,9 @@ func newClient(options ClientOptions) (Client, error) {
 			KeyFile:                 options.TLSKeyFilePath,
 			CertFile:                options.TLSCertificateFile,
 			TrustCertsFilePath:      options.TLSTrustCertsFilePath,
+			CertPEM:                 options.TLSCertPEM,
+			KeyPEM:                  options.TLSKeyPEM,
+			TrustCertsPEM:           options.TLSTrustCertsPEM,
 			ValidateHostname:        options.TLSValidateHostname,
 			ServerName:              url.Hostname(),
 			CipherSuites:            options.TLSCipherSuites,
@@ -101,6 +124,9 @@ func newClient(options ClientOptions) (Client, error) {
 	default:
 		return nil, newError(InvalidConfiguration, fmt.Sprintf("Invalid URL scheme '%s'", url.Scheme))
 	}
+	if err := validateTLSPEMOptions(&options); err != nil {
+		return nil, err
+	}
 
 	var authProvider auth.Provider
 	var ok bool
@@ -129,10 +155,23 @@ func newClient(options ClientOptions) (Client, error) {
 	}
 
 	maxConnectionsPerHost := options.MaxConnectionsPerBroker
-	if maxConnectionsPerHost <= 0 {
+	if maxConnectionsPerHost < 0 {
+		return nil, newError(InvalidConfiguration, "MaxConnectionsPerBroker can not be negative")
+	} else if maxConnectionsPerHost == 0 {
 		maxConnectionsPerHost = 1
 	}
 
+	lookupLimits := internal.LookupLimits{
+		MaxConcurrent:    options.MaxConcurrentLookupRequests,
+		MaxPerConnection: options.MaxLookupRequestsPerConnection,
+	}
+	if lookupLimits.MaxConcurrent == 0 {
+		lookupLimits.MaxConcurrent = defaultMaxConcurrentLookups
+	}
+	if lookupLimits.MaxPerConnection == 0 {
+		lookupLimits.MaxPerConnection = defaultMaxLookupsPerConnection
+	}
+
 	if options.MetricsCardinality == 0 {
 		options.MetricsCardinality = MetricsCardinalityNamespace
 	}
@@ -150,6 +189,9 @@ func newClient(options ClientOptions) (Client, error) {
 			int(options.MetricsCardinality), map[string]string{}, options.MetricsRegisterer)
 	}
 
+	// the default registerer is also a gatherer, custom ones may not be
+	metricsGatherer, _ := options.MetricsRegisterer.(prometheus.Gatherer)
+
 	keepAliveInterval := options.KeepAliveInterval
 	if keepAliveInterval.Nanoseconds() == 0 {
 		keepAliveInterval = defaultKeepAliveInterval
@@ -160,30 +202,72 @@ func newClient(options ClientOptions) (Client, error) {
 		memLimitBytes = defaultMemoryLimitBytes
 	}
 
+	schemaCacheSize := options.SchemaCacheSize
+	if schemaCacheSize <= 0 {
+		schemaCacheSize = defaultSchemaCacheSize
+	}
+
+	var cnxPool internal.ConnectionPool
+	if shared != nil {
+		if shared.tlsEnabled != (tlsConfig != nil) {
+			return nil, newError(InvalidConfiguration, "shared connections must use the same TLS mode as the client")
+		}
+		if err := shared.cnxPool.Retain(); err != nil {
+			return nil, newError(AlreadyClosedError, "the client sharing its connections is already closed")
+		}
+		cnxPool = shared.cnxPool
+	} else {
+		cnxPool = internal.NewConnectionPool(tlsConfig, authProvider, connectionTimeout, keepAliveInterval,
+			maxConnectionsPerHost, logger, metrics, connectionMaxIdleTime)
+	}
+
 	c := &client{
-		cnxPool: internal.NewConnectionPool(tlsConfig, authProvider, connectionTimeout, keepAliveInterval,
-			maxConnectionsPerHost, logger, metrics, connectionMaxIdleTime),
+		cnxPool:          cnxPool,
 		log:              logger,
 		metrics:          metrics,
+		metricsGatherer:  metricsGatherer,
 		memLimit:         internal.NewMemoryLimitController(memLimitBytes, defaultMemoryLimitTriggerThreshold),
+		bufferPool:       options.BufferPool,
 		operationTimeout: operationTimeout,
 		tlsEnabled:       tlsConfig != nil,
+		schemaCache:      newSchemaVersionCache(schemaCacheSize),
+		metricsSink:      options.MetricsSink,
+	}
+	if c.bufferPool == nil {
+		c.bufferPool = defaultBufferPool
+	}
+	for _, provider := range options.CompressionProviders {
+		if !validCustomCompressionType(provider.Type()) {
+			return nil, newError(InvalidConfiguration, fmt.Sprintf("unsupported type %d of CompressionProvider",
+				provider.Type()))
+		}
+		if c.compressionProviders == nil {
+			c.compressionProviders = make(map[CompressionType]CompressionProvider)
+		}
+		c.compressionProviders[provider.Type()] = provider
 	}
 	serviceNameResolver := internal.NewPulsarServiceNameResolver(url)
+	c.serviceNameResolver = serviceNameResolver
 
-	c.rpcClient = internal.NewRPCClient(url, serviceNameResolver, c.cnxPool, operationTimeout, logger, metrics)
+	c.rpcClient = internal.NewRPCClient(url, serviceNameResolver, c.cnxPool, operationTimeout,
+		requestTimeouts(options.OperationTimeouts), lookupLimits, logger, metrics)
 
 	switch url.Scheme {
 	case "pulsar", "pulsar+ssl":
 		c.lookupService = internal.NewLookupService(c.rpcClient, url, serviceNameResolver,
 			c.tlsEnabled, options.ListenerName, logger, metrics)
 	case "http", "https":
+		lookupTimeout := operationTimeout
+		if options.OperationTimeouts.Lookup > 0 {
+			lookupTimeout = options.OperationTimeouts.Lookup
+		}
 		httpClient, err := internal.NewHTTPClient(url, serviceNameResolver, tlsConfig,
-			operationTimeout, logger, metrics, authProvider)
+			lookupTimeout, logger, metrics, authProvider)
 		if err != nil {
 			return nil, newError(InvalidConfiguration, fmt.Sprintf("Failed to init http client with err: '%s'",
 				err.Error()))
 		}
+		c.httpClient = httpClient
 		c.lookupService = internal.NewHTTPLookupService(httpClient, url, serviceNameResolver,
 			c.tlsEnabled, logger, metrics)
 	default:
@@ -203,6 +287,176 @@ func newClient(options ClientOptions) (Client, error) {
 	return c, nil
 }
 
+// requestTimeouts maps the per-operation timeouts to the commands they apply to
+func requestTimeouts(timeouts OperationTimeouts) map[pb.BaseCommand_Type]time.Duration {
+	commands := map[pb.BaseCommand_Type]time.Duration{
+		pb.BaseCommand_LOOKUP:               timeouts.Lookup,
+		pb.BaseCommand_PARTITIONED_METADATA: timeouts.Lookup,
+		pb.BaseCommand_PRODUCER:             timeouts.CreateProducer,
+		pb.BaseCommand_SUBSCRIBE:            timeouts.Subscribe,
+		pb.BaseCommand_ACK:                  timeouts.Ack,
+	}
+	for cmdType, timeout := range commands {
+		if timeout <= 0 {
+			delete(commands, cmdType)
+		}
+	}
+	return commands
+}
+
+// validateTLSPEMOptions checks that the in-memory TLS material is complete, valid, and not also configured
+// from files
+func validateTLSPEMOptions(options *ClientOptions) error {
+	if options.TLSTrustCertsPEM != nil {
+		if options.TLSTrustCertsFilePath != "" {
+			return newError(InvalidConfiguration, "TLSTrustCertsPEM and TLSTrustCertsFilePath are mutually exclusive")
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(options.TLSTrustCertsPEM) {
+			return newError(InvalidConfiguration, "TLSTrustCertsPEM does not hold any PEM encoded certificate")
+		}
+	}
+	if options.TLSCertPEM == nil && options.TLSKeyPEM == nil {
+		return nil
+	}
+	if options.TLSCertificateFile != "" || options.TLSKeyFilePath != "" {
+		return newError(InvalidConfiguration,
+			"TLSCertPEM and TLSKeyPEM can not be used with TLSCertificateFile and TLSKeyFilePath")
+	}
+	if options.TLSCertPEM == nil || options.TLSKeyPEM == nil {
+		return newError(InvalidConfiguration, "TLSCertPEM and TLSKeyPEM must be configured together")
+	}
+	if _, err := tls.X509KeyPair(options.TLSCertPEM, options.TLSKeyPEM); err != nil {
+		return newError(InvalidConfiguration, fmt.Sprintf("invalid TLSCertPEM and TLSKeyPEM: %v", err))
+	}
+	return nil
+}
+
+func validateClientOptions(options *ClientOptions) error {
+	if options.URL == "" {
+		return newError(InvalidConfiguration, "URL is required for client")
+	}
+
+	url, err := url.Parse(options.URL)
+	if err != nil {
+		return newError(InvalidConfiguration, fmt.Sprintf("Invalid service URL '%s'", options.URL))
+	}
+
+	var tlsEnabled bool
+	switch url.Scheme {
+	case "pulsar", "http":
+		tlsEnabled = false
+	case "pulsar+ssl", "https":
+		tlsEnabled = true
+	default:
+		return newError(InvalidConfiguration, fmt.Sprintf("Invalid URL scheme '%s'", url.Scheme))
+	}
+
+	if !tlsEnabled {
+		if options.TLSTrustCertsFilePath != "" || options.TLSCertificateFile != "" || options.TLSKeyFilePath != "" ||
+			options.TLSTrustCertsPEM != nil || options.TLSCertPEM != nil || options.TLSKeyPEM != nil {
+			return newError(InvalidConfiguration, fmt.Sprintf("TLS files are configured but the service URL "+
+				"scheme '%s' does not use TLS", url.Scheme))
+		}
+		if options.TLSAllowInsecureConnection || options.TLSValidateHostname {
+			return newError(InvalidConfiguration, fmt.Sprintf("TLS settings are configured but the service URL "+
+				"scheme '%s' does not use TLS", url.Scheme))
+		}
+	}
+
+	if (options.TLSCertificateFile == "") != (options.TLSKeyFilePath == "") {
+		return newError(InvalidConfiguration, "TLSCertificateFile and TLSKeyFilePath must be configured together")
+	}
+
+	if err := validateTLSPEMOptions(options); err != nil {
+		return err
+	}
+
+	if options.TLSAllowInsecureConnection && options.TLSValidateHostname {
+		return newError(InvalidConfiguration, "TLSAllowInsecureConnection and TLSValidateHostname "+
+			"can not be enabled together")
+	}
+
+	if options.TLSMinVersion != 0 && options.TLSMaxVersion != 0 && options.TLSMinVersion > options.TLSMaxVersion {
+		return newError(InvalidConfiguration, fmt.Sprintf("TLSMinVersion %#x is greater than TLSMaxVersion %#x",
+			options.TLSMinVersion, options.TLSMaxVersion))
+	}
+
+	if options.Authentication != nil {
+		authProvider, ok := options.Authentication.(auth.Provider)
+		if !ok {
+			return newError(AuthenticationError, "invalid auth provider interface")
+		}
+		if authProvider.Name() == "tls" && !tlsEnabled {
+			return newError(InvalidConfiguration, fmt.Sprintf("TLS authentication requires a TLS service URL, "+
+				"got scheme '%s'", url.Scheme))
+		}
+	}
+
+	if options.ConnectionMaxIdleTime > 0 && options.ConnectionMaxIdleTime < minConnMaxIdleTime {
+		return newError(InvalidConfiguration, fmt.Sprintf("Connection max idle time should be at least %f "+
+			"seconds", minConnMaxIdleTime.Seconds()))
+	}
+
+	if options.ConnectionTimeout < 0 {
+		return newError(InvalidConfiguration, "ConnectionTimeout can not be negative")
+	}
+
+	if options.OperationTimeout < 0 {
+		return newError(InvalidConfiguration, "OperationTimeout can not be negative")
+	}
+
+	if options.OperationTimeouts.Lookup < 0 || options.OperationTimeouts.CreateProducer < 0 ||
+		options.OperationTimeouts.Subscribe < 0 || options.OperationTimeouts.Ack < 0 {
+		return newError(InvalidConfiguration, "OperationTimeouts can not be negative")
+	}
+
+	if options.KeepAliveInterval < 0 {
+		return newError(InvalidConfiguration, "KeepAliveInterval can not be negative")
+	}
+
+	if options.MaxConnectionsPerBroker < 0 {
+		return newError(InvalidConfiguration, "MaxConnectionsPerBroker can not be negative")
+	}
+
+	if options.MaxConcurrentLookupRequests < 0 || options.MaxLookupRequestsPerConnection < 0 {
+		return newError(InvalidConfiguration, "MaxConcurrentLookupRequests and MaxLookupRequestsPerConnection "+
+			"can not be negative")
+	}
+
+	if options.SchemaCacheSize < 0 {
+		return newError(InvalidConfiguration, "SchemaCacheSize can not be negative")
+	}
+
+	return nil
+}
+
+func (c *client) MetricsSnapshot() (string, error) {
+	if c.metricsGatherer == nil {
+		return "", newError(OperationNotSupported, "the configured MetricsRegisterer can not gather metrics")
+	}
+	families, err := c.metricsGatherer.Gather()
+	if err != nil {
+		return "", newError(UnknownError, fmt.Sprintf("failed to gather metrics: %v", err))
+	}
+
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.FmtOpenMetrics)
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), metricsNamePrefix) {
+			continue
+		}
+		if err := encoder.Encode(family); err != nil {
+			return "", newError(UnknownError, fmt.Sprintf("failed to encode metrics: %v", err))
+		}
+	}
+	if closer, ok := encoder.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return "", newError(UnknownError, fmt.Sprintf("failed to encode metrics: %v", err))
+		}
+	}
+	return buf.String(), nil
+}
+
 func (c *client) NewTransaction(timeout time.Duration) (Transaction, error) {
 	id, err := c.tcClient.newTransaction(timeout)
 	if err != nil {
@@ -228,6 +482,60 @@ func (c *client) Subscribe(options ConsumerOptions) (Consumer, error) {
 	return consumer, nil
 }
 
+func (c *client) CreateSubscription(topic, subName string, pos MessageID) error {
+	if subName == "" {
+		return newError(InvalidConfiguration, "subscription name is required")
+	}
+	if pos == nil {
+		return newError(InvalidConfiguration, "subscription position is required")
+	}
+
+	id := &messageID{ledgerID: pos.LedgerID(), entryID: pos.EntryID(), batchIdx: pos.BatchIdx()}
+	initialPosition := SubscriptionPositionLatest
+	switch {
+	case id.equal(earliestMessageID):
+		initialPosition = SubscriptionPositionEarliest
+		pos = id
+	case id.equal(latestMessageID):
+		pos = id
+	default:
+		// a durable subscription is created on the earliest or the latest message, and then moved to pos
+		partitions, err := c.TopicPartitions(topic)
+		if err != nil {
+			return err
+		}
+		if len(partitions) > 1 {
+			return newError(InvalidConfiguration,
+				"the position of a subscription on a partitioned topic must be set on each partition")
+		}
+		if !checkMessageIDType(pos) {
+			if pos, err = deserializeMessageID(pos.Serialize()); err != nil {
+				return err
+			}
+		}
+	}
+
+	cons, err := newConsumer(c, ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            subName,
+		Type:                        Exclusive,
+		SubscriptionInitialPosition: initialPosition,
+		ReceiverQueueSize:           1,
+	})
+	if err != nil {
+		return err
+	}
+	defer cons.Close()
+
+	// the broker ignores the initial position when the subscription already exists, which is moved instead
+	for _, pc := range cons.(*consumer).consumers {
+		if err := pc.Seek(pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *client) CreateReader(options ReaderOptions) (Reader, error) {
 	reader, err := newReader(c, options)
 	if err != nil {
@@ -237,6 +545,35 @@ func (c *client) CreateReader(options ReaderOptions) (Reader, error) {
 	return reader, nil
 }
 
+func (c *client) CreateDLQReader(options ConsumerOptions) (Reader, error) {
+	topic, err := dlqTopicName(options, c.lookupService)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+}
+
+func (c *client) RestoreReader(options ReaderOptions, state []byte) (Reader, error) {
+	reader, err := restoreReader(c, options, state)
+	if err != nil {
+		return nil, err
+	}
+	c.handlers.Add(reader)
+	return reader, nil
+}
+
+func (c *client) CreateReaderAtTime(options ReaderOptions, t time.Time) (Reader, error) {
+	reader, err := newReaderAtTime(c, options, t)
+	if err != nil {
+		return nil, err
+	}
+	c.handlers.Add(reader)
+	return reader, nil
+}
+
 func (c *client) CreateTableView(options TableViewOptions) (TableView, error) {
 	tableView, err := newTableView(c, options)
 	if err != nil {
@@ -270,6 +607,51 @@ func (c *client) TopicPartitions(topic string) ([]string, error) {
 	return []string{topicName.Name}, nil
 }
 
+func (c *client) NewResourceGroup() ResourceGroup {
+	return newResourceGroup(c)
+}
+
+func (c *client) CloseIdleConnections() int {
+	return c.cnxPool.CloseIdleConnections()
+}
+
+func (c *client) HealthCheck(ctx context.Context) error {
+	// neither the pool nor the http client take a context, so give up waiting on them when it is done
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.checkBroker(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return newError(ConnectError, fmt.Sprintf("health check failed: %v", ctx.Err()))
+	}
+}
+
+func (c *client) checkBroker(ctx context.Context) error {
+	if c.httpClient != nil {
+		if err := c.httpClient.Get("/admin/v2/brokers/ready", nil, nil); err != nil {
+			return newError(ConnectError, fmt.Sprintf("health check failed: %v", err))
+		}
+		return nil
+	}
+
+	host, err := c.serviceNameResolver.ResolveHost()
+	if err != nil {
+		return newError(ConnectError, fmt.Sprintf("health check failed to resolve the service URL: %v", err))
+	}
+	cnx, err := c.cnxPool.GetConnection(host, host)
+	if err != nil {
+		return newError(ConnectError, fmt.Sprintf("health check failed to connect to %s: %v", host, err))
+	}
+	if err := cnx.Ping(ctx); err != nil {
+		return newError(ConnectError, fmt.Sprintf("health check failed to ping %s: %v", host, err))
+	}
+	return nil
+}
+
 func (c *client) Close() {
 	c.closeOnce.Do(func() {
 		c.handlers.Close()
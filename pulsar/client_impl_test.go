@@ -31,6 +31,9 @@ import (
 
 	"github.com/apache/pulsar-client-go/pulsar/auth"
 	"github.com/apache/pulsar-client-go/pulsar/internal"
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
+	plog "github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,6 +45,74 @@ func TestClient(t *testing.T) {
 	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
 }
 
+func TestValidateOptions(t *testing.T) {
+	assert.NoError(t, ValidateOptions(ClientOptions{URL: serviceURL}))
+	assert.NoError(t, ValidateOptions(ClientOptions{
+		URL:                   serviceURLTLS,
+		TLSTrustCertsFilePath: caCertsPath,
+		Authentication:        NewAuthenticationTLS(tlsClientCertPath, tlsClientKeyPath),
+	}))
+
+	invalid := []ClientOptions{
+		{},
+		{URL: "invalid://localhost:6650"},
+		{URL: serviceURL, TLSTrustCertsFilePath: caCertsPath},
+		{URL: serviceURL, TLSValidateHostname: true},
+		{URL: serviceURL, Authentication: NewAuthenticationTLS(tlsClientCertPath, tlsClientKeyPath)},
+		{URL: serviceURLTLS, TLSCertificateFile: tlsClientCertPath},
+		{URL: serviceURLTLS, TLSAllowInsecureConnection: true, TLSValidateHostname: true},
+		{URL: serviceURLTLS, TLSMinVersion: tls.VersionTLS13, TLSMaxVersion: tls.VersionTLS12},
+		{URL: serviceURL, ConnectionMaxIdleTime: time.Second},
+		{URL: serviceURL, OperationTimeout: -time.Second},
+		{URL: serviceURL, MaxConnectionsPerBroker: -1},
+	}
+	for _, options := range invalid {
+		err := ValidateOptions(options)
+		assert.Error(t, err, "options: %+v", options)
+		assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+	}
+
+	err := ValidateOptions(ClientOptions{URL: serviceURL, Authentication: "token"})
+	assert.Error(t, err)
+	assert.Equal(t, AuthenticationError, err.(*Error).Result())
+}
+
+func TestValidateOptionsTLSPEM(t *testing.T) {
+	caCerts, err := os.ReadFile(caCertsPath)
+	require.NoError(t, err)
+	cert, err := os.ReadFile(tlsClientCertPath)
+	require.NoError(t, err)
+	key, err := os.ReadFile(tlsClientKeyPath)
+	require.NoError(t, err)
+
+	assert.NoError(t, ValidateOptions(ClientOptions{
+		URL:              serviceURLTLS,
+		TLSTrustCertsPEM: caCerts,
+		TLSCertPEM:       cert,
+		TLSKeyPEM:        key,
+	}))
+
+	invalid := []ClientOptions{
+		{URL: serviceURL, TLSTrustCertsPEM: caCerts},
+		{URL: serviceURLTLS, TLSTrustCertsPEM: caCerts, TLSTrustCertsFilePath: caCertsPath},
+		{URL: serviceURLTLS, TLSTrustCertsPEM: []byte("not a certificate")},
+		{URL: serviceURLTLS, TLSCertPEM: cert},
+		{URL: serviceURLTLS, TLSCertPEM: cert, TLSKeyPEM: key, TLSCertificateFile: tlsClientCertPath,
+			TLSKeyFilePath: tlsClientKeyPath},
+		{URL: serviceURLTLS, TLSCertPEM: cert, TLSKeyPEM: caCerts},
+	}
+	for _, options := range invalid {
+		err := ValidateOptions(options)
+		assert.Error(t, err, "options: %+v", options)
+		assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+		if options.URL == serviceURLTLS {
+			_, err = NewClient(options)
+			assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+		}
+	}
+}
+
 func TestTLSConnectionCAError(t *testing.T) {
 	client, err := NewClient(ClientOptions{
 		URL:              serviceURLTLS,
@@ -95,6 +166,26 @@ func TestTLSConnection(t *testing.T) {
 	client.Close()
 }
 
+func TestTLSConnectionPEM(t *testing.T) {
+	caCerts, err := os.ReadFile(caCertsPath)
+	require.NoError(t, err)
+
+	client, err := NewClient(ClientOptions{
+		URL:              serviceURLTLS,
+		TLSTrustCertsPEM: caCerts,
+	})
+	assert.NoError(t, err)
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: newTopicName(),
+	})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, producer)
+
+	client.Close()
+}
+
 func TestTLSConnectionHostNameVerification(t *testing.T) {
 	client, err := NewClient(ClientOptions{
 		URL:                   serviceURLTLS,
@@ -282,13 +373,9 @@ func mockOAuthServer() *httptest.Server {
 	return server
 }
 
-// mockKeyFile will mock a temp key file for testing.
-func mockKeyFile(server string) (string, error) {
-	pwd, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-	kf, err := os.CreateTemp(pwd, "test_oauth2")
+// mockKeyFile will mock a temp key file in dir for testing.
+func mockKeyFile(dir, server string) (string, error) {
+	kf, err := os.CreateTemp(dir, "test_oauth2")
 	if err != nil {
 		return "", err
 	}
@@ -309,8 +396,7 @@ func mockKeyFile(server string) (string, error) {
 func TestOAuth2Auth(t *testing.T) {
 	server := mockOAuthServer()
 	defer server.Close()
-	kf, err := mockKeyFile(server.URL)
-	defer os.Remove(kf)
+	kf, err := mockKeyFile(t.TempDir(), server.URL)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -890,8 +976,7 @@ func TestHTTPSTokenAuthFromFile(t *testing.T) {
 func TestHTTPOAuth2Auth(t *testing.T) {
 	server := mockOAuthServer()
 	defer server.Close()
-	kf, err := mockKeyFile(server.URL)
-	defer os.Remove(kf)
+	kf, err := mockKeyFile(t.TempDir(), server.URL)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -924,8 +1009,7 @@ func TestHTTPOAuth2Auth(t *testing.T) {
 func TestHTTPSOAuth2Auth(t *testing.T) {
 	server := mockOAuthServer()
 	defer server.Close()
-	kf, err := mockKeyFile(server.URL)
-	defer os.Remove(kf)
+	kf, err := mockKeyFile(t.TempDir(), server.URL)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -960,8 +1044,7 @@ func TestHTTPSOAuth2Auth(t *testing.T) {
 func TestHTTPOAuth2AuthFailed(t *testing.T) {
 	server := mockOAuthServer()
 	defer server.Close()
-	kf, err := mockKeyFile(server.URL)
-	defer os.Remove(kf)
+	_, err := mockKeyFile(t.TempDir(), server.URL)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1190,3 +1273,427 @@ func TestMultipleCloseClient(t *testing.T) {
 	client.Close()
 	client.Close()
 }
+
+func TestClientWithSharedConnections(t *testing.T) {
+	cli1, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.Nil(t, err)
+
+	cli2, err := NewClientWithSharedConnections(ClientOptions{
+		URL:              serviceURL,
+		OperationTimeout: 10 * time.Second,
+	}, cli1)
+	assert.Nil(t, err)
+	defer cli2.Close()
+
+	pool := cli1.(*client).cnxPool
+	assert.Equal(t, pool, cli2.(*client).cnxPool)
+
+	topic := newTopicName()
+	consumer, err := cli2.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	producer, err := cli1.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+
+	testSendAndReceive(t, producer, consumer)
+	connections := internal.GetConnectionsCount(&pool)
+	assert.NotEqual(t, 0, connections)
+
+	// the connections must survive the first client being closed
+	producer.Close()
+	cli1.Close()
+	assert.Equal(t, connections, internal.GetConnectionsCount(&pool))
+
+	producer, err = cli2.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+	testSendAndReceive(t, producer, consumer)
+
+	_, err = NewClientWithSharedConnections(ClientOptions{URL: serviceURLTLS}, cli2)
+	assert.NotNil(t, err)
+}
+
+func TestClientMetricsSnapshot(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	cli, err := NewClient(ClientOptions{
+		URL:               serviceURL,
+		MetricsRegisterer: registry,
+	})
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	cli.(*client).metrics.ConnectionsOpened.Inc()
+
+	snapshot, err := cli.MetricsSnapshot()
+	assert.Nil(t, err)
+	assert.Contains(t, snapshot, "pulsar_client_connections_opened")
+	assert.True(t, strings.HasSuffix(snapshot, "# EOF\n"))
+
+	wrapped, err := NewClient(ClientOptions{
+		URL:               serviceURL,
+		MetricsRegisterer: prometheus.WrapRegistererWithPrefix("app_", registry),
+	})
+	assert.Nil(t, err)
+	defer wrapped.Close()
+
+	_, err = wrapped.MetricsSnapshot()
+	assert.Equal(t, OperationNotSupported, err.(*Error).Result())
+}
+
+func TestOperationTimeouts(t *testing.T) {
+	timeouts := requestTimeouts(OperationTimeouts{
+		Lookup: 5 * time.Second,
+		Ack:    time.Second,
+	})
+	assert.Equal(t, map[pb.BaseCommand_Type]time.Duration{
+		pb.BaseCommand_LOOKUP:               5 * time.Second,
+		pb.BaseCommand_PARTITIONED_METADATA: 5 * time.Second,
+		pb.BaseCommand_ACK:                  time.Second,
+	}, timeouts)
+
+	err := ValidateOptions(ClientOptions{
+		URL:               serviceURL,
+		OperationTimeouts: OperationTimeouts{Subscribe: -time.Second},
+	})
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+}
+
+func TestClientCloseIdleConnections(t *testing.T) {
+	cli, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	topic := newTopicName()
+	producer, err := cli.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+
+	// the connection is in use by the producer
+	assert.Equal(t, 0, cli.CloseIdleConnections())
+
+	producer.Close()
+	// the connections are only closed once idle for a while
+	assert.Equal(t, 0, cli.CloseIdleConnections())
+	time.Sleep(1100 * time.Millisecond)
+	assert.NotEqual(t, 0, cli.CloseIdleConnections())
+	pool := cli.(*client).cnxPool
+	assert.Equal(t, 0, internal.GetConnectionsCount(&pool))
+
+	// a new connection is opened on demand
+	producer, err = cli.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+	_, err = producer.Send(context.Background(), &ProducerMessage{Payload: []byte("hello")})
+	assert.Nil(t, err)
+}
+
+func TestClientTopicStats(t *testing.T) {
+	cli, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	topic := newTopicName()
+	_, err = cli.TopicStats(topic)
+	assert.Equal(t, OperationNotSupported, err.(*Error).Result())
+
+	consumer, err := cli.Subscribe(ConsumerOptions{
+		Topic:             topic,
+		SubscriptionName:  "my-sub",
+		ReceiverQueueSize: 1,
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	producer, err := cli.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(context.Background(), &ProducerMessage{Payload: []byte("hello")})
+		assert.Nil(t, err)
+	}
+
+	stats, err := cli.TopicStats(topic)
+	assert.Nil(t, err)
+	assert.Equal(t, "my-sub", stats.Subscription)
+	assert.Equal(t, uint64(10), stats.MsgBacklog)
+}
+
+func TestClientResourceGroup(t *testing.T) {
+	cli, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	topic := newTopicName()
+	group := cli.NewResourceGroup()
+
+	producer, err := group.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+
+	cons, err := group.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+	})
+	assert.Nil(t, err)
+
+	rdr, err := group.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, group.Close())
+
+	_, err = producer.Send(context.Background(), &ProducerMessage{Payload: []byte("hello")})
+	assert.ErrorIs(t, err, ErrProducerClosed)
+	assert.Equal(t, consumerClosed, cons.(*consumer).consumers[0].getConsumerState())
+	assert.Equal(t, consumerClosed, rdr.(*reader).c.consumers[0].getConsumerState())
+
+	// closing twice is a no-op and the group no longer creates resources
+	assert.Nil(t, group.Close())
+	_, err = group.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.ErrorIs(t, err, ErrResourceGroupClosed)
+}
+
+func TestClientCreateSubscription(t *testing.T) {
+	cli, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := cli.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	var ids []MessageID
+	for i := 0; i < 5; i++ {
+		id, err := producer.Send(ctx, &ProducerMessage{Payload: []byte(fmt.Sprintf("msg-%d", i))})
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+
+	assert.Nil(t, cli.CreateSubscription(topic, "sub-earliest", EarliestMessageID()))
+	assert.Nil(t, cli.CreateSubscription(topic, "sub-at-id", ids[2]))
+
+	for sub, expected := range map[string]string{"sub-earliest": "msg-0", "sub-at-id": "msg-2"} {
+		// the initial position of the consumer is ignored since the subscription exists
+		cons, err := cli.Subscribe(ConsumerOptions{
+			Topic:                       topic,
+			SubscriptionName:            sub,
+			SubscriptionInitialPosition: SubscriptionPositionLatest,
+		})
+		assert.Nil(t, err)
+
+		receiveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		msg, err := cons.Receive(receiveCtx)
+		cancel()
+		if assert.Nil(t, err) {
+			assert.Equal(t, expected, string(msg.Payload()))
+		}
+		cons.Close()
+	}
+
+	// an existing subscription is moved to the earliest or the latest message too
+	receiveFirst := func(sub string) (Message, error) {
+		cons, err := cli.Subscribe(ConsumerOptions{
+			Topic:            topic,
+			SubscriptionName: sub,
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer cons.Close()
+		receiveCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		return cons.Receive(receiveCtx)
+	}
+	assert.Nil(t, cli.CreateSubscription(topic, "sub-at-id", LatestMessageID()))
+	_, err = receiveFirst("sub-at-id")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, cli.CreateSubscription(topic, "sub-at-id", EarliestMessageID()))
+	msg, err := receiveFirst("sub-at-id")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "msg-0", string(msg.Payload()))
+	}
+
+	err = cli.CreateSubscription(topic, "", EarliestMessageID())
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	partitionedTopic := newTopicName()
+	assert.Nil(t, createPartitionedTopic(partitionedTopic, 2))
+	err = cli.CreateSubscription(partitionedTopic, "my-sub", ids[0])
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+	assert.Nil(t, cli.CreateSubscription(partitionedTopic, "my-sub", LatestMessageID()))
+}
+
+func TestClientHealthCheck(t *testing.T) {
+	cli, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	producer, err := cli.CreateProducer(ProducerOptions{
+		Topic: newTopicName(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	// the connection of the producer is reused
+	pool := cli.(*client).cnxPool
+	count := internal.GetConnectionsCount(&pool)
+	assert.NoError(t, cli.HealthCheck(context.Background()))
+	assert.NoError(t, cli.HealthCheck(context.Background()))
+	assert.Equal(t, count, internal.GetConnectionsCount(&pool))
+}
+
+func TestClientHealthCheckUnreachable(t *testing.T) {
+	cli, err := NewClient(ClientOptions{
+		URL:               "pulsar://localhost:6666",
+		ConnectionTimeout: time.Second,
+	})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = cli.HealthCheck(ctx)
+	var e *Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, ConnectError, e.Result())
+
+	// a done context fails the check at once
+	cancel()
+	assert.Error(t, cli.HealthCheck(ctx))
+}
+
+func TestClientHealthCheckHTTP(t *testing.T) {
+	ready := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/v2/brokers/ready" || !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cli, err := NewClient(ClientOptions{
+		URL: server.URL,
+	})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	assert.NoError(t, cli.HealthCheck(context.Background()))
+
+	ready = false
+	assert.Error(t, cli.HealthCheck(context.Background()))
+}
+
+func TestClientConnectionStats(t *testing.T) {
+	cli, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	assert.Equal(t, 0, cli.ConnectionStats().Connections)
+
+	topic := newTopicName()
+	producer, err := cli.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	require.NoError(t, err)
+	stats := cli.ConnectionStats()
+	assert.NotEqual(t, 0, stats.Connections)
+	assert.Equal(t, stats.Connections, stats.ConnectionsPerBroker["localhost:6650"])
+	assert.Equal(t, uint64(0), stats.Reconnects)
+
+	// the connections closed by the client are not reconnections
+	producer.Close()
+	time.Sleep(1100 * time.Millisecond)
+	closed := cli.CloseIdleConnections()
+	assert.Equal(t, stats.Connections-closed, cli.ConnectionStats().Connections)
+
+	producer, err = cli.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+	assert.Equal(t, uint64(0), cli.ConnectionStats().Reconnects)
+}
+
+func TestClientMaxConnectionsPerBroker(t *testing.T) {
+	_, err := NewClient(ClientOptions{
+		URL:                     serviceURL,
+		MaxConnectionsPerBroker: -1,
+	})
+	assert.Error(t, err)
+
+	cli, err := NewClient(ClientOptions{
+		URL:                     serviceURL,
+		MaxConnectionsPerBroker: 3,
+	})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	// the producers are spread over the connections of the broker
+	for i := 0; i < 6; i++ {
+		producer, err := cli.CreateProducer(ProducerOptions{
+			Topic: newTopicName(),
+		})
+		require.NoError(t, err)
+		defer producer.Close()
+	}
+	assert.Equal(t, 3, cli.ConnectionStats().ConnectionsPerBroker["localhost:6650"])
+}
+
+func TestClientNopLogger(t *testing.T) {
+	logger := plog.DefaultNopLogger()
+	cli, err := NewClient(ClientOptions{
+		URL:    serviceURL,
+		Logger: logger,
+	})
+	require.NoError(t, err)
+	defer cli.Close()
+	assert.Equal(t, logger, cli.(*client).log)
+
+	// the logs are dropped without allocating
+	allocs := testing.AllocsPerRun(100, func() {
+		logger.SubLogger(nil).WithField("topic", "my-topic").Debug("dropped")
+	})
+	assert.Equal(t, float64(0), allocs)
+}
@@ -0,0 +1,77 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"github.com/apache/pulsar-client-go/pulsar/internal/compression"
+)
+
+// CompressionProvider is a custom codec of the message payloads, e.g. Zstd with a dictionary. The producers
+// compress their payloads with the provider set in ProducerOptions.CompressionProvider and tag them with its Type,
+// while the consumers and readers decompress the payloads of this type with the provider registered in
+// ClientOptions.CompressionProviders, instead of the built-in codec.
+type CompressionProvider interface {
+	// Compress returns the compressed content of src
+	Compress(src []byte) []byte
+
+	// Decompress returns the decompressed content of src, which was compressed from uncompressedSize bytes
+	Decompress(src []byte, uncompressedSize int) ([]byte, error)
+
+	// Type returns the compression type the payloads are tagged with, one of LZ4, ZLib or ZSTD
+	Type() CompressionType
+}
+
+// customCompressionProvider adapts a CompressionProvider to the internal providers
+type customCompressionProvider struct {
+	provider CompressionProvider
+}
+
+func (c customCompressionProvider) CompressMaxSize(originalSize int) int {
+	// unknown, only used to size the destination buffers which Compress grows as needed
+	return originalSize
+}
+
+func (c customCompressionProvider) Compress(dst, src []byte) []byte {
+	compressed := c.provider.Compress(src)
+	if dst == nil {
+		return compressed
+	}
+	return append(dst[:0], compressed...)
+}
+
+func (c customCompressionProvider) Decompress(dst, src []byte, originalSize int) ([]byte, error) {
+	decompressed, err := c.provider.Decompress(src, originalSize)
+	if err != nil || dst == nil {
+		return decompressed, err
+	}
+	return append(dst[:0], decompressed...), nil
+}
+
+func (c customCompressionProvider) Clone() compression.Provider {
+	return c
+}
+
+func (c customCompressionProvider) Close() error {
+	// the provider belongs to the application
+	return nil
+}
+
+// validCustomCompressionType reports whether a CompressionProvider can tag the payloads with the type
+func validCustomCompressionType(t CompressionType) bool {
+	return t == LZ4 || t == ZLib || t == ZSTD
+}
@@ -0,0 +1,125 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCompressionMagic = []byte("dict")
+
+// testCompressionProvider tags the payloads as ZSTD but only prefixes them with a magic
+type testCompressionProvider struct{}
+
+func (testCompressionProvider) Compress(src []byte) []byte {
+	return append(append([]byte{}, testCompressionMagic...), src...)
+}
+
+func (testCompressionProvider) Decompress(src []byte, uncompressedSize int) ([]byte, error) {
+	if !bytes.HasPrefix(src, testCompressionMagic) || len(src)-len(testCompressionMagic) != uncompressedSize {
+		return nil, errors.New("not compressed by testCompressionProvider")
+	}
+	return src[len(testCompressionMagic):], nil
+}
+
+func (testCompressionProvider) Type() CompressionType {
+	return ZSTD
+}
+
+type noCompressionProvider struct {
+	testCompressionProvider
+}
+
+func (noCompressionProvider) Type() CompressionType {
+	return NoCompression
+}
+
+func TestCustomCompressionProvider(t *testing.T) {
+	provider := customCompressionProvider{provider: testCompressionProvider{}}
+	compressed := provider.Compress(make([]byte, 0, 16), []byte("hello"))
+	assert.Equal(t, []byte("dicthello"), compressed)
+
+	decompressed, err := provider.Decompress(nil, compressed, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), decompressed)
+	_, err = provider.Decompress(nil, []byte("hello"), 5)
+	assert.Error(t, err)
+	assert.NoError(t, provider.Close())
+}
+
+func TestCompressionProviderInvalidType(t *testing.T) {
+	_, err := NewClient(ClientOptions{
+		URL:                  lookupURL,
+		CompressionProviders: []CompressionProvider{noCompressionProvider{}},
+	})
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, InvalidConfiguration, e.Result())
+
+	client, err := NewClient(ClientOptions{URL: lookupURL})
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.CreateProducer(ProducerOptions{
+		Topic:               newTopicName(),
+		CompressionProvider: noCompressionProvider{},
+	})
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, InvalidConfiguration, e.Result())
+}
+
+func TestProducerConsumerCompressionProvider(t *testing.T) {
+	for _, disableBatching := range []bool{false, true} {
+		client, err := NewClient(ClientOptions{
+			URL:                  lookupURL,
+			CompressionProviders: []CompressionProvider{testCompressionProvider{}},
+		})
+		require.NoError(t, err)
+
+		topic := newTopicName()
+		consumer, err := client.Subscribe(ConsumerOptions{
+			Topic:            topic,
+			SubscriptionName: "my-sub",
+		})
+		require.NoError(t, err)
+
+		producer, err := client.CreateProducer(ProducerOptions{
+			Topic:               topic,
+			DisableBatching:     disableBatching,
+			CompressionProvider: testCompressionProvider{},
+		})
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		_, err = producer.Send(ctx, &ProducerMessage{Payload: []byte("hello")})
+		require.NoError(t, err)
+
+		msg, err := consumer.Receive(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("hello"), msg.Payload())
+
+		producer.Close()
+		consumer.Close()
+		client.Close()
+	}
+}
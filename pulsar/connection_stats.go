@@ -0,0 +1,40 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+// ConnectionStats holds the statistics of the broker connections of a client, see Client.ConnectionStats.
+type ConnectionStats struct {
+	// Connections is the number of live connections, established with a broker and not closed yet
+	Connections int
+
+	// ConnectionsPerBroker is the number of live connections by broker host, e.g. "localhost:6650"
+	ConnectionsPerBroker map[string]int
+
+	// Reconnects is the number of connections opened to a broker to replace a closed one, e.g. after the
+	// broker dropped a connection, since the client was created
+	Reconnects uint64
+}
+
+func (c *client) ConnectionStats() ConnectionStats {
+	stats := c.cnxPool.Stats()
+	return ConnectionStats{
+		Connections:          stats.Connections,
+		ConnectionsPerBroker: stats.ConnectionsPerBroker,
+		Reconnects:           stats.Reconnects,
+	}
+}
@@ -130,6 +130,13 @@ type ConsumerOptions struct {
 	// Default is `Exclusive`
 	Type SubscriptionType
 
+	// PriorityLevel sets the priority of the consumer on Shared and Failover subscriptions. 0 is the highest
+	// priority: on a Shared subscription the broker dispatches the messages to the consumers with the highest
+	// priority as long as they have permits, and only then to the lower priority ones. On a Failover subscription
+	// of a partitioned topic, the active consumer of each partition is chosen among the highest priority ones.
+	// Default is 0
+	PriorityLevel int
+
 	// SubscriptionInitialPosition is the initial position at which the cursor will be set when subscribe
 	// Default is `Latest`
 	SubscriptionInitialPosition
@@ -137,6 +144,14 @@ type ConsumerOptions struct {
 	// EventListener will be called when active consumer changed (in failover subscription type)
 	EventListener ConsumerEventListener
 
+	// OnAssignmentChanged is called when the broker assigns a partition to this consumer, or revokes it, on a
+	// Failover subscription, with the names of the revoked and assigned partitions, e.g. to commit or roll back
+	// the in-flight work of the revoked partitions. Their unacknowledged messages are redelivered to the newly
+	// active consumer.
+	// The callback is invoked on the connection goroutine and should return quickly. The key ranges of a KeyShared
+	// subscription are not reported, since the broker does not notify their reassignment.
+	OnAssignmentChanged func(revoked, assigned []string)
+
 	// DLQ represents the configuration for Dead Letter Queue consumer policy.
 	// eg. route the message to topic X after N failed attempts at processing it
 	// By default is nil and there's no DLQ
@@ -163,6 +178,8 @@ type ConsumerOptions struct {
 	// EnableAutoScaledReceiverQueueSize, if enabled, the consumer receive queue will be auto-scaled
 	// by the consumer actual throughput. The ReceiverQueueSize will be the maximum size which consumer
 	// receive queue can be scaled.
+	// The queue starts with a single message and doubles as the application drains it. It is halved when the
+	// client memory limit is reached, or when the application does not receive any message for 30 seconds.
 	// Default is false.
 	EnableAutoScaledReceiverQueueSize bool
 
@@ -211,8 +228,9 @@ type ConsumerOptions struct {
 	// NackBackoffPolicy is a redelivery backoff mechanism which we can achieve redelivery with different
 	// delays according to the number of times the message is retried.
 	//
-	// > Notice: the NackBackoffPolicy will not work with `consumer.NackID(MessageID)`
-	// > because we are not able to get the redeliveryCount from the message ID.
+	// With `consumer.NackID(MessageID)`, the redelivery count is the one the message had when it was delivered
+	// by Receive or Chan. The ids of the messages not delivered by this consumer are delayed by
+	// NackBackoffPolicy.Next(1).
 	NackBackoffPolicy NackBackoffPolicy
 
 	// AckWithResponse is a return value added to Ack Command, and its purpose is to confirm whether Ack Command
@@ -222,6 +240,23 @@ type ConsumerOptions struct {
 	// Default: false
 	AckWithResponse bool
 
+	// AckStore persists the acknowledgments of the consumer before they are sent to the broker. The messages
+	// redelivered by the broker whose acknowledgment was persisted, e.g. because the application crashed before
+	// the broker persisted the acknowledgment, are acknowledged again instead of being delivered to the application.
+	// A failure to persist an acknowledgment is logged, and the acknowledgment is still sent to the broker.
+	// See NewFileAckStore for a store backed by a local file. (default: nil, the acknowledgments are not persisted)
+	AckStore AckStore
+
+	// OnOldUnacked is called when the oldest message dispatched to the application but not acknowledged yet
+	// becomes older than OldUnackedThreshold, with its age, e.g. to detect a stuck message handler. It is called
+	// once per message, checked every second, and separately for each topic of a multi-topic consumer.
+	// A nacked message is no longer considered unacknowledged, until it is redelivered. (default: nil)
+	OnOldUnacked func(age time.Duration)
+
+	// OldUnackedThreshold sets the age above which OnOldUnacked is called, it must be positive when
+	// OnOldUnacked is set.
+	OldUnackedThreshold time.Duration
+
 	// MaxPendingChunkedMessage sets the maximum pending chunked messages. (default: 100)
 	MaxPendingChunkedMessage int
 
@@ -232,6 +267,14 @@ type ConsumerOptions struct {
 	// be removed (e.g.the chunked message pending queue is full). (default: false)
 	AutoAckIncompleteChunk bool
 
+	// LazyChunkedPayloadDecompression sets whether the payload of compressed chunked messages is only decompressed
+	// while it is read through `Message.PayloadReader()`, instead of being decompressed in memory before delivery, so
+	// that the uncompressed payload is never held in memory as a whole. `Message.Payload()` returns nil for these
+	// messages, and their payload reader must be closed when it is not read to the end. This does not stream the
+	// chunks: they are still received and reassembled in memory, in their compressed form, before the message is
+	// delivered. Uncompressed and LZ4 payloads are delivered as usual. (default: false)
+	LazyChunkedPayloadDecompression bool
+
 	// Enable or disable batch index acknowledgment. To enable this feature, ensure batch index acknowledgment
 	// is enabled on the broker side. (default: false)
 	EnableBatchIndexAcknowledgment bool
@@ -251,8 +294,31 @@ type ConsumerOptions struct {
 	// Default is `false` and the consumer will start from the "next" message
 	StartMessageIDInclusive bool
 
+	// StopAtMessageID bounds the consumption: once the message with this id (or any message after it) has been
+	// received, Receive returns an error with the StopMessageIDReached result instead of waiting for more messages.
+	// The id may be the one of a whole entry, in which case the consumption stops after the last message of the
+	// batch stored in this entry. Messages already received past the stop position are dropped, and logged, without
+	// being acknowledged: they remain on the subscription and are redelivered once the consumer is closed.
+	// It is only supported for a single non-partitioned topic (or a single partition), and is only enforced by
+	// Receive, not by the channel returned by Chan. Default is nil, the consumer keeps tailing the topic.
+	StopAtMessageID MessageID
+
 	// startMessageID specifies the message id to start from. Currently, it's only used for the reader internally.
 	startMessageID *trackingMessageID
+
+	// startMessageTime, if set, makes the reader start at the first message published at or after this time.
+	startMessageTime time.Time
+
+	// startPositions overrides startMessageID and StartMessageIDInclusive on some partitions, for the readers
+	// restored from a saved state.
+	startPositions map[int]startPosition
+
+	// metadataOnly discards the payload of the messages once their metadata is parsed, for the readers only.
+	metadataOnly bool
+
+	// maxReceiverQueueSizeBytes, if positive, stops the flow of messages while the receive queue holds this many
+	// payload bytes, for the readers only.
+	maxReceiverQueueSizeBytes int
 }
 
 // Consumer is an interface that abstracts behavior of Pulsar's consumer
@@ -293,11 +359,23 @@ type Consumer interface {
 	// the provided message, identified by its MessageID
 	AckIDCumulative(msgID MessageID) error
 
-	// ReconsumeLater mark a message for redelivery after custom delay
-	ReconsumeLater(msg Message, delay time.Duration)
+	// AckAllFromTopic cumulatively acks, on each partition of the given topic (or on the given partition only),
+	// the last message returned by Receive. It is mostly useful on a multi-topic consumer, when one of the
+	// topics reaches a checkpoint independently of the others.
+	// The messages consumed through Chan are not tracked, and like AckCumulative it is not allowed on the
+	// Shared and KeyShared subscription types.
+	AckAllFromTopic(topic string) error
+
+	// ReconsumeLater mark a message for redelivery after custom delay.
+	//
+	// The message is republished to the retry letter topic with an incremented reconsume count, and the
+	// original message is acked once the republish succeeds (or nacked if it fails). After
+	// DLQPolicy.MaxDeliveries attempts, the message is routed to the dead letter topic instead.
+	// It requires ConsumerOptions.RetryEnable, otherwise an InvalidConfiguration error is returned.
+	ReconsumeLater(msg Message, delay time.Duration) error
 
 	// ReconsumeLaterWithCustomProperties mark a message for redelivery after custom delay with custom properties
-	ReconsumeLaterWithCustomProperties(msg Message, customProperties map[string]string, delay time.Duration)
+	ReconsumeLaterWithCustomProperties(msg Message, customProperties map[string]string, delay time.Duration) error
 
 	// Nack acknowledges the failure to process a single message.
 	//
@@ -317,6 +395,11 @@ type Consumer interface {
 	// This call is not blocking.
 	NackID(MessageID)
 
+	// OldestUnackedAge returns the time elapsed since the oldest message dispatched to the application but not
+	// acknowledged yet was dispatched, or zero if all the messages dispatched were acknowledged.
+	// It is a signal of stuck processing that the count of unacknowledged messages can miss.
+	OldestUnackedAge() time.Duration
+
 	// Close the consumer and stop the broker to push more messages
 	Close()
 
@@ -29,7 +29,7 @@ import (
 	"github.com/apache/pulsar-client-go/pulsar/internal"
 	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
 	"github.com/apache/pulsar-client-go/pulsar/log"
-	pkgerrors "github.com/pkg/errors"
+	uAtomic "go.uber.org/atomic"
 )
 
 const defaultNackRedeliveryDelay = 1 * time.Minute
@@ -63,6 +63,7 @@ type consumer struct {
 	closeCh       chan struct{}
 	errorCh       chan error
 	stopDiscovery func()
+	stopReached   uAtomic.Bool
 
 	log     log.Logger
 	metrics *internal.LeveledMetrics
@@ -107,6 +108,18 @@ func newConsumer(client *client, options ConsumerOptions) (Consumer, error) {
 		options.NackBackoffPolicy = new(defaultNackBackoffPolicy)
 	}
 
+	if options.PriorityLevel < 0 {
+		return nil, newError(InvalidConfiguration, "PriorityLevel can not be negative")
+	}
+
+	if options.OnOldUnacked != nil && options.OldUnackedThreshold <= 0 {
+		return nil, newError(InvalidConfiguration, "OldUnackedThreshold must be positive when OnOldUnacked is set")
+	}
+
+	if options.StopAtMessageID != nil && (len(options.Topics) > 1 || options.TopicsPattern != "") {
+		return nil, newError(InvalidConfiguration, "StopAtMessageID is only supported for a single topic")
+	}
+
 	// did the user pass in a message channel?
 	messageCh := options.MessageChannel
 	if options.MessageChannel == nil {
@@ -114,35 +127,13 @@ func newConsumer(client *client, options ConsumerOptions) (Consumer, error) {
 	}
 
 	if options.RetryEnable {
-		usingTopic := ""
-		if options.Topic != "" {
-			usingTopic = options.Topic
-		} else if len(options.Topics) > 0 {
-			usingTopic = options.Topics[0]
-		}
-		tn, err := internal.ParseTopicName(usingTopic)
+		retryTopic, err := retryLetterTopicName(options, RetryTopicSuffix, client.lookupService)
 		if err != nil {
 			return nil, err
 		}
-
-		topicName := internal.TopicNameWithoutPartitionPart(tn)
-
-		retryTopic := topicName + "-" + options.SubscriptionName + RetryTopicSuffix
-		dlqTopic := topicName + "-" + options.SubscriptionName + DlqTopicSuffix
-
-		oldRetryTopic := tn.Domain + "://" + tn.Namespace + "/" + options.SubscriptionName + RetryTopicSuffix
-		oldDlqTopic := tn.Domain + "://" + tn.Namespace + "/" + options.SubscriptionName + DlqTopicSuffix
-
-		if r, err := client.lookupService.GetPartitionedTopicMetadata(oldRetryTopic); err == nil &&
-			r != nil &&
-			r.Partitions > 0 {
-			retryTopic = oldRetryTopic
-		}
-
-		if r, err := client.lookupService.GetPartitionedTopicMetadata(oldDlqTopic); err == nil &&
-			r != nil &&
-			r.Partitions > 0 {
-			dlqTopic = oldDlqTopic
+		dlqTopic, err := retryLetterTopicName(options, DlqTopicSuffix, client.lookupService)
+		if err != nil {
+			return nil, err
 		}
 
 		if options.DLQ == nil {
@@ -238,6 +229,16 @@ func newConsumer(client *client, options ConsumerOptions) (Consumer, error) {
 func newInternalConsumer(client *client, options ConsumerOptions, topic string,
 	messageCh chan ConsumerMessage, dlq *dlqRouter, rlq *retryRouter, disableForceTopicCreation bool) (*consumer, error) {
 
+	if d := options.Decryption; d != nil && d.ConsumerCryptoFailureAction == crypto.ConsumerCryptoFailureActionCallback &&
+		d.CryptoFailureHandler == nil {
+		return nil, newError(InvalidConfiguration,
+			"CryptoFailureHandler is required by the ConsumerCryptoFailureActionCallback action")
+	}
+
+	logger := client.log.SubLogger(log.Fields{
+		"topic":        topic,
+		"subscription": options.SubscriptionName,
+	})
 	consumer := &consumer{
 		topic:                     topic,
 		client:                    client,
@@ -248,7 +249,7 @@ func newInternalConsumer(client *client, options ConsumerOptions, topic string,
 		errorCh:                   make(chan error),
 		dlq:                       dlq,
 		rlq:                       rlq,
-		log:                       client.log.SubLogger(log.Fields{"topic": topic}),
+		log:                       logger,
 		consumerName:              options.Name,
 		metrics:                   client.metrics.GetLeveledMetrics(topic),
 	}
@@ -258,6 +259,11 @@ func newInternalConsumer(client *client, options ConsumerOptions, topic string,
 		return nil, err
 	}
 
+	if options.StopAtMessageID != nil && len(consumer.consumers) > 1 {
+		consumer.Close()
+		return nil, newError(InvalidConfiguration, "StopAtMessageID is not supported for partitioned topics")
+	}
+
 	// set up timer to monitor for new partitions being added
 	duration := options.AutoDiscoveryPeriod
 	if duration <= 0 {
@@ -265,6 +271,10 @@ func newInternalConsumer(client *client, options ConsumerOptions, topic string,
 	}
 	consumer.stopDiscovery = consumer.runBackgroundPartitionDiscovery(duration)
 
+	if options.OnOldUnacked != nil {
+		go consumer.oldUnackedMonitor()
+	}
+
 	consumer.metrics.ConsumersOpened.Inc()
 	return consumer, nil
 }
@@ -372,36 +382,48 @@ func (c *consumer) internalTopicSubscribeToPartitions() error {
 				nackRedeliveryDelay = c.options.NackRedeliveryDelay
 			}
 			opts := &partitionConsumerOpts{
-				topic:                       pt,
-				consumerName:                c.consumerName,
-				subscription:                c.options.SubscriptionName,
-				subscriptionType:            c.options.Type,
-				subscriptionInitPos:         c.options.SubscriptionInitialPosition,
-				partitionIdx:                idx,
-				receiverQueueSize:           receiverQueueSize,
-				nackRedeliveryDelay:         nackRedeliveryDelay,
-				nackBackoffPolicy:           c.options.NackBackoffPolicy,
-				metadata:                    metadata,
-				subProperties:               subProperties,
-				replicateSubscriptionState:  c.options.ReplicateSubscriptionState,
-				startMessageID:              c.options.startMessageID,
-				startMessageIDInclusive:     c.options.StartMessageIDInclusive,
-				subscriptionMode:            c.options.SubscriptionMode,
-				readCompacted:               c.options.ReadCompacted,
-				interceptors:                c.options.Interceptors,
-				maxReconnectToBroker:        c.options.MaxReconnectToBroker,
-				backoffPolicy:               c.options.BackoffPolicy,
-				keySharedPolicy:             c.options.KeySharedPolicy,
-				schema:                      c.options.Schema,
-				decryption:                  c.options.Decryption,
-				ackWithResponse:             c.options.AckWithResponse,
-				maxPendingChunkedMessage:    c.options.MaxPendingChunkedMessage,
-				expireTimeOfIncompleteChunk: c.options.ExpireTimeOfIncompleteChunk,
-				autoAckIncompleteChunk:      c.options.AutoAckIncompleteChunk,
-				consumerEventListener:       c.options.EventListener,
-				enableBatchIndexAck:         c.options.EnableBatchIndexAcknowledgment,
-				ackGroupingOptions:          c.options.AckGroupingOptions,
-				autoReceiverQueueSize:       c.options.EnableAutoScaledReceiverQueueSize,
+				topic:                           pt,
+				consumerName:                    c.consumerName,
+				subscription:                    c.options.SubscriptionName,
+				subscriptionType:                c.options.Type,
+				priorityLevel:                   c.options.PriorityLevel,
+				subscriptionInitPos:             c.options.SubscriptionInitialPosition,
+				partitionIdx:                    idx,
+				receiverQueueSize:               receiverQueueSize,
+				nackRedeliveryDelay:             nackRedeliveryDelay,
+				nackBackoffPolicy:               c.options.NackBackoffPolicy,
+				metadata:                        metadata,
+				subProperties:                   subProperties,
+				replicateSubscriptionState:      c.options.ReplicateSubscriptionState,
+				startMessageID:                  c.options.startMessageID,
+				startMessageIDInclusive:         c.options.StartMessageIDInclusive,
+				startMessageTime:                c.options.startMessageTime,
+				subscriptionMode:                c.options.SubscriptionMode,
+				readCompacted:                   c.options.ReadCompacted,
+				interceptors:                    c.options.Interceptors,
+				maxReconnectToBroker:            c.options.MaxReconnectToBroker,
+				backoffPolicy:                   c.options.BackoffPolicy,
+				keySharedPolicy:                 c.options.KeySharedPolicy,
+				schema:                          c.options.Schema,
+				decryption:                      c.options.Decryption,
+				ackWithResponse:                 c.options.AckWithResponse,
+				ackStore:                        c.options.AckStore,
+				maxPendingChunkedMessage:        c.options.MaxPendingChunkedMessage,
+				expireTimeOfIncompleteChunk:     c.options.ExpireTimeOfIncompleteChunk,
+				autoAckIncompleteChunk:          c.options.AutoAckIncompleteChunk,
+				lazyChunkedPayloadDecompression: c.options.LazyChunkedPayloadDecompression,
+				metadataOnly:                    c.options.metadataOnly,
+				maxReceiverQueueSizeBytes:       c.options.maxReceiverQueueSizeBytes,
+				consumerEventListener:           c.options.EventListener,
+				onAssignmentChanged:             c.options.OnAssignmentChanged,
+				enableBatchIndexAck:             c.options.EnableBatchIndexAcknowledgment,
+				ackGroupingOptions:              c.options.AckGroupingOptions,
+				autoReceiverQueueSize:           c.options.EnableAutoScaledReceiverQueueSize,
+			}
+			if pos, ok := c.options.startPositions[idx]; ok {
+				opts.startMessageID = pos.id
+				opts.startMessageIDInclusive = pos.inclusive
+				opts.startMessageTime = time.Time{}
 			}
 			cons, err := newPartitionConsumer(c, c.client, opts, c.messageCh, c.dlq, c.metrics)
 			ch <- ConsumerError{
@@ -466,6 +488,9 @@ func (c *consumer) Unsubscribe() error {
 
 func (c *consumer) Receive(ctx context.Context) (message Message, err error) {
 	for {
+		if c.stopReached.Load() {
+			return nil, newError(StopMessageIDReached, "consumer reached its stop message id")
+		}
 		select {
 		case <-c.closeCh:
 			return nil, newError(ConsumerClosed, "consumer closed")
@@ -473,6 +498,19 @@ func (c *consumer) Receive(ctx context.Context) (message Message, err error) {
 			if !ok {
 				return nil, newError(ConsumerClosed, "consumer closed")
 			}
+			if stop := c.options.StopAtMessageID; stop != nil {
+				if reachedStopMessageID(cm.ID(), stop) && c.stopReached.CAS(false, true) {
+					c.log.WithField("stopMessageID", stop).
+						Info("Reached the stop message id, the messages received past it are left unacknowledged")
+				}
+				if messageIDCompare(cm.ID(), stop) > 0 {
+					// past the stop position, leave it unacknowledged for a later run
+					c.log.WithField("messageID", cm.ID()).Debug("Dropping message received past the stop message id")
+					releasePayload(cm.Message)
+					continue
+				}
+			}
+			setLastReceivedMsg(cm.ID())
 			return cm.Message, nil
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -480,6 +518,17 @@ func (c *consumer) Receive(ctx context.Context) (message Message, err error) {
 	}
 }
 
+// reachedStopMessageID reports whether the message is at or past the stop message id. The last message of the
+// entry of the stop id reaches it too, as no message of a batch matches a whole entry id or a batch index past the
+// end of the batch.
+func reachedStopMessageID(msgID MessageID, stop MessageID) bool {
+	if messageIDCompare(msgID, stop) >= 0 {
+		return true
+	}
+	return msgID.LedgerID() == stop.LedgerID() && msgID.EntryID() == stop.EntryID() &&
+		msgID.BatchIdx() == msgID.BatchSize()-1
+}
+
 func (c *consumer) AckWithTxn(msg Message, txn Transaction) error {
 	msgID := msg.ID()
 	if err := c.checkMsgIDPartition(msgID); err != nil {
@@ -532,26 +581,60 @@ func (c *consumer) AckIDCumulative(msgID MessageID) error {
 	return c.consumers[msgID.PartitionIdx()].AckIDCumulative(msgID)
 }
 
+func (c *consumer) AckAllFromTopic(topic string) error {
+	tn, err := internal.ParseTopicName(topic)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, pc := range c.consumers {
+		if !pc.consumesTopic(tn) {
+			continue
+		}
+		found = true
+		if err := pc.ackLastReceivedCumulative(c.options.AckWithResponse); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return newError(InvalidTopicName, fmt.Sprintf("topic %s is not consumed by this consumer", topic))
+	}
+	return nil
+}
+
 // ReconsumeLater mark a message for redelivery after custom delay
-func (c *consumer) ReconsumeLater(msg Message, delay time.Duration) {
-	c.ReconsumeLaterWithCustomProperties(msg, map[string]string{}, delay)
+func (c *consumer) ReconsumeLater(msg Message, delay time.Duration) error {
+	return c.ReconsumeLaterWithCustomProperties(msg, map[string]string{}, delay)
 }
 
 // ReconsumeLaterWithCustomProperties mark a message for redelivery after custom delay with custom properties
 func (c *consumer) ReconsumeLaterWithCustomProperties(msg Message, customProperties map[string]string,
-	delay time.Duration) {
+	delay time.Duration) error {
+	if !c.options.RetryEnable {
+		return newError(InvalidConfiguration, "ReconsumeLater requires ConsumerOptions.RetryEnable")
+	}
+
 	if delay < 0 {
 		delay = 0
 	}
 
 	if !checkMessageIDType(msg.ID()) {
 		c.log.Warnf("invalid message id type %T", msg.ID())
-		return
+		return newError(InvalidMessage, fmt.Sprintf("invalid message id type %T", msg.ID()))
 	}
 
 	msgID := c.messageID(msg.ID())
 	if msgID == nil {
-		return
+		return newError(InvalidMessage, "invalid message id")
+	}
+
+	payload := msg.Payload()
+	if m, ok := msg.(*message); ok {
+		var err error
+		if payload, err = m.fullPayload(); err != nil {
+			return newError(InvalidMessage, fmt.Sprintf("failed to decompress the message payload: %v", err))
+		}
 	}
 
 	props := make(map[string]string)
@@ -577,7 +660,7 @@ func (c *consumer) ReconsumeLaterWithCustomProperties(msg Message, customPropert
 	consumerMsg := ConsumerMessage{
 		Consumer: c,
 		Message: &message{
-			payLoad:    msg.Payload(),
+			payLoad:    payload,
 			properties: props,
 			msgID:      msgID,
 		},
@@ -588,7 +671,7 @@ func (c *consumer) ReconsumeLaterWithCustomProperties(msg Message, customPropert
 		c.rlq.Chan() <- RetryMessage{
 			consumerMsg: consumerMsg,
 			producerMsg: ProducerMessage{
-				Payload:      msg.Payload(),
+				Payload:      payload,
 				Key:          msg.Key(),
 				OrderingKey:  msg.OrderingKey(),
 				Properties:   props,
@@ -596,6 +679,7 @@ func (c *consumer) ReconsumeLaterWithCustomProperties(msg Message, customPropert
 			},
 		}
 	}
+	return nil
 }
 
 func (c *consumer) Nack(msg Message) {
@@ -678,23 +762,47 @@ func (c *consumer) Seek(msgID MessageID) error {
 }
 
 func (c *consumer) SeekByTime(time time.Time) error {
+	return c.seekByTime(context.Background(), time)
+}
+
+// seekByTime seeks all the partitions concurrently. If any fails, the error is a PartitionedSeekError, wrapping a
+// TimeoutError if the context is done and a SeekFailed error otherwise.
+func (c *consumer) seekByTime(ctx context.Context, time time.Time) error {
 	c.Lock()
 	defer c.Unlock()
-	var errs error
+
+	errs := make([]error, len(c.consumers))
+	var wg sync.WaitGroup
+	wg.Add(len(c.consumers))
 	// run SeekByTime on every partition of topic
-	for _, cons := range c.consumers {
-		if err := cons.SeekByTime(time); err != nil {
-			msg := fmt.Sprintf("unable to SeekByTime for topic=%s subscription=%s", c.topic, c.Subscription())
-			errs = pkgerrors.Wrap(newError(SeekFailed, err.Error()), msg)
-		}
+	for idx, cons := range c.consumers {
+		go func(idx int, cons *partitionConsumer) {
+			defer wg.Done()
+			errs[idx] = cons.seekByTimeWithContext(ctx, time)
+		}(idx, cons)
 	}
+	wg.Wait()
 
 	// clear messageCh
 	for len(c.messageCh) > 0 {
 		<-c.messageCh
 	}
 
-	return errs
+	failed := make(map[int]error)
+	for idx, err := range errs {
+		if err != nil {
+			failed[idx] = err
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("unable to SeekByTime for topic=%s subscription=%s", c.topic, c.Subscription())
+	cause := newError(SeekFailed, msg)
+	if ctx.Err() != nil {
+		cause = newError(TimeoutError, msg)
+	}
+	return &partitionedSeekError{cause: cause, partitions: len(c.consumers), failed: failed}
 }
 
 func (c *consumer) checkMsgIDPartition(msgID MessageID) error {
@@ -708,40 +816,49 @@ func (c *consumer) checkMsgIDPartition(msgID MessageID) error {
 	return nil
 }
 
-func (c *consumer) hasNext() bool {
-	ctx, cancel := context.WithCancel(context.Background())
+// hasNext returns true as soon as one of the partitions has more messages. If none has, the first error
+// encountered is returned.
+func (c *consumer) hasNext(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel() // Make sure all paths cancel the context to avoid context leak
 
 	var wg sync.WaitGroup
 	wg.Add(len(c.consumers))
 
-	hasNext := make(chan bool)
+	type result struct {
+		hasNext bool
+		err     error
+	}
+	results := make(chan result)
 	for _, pc := range c.consumers {
 		pc := pc
 		go func() {
 			defer wg.Done()
-			if pc.hasNext() {
-				select {
-				case hasNext <- true:
-				case <-ctx.Done():
-				}
+			hn, err := pc.hasNext(ctx)
+			select {
+			case results <- result{hasNext: hn, err: err}:
+			case <-ctx.Done():
 			}
 		}()
 	}
 
 	go func() {
 		wg.Wait()
-		close(hasNext) // Close the channel after all goroutines have finished
+		close(results) // Close the channel after all goroutines have finished
 	}()
 
 	// Wait for either a 'true' result or for all goroutines to finish
-	for hn := range hasNext {
-		if hn {
-			return true
+	var firstErr error
+	for res := range results {
+		if res.hasNext {
+			return true, nil
+		}
+		if res.err != nil && firstErr == nil {
+			firstErr = res.err
 		}
 	}
 
-	return false
+	return false, firstErr
 }
 
 func (c *consumer) setLastDequeuedMsg(msgID MessageID) error {
@@ -106,6 +106,7 @@ func (c *multiTopicConsumer) Receive(ctx context.Context) (message Message, err
 			if !ok {
 				return nil, newError(ConsumerClosed, "consumer closed")
 			}
+			setLastReceivedMsg(cm.ID())
 			return cm.Message, nil
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -187,20 +188,35 @@ func (c *multiTopicConsumer) AckIDCumulative(msgID MessageID) error {
 	return mid.consumer.AckIDCumulative(msgID)
 }
 
-func (c *multiTopicConsumer) ReconsumeLater(msg Message, delay time.Duration) {
-	c.ReconsumeLaterWithCustomProperties(msg, map[string]string{}, delay)
+func (c *multiTopicConsumer) AckAllFromTopic(topic string) error {
+	tn, err := internal.ParseTopicName(topic)
+	if err != nil {
+		return err
+	}
+	consumer, ok := c.consumers[internal.TopicNameWithoutPartitionPart(tn)]
+	if !ok {
+		// the consumer may be configured to consume from a specific partition
+		if consumer, ok = c.consumers[tn.Name]; !ok {
+			return newError(InvalidTopicName, fmt.Sprintf("topic %s is not consumed by this consumer", topic))
+		}
+	}
+	return consumer.AckAllFromTopic(topic)
+}
+
+func (c *multiTopicConsumer) ReconsumeLater(msg Message, delay time.Duration) error {
+	return c.ReconsumeLaterWithCustomProperties(msg, map[string]string{}, delay)
 }
 
 func (c *multiTopicConsumer) ReconsumeLaterWithCustomProperties(msg Message, customProperties map[string]string,
-	delay time.Duration) {
+	delay time.Duration) error {
 	names, err := validateTopicNames(msg.Topic())
 	if err != nil {
-		c.log.Errorf("validate msg topic %q failed: %v", msg.Topic(), err)
-		return
+		c.log.WithError(err).WithField("topic", msg.Topic()).Error("validate msg topic failed")
+		return err
 	}
 	if len(names) != 1 {
-		c.log.Errorf("invalid msg topic %q names: %+v ", msg.Topic(), names)
-		return
+		c.log.WithField("topic", msg.Topic()).Errorf("invalid msg topic names: %+v", names)
+		return newError(InvalidTopicName, fmt.Sprintf("invalid msg topic %q", msg.Topic()))
 	}
 
 	tn := names[0]
@@ -210,11 +226,11 @@ func (c *multiTopicConsumer) ReconsumeLaterWithCustomProperties(msg Message, cus
 		// check to see if the topic with the partition part is in the consumers
 		// this can happen when the consumer is configured to consume from a specific partition
 		if consumer, ok = c.consumers[tn.Name]; !ok {
-			c.log.Warnf("consumer of topic %s not exist unexpectedly", msg.Topic())
-			return
+			c.log.WithField("topic", msg.Topic()).Warn("consumer of topic not exist unexpectedly")
+			return newError(InvalidMessage, fmt.Sprintf("consumer of topic %s not exist", msg.Topic()))
 		}
 	}
-	consumer.ReconsumeLaterWithCustomProperties(msg, customProperties, delay)
+	return consumer.ReconsumeLaterWithCustomProperties(msg, customProperties, delay)
 }
 
 func (c *multiTopicConsumer) Nack(msg Message) {
@@ -282,3 +298,13 @@ func (c *multiTopicConsumer) SeekByTime(time time.Time) error {
 func (c *multiTopicConsumer) Name() string {
 	return c.consumerName
 }
+
+func (c *multiTopicConsumer) OldestUnackedAge() time.Duration {
+	var age time.Duration
+	for _, con := range c.consumers {
+		if a := con.OldestUnackedAge(); a > age {
+			age = a
+		}
+	}
+	return age
+}
@@ -18,9 +18,11 @@
 package pulsar
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -84,3 +86,61 @@ func TestMultiTopicConsumerReceive(t *testing.T) {
 	}
 	assert.Equal(t, receivedTopic1, receivedTopic2)
 }
+
+func TestMultiTopicConsumerAckAllFromTopic(t *testing.T) {
+	topic1 := newTopicName()
+	topic2 := newTopicName()
+	ctx := context.Background()
+
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	options := ConsumerOptions{
+		Topics:           []string{topic1, topic2},
+		SubscriptionName: "multi-topic-sub",
+		AckWithResponse:  true,
+	}
+	consumer, err := client.Subscribe(options)
+	assert.Nil(t, err)
+
+	for _, topic := range []string{topic1, topic2} {
+		p, err := client.CreateProducer(ProducerOptions{
+			Topic:           topic,
+			DisableBatching: true,
+		})
+		assert.Nil(t, err)
+		for i := 0; i < 3; i++ {
+			_, err := p.Send(ctx, &ProducerMessage{
+				Payload: []byte(fmt.Sprintf("%s-%d", topic, i)),
+			})
+			assert.Nil(t, err)
+		}
+		p.Close()
+	}
+
+	for i := 0; i < 6; i++ {
+		_, err := consumer.Receive(ctx)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, consumer.AckAllFromTopic(topic1))
+	err = consumer.AckAllFromTopic(newTopicName())
+	assert.Equal(t, InvalidTopicName, err.(*Error).Result())
+	consumer.Close()
+
+	// only the messages of the second topic are redelivered
+	consumer, err = client.Subscribe(options)
+	assert.Nil(t, err)
+	defer consumer.Close()
+	for i := 0; i < 3; i++ {
+		msg, err := consumer.Receive(ctx)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(msg.Payload()), topic2))
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	_, err = consumer.Receive(timeoutCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -19,6 +19,7 @@ package pulsar
 
 import (
 	"container/list"
+	"context"
 	"encoding/hex"
 	"fmt"
 	"math"
@@ -51,7 +52,8 @@ const (
 )
 
 var (
-	ErrInvalidAck = errors.New("invalid ack")
+	ErrInvalidAck         = errors.New("invalid ack")
+	ErrInvalidAckPosition = newError(InvalidAckPosition, "cumulative ack position is before the last cumulative ack")
 )
 
 func (s consumerState) String() string {
@@ -83,6 +85,9 @@ const (
 const (
 	initialReceiverQueueSize           = 1
 	receiverQueueExpansionMemThreshold = 0.75
+	// receiverQueueIdleShrinkInterval is how long the application can go without receiving a message before
+	// an auto-scaled receiver queue is halved
+	receiverQueueIdleShrinkInterval = 30 * time.Second
 )
 
 const (
@@ -90,36 +95,43 @@ const (
 )
 
 type partitionConsumerOpts struct {
-	topic                       string
-	consumerName                string
-	subscription                string
-	subscriptionType            SubscriptionType
-	subscriptionInitPos         SubscriptionInitialPosition
-	partitionIdx                int
-	receiverQueueSize           int
-	autoReceiverQueueSize       bool
-	nackRedeliveryDelay         time.Duration
-	nackBackoffPolicy           NackBackoffPolicy
-	metadata                    map[string]string
-	subProperties               map[string]string
-	replicateSubscriptionState  bool
-	startMessageID              *trackingMessageID
-	startMessageIDInclusive     bool
-	subscriptionMode            SubscriptionMode
-	readCompacted               bool
-	disableForceTopicCreation   bool
-	interceptors                ConsumerInterceptors
-	maxReconnectToBroker        *uint
-	backoffPolicy               internal.BackoffPolicy
-	keySharedPolicy             *KeySharedPolicy
-	schema                      Schema
-	decryption                  *MessageDecryptionInfo
-	ackWithResponse             bool
-	maxPendingChunkedMessage    int
-	expireTimeOfIncompleteChunk time.Duration
-	autoAckIncompleteChunk      bool
+	topic                           string
+	consumerName                    string
+	subscription                    string
+	subscriptionType                SubscriptionType
+	subscriptionInitPos             SubscriptionInitialPosition
+	partitionIdx                    int
+	receiverQueueSize               int
+	autoReceiverQueueSize           bool
+	nackRedeliveryDelay             time.Duration
+	nackBackoffPolicy               NackBackoffPolicy
+	metadata                        map[string]string
+	subProperties                   map[string]string
+	priorityLevel                   int
+	replicateSubscriptionState      bool
+	startMessageID                  *trackingMessageID
+	startMessageIDInclusive         bool
+	startMessageTime                time.Time
+	subscriptionMode                SubscriptionMode
+	readCompacted                   bool
+	disableForceTopicCreation       bool
+	interceptors                    ConsumerInterceptors
+	maxReconnectToBroker            *uint
+	backoffPolicy                   internal.BackoffPolicy
+	keySharedPolicy                 *KeySharedPolicy
+	schema                          Schema
+	decryption                      *MessageDecryptionInfo
+	ackWithResponse                 bool
+	ackStore                        AckStore
+	maxPendingChunkedMessage        int
+	expireTimeOfIncompleteChunk     time.Duration
+	autoAckIncompleteChunk          bool
+	lazyChunkedPayloadDecompression bool
+	metadataOnly                    bool
+	maxReceiverQueueSizeBytes       int
 	// in failover mode, this callback will be called when consumer change
 	consumerEventListener ConsumerEventListener
+	onAssignmentChanged   func(revoked, assigned []string)
 	enableBatchIndexAck   bool
 	ackGroupingOptions    *AckGroupingOptions
 }
@@ -138,6 +150,8 @@ type partitionConsumer struct {
 	options        *partitionConsumerOpts
 
 	conn uAtomic.Value
+	// reconnects counts the successful reconnections to a broker
+	reconnects uAtomic.Int64
 
 	topic        string
 	name         string
@@ -155,10 +169,29 @@ type partitionConsumer struct {
 	queueCh         chan []*message
 	startMessageID  atomicMessageID
 	lastDequeuedMsg *trackingMessageID
+	// startMessageTime is the publish time in milliseconds of the first message to deliver when the consumer
+	// starts by time, until it seeks
+	startMessageTime uAtomic.Int64
+	// lastSkipped is the last message skipped as published before startMessageTime, or filtered out by a reader
+	// while peeking its first message
+	lastSkipped atomicMessageID
+
+	// the last position acked cumulatively, used to reject acks moving the cursor backward
+	lastCumulativeAck atomicMessageID
+	// cumulativeAckMu serializes the cumulative acks, so that lastCumulativeAck is checked and moved atomically
+	cumulativeAckMu sync.Mutex
+
+	// the messages dispatched to the application and not acknowledged yet
+	unacked *unackedTracker
+	// lastReceivedMsg is the last message returned by Receive, used by AckAllFromTopic
+	lastReceivedMsg atomicMessageID
 
 	currentQueueSize       uAtomic.Int32
 	scaleReceiverQueueHint uAtomic.Bool
 	incomingMessages       uAtomic.Int32
+	// queuedBytes is the size of the payloads received and not dispatched yet, when maxReceiverQueueSizeBytes
+	// is set
+	queuedBytes uAtomic.Int64
 
 	eventsCh        chan interface{}
 	connectedCh     chan struct{}
@@ -180,9 +213,20 @@ type partitionConsumer struct {
 	ackGroupingTracker ackGroupingTracker
 
 	lastMessageInBroker *trackingMessageID
+
+	// active tracks whether the broker made this consumer the active one of the partition
+	active uAtomic.Bool
 }
 
 func (pc *partitionConsumer) ActiveConsumerChanged(isActive bool) {
+	if pc.options.onAssignmentChanged != nil && pc.active.Swap(isActive) != isActive {
+		if isActive {
+			pc.options.onAssignmentChanged(nil, []string{pc.topic})
+		} else {
+			pc.options.onAssignmentChanged([]string{pc.topic}, nil)
+		}
+	}
+
 	listener := pc.options.consumerEventListener
 	if listener == nil {
 		// didn't set a listener
@@ -219,6 +263,11 @@ func (p *availablePermits) get() int32 {
 }
 
 func (p *availablePermits) flowIfNeed() {
+	// the permits are kept until the queued payloads are dispatched below the byte limit
+	if p.pc.queueBytesFull() {
+		return
+	}
+
 	// TODO implement a better flow controller
 	// send more permits if needed
 	var flowThreshold int32
@@ -262,28 +311,47 @@ func (a *atomicMessageID) set(msgID *trackingMessageID) {
 	a.msgID = msgID
 }
 
+// advance sets msgID unless it is before the current message id
+func (a *atomicMessageID) advance(msgID *trackingMessageID) {
+	a.Lock()
+	defer a.Unlock()
+	if a.msgID != nil && messageIDCompare(msgID, a.msgID) < 0 {
+		return
+	}
+	a.msgID = msgID
+}
+
 type schemaInfoCache struct {
-	lock   sync.RWMutex
-	cache  map[string]Schema
-	client *client
-	topic  string
+	versions *schemaVersionCache
+	client   *client
+	topic    string
 }
 
 func newSchemaInfoCache(client *client, topic string) *schemaInfoCache {
-	return &schemaInfoCache{
-		cache:  make(map[string]Schema),
+	s := &schemaInfoCache{
 		client: client,
 		topic:  topic,
 	}
+	if client != nil {
+		s.versions = client.schemaCache
+	} else {
+		s.versions = newSchemaVersionCache(defaultSchemaCacheSize)
+	}
+	return s
 }
 
 func (s *schemaInfoCache) Get(schemaVersion []byte) (schema Schema, err error) {
-	key := hex.EncodeToString(schemaVersion)
-	s.lock.RLock()
-	schema, ok := s.cache[key]
-	s.lock.RUnlock()
-	if ok {
-		return schema, nil
+	return s.versions.get(s.key(hex.EncodeToString(schemaVersion)), func() (Schema, error) {
+		return s.fetch(schemaVersion)
+	})
+}
+
+func (s *schemaInfoCache) fetch(schemaVersion []byte) (schema Schema, err error) {
+	globalCache := getGlobalSchemaCache()
+	if globalCache != nil {
+		if schema, ok := globalCache.Get(schemaCacheTopic(s.topic), schemaVersion); ok {
+			return schema, nil
+		}
 	}
 
 	pbSchema, err := s.client.lookupService.GetSchema(s.topic, schemaVersion)
@@ -302,15 +370,19 @@ func (s *schemaInfoCache) Get(schemaVersion []byte) (schema Schema, err error) {
 	if err != nil {
 		return nil, err
 	}
-	s.add(key, schema)
+	if globalCache != nil {
+		globalCache.Put(schemaCacheTopic(s.topic), schemaVersion, schema)
+	}
 	return schema, nil
 }
 
 func (s *schemaInfoCache) add(schemaVersionHash string, schema Schema) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+	s.versions.add(s.key(schemaVersionHash), schema)
+}
 
-	s.cache[schemaVersionHash] = schema
+// key identifies the schema versions of the topic in the cache shared by all the consumers of the client
+func (s *schemaInfoCache) key(schemaVersionHash string) string {
+	return schemaCacheTopic(s.topic) + "/" + schemaVersionHash
 }
 
 func newPartitionConsumer(parent Consumer, client *client, options *partitionConsumerOpts,
@@ -337,6 +409,7 @@ func newPartitionConsumer(parent Consumer, client *client, options *partitionCon
 		dlq:                  dlq,
 		metrics:              metrics,
 		schemaInfoCache:      newSchemaInfoCache(client, options.topic),
+		unacked:              newUnackedTracker(),
 	}
 	if pc.options.autoReceiverQueueSize {
 		pc.currentQueueSize.Store(initialReceiverQueueSize)
@@ -344,6 +417,9 @@ func newPartitionConsumer(parent Consumer, client *client, options *partitionCon
 	} else {
 		pc.currentQueueSize.Store(int32(pc.options.receiverQueueSize))
 	}
+	if !options.startMessageTime.IsZero() {
+		pc.startMessageTime.Store(options.startMessageTime.UnixNano() / int64(time.Millisecond))
+	}
 	pc.availablePermits = &availablePermits{pc: pc}
 	pc.chunkedMsgCtxMap = newChunkedMsgCtxMap(options.maxPendingChunkedMessage, pc)
 	pc.unAckChunksTracker = newUnAckChunksTracker(pc)
@@ -355,6 +431,7 @@ func newPartitionConsumer(parent Consumer, client *client, options *partitionCon
 	pc.log = client.log.SubLogger(log.Fields{
 		"name":         pc.name,
 		"topic":        options.topic,
+		"partition":    options.partitionIdx,
 		"subscription": options.subscription,
 		"consumerID":   pc.consumerID,
 	})
@@ -433,16 +510,25 @@ func (pc *partitionConsumer) ackIDCommon(msgID MessageID, withResponse bool, txn
 		return errors.New("consumer state is closed")
 	}
 
+	pc.unacked.remove(msgID)
+
 	if cmid, ok := msgID.(*chunkMessageID); ok {
-		if txn == nil {
-			return pc.unAckChunksTracker.ack(cmid)
+		if txn != nil {
+			return pc.unAckChunksTracker.ackWithTxn(cmid, txn)
+		}
+		if err := pc.unAckChunksTracker.ack(cmid); err != nil {
+			return err
 		}
-		return pc.unAckChunksTracker.ackWithTxn(cmid, txn)
+		pc.persistAck(msgID, false)
+		return nil
 	}
 
 	trackingID := toTrackingMessageID(msgID)
+	if trackingID == nil {
+		return errors.New("failed to convert trackingMessageID")
+	}
 
-	if trackingID != nil && trackingID.ack() {
+	if trackingID.ack() {
 		// All messages in the same batch have been acknowledged, we only need to acknowledge the
 		// MessageID that represents the entry that stores the whole batch
 		trackingID = &trackingMessageID{
@@ -454,6 +540,9 @@ func (pc *partitionConsumer) ackIDCommon(msgID MessageID, withResponse bool, txn
 		pc.metrics.AcksCounter.Inc()
 		pc.metrics.ProcessingTime.Observe(float64(time.Now().UnixNano()-trackingID.receivedTime.UnixNano()) / 1.0e9)
 	} else if !pc.options.enableBatchIndexAck {
+		if txn == nil {
+			pc.persistAck(msgID, false)
+		}
 		return nil
 	}
 
@@ -471,6 +560,10 @@ func (pc *partitionConsumer) ackIDCommon(msgID MessageID, withResponse bool, txn
 	} else {
 		pc.ackGroupingTracker.add(trackingID)
 	}
+	if err == nil && txn == nil {
+		// only persisted once accepted, for a failed ack to be redelivered after a crash
+		pc.persistAck(msgID, false)
+	}
 	pc.options.interceptors.OnAcknowledge(pc.parentConsumer, msgID)
 	return err
 }
@@ -573,9 +666,22 @@ func (pc *partitionConsumer) internalUnsubscribe(unsub *unsubscribeRequest) {
 }
 
 func (pc *partitionConsumer) getLastMessageID() (*trackingMessageID, error) {
+	return pc.getLastMessageIDWithContext(context.Background())
+}
+
+// getLastMessageIDWithContext retries the request until it succeeds, the operation timeout elapses or the
+// context is done
+func (pc *partitionConsumer) getLastMessageIDWithContext(ctx context.Context) (*trackingMessageID, error) {
+	msgID, _, err := pc.getLastMessageIDs(ctx)
+	return msgID, err
+}
+
+// getLastMessageIDs returns the id of the last message, along with the mark delete position of the subscription,
+// which is nil if the broker does not report it, see getLastMessageIDWithContext
+func (pc *partitionConsumer) getLastMessageIDs(ctx context.Context) (*trackingMessageID, *trackingMessageID, error) {
 	if state := pc.getConsumerState(); state == consumerClosed || state == consumerClosing {
 		pc.log.WithField("state", state).Error("Failed to getLastMessageID for the closing or closed consumer")
-		return nil, errors.New("failed to getLastMessageID for the closing or closed consumer")
+		return nil, nil, errors.New("failed to getLastMessageID for the closing or closed consumer")
 	}
 	remainTime := pc.client.operationTimeout
 	var backoff internal.BackoffPolicy
@@ -584,22 +690,33 @@ func (pc *partitionConsumer) getLastMessageID() (*trackingMessageID, error) {
 	} else {
 		backoff = &internal.DefaultBackoff{}
 	}
-	request := func() (*trackingMessageID, error) {
+	request := func() (*getLastMsgIDRequest, error) {
 		req := &getLastMsgIDRequest{doneCh: make(chan struct{})}
-		pc.eventsCh <- req
+		select {
+		case pc.eventsCh <- req:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 
 		// wait for the request to complete
-		<-req.doneCh
-		return req.msgID, req.err
+		select {
+		case <-req.doneCh:
+			return req, req.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 	for {
-		msgID, err := request()
+		req, err := request()
 		if err == nil {
-			return msgID, nil
+			return req.msgID, req.markDeleteID, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, nil, ctxErr
 		}
 		if remainTime <= 0 {
 			pc.log.WithError(err).Error("Failed to getLastMessageID")
-			return nil, fmt.Errorf("failed to getLastMessageID due to %w", err)
+			return nil, nil, fmt.Errorf("failed to getLastMessageID due to %w", err)
 		}
 		nextDelay := backoff.Next()
 		if nextDelay > remainTime {
@@ -607,19 +724,30 @@ func (pc *partitionConsumer) getLastMessageID() (*trackingMessageID, error) {
 		}
 		remainTime -= nextDelay
 		pc.log.WithError(err).Errorf("Failed to get last message id from broker, retrying in %v...", nextDelay)
-		time.Sleep(nextDelay)
+		timer := time.NewTimer(nextDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		}
 	}
 }
 
 func (pc *partitionConsumer) internalGetLastMessageID(req *getLastMsgIDRequest) {
 	defer close(req.doneCh)
-	req.msgID, req.err = pc.requestGetLastMessageID()
+	req.msgID, req.markDeleteID, req.err = pc.requestGetLastMessageIDs()
 }
 
 func (pc *partitionConsumer) requestGetLastMessageID() (*trackingMessageID, error) {
+	msgID, _, err := pc.requestGetLastMessageIDs()
+	return msgID, err
+}
+
+func (pc *partitionConsumer) requestGetLastMessageIDs() (*trackingMessageID, *trackingMessageID, error) {
 	if state := pc.getConsumerState(); state == consumerClosed || state == consumerClosing {
 		pc.log.WithField("state", state).Error("Failed to getLastMessageID closing or closed consumer")
-		return nil, errors.New("failed to getLastMessageID closing or closed consumer")
+		return nil, nil, errors.New("failed to getLastMessageID closing or closed consumer")
 	}
 
 	requestID := pc.client.rpcClient.NewRequestID()
@@ -631,10 +759,11 @@ func (pc *partitionConsumer) requestGetLastMessageID() (*trackingMessageID, erro
 		pb.BaseCommand_GET_LAST_MESSAGE_ID, cmdGetLastMessageID)
 	if err != nil {
 		pc.log.WithError(err).Error("Failed to get last message id")
-		return nil, err
+		return nil, nil, err
 	}
-	id := res.Response.GetLastMessageIdResponse.GetLastMessageId()
-	return convertToMessageID(id), nil
+	response := res.Response.GetLastMessageIdResponse
+	return convertToMessageID(response.GetLastMessageId()),
+		convertToMessageID(response.GetConsumerMarkDeletePosition()), nil
 }
 
 func (pc *partitionConsumer) sendIndividualAck(msgID MessageID) *ackRequest {
@@ -690,6 +819,39 @@ func (pc *partitionConsumer) AckIDWithResponseCumulative(msgID MessageID) error
 	return pc.internalAckIDCumulative(msgID, true)
 }
 
+// ackLastReceivedCumulative cumulatively acks the last message returned by Receive, if any
+func (pc *partitionConsumer) ackLastReceivedCumulative(withResponse bool) error {
+	msgID := pc.lastReceivedMsg.get()
+	if msgID == nil {
+		return nil
+	}
+	return pc.internalAckIDCumulative(msgID, withResponse)
+}
+
+// consumesTopic reports whether the partition consumer reads from the topic, or from one of its partitions
+func (pc *partitionConsumer) consumesTopic(tn *internal.TopicName) bool {
+	if pc.topic == tn.Name {
+		return true
+	}
+	if tn.Partition >= 0 {
+		return false
+	}
+	ptn, err := internal.ParseTopicName(pc.topic)
+	return err == nil && internal.TopicNameWithoutPartitionPart(ptn) == tn.Name
+}
+
+// setLastReceivedMsg records msgID as the last message returned by Receive on its partition consumer
+func setLastReceivedMsg(msgID MessageID) {
+	if !checkMessageIDType(msgID) {
+		return
+	}
+	if mid := toTrackingMessageID(msgID); mid != nil {
+		if pc, ok := mid.consumer.(*partitionConsumer); ok {
+			pc.lastReceivedMsg.set(mid)
+		}
+	}
+}
+
 func (pc *partitionConsumer) isAllowAckCumulative() bool {
 	return pc.options.subscriptionType != Shared && pc.options.subscriptionType != KeyShared
 }
@@ -710,6 +872,15 @@ func (pc *partitionConsumer) internalAckIDCumulative(msgID MessageID, withRespon
 		return errors.New("failed to convert trackingMessageID")
 	}
 
+	// an ack checked against lastCumulativeAck must be sent before any later one, or the cursor could move backward
+	pc.cumulativeAckMu.Lock()
+	defer pc.cumulativeAckMu.Unlock()
+	if last := pc.lastCumulativeAck.get(); last != nil && messageIDCompare(trackingID, last) < 0 {
+		return joinErrors(ErrInvalidAckPosition, fmt.Errorf("message id %s is before the last cumulative ack %s",
+			trackingID.String(), last.String()))
+	}
+	pc.unacked.removeUpTo(trackingID)
+
 	var msgIDToAck *trackingMessageID
 	if trackingID.ackCumulative() || pc.options.enableBatchIndexAck {
 		msgIDToAck = trackingID
@@ -719,6 +890,7 @@ func (pc *partitionConsumer) internalAckIDCumulative(msgID MessageID, withRespon
 		trackingID.tracker.setPrevBatchAcked()
 	} else {
 		// waiting for all the msgs are acked in this batch
+		pc.persistAck(trackingID, true)
 		return nil
 	}
 
@@ -727,11 +899,17 @@ func (pc *partitionConsumer) internalAckIDCumulative(msgID MessageID, withRespon
 
 	var ackReq *ackRequest
 	if withResponse {
-		ackReq := pc.sendCumulativeAck(msgIDToAck)
+		ackReq = pc.sendCumulativeAck(msgIDToAck)
 		<-ackReq.doneCh
+		if ackReq.err != nil {
+			return ackReq.err
+		}
 	} else {
 		pc.ackGroupingTracker.addCumulative(msgIDToAck)
 	}
+	// only move the last cumulative ack once the broker confirmed it, or it was handed over to the grouping tracker
+	pc.lastCumulativeAck.advance(trackingID)
+	pc.persistAck(trackingID, true)
 
 	pc.options.interceptors.OnAcknowledge(pc.parentConsumer, msgID)
 
@@ -739,10 +917,30 @@ func (pc *partitionConsumer) internalAckIDCumulative(msgID MessageID, withRespon
 		pc.unAckChunksTracker.remove(cmid)
 	}
 
-	if ackReq == nil {
-		return nil
+	return nil
+}
+
+// persistAck records the acknowledgment in the AckStore of the consumer, if any
+func (pc *partitionConsumer) persistAck(msgID MessageID, cumulative bool) {
+	store := pc.options.ackStore
+	if store == nil {
+		return
+	}
+	var err error
+	if cumulative {
+		err = store.AckCumulative(pc.topic, pc.options.subscription, msgID)
+	} else {
+		err = store.Ack(pc.topic, pc.options.subscription, msgID)
 	}
-	return ackReq.err
+	if err != nil {
+		pc.log.WithError(err).Warnf("Failed to persist the acknowledgment of %v", msgID)
+	}
+}
+
+// isAckPersisted returns whether the acknowledgment of a redelivered message was persisted in the AckStore
+func (pc *partitionConsumer) isAckPersisted(msgID MessageID) bool {
+	store := pc.options.ackStore
+	return store != nil && store.IsAcked(pc.topic, pc.options.subscription, msgID)
 }
 
 func (pc *partitionConsumer) sendCumulativeAck(msgID MessageID) *ackRequest {
@@ -761,6 +959,8 @@ func (pc *partitionConsumer) NackID(msgID MessageID) {
 		return
 	}
 
+	redeliveryCount, tracked := pc.unacked.remove(msgID)
+
 	if cmid, ok := msgID.(*chunkMessageID); ok {
 		pc.unAckChunksTracker.nack(cmid)
 		return
@@ -768,11 +968,17 @@ func (pc *partitionConsumer) NackID(msgID MessageID) {
 
 	trackingID := toTrackingMessageID(msgID)
 
-	pc.nackTracker.Add(trackingID.messageID)
+	if tracked {
+		// the redelivery count of the message delivered with this id drives the NackBackoffPolicy
+		pc.nackTracker.AddWithRedeliveryCount(trackingID.messageID, redeliveryCount)
+	} else {
+		pc.nackTracker.Add(trackingID.messageID)
+	}
 	pc.metrics.NacksCounter.Inc()
 }
 
 func (pc *partitionConsumer) NackMsg(msg Message) {
+	pc.unacked.remove(msg.ID())
 	pc.nackTracker.AddMessage(msg)
 	pc.metrics.NacksCounter.Inc()
 }
@@ -911,10 +1117,20 @@ func (pc *partitionConsumer) requestSeekWithoutClear(msgID *messageID) error {
 		pc.log.WithError(err).Error("Failed to reset to message id")
 		return err
 	}
+	pc.startMessageTime.Store(0)
+	pc.lastSkipped.set(nil)
+	pc.lastCumulativeAck.set(nil)
+	pc.unacked.clear()
 	return nil
 }
 
 func (pc *partitionConsumer) SeekByTime(time time.Time) error {
+	return pc.seekByTimeWithContext(context.Background(), time)
+}
+
+// seekByTimeWithContext stops waiting for the seek when the context is done, returning a TimeoutError. The seek
+// may still complete afterwards.
+func (pc *partitionConsumer) seekByTimeWithContext(ctx context.Context, time time.Time) error {
 	if state := pc.getConsumerState(); state == consumerClosing || state == consumerClosed {
 		pc.log.WithField("state", pc.state).Error("Failed seekByTime by consumer is closing or has closed")
 		return errors.New("failed seekByTime by consumer is closing or has closed")
@@ -924,11 +1140,19 @@ func (pc *partitionConsumer) SeekByTime(time time.Time) error {
 		publishTime: time,
 	}
 	pc.ackGroupingTracker.flushAndClean()
-	pc.eventsCh <- req
+	select {
+	case pc.eventsCh <- req:
+	case <-ctx.Done():
+		return newError(TimeoutError, fmt.Sprintf("seek by time was not sent: %v", ctx.Err()))
+	}
 
 	// wait for the request to complete
-	<-req.doneCh
-	return req.err
+	select {
+	case <-req.doneCh:
+		return req.err
+	case <-ctx.Done():
+		return newError(TimeoutError, fmt.Sprintf("seek by time did not complete: %v", ctx.Err()))
+	}
 }
 
 func (pc *partitionConsumer) internalSeekByTime(seek *seekByTimeRequest) {
@@ -953,6 +1177,10 @@ func (pc *partitionConsumer) internalSeekByTime(seek *seekByTimeRequest) {
 		seek.err = err
 		return
 	}
+	pc.startMessageTime.Store(0)
+	pc.lastSkipped.set(nil)
+	pc.lastCumulativeAck.set(nil)
+	pc.unacked.clear()
 	pc.clearReceiverQueue()
 }
 
@@ -1029,6 +1257,12 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 		pc.discardCorruptedMessage(pbMsgID, pb.CommandAck_ChecksumMismatch)
 		return err
 	}
+	// the stored entry is made of the size-prefixed metadata followed by the payload
+	entrySize := int64(4+proto.Size(msgMeta)) + int64(headersAndPayload.ReadableBytes())
+	if isServerOnlyMarker(msgMeta) {
+		pc.skipMarkerMessage(pbMsgID, msgMeta.GetMarkerType())
+		return nil
+	}
 	decryptedPayload, err := pc.decryptor.Decrypt(headersAndPayload.ReadableSlice(), pbMsgID, msgMeta)
 	// error decrypting the payload
 	if err != nil {
@@ -1038,6 +1272,43 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 			crypToFailureAction = pc.options.decryption.ConsumerCryptoFailureAction
 		}
 
+		encryptedMsg := func() *message {
+			return &message{
+				publishTime:  timeFromUnixTimestampMillis(msgMeta.GetPublishTime()),
+				eventTime:    timeFromUnixTimestampMillis(msgMeta.GetEventTime()),
+				key:          msgMeta.GetPartitionKey(),
+				producerName: msgMeta.GetProducerName(),
+				properties:   internal.ConvertToStringMap(msgMeta.GetProperties()),
+				topic:        pc.topic,
+				msgID: newMessageID(
+					int64(pbMsgID.GetLedgerId()),
+					int64(pbMsgID.GetEntryId()),
+					pbMsgID.GetBatchIndex(),
+					pc.partitionIdx,
+					pbMsgID.GetBatchSize(),
+				),
+				payLoad:             headersAndPayload.ReadableSlice(),
+				schema:              pc.options.schema,
+				replicationClusters: msgMeta.GetReplicateTo(),
+				replicatedFrom:      msgMeta.GetReplicatedFrom(),
+				redeliveryCount:     response.GetRedeliveryCount(),
+				encryptionContext:   createEncryptionContext(msgMeta),
+				orderingKey:         string(msgMeta.OrderingKey),
+			}
+		}
+
+		var msg *message
+		if crypToFailureAction == crypto.ConsumerCryptoFailureActionCallback {
+			// the handler decides of the action for this message
+			msg = encryptedMsg()
+			switch action := int(pc.options.decryption.CryptoFailureHandler(msg, err)); action {
+			case crypto.ConsumerCryptoFailureActionDiscard, crypto.ConsumerCryptoFailureActionConsume:
+				crypToFailureAction = action
+			default:
+				crypToFailureAction = crypto.ConsumerCryptoFailureActionFail
+			}
+		}
+
 		switch crypToFailureAction {
 		case crypto.ConsumerCryptoFailureActionFail:
 			pc.log.Errorf("consuming message failed due to decryption err :%v", err)
@@ -1048,36 +1319,17 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 			return fmt.Errorf("discarding message on decryption error :%v", err)
 		case crypto.ConsumerCryptoFailureActionConsume:
 			pc.log.Warnf("consuming encrypted message due to error in decryption :%v", err)
-			messages := []*message{
-				{
-					publishTime:  timeFromUnixTimestampMillis(msgMeta.GetPublishTime()),
-					eventTime:    timeFromUnixTimestampMillis(msgMeta.GetEventTime()),
-					key:          msgMeta.GetPartitionKey(),
-					producerName: msgMeta.GetProducerName(),
-					properties:   internal.ConvertToStringMap(msgMeta.GetProperties()),
-					topic:        pc.topic,
-					msgID: newMessageID(
-						int64(pbMsgID.GetLedgerId()),
-						int64(pbMsgID.GetEntryId()),
-						pbMsgID.GetBatchIndex(),
-						pc.partitionIdx,
-						pbMsgID.GetBatchSize(),
-					),
-					payLoad:             headersAndPayload.ReadableSlice(),
-					schema:              pc.options.schema,
-					replicationClusters: msgMeta.GetReplicateTo(),
-					replicatedFrom:      msgMeta.GetReplicatedFrom(),
-					redeliveryCount:     response.GetRedeliveryCount(),
-					encryptionContext:   createEncryptionContext(msgMeta),
-					orderingKey:         string(msgMeta.OrderingKey),
-				},
+			if msg == nil {
+				msg = encryptedMsg()
 			}
+			messages := []*message{msg}
 
 			if pc.options.autoReceiverQueueSize {
 				pc.incomingMessages.Inc()
 				pc.markScaleIfNeed()
 			}
 
+			pc.addQueuedBytes(messages, 1)
 			pc.queueCh <- messages
 			return nil
 		}
@@ -1090,17 +1342,31 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 
 	processedPayloadBuffer := internal.NewBufferWrapper(decryptedPayload)
 	if isChunkedMsg {
-		processedPayloadBuffer = pc.processMessageChunk(processedPayloadBuffer, msgMeta, pbMsgID)
+		processedPayloadBuffer = pc.processMessageChunk(processedPayloadBuffer, msgMeta, pbMsgID, entrySize)
 		if processedPayloadBuffer == nil {
 			return nil
 		}
 	}
 
-	// decryption is success, decompress the payload
-	uncompressedHeadersAndPayload, err := pc.Decompress(msgMeta, processedPayloadBuffer)
-	if err != nil {
-		pc.discardCorruptedMessage(pbMsgID, pb.CommandAck_DecompressionError)
-		return err
+	var payloadReader *lazyPayloadReader
+	if isChunkedMsg && pc.options.lazyChunkedPayloadDecompression && !pc.options.metadataOnly {
+		payloadReader, err = pc.newLazyPayloadReader(msgMeta, processedPayloadBuffer)
+		if err != nil {
+			pc.discardCorruptedMessage(pbMsgID, pb.CommandAck_DecompressionError)
+			return err
+		}
+	}
+
+	// decryption is success, decompress the payload, unless it is decompressed lazily by the application or it is
+	// a single message whose payload is discarded anyway
+	uncompressedHeadersAndPayload := processedPayloadBuffer
+	skipDecompression := pc.options.metadataOnly && msgMeta.NumMessagesInBatch == nil
+	if payloadReader == nil && !skipDecompression {
+		uncompressedHeadersAndPayload, err = pc.Decompress(msgMeta, processedPayloadBuffer)
+		if err != nil {
+			pc.discardCorruptedMessage(pbMsgID, pb.CommandAck_DecompressionError)
+			return err
+		}
 	}
 
 	// Reset the reader on the uncompressed buffer
@@ -1160,7 +1426,11 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 		// set the consumer so we know how to ack the message id
 		trackingMsgID.consumer = pc
 
-		if pc.messageShouldBeDiscarded(trackingMsgID) {
+		beforeStartTime := pc.publishedBeforeStartTime(msgMeta)
+		if beforeStartTime {
+			pc.lastSkipped.advance(trackingMsgID)
+		}
+		if pc.messageShouldBeDiscarded(trackingMsgID) || beforeStartTime {
 			pc.AckID(trackingMsgID)
 			skippedMessages++
 			continue
@@ -1176,11 +1446,12 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 				return nil
 			}
 			cmid := newChunkMessageID(ctx.firstChunkID(), ctx.lastChunkID())
+			entrySize = ctx.entriesSize()
 			// set the consumer so we know how to ack the message id
 			cmid.consumer = pc
-			// clean chunkedMsgCtxMap
-			pc.chunkedMsgCtxMap.remove(msgMeta.GetUuid())
+			// track the message before cleaning chunkedMsgCtxMap, so that it is always pending in one of them
 			pc.unAckChunksTracker.add(cmid, ctx.chunkedMsgIDs)
+			pc.chunkedMsgCtxMap.remove(msgMeta.GetUuid())
 			msgID = cmid
 		} else {
 			msgID = trackingMsgID
@@ -1191,9 +1462,17 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 			continue
 		}
 
+		if pc.isAckPersisted(msgID) {
+			pc.AckID(msgID)
+			skippedMessages++
+			continue
+		}
+
 		var messageIndex *uint64
 		var brokerPublishTime *time.Time
+		var brokerEntrySize int64
 		if brokerMetadata != nil {
+			brokerEntrySize = entrySize
 			if brokerMetadata.Index != nil {
 				aux := brokerMetadata.GetIndex() - uint64(numMsgs) + uint64(i) + 1
 				messageIndex = &aux
@@ -1210,6 +1489,7 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 				publishTime:         timeFromUnixTimestampMillis(msgMeta.GetPublishTime()),
 				eventTime:           timeFromUnixTimestampMillis(smm.GetEventTime()),
 				key:                 smm.GetPartitionKey(),
+				keyB64Encoded:       smm.GetPartitionKeyB64Encoded(),
 				producerName:        msgMeta.GetProducerName(),
 				properties:          internal.ConvertToStringMap(smm.GetProperties()),
 				topic:               pc.topic,
@@ -1224,12 +1504,14 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 				orderingKey:         string(smm.OrderingKey),
 				index:               messageIndex,
 				brokerPublishTime:   brokerPublishTime,
+				brokerEntrySize:     brokerEntrySize,
 			}
 		} else {
 			msg = &message{
 				publishTime:         timeFromUnixTimestampMillis(msgMeta.GetPublishTime()),
 				eventTime:           timeFromUnixTimestampMillis(msgMeta.GetEventTime()),
 				key:                 msgMeta.GetPartitionKey(),
+				keyB64Encoded:       msgMeta.GetPartitionKeyB64Encoded(),
 				producerName:        msgMeta.GetProducerName(),
 				properties:          internal.ConvertToStringMap(msgMeta.GetProperties()),
 				topic:               pc.topic,
@@ -1244,9 +1526,19 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 				orderingKey:         string(msgMeta.GetOrderingKey()),
 				index:               messageIndex,
 				brokerPublishTime:   brokerPublishTime,
+				brokerEntrySize:     brokerEntrySize,
 			}
 		}
 
+		if payloadReader != nil {
+			msg.payLoad = nil
+			msg.payloadReader = payloadReader
+		}
+		if pc.options.metadataOnly {
+			msg.payLoad = nil
+			msg.payloadDiscarded = true
+		}
+
 		pc.options.interceptors.BeforeConsume(ConsumerMessage{
 			Consumer: pc.parentConsumer,
 			Message:  msg,
@@ -1267,13 +1559,15 @@ func (pc *partitionConsumer) MessageReceived(response *pb.CommandMessage, header
 	}
 
 	// send messages to the dispatcher
+	pc.addQueuedBytes(messages, 1)
 	pc.queueCh <- messages
 	return nil
 }
 
 func (pc *partitionConsumer) processMessageChunk(compressedPayload internal.Buffer,
 	msgMeta *pb.MessageMetadata,
-	pbMsgID *pb.MessageIdData) internal.Buffer {
+	pbMsgID *pb.MessageIdData,
+	entrySize int64) internal.Buffer {
 	uuid := msgMeta.GetUuid()
 	numChunks := msgMeta.GetNumChunksFromMsg()
 	totalChunksSize := int(msgMeta.GetTotalChunkMsgSize())
@@ -1310,7 +1604,7 @@ func (pc *partitionConsumer) processMessageChunk(compressedPayload internal.Buff
 		return nil
 	}
 
-	ctx.append(chunkID, msgID, compressedPayload)
+	ctx.append(chunkID, msgID, compressedPayload, entrySize)
 
 	if msgMeta.GetChunkId() != msgMeta.GetNumChunksFromMsg()-1 {
 		pc.availablePermits.inc()
@@ -1337,6 +1631,19 @@ func (pc *partitionConsumer) messageShouldBeDiscarded(msgID *trackingMessageID)
 	return pc.startMessageID.get().greaterEqual(msgID.messageID)
 }
 
+// publishedBeforeStartTime reports whether the message precedes the start time of the consumer. The broker
+// rolls the subscription back by whole seconds, so it may deliver a few messages published just before.
+func (pc *partitionConsumer) publishedBeforeStartTime(msgMeta *pb.MessageMetadata) bool {
+	startTime := pc.startMessageTime.Load()
+	return startTime > 0 && msgMeta.GetPublishTime() < uint64(startTime)
+}
+
+// skippedUpTo reports whether the entry of msgID was skipped, see lastSkipped
+func (pc *partitionConsumer) skippedUpTo(msgID *trackingMessageID) bool {
+	skipped := pc.lastSkipped.get()
+	return skipped != nil && compareEntries(skipped.messageID, msgID.messageID) >= 0
+}
+
 // create EncryptionContext from message metadata
 // this will be used to decrypt the message payload outside of this client
 // it is the responsibility of end user to decrypt the payload
@@ -1409,12 +1716,42 @@ func (pc *partitionConsumer) internalFlow(permits uint32) error {
 	return nil
 }
 
+// addQueuedBytes adds, or subtracts if sign is negative, the size of the messages to the bytes queued
+func (pc *partitionConsumer) addQueuedBytes(messages []*message, sign int64) {
+	if pc.options.maxReceiverQueueSizeBytes <= 0 {
+		return
+	}
+	var size int64
+	for _, m := range messages {
+		if m != nil {
+			size += int64(m.size())
+		}
+	}
+	pc.queuedBytes.Add(sign * size)
+}
+
+// queueBytesFull reports whether the queued messages reached maxReceiverQueueSizeBytes
+func (pc *partitionConsumer) queueBytesFull() bool {
+	return pc.options.maxReceiverQueueSizeBytes > 0 &&
+		pc.queuedBytes.Load() >= int64(pc.options.maxReceiverQueueSizeBytes)
+}
+
 // dispatcher manages the internal message queue channel
 // and manages the flow control
 func (pc *partitionConsumer) dispatcher() {
 	defer func() {
 		pc.log.Debug("exiting dispatch loop")
 	}()
+
+	// an auto-scaled receiver queue shrinks while the application does not receive messages
+	var idleCh <-chan time.Time
+	if pc.options.autoReceiverQueueSize {
+		idleTicker := time.NewTicker(receiverQueueIdleShrinkInterval)
+		defer idleTicker.Stop()
+		idleCh = idleTicker.C
+	}
+	dispatched := false
+
 	var messages []*message
 	for {
 		var queueCh chan []*message
@@ -1455,6 +1792,7 @@ func (pc *partitionConsumer) dispatcher() {
 			}
 			pc.log.Debug("dispatcher received connection event")
 
+			pc.addQueuedBytes(messages, -1)
 			messages = nil
 
 			// reset available permits
@@ -1467,6 +1805,12 @@ func (pc *partitionConsumer) dispatcher() {
 				initialPermits = uint32(pc.maxQueueSize)
 			}
 
+			if pc.queueBytesFull() {
+				// keep the permits until the queued messages are dispatched
+				pc.availablePermits.add(int32(initialPermits))
+				break
+			}
+
 			pc.log.Debugf("dispatcher requesting initial permits=%d", initialPermits)
 			// send initial permits
 			if err := pc.internalFlow(initialPermits); err != nil {
@@ -1487,14 +1831,27 @@ func (pc *partitionConsumer) dispatcher() {
 			messages[0] = nil
 			messages = messages[1:]
 
+			if pc.options.maxReceiverQueueSizeBytes > 0 {
+				pc.queuedBytes.Sub(int64(nextMessageSize))
+			}
 			pc.availablePermits.inc()
+			if messageCh == pc.messageCh {
+				pc.unacked.add(nextMessage.ID(), time.Now(), nextMessage.RedeliveryCount())
+			}
 
 			if pc.options.autoReceiverQueueSize {
 				pc.incomingMessages.Dec()
 				pc.client.memLimit.ReleaseMemory(int64(nextMessageSize))
 				pc.expectMoreIncomingMessages()
+				dispatched = true
 			}
 
+		case <-idleCh:
+			if !dispatched {
+				pc.shrinkReceiverQueueSize()
+			}
+			dispatched = false
+
 		case clearQueueCb := <-pc.clearQueueCh:
 			// drain the message queue on any new connection by sending a
 			// special nil message to the channel so we know when to stop dropping messages
@@ -1513,9 +1870,15 @@ func (pc *partitionConsumer) dispatcher() {
 				if pc.options.autoReceiverQueueSize {
 					pc.incomingMessages.Sub(int32(len(m)))
 				}
+				pc.addQueuedBytes(m, -1)
 			}
 
+			pc.addQueuedBytes(messages, -1)
 			messages = nil
+			if pc.options.maxReceiverQueueSizeBytes > 0 {
+				// send the permits kept while the queue was full
+				pc.availablePermits.flowIfNeed()
+			}
 
 			clearQueueCb(nextMessageInQueue)
 		}
@@ -1556,9 +1919,10 @@ type redeliveryRequest struct {
 }
 
 type getLastMsgIDRequest struct {
-	doneCh chan struct{}
-	msgID  *trackingMessageID
-	err    error
+	doneCh       chan struct{}
+	msgID        *trackingMessageID
+	markDeleteID *trackingMessageID
+	err          error
 }
 
 type seekRequest struct {
@@ -1721,6 +2085,7 @@ func (pc *partitionConsumer) reconnectToBroker(connectionClosed *connectionClose
 		if err == nil {
 			// Successfully reconnected
 			pc.log.Info("Reconnected consumer to broker")
+			pc.reconnects.Inc()
 			return
 		}
 		pc.log.WithError(err).Error("Failed to create consumer at reconnect")
@@ -1768,7 +2133,9 @@ func (pc *partitionConsumer) grabConn(assignedBrokerURL string) error {
 
 	var pbSchema *pb.Schema
 
-	if pc.options.schema != nil && pc.options.schema.GetSchemaInfo() != nil {
+	// the consumers without schema can read the topics of any schema
+	if pc.options.schema != nil && pc.options.schema.GetSchemaInfo() != nil &&
+		pc.options.schema.GetSchemaInfo().Type != AutoConsume {
 		tmpSchemaType := pb.Schema_Type(int32(pc.options.schema.GetSchemaInfo().Type))
 		pbSchema = &pb.Schema{
 			Name:       proto.String(pc.options.schema.GetSchemaInfo().Name),
@@ -1788,7 +2155,7 @@ func (pc *partitionConsumer) grabConn(assignedBrokerURL string) error {
 		ConsumerId:                 proto.Uint64(pc.consumerID),
 		RequestId:                  proto.Uint64(requestID),
 		ConsumerName:               proto.String(pc.name),
-		PriorityLevel:              nil,
+		PriorityLevel:              proto.Int32(int32(pc.options.priorityLevel)),
 		Durable:                    proto.Bool(pc.options.subscriptionMode == Durable),
 		Metadata:                   internal.ConvertFromStringMap(pc.options.metadata),
 		SubscriptionProperties:     internal.ConvertFromStringMap(pc.options.subProperties),
@@ -1803,6 +2170,15 @@ func (pc *partitionConsumer) grabConn(assignedBrokerURL string) error {
 	if pc.options.subscriptionMode != Durable {
 		// For regular subscriptions the broker will determine the restarting point
 		cmdSubscribe.StartMessageId = convertToMessageIDData(pc.startMessageID.get())
+
+		// as long as no message was received, the broker resolves the start time into a position
+		startTime := pc.startMessageTime.Load()
+		if startID := pc.startMessageID.get(); startTime > 0 && startID != nil && startID.equal(latestMessageID) {
+			rollback := time.Since(time.Unix(0, startTime*int64(time.Millisecond)))
+			if rollback > 0 {
+				cmdSubscribe.StartMessageRollbackDurationSec = proto.Uint64(uint64((rollback + time.Second - 1) / time.Second))
+			}
+		}
 	}
 
 	if len(pc.options.metadata) > 0 {
@@ -1977,6 +2353,39 @@ func (pc *partitionConsumer) shrinkReceiverQueueSize() {
 }
 
 func (pc *partitionConsumer) Decompress(msgMeta *pb.MessageMetadata, payload internal.Buffer) (internal.Buffer, error) {
+	provider, err := pc.compressionProvider(msgMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	uncompressed, err := provider.Decompress(nil, payload.ReadableSlice(), int(msgMeta.GetUncompressedSize()))
+	if err != nil {
+		return nil, err
+	}
+
+	return internal.NewBufferWrapper(uncompressed), nil
+}
+
+// newLazyPayloadReader returns a reader decompressing the payload while it is read, or nil if the payload is not
+// compressed or its compression type does not support stream decompression.
+func (pc *partitionConsumer) newLazyPayloadReader(msgMeta *pb.MessageMetadata,
+	payload internal.Buffer) (*lazyPayloadReader, error) {
+	if msgMeta.GetCompression() == pb.CompressionType_NONE {
+		return nil, nil
+	}
+	provider, err := pc.compressionProvider(msgMeta)
+	if err != nil {
+		return nil, err
+	}
+	streamProvider, ok := provider.(compression.StreamProvider)
+	if !ok {
+		return nil, nil
+	}
+
+	return &lazyPayloadReader{provider: streamProvider, payload: payload.ReadableSlice()}, nil
+}
+
+func (pc *partitionConsumer) compressionProvider(msgMeta *pb.MessageMetadata) (compression.Provider, error) {
 	providerEntry, ok := pc.compressionProviders.Load(msgMeta.GetCompression())
 	if !ok {
 		newProvider, err := pc.initializeCompressionProvider(msgMeta.GetCompression())
@@ -1998,17 +2407,16 @@ func (pc *partitionConsumer) Decompress(msgMeta *pb.MessageMetadata, payload int
 		pc.log.WithError(err).Error("Failed to decompress message.")
 		return nil, err
 	}
-
-	uncompressed, err := provider.Decompress(nil, payload.ReadableSlice(), int(msgMeta.GetUncompressedSize()))
-	if err != nil {
-		return nil, err
-	}
-
-	return internal.NewBufferWrapper(uncompressed), nil
+	return provider, nil
 }
 
 func (pc *partitionConsumer) initializeCompressionProvider(
 	compressionType pb.CompressionType) (compression.Provider, error) {
+	if pc.client != nil {
+		if provider, ok := pc.client.compressionProviders[CompressionType(compressionType)]; ok {
+			return customCompressionProvider{provider: provider}, nil
+		}
+	}
 	switch compressionType {
 	case pb.CompressionType_NONE:
 		return compression.NewNoopProvider(), nil
@@ -2023,6 +2431,45 @@ func (pc *partitionConsumer) initializeCompressionProvider(
 	return nil, fmt.Errorf("unsupported compression type: %v", compressionType)
 }
 
+// Types of the marker entries written by the brokers in the topics, see Markers.MarkerType in the broker.
+const (
+	markerTypeReplicatedSubscriptionSnapshotRequest  = 10
+	markerTypeReplicatedSubscriptionSnapshotResponse = 11
+	markerTypeReplicatedSubscriptionSnapshot         = 12
+	markerTypeReplicatedSubscriptionUpdate           = 13
+	markerTypeTxnCommit                              = 20
+	markerTypeTxnAbort                               = 21
+)
+
+// isServerOnlyMarker reports whether the entry is a marker used internally by the brokers, which must never
+// be delivered to the application
+func isServerOnlyMarker(msgMeta *pb.MessageMetadata) bool {
+	if msgMeta.MarkerType == nil {
+		return false
+	}
+	switch msgMeta.GetMarkerType() {
+	case markerTypeReplicatedSubscriptionSnapshotRequest, markerTypeReplicatedSubscriptionSnapshotResponse,
+		markerTypeReplicatedSubscriptionSnapshot, markerTypeReplicatedSubscriptionUpdate,
+		markerTypeTxnCommit, markerTypeTxnAbort:
+		return true
+	default:
+		return false
+	}
+}
+
+// skipMarkerMessage acks a server-only marker entry instead of delivering it, the brokers usually filtering
+// them out before the dispatch
+func (pc *partitionConsumer) skipMarkerMessage(msgID *pb.MessageIdData, markerType int32) {
+	pc.log.WithFields(log.Fields{
+		"msgID":      msgID,
+		"markerType": markerType,
+	}).Debug("Skipping marker message")
+
+	pc.ackGroupingTracker.add(newTrackingMessageID(int64(msgID.GetLedgerId()), int64(msgID.GetEntryId()),
+		0, pc.partitionIdx, 0, nil))
+	pc.availablePermits.inc()
+}
+
 func (pc *partitionConsumer) discardCorruptedMessage(msgID *pb.MessageIdData,
 	validationError pb.CommandAck_ValidationError) {
 	if state := pc.getConsumerState(); state == consumerClosed || state == consumerClosing {
@@ -2048,18 +2495,18 @@ func (pc *partitionConsumer) discardCorruptedMessage(msgID *pb.MessageIdData,
 	pc.availablePermits.inc()
 }
 
-func (pc *partitionConsumer) hasNext() bool {
+func (pc *partitionConsumer) hasNext(ctx context.Context) (bool, error) {
 	if pc.lastMessageInBroker != nil && pc.hasMoreMessages() {
-		return true
+		return true, nil
 	}
 
-	lastMsgID, err := pc.getLastMessageID()
+	lastMsgID, err := pc.getLastMessageIDWithContext(ctx)
 	if err != nil {
-		return false
+		return false, err
 	}
 	pc.lastMessageInBroker = lastMsgID
 
-	return pc.hasMoreMessages()
+	return pc.hasMoreMessages(), nil
 }
 
 func (pc *partitionConsumer) hasMoreMessages() bool {
@@ -2127,6 +2574,7 @@ type chunkedMsgCtx struct {
 	lastChunkedMsgID int32
 	chunkedMsgIDs    []*messageID
 	receivedTime     int64
+	entrySize        int64
 
 	mu sync.Mutex
 }
@@ -2141,12 +2589,20 @@ func newChunkedMsgCtx(numChunksFromMsg int32, totalChunkMsgSize int) *chunkedMsg
 	}
 }
 
-func (c *chunkedMsgCtx) append(chunkID int32, msgID *messageID, partPayload internal.Buffer) {
+func (c *chunkedMsgCtx) append(chunkID int32, msgID *messageID, partPayload internal.Buffer, entrySize int64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.chunkedMsgIDs[chunkID] = msgID
 	c.chunkedMsgBuffer.Write(partPayload.ReadableSlice())
 	c.lastChunkedMsgID = chunkID
+	c.entrySize += entrySize
+}
+
+// entriesSize returns the sum of the sizes of the entries holding the chunks received so far
+func (c *chunkedMsgCtx) entriesSize() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entrySize
 }
 
 func (c *chunkedMsgCtx) firstChunkID() *messageID {
@@ -2241,6 +2697,19 @@ func (c *chunkedMsgCtxMap) remove(uuid string) {
 	}
 }
 
+// firstChunkIDs returns the ids of the first chunk of the messages being reassembled
+func (c *chunkedMsgCtxMap) firstChunkIDs() []*messageID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]*messageID, 0, len(c.chunkedMsgCtxs))
+	for _, ctx := range c.chunkedMsgCtxs {
+		if id := ctx.firstChunkID(); id != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 func (c *chunkedMsgCtxMap) discardOldestChunkMessage(autoAck bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
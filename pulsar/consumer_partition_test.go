@@ -18,13 +18,20 @@
 package pulsar
 
 import (
+	"encoding/binary"
+	"errors"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar/internal"
 	"github.com/apache/pulsar-client-go/pulsar/internal/crypto"
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
+	"github.com/apache/pulsar-client-go/pulsar/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestSingleMessageIDNoAckTracker(t *testing.T) {
@@ -36,6 +43,7 @@ func TestSingleMessageIDNoAckTracker(t *testing.T) {
 		options:              &partitionConsumerOpts{},
 		metrics:              newTestMetrics(),
 		decryptor:            crypto.NewNoopDecryptor(),
+		unacked:              newUnackedTracker(),
 	}
 	pc.availablePermits = &availablePermits{pc: &pc}
 	pc.ackGroupingTracker = newAckGroupingTracker(&AckGroupingOptions{MaxSize: 0},
@@ -75,6 +83,7 @@ func TestBatchMessageIDNoAckTracker(t *testing.T) {
 		options:              &partitionConsumerOpts{},
 		metrics:              newTestMetrics(),
 		decryptor:            crypto.NewNoopDecryptor(),
+		unacked:              newUnackedTracker(),
 	}
 	pc.availablePermits = &availablePermits{pc: &pc}
 	pc.ackGroupingTracker = newAckGroupingTracker(&AckGroupingOptions{MaxSize: 0},
@@ -111,6 +120,7 @@ func TestBatchMessageIDWithAckTracker(t *testing.T) {
 		options:              &partitionConsumerOpts{},
 		metrics:              newTestMetrics(),
 		decryptor:            crypto.NewNoopDecryptor(),
+		unacked:              newUnackedTracker(),
 	}
 	pc.availablePermits = &availablePermits{pc: &pc}
 	pc.ackGroupingTracker = newAckGroupingTracker(&AckGroupingOptions{MaxSize: 0},
@@ -226,3 +236,236 @@ var rawBatchMessage10 = []byte{
 	0x28, 0x05, 0x40, 0x09, 0x68, 0x65, 0x6c, 0x6c,
 	0x6f,
 }
+
+// newTestMessageFrame serializes the metadata and the payload of a message as sent by the broker
+func newTestMessageFrame(t *testing.T, meta *pb.MessageMetadata, payload []byte) internal.Buffer {
+	metadata, err := proto.Marshal(meta)
+	assert.NoError(t, err)
+
+	body := binary.BigEndian.AppendUint32(nil, uint32(len(metadata)))
+	body = append(body, metadata...)
+	body = append(body, payload...)
+
+	frame := []byte{0x0e, 0x01}
+	frame = binary.BigEndian.AppendUint32(frame, internal.Crc32cCheckSum(body))
+	return internal.NewBufferWrapper(append(frame, body...))
+}
+
+func TestSkipMarkerMessages(t *testing.T) {
+	eventsCh := make(chan interface{}, 10)
+	pc := partitionConsumer{
+		queueCh:              make(chan []*message, 10),
+		eventsCh:             eventsCh,
+		compressionProviders: sync.Map{},
+		options:              &partitionConsumerOpts{},
+		metrics:              newTestMetrics(),
+		decryptor:            crypto.NewNoopDecryptor(),
+		unacked:              newUnackedTracker(),
+		log:                  log.DefaultNopLogger(),
+		// large enough for the permits of the skipped markers not to trigger a flow request
+		maxQueueSize: 100,
+	}
+	pc.availablePermits = &availablePermits{pc: &pc}
+	pc.ackGroupingTracker = newAckGroupingTracker(&AckGroupingOptions{MaxSize: 0},
+		func(id MessageID) { pc.sendIndividualAck(id) }, nil, nil)
+
+	markers := []int32{
+		markerTypeReplicatedSubscriptionSnapshotRequest,
+		markerTypeReplicatedSubscriptionSnapshotResponse,
+		markerTypeReplicatedSubscriptionSnapshot,
+		markerTypeReplicatedSubscriptionUpdate,
+		markerTypeTxnCommit,
+		markerTypeTxnAbort,
+	}
+	for i, markerType := range markers {
+		frame := newTestMessageFrame(t, &pb.MessageMetadata{
+			ProducerName: proto.String("broker"),
+			SequenceId:   proto.Uint64(uint64(i)),
+			PublishTime:  proto.Uint64(0),
+			MarkerType:   proto.Int32(markerType),
+		}, []byte("marker"))
+		response := &pb.CommandMessage{
+			MessageId: &pb.MessageIdData{LedgerId: proto.Uint64(1), EntryId: proto.Uint64(uint64(i))},
+		}
+		assert.NoError(t, pc.MessageReceived(response, frame))
+
+		// the marker is acked instead of being delivered
+		select {
+		case <-eventsCh:
+		default:
+			t.Errorf("Expected an ack request for marker type %d", markerType)
+		}
+	}
+	assert.Equal(t, int32(len(markers)), pc.availablePermits.get())
+
+	frame := newTestMessageFrame(t, &pb.MessageMetadata{
+		ProducerName: proto.String("producer"),
+		SequenceId:   proto.Uint64(0),
+		PublishTime:  proto.Uint64(0),
+	}, []byte("hello"))
+	assert.NoError(t, pc.MessageReceived(&pb.CommandMessage{MessageId: &pb.MessageIdData{
+		LedgerId: proto.Uint64(1), EntryId: proto.Uint64(uint64(len(markers))),
+	}}, frame))
+
+	messages := <-pc.queueCh
+	assert.Len(t, messages, 1)
+	assert.Equal(t, []byte("hello"), messages[0].Payload())
+	assert.Len(t, pc.queueCh, 0)
+}
+
+func TestReceiverQueueSizeBytes(t *testing.T) {
+	pc := partitionConsumer{
+		options:      &partitionConsumerOpts{maxReceiverQueueSizeBytes: 10},
+		log:          log.DefaultNopLogger(),
+		maxQueueSize: 2,
+	}
+	pc.availablePermits = &availablePermits{pc: &pc}
+
+	messages := []*message{{payLoad: []byte("hello")}, {payLoad: []byte("world!")}}
+	pc.addQueuedBytes(messages[:1], 1)
+	assert.False(t, pc.queueBytesFull())
+	pc.addQueuedBytes(messages[1:], 1)
+	assert.Equal(t, int64(11), pc.queuedBytes.Load())
+	assert.True(t, pc.queueBytesFull())
+
+	// the permits are kept while the queue is full, the consumer has no connection to send them on
+	pc.availablePermits.add(2)
+	assert.Equal(t, int32(2), pc.availablePermits.get())
+
+	pc.addQueuedBytes(messages, -1)
+	assert.Equal(t, int64(0), pc.queuedBytes.Load())
+	assert.False(t, pc.queueBytesFull())
+}
+
+func TestChunkedMsgCtxEntriesSize(t *testing.T) {
+	ctx := newChunkedMsgCtx(2, 8)
+	ctx.append(0, &messageID{entryID: 1}, internal.NewBufferWrapper([]byte("abcd")), 40)
+	ctx.append(1, &messageID{entryID: 2}, internal.NewBufferWrapper([]byte("efgh")), 42)
+
+	assert.Equal(t, int64(82), ctx.entriesSize())
+	assert.Equal(t, int64(2), ctx.lastChunkID().entryID)
+}
+
+func TestLastCumulativeAckAdvancesOnSuccess(t *testing.T) {
+	eventsCh := make(chan interface{}, 1)
+	pc := partitionConsumer{
+		eventsCh: eventsCh,
+		options:  &partitionConsumerOpts{subscriptionType: Exclusive},
+		metrics:  newTestMetrics(),
+		unacked:  newUnackedTracker(),
+		log:      log.DefaultNopLogger(),
+	}
+
+	ackErr := errors.New("ack failed")
+	go func() {
+		for e := range eventsCh {
+			req := e.(*ackRequest)
+			if req.msgID.entryID == 5 {
+				req.err = ackErr
+			}
+			close(req.doneCh)
+		}
+	}()
+	defer close(eventsCh)
+
+	// the broker rejected the ack, the position must not move
+	err := pc.internalAckIDCumulative(newTrackingMessageID(1, 5, 0, 0, 1, nil), true)
+	assert.ErrorIs(t, err, ackErr)
+	assert.Nil(t, pc.lastCumulativeAck.get())
+
+	assert.Nil(t, pc.internalAckIDCumulative(newTrackingMessageID(1, 3, 0, 0, 1, nil), true))
+	assert.Equal(t, int64(3), pc.lastCumulativeAck.get().entryID)
+
+	err = pc.internalAckIDCumulative(newTrackingMessageID(1, 2, 0, 0, 1, nil), true)
+	assert.ErrorIs(t, err, ErrInvalidAckPosition)
+}
+
+func TestLastCumulativeAckConcurrent(t *testing.T) {
+	eventsCh := make(chan interface{}, 1)
+	pc := partitionConsumer{
+		eventsCh: eventsCh,
+		options:  &partitionConsumerOpts{subscriptionType: Failover},
+		metrics:  newTestMetrics(),
+		unacked:  newUnackedTracker(),
+		log:      log.DefaultNopLogger(),
+	}
+
+	var sent []int64
+	go func() {
+		for e := range eventsCh {
+			req := e.(*ackRequest)
+			sent = append(sent, req.msgID.entryID)
+			// give the concurrent acks time to pile up
+			time.Sleep(time.Millisecond)
+			close(req.doneCh)
+		}
+	}()
+	defer close(eventsCh)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(entryID int64) {
+			defer wg.Done()
+			err := pc.internalAckIDCumulative(newTrackingMessageID(1, entryID, 0, 0, 1, nil), true)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrInvalidAckPosition)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	// the acks reached the broker in order, the cursor never moved backward
+	assert.NotEmpty(t, sent)
+	for i := 1; i < len(sent); i++ {
+		assert.Greater(t, sent[i], sent[i-1])
+	}
+	assert.Equal(t, sent[len(sent)-1], pc.lastCumulativeAck.get().entryID)
+}
+
+func TestAckPersistedOnceAccepted(t *testing.T) {
+	store, err := NewFileAckStore(filepath.Join(t.TempDir(), "acks"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	eventsCh := make(chan interface{}, 1)
+	pc := &partitionConsumer{
+		eventsCh: eventsCh,
+		topic:    "topic",
+		options:  &partitionConsumerOpts{subscription: "sub", ackStore: store},
+		metrics:  newTestMetrics(),
+		unacked:  newUnackedTracker(),
+		log:      log.DefaultNopLogger(),
+	}
+	pc.unAckChunksTracker = newUnAckChunksTracker(pc)
+	pc.ackGroupingTracker = newAckGroupingTracker(&AckGroupingOptions{MaxSize: 1}, func(id MessageID) {},
+		func(id MessageID) {}, nil)
+
+	ackErr := errors.New("ack failed")
+	go func() {
+		for e := range eventsCh {
+			req := e.(*ackRequest)
+			if req.msgID.entryID == 5 {
+				req.err = ackErr
+			}
+			close(req.doneCh)
+		}
+	}()
+	defer close(eventsCh)
+
+	// the broker rejected the ack, it must be redelivered after a crash
+	failed := newTrackingMessageID(1, 5, -1, 0, 0, nil)
+	assert.ErrorIs(t, pc.ackIDCommon(failed, true, nil), ackErr)
+	assert.False(t, pc.isAckPersisted(failed))
+
+	acked := newTrackingMessageID(1, 3, -1, 0, 0, nil)
+	assert.Nil(t, pc.ackIDCommon(acked, true, nil))
+	assert.True(t, pc.isAckPersisted(acked))
+
+	first := &messageID{ledgerID: 1, entryID: 6, batchIdx: -1}
+	last := &messageID{ledgerID: 1, entryID: 7, batchIdx: -1}
+	chunked := newChunkMessageID(first, last)
+	pc.unAckChunksTracker.add(chunked, []*messageID{first, last})
+	assert.Nil(t, pc.ackIDCommon(chunked, false, nil))
+	assert.True(t, pc.isAckPersisted(chunked))
+}
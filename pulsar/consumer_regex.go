@@ -142,6 +142,7 @@ func (c *regexConsumer) Receive(ctx context.Context) (message Message, err error
 			if !ok {
 				return nil, newError(ConsumerClosed, "consumer closed")
 			}
+			setLastReceivedMsg(cm.ID())
 			return cm.Message, nil
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -159,12 +160,14 @@ func (c *regexConsumer) Ack(msg Message) error {
 	return c.AckID(msg.ID())
 }
 
-func (c *regexConsumer) ReconsumeLater(_ Message, _ time.Duration) {
+func (c *regexConsumer) ReconsumeLater(_ Message, _ time.Duration) error {
 	c.log.Warnf("regexp consumer not support ReconsumeLater yet.")
+	return newError(OperationNotSupported, "regexp consumer not support ReconsumeLater yet")
 }
 
-func (c *regexConsumer) ReconsumeLaterWithCustomProperties(_ Message, _ map[string]string, _ time.Duration) {
+func (c *regexConsumer) ReconsumeLaterWithCustomProperties(_ Message, _ map[string]string, _ time.Duration) error {
 	c.log.Warnf("regexp consumer not support ReconsumeLaterWithCustomProperties yet.")
+	return newError(OperationNotSupported, "regexp consumer not support ReconsumeLaterWithCustomProperties yet")
 }
 
 // AckID the consumption of a single message, identified by its MessageID
@@ -234,6 +237,21 @@ func (c *regexConsumer) AckIDCumulative(msgID MessageID) error {
 	return mid.consumer.AckIDCumulative(msgID)
 }
 
+func (c *regexConsumer) AckAllFromTopic(topic string) error {
+	tn, err := internal.ParseTopicName(topic)
+	if err != nil {
+		return err
+	}
+
+	c.consumersLock.Lock()
+	consumer, ok := c.consumers[internal.TopicNameWithoutPartitionPart(tn)]
+	c.consumersLock.Unlock()
+	if !ok {
+		return newError(InvalidTopicName, fmt.Sprintf("topic %s is not consumed by this consumer", topic))
+	}
+	return consumer.AckAllFromTopic(topic)
+}
+
 func (c *regexConsumer) Nack(msg Message) {
 	if c.options.EnableDefaultNackBackoffPolicy || c.options.NackBackoffPolicy != nil {
 		msgID := msg.ID()
@@ -305,6 +323,19 @@ func (c *regexConsumer) Name() string {
 	return c.consumerName
 }
 
+func (c *regexConsumer) OldestUnackedAge() time.Duration {
+	c.consumersLock.Lock()
+	defer c.consumersLock.Unlock()
+
+	var age time.Duration
+	for _, con := range c.consumers {
+		if a := con.OldestUnackedAge(); a > age {
+			age = a
+		}
+	}
+	return age
+}
+
 func (c *regexConsumer) closed() bool {
 	select {
 	case <-c.closeCh:
@@ -371,7 +402,7 @@ func (c *regexConsumer) subscribe(topics []string, dlq *dlqRouter, rlq *retryRou
 	consumers := make(map[string]Consumer, len(topics))
 	for ce := range subscriber(c.client, topics, c.options, c.messageCh, dlq, rlq) {
 		if ce.err != nil {
-			c.log.Warnf("Failed to subscribe to topic=%s", ce.topic)
+			c.log.WithError(ce.err).WithField("topic", ce.topic).Warn("Failed to subscribe to topic")
 		} else {
 			consumers[ce.topic] = ce.consumer
 		}
@@ -399,10 +430,10 @@ func (c *regexConsumer) unsubscribe(topics []string) {
 	c.consumersLock.Unlock()
 
 	for t, consumer := range consumers {
-		c.log.Debugf("unsubscribe from topic=%s subscription=%s", t, c.options.SubscriptionName)
+		fields := log.Fields{"topic": t, "subscription": c.options.SubscriptionName}
+		c.log.WithFields(fields).Debug("unsubscribe from topic")
 		if err := consumer.Unsubscribe(); err != nil {
-			c.log.Warnf("unable to unsubscribe from topic=%s subscription=%s",
-				t, c.options.SubscriptionName)
+			c.log.WithError(err).WithFields(fields).Warn("unable to unsubscribe from topic")
 		}
 		consumer.Close()
 	}
@@ -24,6 +24,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -36,6 +37,8 @@ import (
 	plog "github.com/apache/pulsar-client-go/pulsar/log"
 	"github.com/google/uuid"
 	"github.com/pierrec/lz4"
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/proto"
 )
@@ -1683,7 +1686,7 @@ func TestRLQ(t *testing.T) {
 	for rlqReceived < N*(maxRedeliveries+1) {
 		msg, err := rlqConsumer.Receive(ctx)
 		assert.Nil(t, err)
-		rlqConsumer.ReconsumeLater(msg, 1*time.Second)
+		assert.NoError(t, rlqConsumer.ReconsumeLater(msg, 1*time.Second))
 		rlqReceived++
 	}
 	fmt.Println("retry consumed:", rlqReceived) // 300
@@ -1737,6 +1740,102 @@ func TestRLQ(t *testing.T) {
 	assert.Nil(t, checkMsg)
 }
 
+func TestDLQTopicName(t *testing.T) {
+	name, err := DLQTopicName(ConsumerOptions{
+		Topic:            "persistent://public/default/my-topic-partition-1",
+		SubscriptionName: "my-sub",
+		RetryEnable:      true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "persistent://public/default/my-topic-my-sub-DLQ", name)
+
+	name, err = DLQTopicName(ConsumerOptions{
+		Topics:           []string{"my-topic", "other-topic"},
+		SubscriptionName: "my-sub",
+		RetryEnable:      true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "persistent://public/default/my-topic-my-sub-DLQ", name)
+
+	name, err = DLQTopicName(ConsumerOptions{
+		Topic:            "my-topic",
+		SubscriptionName: "my-sub",
+		DLQ:              &DLQPolicy{MaxDeliveries: 3, DeadLetterTopic: "my-dlq"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "my-dlq", name)
+
+	_, err = DLQTopicName(ConsumerOptions{Topic: "my-topic", SubscriptionName: "my-sub"})
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, InvalidConfiguration, e.Result())
+
+	_, err = DLQTopicName(ConsumerOptions{Topic: "my-topic", RetryEnable: true})
+	assert.Error(t, err)
+}
+
+func TestCreateDLQReader(t *testing.T) {
+	client, err := NewClient(ClientOptions{URL: lookupURL})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	options := ConsumerOptions{
+		Topic:            newTopicName(),
+		SubscriptionName: "my-sub",
+		RetryEnable:      true,
+	}
+	dlqTopic, err := DLQTopicName(options)
+	assert.Nil(t, err)
+
+	producer, err := client.CreateProducer(ProducerOptions{Topic: dlqTopic})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	ctx := context.Background()
+	_, err = producer.Send(ctx, &ProducerMessage{Payload: []byte("dead")})
+	assert.Nil(t, err)
+
+	reader, err := client.CreateDLQReader(options)
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	msg, err := reader.Next(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("dead"), msg.Payload())
+}
+
+func TestRLQNotEnabled(t *testing.T) {
+	client, err := NewClient(ClientOptions{URL: lookupURL})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{Topic: topic})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	_, err = producer.Send(ctx, &ProducerMessage{Payload: []byte("hello")})
+	assert.Nil(t, err)
+
+	msg, err := consumer.Receive(ctx)
+	assert.Nil(t, err)
+
+	err = consumer.ReconsumeLater(msg, time.Second)
+	assert.Error(t, err)
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, InvalidConfiguration, e.Result())
+}
+
 func TestRLQWithCustomProperties(t *testing.T) {
 	topic := newTopicName()
 	testURL := adminURL + "/" + "admin/v2/persistent/public/default/" + topic + "/partitions"
@@ -4427,3 +4526,535 @@ func TestConsumerAckCumulativeOnSharedSubShouldFailed(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.ErrorIs(t, err, ErrInvalidAck)
 }
+
+func TestConsumerStopAtMessageID(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	var stopAt MessageID
+	for i := 0; i < 10; i++ {
+		msgID, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.Nil(t, err)
+		if i == 4 {
+			stopAt = msgID
+		}
+	}
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            "my-sub",
+		SubscriptionInitialPosition: SubscriptionPositionEarliest,
+		StopAtMessageID:             stopAt,
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	for i := 0; i < 5; i++ {
+		msg, err := consumer.Receive(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("hello-%d", i), string(msg.Payload()))
+		consumer.Ack(msg)
+	}
+
+	msg, err := consumer.Receive(ctx)
+	assert.Nil(t, msg)
+	assert.Equal(t, StopMessageIDReached, err.(*Error).Result())
+
+	_, err = client.Subscribe(ConsumerOptions{
+		Topics:           []string{topic, newTopicName()},
+		SubscriptionName: "my-sub-2",
+		StopAtMessageID:  stopAt,
+	})
+	assert.NotNil(t, err)
+}
+
+func TestConsumerAckCumulativeBackward(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		Type:             Exclusive,
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	msgs := make([]Message, 0, 3)
+	for i := 0; i < 3; i++ {
+		_, err = producer.Send(context.Background(), &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.Nil(t, err)
+
+		msg, err := consumer.Receive(context.Background())
+		assert.Nil(t, err)
+		msgs = append(msgs, msg)
+	}
+
+	assert.Nil(t, consumer.AckCumulative(msgs[1]))
+	// acking the same position again is allowed
+	assert.Nil(t, consumer.AckCumulative(msgs[1]))
+
+	err = consumer.AckCumulative(msgs[0])
+	assert.ErrorIs(t, err, ErrInvalidAckPosition)
+
+	assert.Nil(t, consumer.AckCumulative(msgs[2]))
+}
+
+func TestConsumerPriorityLevel(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	_, err = client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		PriorityLevel:    -1,
+	})
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	primary, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		Type:             Shared,
+	})
+	assert.Nil(t, err)
+	defer primary.Close()
+
+	standby, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		Type:             Shared,
+		PriorityLevel:    1,
+	})
+	assert.Nil(t, err)
+	defer standby.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.Nil(t, err)
+	}
+
+	// the primary consumer has enough permits to receive all the messages
+	for i := 0; i < 10; i++ {
+		msg, err := primary.Receive(ctx)
+		assert.Nil(t, err)
+		assert.Nil(t, primary.Ack(msg))
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	msg, err := standby.Receive(timeoutCtx)
+	assert.Nil(t, msg)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConsumerOnAssignmentChanged(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := "persistent://public/default/" + newTopicName()
+
+	type assignment struct {
+		revoked, assigned []string
+	}
+	changes := make(chan assignment, 10)
+	standby, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		Name:             "b",
+		Type:             Failover,
+		PriorityLevel:    1,
+		OnAssignmentChanged: func(revoked, assigned []string) {
+			changes <- assignment{revoked, assigned}
+		},
+	})
+	assert.Nil(t, err)
+	defer standby.Close()
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, assignment{nil, []string{topic}}, change)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the topic was not assigned")
+	}
+
+	primary, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		Name:             "a",
+		Type:             Failover,
+	})
+	assert.Nil(t, err)
+	defer primary.Close()
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, assignment{[]string{topic}, nil}, change)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the topic was not revoked")
+	}
+}
+
+func TestConsumerAckStore(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	store, err := NewFileAckStore(filepath.Join(t.TempDir(), "acks"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	c, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+	})
+	assert.Nil(t, err)
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.Nil(t, err)
+	}
+
+	// the acknowledgment of the first messages was persisted, but never reached the broker
+	for i := 0; i < 10; i++ {
+		msg, err := c.Receive(ctx)
+		assert.Nil(t, err)
+		if i < 5 {
+			assert.Nil(t, store.Ack(msg.Topic(), "my-sub", msg.ID()))
+		}
+	}
+	c.Close()
+
+	c, err = client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		AckStore:         store,
+	})
+	assert.Nil(t, err)
+	defer c.Close()
+
+	for i := 5; i < 10; i++ {
+		msg, err := c.Receive(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("hello-%d", i), string(msg.Payload()))
+		assert.Nil(t, c.Ack(msg))
+		assert.True(t, store.IsAcked(msg.Topic(), "my-sub", msg.ID()))
+	}
+}
+
+func TestConsumerOldestUnackedAge(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	_, err = client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		OnOldUnacked:     func(time.Duration) {},
+	})
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	ages := make(chan time.Duration, 10)
+	c, err := client.Subscribe(ConsumerOptions{
+		Topic:               topic,
+		SubscriptionName:    "my-sub",
+		OldUnackedThreshold: time.Second,
+		OnOldUnacked: func(age time.Duration) {
+			ages <- age
+		},
+	})
+	assert.Nil(t, err)
+	defer c.Close()
+	assert.Equal(t, time.Duration(0), c.OldestUnackedAge())
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err = producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.Nil(t, err)
+	}
+
+	first, err := c.Receive(ctx)
+	assert.Nil(t, err)
+	second, err := c.Receive(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Ack(second))
+
+	// the first message is still unacked
+	select {
+	case age := <-ages:
+		assert.Greater(t, age, time.Second)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnOldUnacked was not called")
+	}
+	assert.Greater(t, c.OldestUnackedAge(), time.Second)
+
+	assert.Nil(t, c.Ack(first))
+	assert.Equal(t, time.Duration(0), c.OldestUnackedAge())
+	select {
+	case <-ages:
+		t.Fatal("OnOldUnacked was called twice for the same message")
+	case <-time.After(2 * time.Second):
+	}
+}
+
+func TestConsumerCryptoFailureHandler(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+
+	// the handler is required by the callback action
+	_, err = client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "crypto-handler",
+		Decryption: &MessageDecryptionInfo{
+			ConsumerCryptoFailureAction: crypto.ConsumerCryptoFailureActionCallback,
+		},
+	})
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	var mu sync.Mutex
+	var failed []string
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "crypto-handler",
+		Decryption: &MessageDecryptionInfo{
+			KeyReader:                   crypto.NewFileKeyReader("", "crypto/testdata/pri_key_rsa_old.pem"),
+			ConsumerCryptoFailureAction: crypto.ConsumerCryptoFailureActionCallback,
+			CryptoFailureHandler: func(msg Message, err error) CryptoFailureAction {
+				assert.Error(t, err)
+				assert.NotNil(t, msg.GetEncryptionContext())
+				mu.Lock()
+				defer mu.Unlock()
+				failed = append(failed, msg.Properties()["action"])
+				if msg.Properties()["action"] == "discard" {
+					return crypto.ConsumerCryptoFailureActionDiscard
+				}
+				return crypto.ConsumerCryptoFailureActionConsume
+			},
+		},
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+		Encryption: &ProducerEncryptionInfo{
+			KeyReader: crypto.NewFileKeyReader("crypto/testdata/pub_key_rsa.pem", ""),
+			Keys:      []string{"client-rsa.pem"},
+		},
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	ctx := context.Background()
+	for _, action := range []string{"discard", "consume"} {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload:    []byte("hello"),
+			Properties: map[string]string{"action": action},
+		})
+		assert.Nil(t, err)
+	}
+
+	// the discarded message is not delivered, the other one is delivered encrypted
+	msg, err := consumer.Receive(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "consume", msg.Properties()["action"])
+	assert.NotEqual(t, []byte("hello"), msg.Payload())
+	assert.NotNil(t, msg.GetEncryptionContext())
+	consumer.Ack(msg)
+
+	mu.Lock()
+	assert.Equal(t, []string{"discard", "consume"}, failed)
+	mu.Unlock()
+}
+
+func TestConsumerLogFields(t *testing.T) {
+	logger, hook := logrustest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	client, err := NewClient(ClientOptions{
+		URL:    lookupURL,
+		Logger: plog.NewLoggerWithLogrus(logger),
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	assert.Nil(t, createPartitionedTopic(topic, 2))
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+	})
+	assert.Nil(t, err)
+	consumer.Close()
+
+	// the logs of the partition consumers carry their topic, partition and subscription as fields
+	partitions := map[interface{}]bool{}
+	for _, entry := range hook.AllEntries() {
+		if entry.Message != "Created consumer" {
+			continue
+		}
+		assert.Contains(t, entry.Data["topic"], topic+"-partition-")
+		assert.Equal(t, "my-sub", entry.Data["subscription"])
+		assert.NotContains(t, entry.Message, topic)
+		partitions[entry.Data["partition"]] = true
+	}
+	assert.Equal(t, map[interface{}]bool{0: true, 1: true}, partitions)
+}
+
+func TestReachedStopMessageID(t *testing.T) {
+	entry := newMessageID(1, 2, -1, 0, 0)
+	batchIdx := newMessageID(1, 2, 1, 0, 3)
+
+	// a whole entry stop id is reached by the last message of the batch
+	assert.False(t, reachedStopMessageID(newMessageID(1, 2, 0, 0, 3), entry))
+	assert.False(t, reachedStopMessageID(newMessageID(1, 2, 1, 0, 3), entry))
+	assert.True(t, reachedStopMessageID(newMessageID(1, 2, 2, 0, 3), entry))
+
+	// a batch index stop id is reached by the message with this index, or any message after it
+	assert.False(t, reachedStopMessageID(newMessageID(1, 2, 0, 0, 3), batchIdx))
+	assert.True(t, reachedStopMessageID(newMessageID(1, 2, 1, 0, 3), batchIdx))
+	assert.True(t, reachedStopMessageID(newMessageID(1, 2, 2, 0, 3), batchIdx))
+	assert.True(t, reachedStopMessageID(newMessageID(1, 3, 0, 0, 1), batchIdx))
+
+	// a batch index past the end of the batch is reached by its last message
+	assert.True(t, reachedStopMessageID(newMessageID(1, 2, 2, 0, 3), newMessageID(1, 2, 5, 0, 6)))
+	assert.False(t, reachedStopMessageID(newMessageID(1, 1, 2, 0, 3), entry))
+}
+
+func TestConsumerStopAtBatchMessageID(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:                   topic,
+		BatchingMaxMessages:     5,
+		BatchingMaxPublishDelay: time.Minute,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	// two batches of 5 messages
+	ids := make(chan MessageID, 10)
+	for i := 0; i < 10; i++ {
+		producer.SendAsync(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		}, func(id MessageID, _ *ProducerMessage, err error) {
+			assert.Nil(t, err)
+			ids <- id
+		})
+	}
+	assert.Nil(t, producer.Flush())
+	var first MessageID
+	for i := 0; i < 10; i++ {
+		id := <-ids
+		if i == 0 {
+			first = id
+		}
+	}
+
+	// stop at the whole entry of the first batch
+	stopAt := newMessageID(first.LedgerID(), first.EntryID(), -1, first.PartitionIdx(), 0)
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            "my-sub",
+		SubscriptionInitialPosition: SubscriptionPositionEarliest,
+		StopAtMessageID:             stopAt,
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	for i := 0; i < 5; i++ {
+		msg, err := consumer.Receive(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("hello-%d", i), string(msg.Payload()))
+		consumer.Ack(msg)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	msg, err := consumer.Receive(ctx)
+	assert.Nil(t, msg)
+	assert.Equal(t, StopMessageIDReached, err.(*Error).Result())
+}
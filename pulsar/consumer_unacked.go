@@ -0,0 +1,156 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const oldUnackedCheckInterval = time.Second
+
+type unackedMessage struct {
+	key         messageKey
+	deliveredAt time.Time
+	// redeliveryCount of the message when it was delivered, for the NackBackoffPolicy of NackID
+	redeliveryCount uint32
+}
+
+// unackedTracker keeps track of the messages dispatched to the application which are not acknowledged yet, in
+// the order of their delivery, so that the oldest one is always at the front.
+type unackedTracker struct {
+	sync.Mutex
+	order    *list.List
+	messages map[messageKey]*list.Element
+}
+
+func newUnackedTracker() *unackedTracker {
+	return &unackedTracker{
+		order:    list.New(),
+		messages: make(map[messageKey]*list.Element),
+	}
+}
+
+func (t *unackedTracker) add(id MessageID, deliveredAt time.Time, redeliveryCount uint32) {
+	key := newMessageKey(id)
+
+	t.Lock()
+	defer t.Unlock()
+	if _, ok := t.messages[key]; ok {
+		return
+	}
+	t.messages[key] = t.order.PushBack(&unackedMessage{
+		key:             key,
+		deliveredAt:     deliveredAt,
+		redeliveryCount: redeliveryCount,
+	})
+}
+
+// remove stops tracking the message, and returns its redelivery count if it was tracked
+func (t *unackedTracker) remove(id MessageID) (redeliveryCount uint32, tracked bool) {
+	key := newMessageKey(id)
+
+	t.Lock()
+	defer t.Unlock()
+	e, ok := t.messages[key]
+	if !ok {
+		return 0, false
+	}
+	t.order.Remove(e)
+	delete(t.messages, key)
+	return e.Value.(*unackedMessage).redeliveryCount, true
+}
+
+// removeUpTo removes the messages up to and including the given message, after a cumulative ack
+func (t *unackedTracker) removeUpTo(id MessageID) {
+	t.Lock()
+	defer t.Unlock()
+	for e := t.order.Front(); e != nil; {
+		next := e.Next()
+		m := e.Value.(*unackedMessage)
+		if messageIDCompare(m.key.messageID(), id) <= 0 {
+			t.order.Remove(e)
+			delete(t.messages, m.key)
+		}
+		e = next
+	}
+}
+
+func (t *unackedTracker) clear() {
+	t.Lock()
+	defer t.Unlock()
+	t.order.Init()
+	t.messages = make(map[messageKey]*list.Element)
+}
+
+// oldest returns the oldest unacknowledged message, or nil if there is none
+func (t *unackedTracker) oldest() *unackedMessage {
+	t.Lock()
+	defer t.Unlock()
+	if e := t.order.Front(); e != nil {
+		m := *e.Value.(*unackedMessage)
+		return &m
+	}
+	return nil
+}
+
+// oldUnackedMonitor invokes ConsumerOptions.OnOldUnacked once for each message that becomes the oldest
+// unacknowledged message of the consumer while being older than ConsumerOptions.OldUnackedThreshold.
+func (c *consumer) oldUnackedMonitor() {
+	ticker := time.NewTicker(oldUnackedCheckInterval)
+	defer ticker.Stop()
+
+	var reported *unackedMessage
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case now := <-ticker.C:
+			oldest := c.oldestUnacked()
+			if oldest == nil {
+				continue
+			}
+			age := now.Sub(oldest.deliveredAt)
+			if age > c.options.OldUnackedThreshold && (reported == nil || *reported != *oldest) {
+				reported = oldest
+				c.options.OnOldUnacked(age)
+			}
+		}
+	}
+}
+
+func (c *consumer) oldestUnacked() *unackedMessage {
+	c.Lock()
+	defer c.Unlock()
+
+	var oldest *unackedMessage
+	for _, pc := range c.consumers {
+		if m := pc.unacked.oldest(); m != nil && (oldest == nil || m.deliveredAt.Before(oldest.deliveredAt)) {
+			oldest = m
+		}
+	}
+	return oldest
+}
+
+func (c *consumer) OldestUnackedAge() time.Duration {
+	if oldest := c.oldestUnacked(); oldest != nil {
+		return time.Since(oldest.deliveredAt)
+	}
+	return 0
+}
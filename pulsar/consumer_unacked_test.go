@@ -0,0 +1,61 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnackedTracker(t *testing.T) {
+	tracker := newUnackedTracker()
+	assert.Nil(t, tracker.oldest())
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		tracker.add(NewMessageID(1, int64(i), -1, 0), now.Add(time.Duration(i)*time.Second), 0)
+	}
+	// a message is only tracked from its first delivery
+	tracker.add(NewMessageID(1, 0, -1, 0), now.Add(time.Minute), 0)
+	assert.Equal(t, now, tracker.oldest().deliveredAt)
+
+	tracker.remove(NewMessageID(1, 0, -1, 0))
+	assert.Equal(t, now.Add(time.Second), tracker.oldest().deliveredAt)
+
+	tracker.remove(NewMessageID(1, 3, -1, 0))
+	tracker.removeUpTo(NewMessageID(1, 2, -1, 0))
+	assert.Equal(t, now.Add(4*time.Second), tracker.oldest().deliveredAt)
+
+	tracker.clear()
+	assert.Nil(t, tracker.oldest())
+}
+
+func TestUnackedTrackerRedeliveryCount(t *testing.T) {
+	tracker := newUnackedTracker()
+	id := NewMessageID(1, 2, -1, 0)
+	tracker.add(id, time.Now(), 3)
+
+	count, tracked := tracker.remove(id)
+	assert.True(t, tracked)
+	assert.Equal(t, uint32(3), count)
+
+	_, tracked = tracker.remove(id)
+	assert.False(t, tracked)
+}
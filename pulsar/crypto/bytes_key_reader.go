@@ -0,0 +1,64 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import "errors"
+
+var (
+	errNoPublicKey  = errors.New("no public key configured")
+	errNoPrivateKey = errors.New("no private key configured")
+)
+
+// BytesKeyReader is a KeyReader providing PEM encoded keys held in memory, e.g. the keys fetched from a KMS
+type BytesKeyReader struct {
+	publicKey  []byte
+	privateKey []byte
+}
+
+// NewBytesKeyReader creates a BytesKeyReader over the given PEM encoded keys. Either key may be nil, when the
+// reader is only used by a producer (public key) or by a consumer (private key).
+func NewBytesKeyReader(publicKeyPEM, privateKeyPEM []byte) *BytesKeyReader {
+	return &BytesKeyReader{
+		publicKey:  copyKey(publicKeyPEM),
+		privateKey: copyKey(privateKeyPEM),
+	}
+}
+
+// PublicKey get the public key given to the reader
+func (b *BytesKeyReader) PublicKey(keyName string, keyMeta map[string]string) (*EncryptionKeyInfo, error) {
+	if len(b.publicKey) == 0 {
+		return nil, errNoPublicKey
+	}
+	return NewEncryptionKeyInfo(keyName, b.publicKey, keyMeta), nil
+}
+
+// PrivateKey get the private key given to the reader
+func (b *BytesKeyReader) PrivateKey(keyName string, keyMeta map[string]string) (*EncryptionKeyInfo, error) {
+	if len(b.privateKey) == 0 {
+		return nil, errNoPrivateKey
+	}
+	return NewEncryptionKeyInfo(keyName, b.privateKey, keyMeta), nil
+}
+
+// copyKey copies a key, so that the caller can reuse its buffer
+func copyKey(key []byte) []byte {
+	if key == nil {
+		return nil
+	}
+	return append([]byte{}, key...)
+}
@@ -0,0 +1,85 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import (
+	"os"
+	"testing"
+
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBytesKeyReader(t *testing.T) {
+	publicKey, err := os.ReadFile("../crypto/testdata/pub_key_rsa.pem")
+	require.NoError(t, err)
+	privateKey, err := os.ReadFile("../crypto/testdata/pri_key_rsa.pem")
+	require.NoError(t, err)
+
+	keyReader := NewBytesKeyReader(publicKey, privateKey)
+	keyInfo, err := keyReader.PublicKey("test-key", map[string]string{"key": "value"})
+	assert.Nil(t, err)
+	assert.Equal(t, "test-key", keyInfo.Name())
+	assert.Equal(t, publicKey, keyInfo.Key())
+	assert.Equal(t, "value", keyInfo.Metadata()["key"])
+
+	keyInfo, err = keyReader.PrivateKey("test-key", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, privateKey, keyInfo.Key())
+
+	// the keys are copied
+	publicKey[0] = 'x'
+	keyInfo, err = keyReader.PublicKey("test-key", nil)
+	assert.Nil(t, err)
+	assert.NotEqual(t, publicKey, keyInfo.Key())
+}
+
+func TestBytesKeyReaderSingleKey(t *testing.T) {
+	publicKey, err := os.ReadFile("../crypto/testdata/pub_key_rsa.pem")
+	require.NoError(t, err)
+	privateKey, err := os.ReadFile("../crypto/testdata/pri_key_rsa.pem")
+	require.NoError(t, err)
+
+	// producer side, only the public key is available
+	producerReader := NewBytesKeyReader(publicKey, nil)
+	keyInfo, err := producerReader.PrivateKey("test-key", nil)
+	assert.Nil(t, keyInfo)
+	assert.Error(t, err)
+
+	// consumer side, only the private key is available
+	consumerReader := NewBytesKeyReader(nil, privateKey)
+	keyInfo, err = consumerReader.PublicKey("test-key", nil)
+	assert.Nil(t, keyInfo)
+	assert.Error(t, err)
+
+	msgCrypto, err := NewDefaultMessageCrypto("bytes-key-reader", true, log.DefaultNopLogger())
+	require.NoError(t, err)
+	msgMetadata := &pb.MessageMetadata{}
+	metadataSupplier := NewMessageMetadataSupplier(msgMetadata)
+
+	encrypted, err := msgCrypto.Encrypt([]string{"test-key"}, producerReader, metadataSupplier, []byte("hello"))
+	require.NoError(t, err)
+
+	msgCryptoDecrypt, err := NewDefaultMessageCrypto("bytes-key-reader", true, log.DefaultNopLogger())
+	require.NoError(t, err)
+	decrypted, err := msgCryptoDecrypt.Decrypt(metadataSupplier, encrypted, consumerReader)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), decrypted)
+}
@@ -40,4 +40,8 @@ const (
 	// delivered encrypted message contains EncryptionContext which contains encryption
 	// and compression information in it using which application can decrypt the payload.
 	ConsumerCryptoFailureActionConsume
+
+	// ConsumerCryptoFailureActionCallback calls the handler configured with the decryption settings of the consumer,
+	// which decides of the action to take for each message, e.g. after sending it to a quarantine topic.
+	ConsumerCryptoFailureActionCallback
 )
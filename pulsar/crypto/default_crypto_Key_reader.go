@@ -25,6 +25,9 @@ type FileKeyReader struct {
 	privateKeyPath string
 }
 
+// NewFileKeyReader creates a FileKeyReader over the given PEM files. The keys can be RSA or EC keys, e.g. P-256
+// keys generated by `openssl ecparam -name prime256v1 -genkey`, the encryption algorithm of the data key being
+// selected from the type of the key.
 func NewFileKeyReader(publicKeyPath, privateKeyPath string) *FileKeyReader {
 	return &FileKeyReader{
 		publicKeyPath:  publicKeyPath,
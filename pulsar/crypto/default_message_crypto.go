@@ -21,6 +21,7 @@ import (
 	gocrypto "crypto"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ecdsa"
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/rsa"
@@ -74,55 +75,84 @@ func NewDefaultMessageCrypto(logCtx string, keyGenNeeded bool, logger log.Logger
 	return d, nil
 }
 
-// AddPublicKeyCipher encrypt data key using keyCrypto and cache
+// AddPublicKeyCipher generates a new data key, encrypts it using the public keys read again from keyReader and
+// caches them, so that the messages encrypted afterwards use the new data key. The data key is only replaced once
+// it has been encrypted with all the keys: on error, the previous data key is still used.
 func (d *DefaultMessageCrypto) AddPublicKeyCipher(keyNames []string, keyReader KeyReader) error {
 	key, err := generateDataKey()
 	if err != nil {
 		return err
 	}
 
-	d.dataKey = key
+	encryptedDataKeys := make(map[string]*EncryptionKeyInfo, len(keyNames))
 	for _, keyName := range keyNames {
-		err := d.addPublicKeyCipher(keyName, keyReader)
+		keyInfo, err := d.encryptDataKey(keyName, keyReader, key)
 		if err != nil {
 			return err
 		}
+		encryptedDataKeys[keyName] = keyInfo
+	}
+
+	d.encryptLock.Lock()
+	defer d.encryptLock.Unlock()
+	d.dataKey = key
+	// the other keys encrypt the previous data key, they are encrypted again when used
+	d.encryptedDataKeyMap.Range(func(keyName, _ interface{}) bool {
+		if _, ok := encryptedDataKeys[keyName.(string)]; !ok {
+			d.encryptedDataKeyMap.Delete(keyName)
+		}
+		return true
+	})
+	for keyName, keyInfo := range encryptedDataKeys {
+		d.encryptedDataKeyMap.Store(keyName, keyInfo)
 	}
 	return nil
 }
 
 func (d *DefaultMessageCrypto) addPublicKeyCipher(keyName string, keyReader KeyReader) error {
+	keyInfo, err := d.encryptDataKey(keyName, keyReader, d.dataKey)
+	if err != nil {
+		return err
+	}
+	d.encryptedDataKeyMap.Store(keyName, keyInfo)
+	return nil
+}
+
+// encryptDataKey encrypts the data key using the public key keyName read from keyReader
+func (d *DefaultMessageCrypto) encryptDataKey(keyName string, keyReader KeyReader,
+	dataKey []byte) (*EncryptionKeyInfo, error) {
 	d.cipherLock.Lock()
 	defer d.cipherLock.Unlock()
 	if keyName == "" || keyReader == nil {
-		return fmt.Errorf("keyname or keyreader is null")
+		return nil, fmt.Errorf("keyname or keyreader is null")
 	}
 
 	// read the public key and its info using keyReader
 	keyInfo, err := keyReader.PublicKey(keyName, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	parsedKey, err := d.loadPublicKey(keyInfo.Key())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// try to cast to RSA key
-	rsaPubKey, ok := parsedKey.(*rsa.PublicKey)
-	if !ok {
-		return fmt.Errorf("only RSA keys are supported")
+	// the algorithm depends on the type of the key
+	var encryptedDataKey []byte
+	switch pubKey := parsedKey.(type) {
+	case *rsa.PublicKey:
+		encryptedDataKey, err = rsa.EncryptOAEP(sha1.New(), rand.Reader, pubKey, dataKey, nil)
+	case *ecdsa.PublicKey:
+		encryptedDataKey, err = eciesEncrypt(pubKey, dataKey)
+	default:
+		return nil, fmt.Errorf("only RSA and EC keys are supported")
 	}
-
-	encryptedDataKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, rsaPubKey, d.dataKey, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	d.encryptedDataKeyMap.Store(keyName, NewEncryptionKeyInfo(keyName, encryptedDataKey, keyInfo.Metadata()))
-
-	return nil
+	return NewEncryptionKeyInfo(keyName, encryptedDataKey, keyInfo.Metadata()), nil
 }
 
 // RemoveKeyCipher remove encrypted data key from cache
@@ -297,41 +327,71 @@ func (d *DefaultMessageCrypto) decryptDataKey(keyName string,
 	keyMeta map[string]string,
 	keyReader KeyReader) bool {
 
-	keyInfo, err := keyReader.PrivateKey(keyName, keyMeta)
-	if err != nil {
-		d.logger.Error(err)
-		return false
+	var keyInfos []*EncryptionKeyInfo
+	if multiKeyReader, ok := keyReader.(PrivateKeysReader); ok {
+		infos, err := multiKeyReader.PrivateKeys(keyName, keyMeta)
+		if err != nil {
+			d.logger.Error(err)
+			return false
+		}
+		keyInfos = infos
+	} else {
+		keyInfo, err := keyReader.PrivateKey(keyName, keyMeta)
+		if err != nil {
+			d.logger.Error(err)
+			return false
+		}
+		keyInfos = []*EncryptionKeyInfo{keyInfo}
 	}
 
-	parsedKey, err := d.loadPrivateKey(keyInfo.Key())
-	if err != nil {
-		d.logger.Error(err)
-		return false
+	// try each candidate private key until one decrypts the data key
+	for _, keyInfo := range keyInfos {
+		decryptedDataKey, err := d.decryptDataKeyWith(keyInfo, encDatakey)
+		if err != nil {
+			d.logger.Error(err)
+			continue
+		}
+		d.dataKey = decryptedDataKey
+		d.loadingCache.Store(fmt.Sprintf("%x", md5.Sum(encDatakey)), d.dataKey)
+		return true
 	}
 
-	rsaPriKey, ok := parsedKey.(*rsa.PrivateKey)
-	if !ok {
-		d.logger.Error("only RSA keys are supported")
-		return false
-	}
+	return false
+}
 
-	decryptedDataKey, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, rsaPriKey, encDatakey, nil)
+func (d *DefaultMessageCrypto) decryptDataKeyWith(keyInfo *EncryptionKeyInfo, encDatakey []byte) ([]byte, error) {
+	parsedKey, err := d.loadPrivateKey(keyInfo.Key())
 	if err != nil {
-		d.logger.Error(err)
-		return false
+		return nil, err
 	}
-	d.dataKey = decryptedDataKey
-	d.loadingCache.Store(fmt.Sprintf("%x", md5.Sum(encDatakey)), d.dataKey)
 
-	return true
+	switch priKey := parsedKey.(type) {
+	case *rsa.PrivateKey:
+		return rsa.DecryptOAEP(sha1.New(), rand.Reader, priKey, encDatakey, nil)
+	case *ecdsa.PrivateKey:
+		return eciesDecrypt(priKey, encDatakey)
+	default:
+		return nil, errors.New("only RSA and EC keys are supported")
+	}
 }
 
+// read the private key into an RSA or EC key
 func (d *DefaultMessageCrypto) loadPrivateKey(key []byte) (gocrypto.PrivateKey, error) {
 	var privateKey gocrypto.PrivateKey
-	priPem, _ := pem.Decode(key)
+	priPem, rest := pem.Decode(key)
+	// the EC keys generated by openssl are preceded by their parameters
+	for priPem != nil && priPem.Type == "EC PARAMETERS" {
+		priPem, rest = pem.Decode(rest)
+	}
 	if priPem == nil {
 		return privateKey, fmt.Errorf("failed to decode private key")
 	}
+	switch priPem.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(priPem.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(priPem.Bytes)
+	}
 	genericPrivateKey, err := x509.ParsePKCS1PrivateKey(priPem.Bytes)
 	if err != nil {
 		return privateKey, err
@@ -340,7 +400,7 @@ func (d *DefaultMessageCrypto) loadPrivateKey(key []byte) (gocrypto.PrivateKey,
 	return privateKey, nil
 }
 
-// read the public key into RSA key
+// read the public key into an RSA or EC key
 func (d *DefaultMessageCrypto) loadPublicKey(key []byte) (gocrypto.PublicKey, error) {
 	var publickKey gocrypto.PublicKey
 
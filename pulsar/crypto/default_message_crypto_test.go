@@ -18,6 +18,10 @@
 package crypto
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 
 	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
@@ -66,6 +70,63 @@ func TestAddPublicKeyCipher(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestAddPublicKeyCipherRotation(t *testing.T) {
+	newKeyReader := func() KeyReader {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.Nil(t, err)
+		publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		assert.Nil(t, err)
+		return NewBytesKeyReader(
+			pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}),
+			pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		)
+	}
+	oldKeyReader := newKeyReader()
+	rotatedKeyReader := newKeyReader()
+
+	msgCrypto, err := NewDefaultMessageCrypto("my-app", true, log.DefaultNopLogger())
+	assert.Nil(t, err)
+
+	encrypt := func(keyReader KeyReader) (*pb.MessageMetadata, []byte) {
+		msgMetadata := &pb.MessageMetadata{}
+		encrypted, err := msgCrypto.Encrypt([]string{"my-app.key"}, keyReader,
+			NewMessageMetadataSupplier(msgMetadata), []byte("my-message"))
+		assert.Nil(t, err)
+		return msgMetadata, encrypted
+	}
+	decrypt := func(msgMetadata *pb.MessageMetadata, encrypted []byte, keyReader KeyReader) error {
+		msgCryptoDecrypt, err := NewDefaultMessageCrypto("my-app", false, log.DefaultNopLogger())
+		assert.Nil(t, err)
+		decrypted, err := msgCryptoDecrypt.Decrypt(NewMessageMetadataSupplier(msgMetadata), encrypted, keyReader)
+		if err == nil {
+			assert.Equal(t, "my-message", string(decrypted))
+		}
+		return err
+	}
+
+	oldMetadata, oldEncrypted := encrypt(oldKeyReader)
+
+	// the cached data key is used until it is refreshed
+	newMetadata, newEncrypted := encrypt(rotatedKeyReader)
+	assert.Nil(t, decrypt(newMetadata, newEncrypted, oldKeyReader))
+
+	// a failed refresh keeps the previous data key
+	assert.NotNil(t, msgCrypto.AddPublicKeyCipher([]string{"my-app.key"},
+		NewFileKeyReader("../crypto/testdata/no_pub_key_rsa.pem", "")))
+	newMetadata, newEncrypted = encrypt(rotatedKeyReader)
+	assert.Nil(t, decrypt(newMetadata, newEncrypted, oldKeyReader))
+
+	assert.Nil(t, msgCrypto.AddPublicKeyCipher([]string{"my-app.key"}, rotatedKeyReader))
+	newMetadata, newEncrypted = encrypt(rotatedKeyReader)
+	assert.NotNil(t, decrypt(newMetadata, newEncrypted, oldKeyReader))
+	assert.Nil(t, decrypt(newMetadata, newEncrypted, rotatedKeyReader))
+
+	// the messages of both keys are decrypted with both private keys
+	keyReaders := NewMultiKeyReader(rotatedKeyReader, oldKeyReader)
+	assert.Nil(t, decrypt(oldMetadata, oldEncrypted, keyReaders))
+	assert.Nil(t, decrypt(newMetadata, newEncrypted, keyReaders))
+}
+
 func TestEncrypt(t *testing.T) {
 	msgMetadata := &pb.MessageMetadata{}
 	msgMetadataSupplier := NewMessageMetadataSupplier(msgMetadata)
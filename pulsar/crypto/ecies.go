@@ -0,0 +1,122 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// The data key is encrypted with an EC public key using the ECIES scheme of Bouncy Castle used by the Java
+// client: an ephemeral key agreement (ECDH), the KDF2 key derivation with SHA-1, the XOR of the data with the
+// derived key, and an HMAC-SHA1 tag. The encrypted data key is the uncompressed ephemeral public key followed by
+// the encrypted data and the tag.
+
+// eciesMacKeySize is the size of the HMAC key, as Bouncy Castle defaults it to 128 bits without cipher
+const eciesMacKeySize = 16
+
+// eciesEncrypt encrypts data with the public key pub
+func eciesEncrypt(pub *ecdsa.PublicKey, data []byte) ([]byte, error) {
+	ephemeral, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	v := elliptic.Marshal(pub.Curve, ephemeral.X, ephemeral.Y)
+	z, _ := pub.Curve.ScalarMult(pub.X, pub.Y, ephemeral.D.Bytes())
+
+	encKey, macKey := eciesDeriveKeys(pub.Curve, v, z, len(data))
+	out := make([]byte, 0, len(v)+len(data)+sha1.Size)
+	out = append(out, v...)
+	for i, b := range data {
+		out = append(out, b^encKey[i])
+	}
+	return append(out, eciesTag(macKey, out[len(v):])...), nil
+}
+
+// eciesDecrypt decrypts data encrypted with the public key of priv
+func eciesDecrypt(priv *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("invalid EC encrypted data key")
+	}
+	byteLen := (priv.Curve.Params().BitSize + 7) / 8
+	var x, y *big.Int
+	var vLen int
+	switch data[0] {
+	case 2, 3:
+		vLen = 1 + byteLen
+		if len(data) >= vLen {
+			x, y = elliptic.UnmarshalCompressed(priv.Curve, data[:vLen])
+		}
+	case 4:
+		vLen = 1 + 2*byteLen
+		if len(data) >= vLen {
+			x, y = elliptic.Unmarshal(priv.Curve, data[:vLen])
+		}
+	}
+	if x == nil || len(data) < vLen+sha1.Size {
+		return nil, errors.New("invalid EC encrypted data key")
+	}
+	v := data[:vLen]
+	encrypted := data[vLen : len(data)-sha1.Size]
+	tag := data[len(data)-sha1.Size:]
+
+	z, _ := priv.Curve.ScalarMult(x, y, priv.D.Bytes())
+	encKey, macKey := eciesDeriveKeys(priv.Curve, v, z, len(encrypted))
+	if !hmac.Equal(tag, eciesTag(macKey, encrypted)) {
+		return nil, errors.New("invalid EC encrypted data key tag")
+	}
+	decrypted := make([]byte, len(encrypted))
+	for i, b := range encrypted {
+		decrypted[i] = b ^ encKey[i]
+	}
+	return decrypted, nil
+}
+
+// eciesDeriveKeys derives the encryption and MAC keys from the ephemeral public key v and the shared secret z
+func eciesDeriveKeys(curve elliptic.Curve, v []byte, z *big.Int, size int) (encKey, macKey []byte) {
+	byteLen := (curve.Params().BitSize + 7) / 8
+	shared := make([]byte, 0, len(v)+byteLen)
+	shared = append(shared, v...)
+	shared = append(shared, z.FillBytes(make([]byte, byteLen))...)
+
+	// KDF2: the hashes of the shared secret followed by a counter starting at 1
+	key := make([]byte, 0, eciesMacKeySize+size+sha1.Size)
+	var counter [4]byte
+	for i := uint32(1); len(key) < eciesMacKeySize+size; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h := sha1.New()
+		h.Write(shared)
+		h.Write(counter[:])
+		key = h.Sum(key)
+	}
+	return key[eciesMacKeySize : eciesMacKeySize+size], key[:eciesMacKeySize]
+}
+
+// eciesTag returns the HMAC of the encrypted data, followed by the 64 bits length of the (empty) encoding vector
+func eciesTag(macKey, encrypted []byte) []byte {
+	mac := hmac.New(sha1.New, macKey)
+	mac.Write(encrypted)
+	mac.Write(make([]byte, 8))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,103 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECIES(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		require.NoError(t, err)
+
+		dataKey := make([]byte, 32)
+		_, err = rand.Read(dataKey)
+		require.NoError(t, err)
+
+		encrypted, err := eciesEncrypt(&key.PublicKey, dataKey)
+		require.NoError(t, err)
+		byteLen := (curve.Params().BitSize + 7) / 8
+		// uncompressed ephemeral key, encrypted data key and HMAC-SHA1 tag
+		assert.Len(t, encrypted, 1+2*byteLen+len(dataKey)+20)
+		assert.Equal(t, byte(4), encrypted[0])
+
+		decrypted, err := eciesDecrypt(key, encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, dataKey, decrypted)
+
+		// the tag is checked
+		encrypted[len(encrypted)-30] ^= 1
+		_, err = eciesDecrypt(key, encrypted)
+		assert.Error(t, err)
+
+		otherKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		require.NoError(t, err)
+		encrypted[len(encrypted)-30] ^= 1
+		_, err = eciesDecrypt(otherKey, encrypted)
+		assert.Error(t, err)
+
+		_, err = eciesDecrypt(key, encrypted[:10])
+		assert.Error(t, err)
+	}
+}
+
+func TestEncryptDecryptEC(t *testing.T) {
+	msgMetadata := &pb.MessageMetadata{}
+	msgMetadataSupplier := NewMessageMetadataSupplier(msgMetadata)
+
+	msgCrypto, err := NewDefaultMessageCrypto("my-app", true, log.DefaultNopLogger())
+	assert.Nil(t, err)
+
+	encryptedData, err := msgCrypto.Encrypt(
+		[]string{"my-app.key"},
+		NewFileKeyReader("../crypto/testdata/pub_key_ec.pem", ""),
+		msgMetadataSupplier,
+		[]byte("my-message-01"),
+	)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, msgMetadataSupplier.EncryptionKeys())
+
+	msgCryptoDecrypt, err := NewDefaultMessageCrypto("my-app", false, log.DefaultNopLogger())
+	assert.Nil(t, err)
+
+	// the RSA private key can not decrypt the data key
+	decryptedData, err := msgCryptoDecrypt.Decrypt(
+		msgMetadataSupplier,
+		encryptedData,
+		NewFileKeyReader("", "../crypto/testdata/pri_key_rsa.pem"),
+	)
+	assert.NotNil(t, err)
+	assert.Nil(t, decryptedData)
+
+	decryptedData, err = msgCryptoDecrypt.Decrypt(
+		msgMetadataSupplier,
+		encryptedData,
+		NewFileKeyReader("", "../crypto/testdata/pri_key_ec.pem"),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "my-message-01", string(decryptedData))
+}
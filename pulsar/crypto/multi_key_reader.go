@@ -0,0 +1,91 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import "errors"
+
+var errNoKeyReader = errors.New("no key reader configured")
+
+// PrivateKeysReader can be implemented by a KeyReader to provide several candidate private keys for a key name,
+// e.g. both the new and the previous key during a key rotation. The consumer tries each of them, in order,
+// until the data key of the message is decrypted.
+type PrivateKeysReader interface {
+	// PrivateKeys get the candidate private keys that are used by the consumer to decrypt data key
+	PrivateKeys(keyName string, metadata map[string]string) ([]*EncryptionKeyInfo, error)
+}
+
+// MultiKeyReader is a KeyReader combining several KeyReaders, which lets consumers decrypt the messages
+// encrypted with any of their keys
+type MultiKeyReader struct {
+	readers []KeyReader
+}
+
+// NewMultiKeyReader creates a MultiKeyReader over the given readers, in order of preference
+func NewMultiKeyReader(readers ...KeyReader) *MultiKeyReader {
+	return &MultiKeyReader{
+		readers: readers,
+	}
+}
+
+// PublicKey get the public key of the first reader providing one
+func (m *MultiKeyReader) PublicKey(keyName string, metadata map[string]string) (*EncryptionKeyInfo, error) {
+	return m.first(func(r KeyReader) (*EncryptionKeyInfo, error) {
+		return r.PublicKey(keyName, metadata)
+	})
+}
+
+// PrivateKey get the private key of the first reader providing one
+func (m *MultiKeyReader) PrivateKey(keyName string, metadata map[string]string) (*EncryptionKeyInfo, error) {
+	return m.first(func(r KeyReader) (*EncryptionKeyInfo, error) {
+		return r.PrivateKey(keyName, metadata)
+	})
+}
+
+// PrivateKeys get the private keys of all the readers providing one
+func (m *MultiKeyReader) PrivateKeys(keyName string, metadata map[string]string) ([]*EncryptionKeyInfo, error) {
+	var keys []*EncryptionKeyInfo
+	err := errNoKeyReader
+	for i, r := range m.readers {
+		key, e := r.PrivateKey(keyName, metadata)
+		if e != nil {
+			if i == 0 {
+				err = e
+			}
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (m *MultiKeyReader) first(read func(KeyReader) (*EncryptionKeyInfo, error)) (*EncryptionKeyInfo, error) {
+	err := errNoKeyReader
+	for i, r := range m.readers {
+		key, e := read(r)
+		if e == nil {
+			return key, nil
+		}
+		if i == 0 {
+			err = e
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,83 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import (
+	"testing"
+
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiKeyReader(t *testing.T) {
+	reader := NewMultiKeyReader(
+		NewFileKeyReader("../crypto/testdata/no_pub_key_rsa.pem", "../crypto/testdata/no_pri_key_rsa.pem"),
+		NewFileKeyReader("../crypto/testdata/pub_key_rsa.pem", "../crypto/testdata/pri_key_rsa_old.pem"),
+		NewFileKeyReader("", "../crypto/testdata/pri_key_rsa.pem"),
+	)
+
+	pub, err := reader.PublicKey("my-app.key", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "my-app.key", pub.Name())
+
+	keys, err := reader.PrivateKeys("my-app.key", nil)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+
+	_, err = NewMultiKeyReader().PrivateKeys("my-app.key", nil)
+	assert.NotNil(t, err)
+}
+
+func TestDecryptWithMultipleKeys(t *testing.T) {
+	msgMetadataSupplier := NewMessageMetadataSupplier(&pb.MessageMetadata{})
+	msg := "my-message-01"
+
+	msgCrypto, err := NewDefaultMessageCrypto("my-app", true, log.DefaultNopLogger())
+	assert.Nil(t, err)
+	encryptedData, err := msgCrypto.Encrypt(
+		[]string{"my-app.key"},
+		NewFileKeyReader("../crypto/testdata/pub_key_rsa.pem", ""),
+		msgMetadataSupplier,
+		[]byte(msg),
+	)
+	assert.Nil(t, err)
+
+	// the previous key alone can not decrypt the message
+	msgCryptoDecrypt, err := NewDefaultMessageCrypto("my-app", true, log.DefaultNopLogger())
+	assert.Nil(t, err)
+	decryptedData, err := msgCryptoDecrypt.Decrypt(
+		msgMetadataSupplier,
+		encryptedData,
+		NewFileKeyReader("", "../crypto/testdata/pri_key_rsa_old.pem"),
+	)
+	assert.NotNil(t, err)
+	assert.Nil(t, decryptedData)
+
+	// the key which encrypted the message is found among the candidates
+	decryptedData, err = msgCryptoDecrypt.Decrypt(
+		msgMetadataSupplier,
+		encryptedData,
+		NewMultiKeyReader(
+			NewFileKeyReader("", "../crypto/testdata/pri_key_rsa_old.pem"),
+			NewFileKeyReader("", "../crypto/testdata/pri_key_rsa.pem"),
+		),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, string(decryptedData))
+}
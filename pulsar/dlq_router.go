@@ -101,6 +101,13 @@ func (r *dlqRouter) run() {
 			msg := cm.Message.(*message)
 			msgID := msg.ID()
 
+			payload, err := msg.fullPayload()
+			if err != nil {
+				r.log.WithError(err).WithField("msgID", msgID).Error("Failed to decompress message for DLQ")
+				go cm.Consumer.Nack(cm)
+				continue
+			}
+
 			// properties associated with original message
 			properties := msg.Properties()
 
@@ -111,7 +118,7 @@ func (r *dlqRouter) run() {
 			properties[SysPropertyRealTopic] = msg.Topic()
 
 			producer.SendAsync(context.Background(), &ProducerMessage{
-				Payload:             msg.Payload(),
+				Payload:             payload,
 				Key:                 msg.Key(),
 				OrderingKey:         msg.OrderingKey(),
 				Properties:          properties,
@@ -159,7 +166,10 @@ func (r *dlqRouter) getProducer(schema Schema) Producer {
 	for {
 		opt := r.policy.ProducerOptions
 		opt.Topic = r.policy.DeadLetterTopic
-		opt.Schema = schema
+		// the AutoConsumeSchema can not produce, the messages are forwarded without schema
+		if _, ok := schema.(*AutoConsumeSchema); !ok {
+			opt.Schema = schema
+		}
 		if opt.Name == "" {
 			opt.Name = fmt.Sprintf("%s-%s-%s-DLQ", r.topicName, r.subscriptionName, r.consumerName)
 		}
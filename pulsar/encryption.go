@@ -38,6 +38,8 @@ type ProducerEncryptionInfo struct {
 // MessageDecryptionInfo encryption related fields required by the consumer to decrypt the message
 type MessageDecryptionInfo struct {
 	// KeyReader read RSA public/private key pairs
+	// To decrypt messages encrypted with any of several keys, e.g. across a key rotation, use a
+	// crypto.MultiKeyReader or a KeyReader implementing crypto.PrivateKeysReader.
 	KeyReader crypto.KeyReader
 
 	// MessageCrypto used to encrypt and decrypt the data and session keys
@@ -45,4 +47,16 @@ type MessageDecryptionInfo struct {
 
 	// ConsumerCryptoFailureAction action to be taken on failure of message decryption
 	ConsumerCryptoFailureAction int
+
+	// CryptoFailureHandler decides of the action to take for a message that failed to be decrypted, when
+	// ConsumerCryptoFailureAction is crypto.ConsumerCryptoFailureActionCallback. The message is given as it
+	// would be delivered by crypto.ConsumerCryptoFailureActionConsume, with its encrypted payload and its
+	// EncryptionContext. The handler returns crypto.ConsumerCryptoFailureActionFail, Discard or Consume, any
+	// other value failing the message. It is called from the goroutine receiving the messages of the partition,
+	// which it blocks until it returns.
+	CryptoFailureHandler func(msg Message, err error) CryptoFailureAction
 }
+
+// CryptoFailureAction is the action returned by MessageDecryptionInfo.CryptoFailureHandler, one of the
+// crypto.ConsumerCryptoFailureAction constants
+type CryptoFailureAction int
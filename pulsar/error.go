@@ -19,6 +19,8 @@ package pulsar
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	proto "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
 	"github.com/hashicorp/go-multierror"
@@ -121,6 +123,10 @@ const (
 	// fenced. Applications are now supposed to close it and create a
 	// new producer
 	ProducerFenced
+	// StopMessageIDReached means the consumer reached the end of the range bounded by ConsumerOptions.StopAtMessageID
+	StopMessageIDReached
+	// InvalidAckPosition means a cumulative ack would move the subscription cursor backward
+	InvalidAckPosition
 )
 
 // Error implement error interface, composed of two parts: msg and result.
@@ -231,6 +237,10 @@ func getResultStr(r Result) string {
 		return "ClientMemoryBufferIsFull"
 	case TransactionNoFoundError:
 		return "TransactionNoFoundError"
+	case StopMessageIDReached:
+		return "StopMessageIDReached"
+	case InvalidAckPosition:
+		return "InvalidAckPosition"
 	default:
 		return fmt.Sprintf("Result(%d)", r)
 	}
@@ -253,3 +263,108 @@ func getErrorFromServerError(serverError *proto.ServerError) error {
 func joinErrors(errs ...error) error {
 	return multierror.Append(nil, errs...)
 }
+
+// PartitionedSeekError is the error of a seek applied concurrently to all the partitions of a topic, when some of
+// them failed to seek. The partitions that succeeded are at the new position while the others are not, so the
+// caller can retry the seek. It wraps an *Error with the SeekFailed result, or TimeoutError if the seek was
+// interrupted by its context.
+type PartitionedSeekError interface {
+	error
+
+	// Succeeded returns the partitions that seeked
+	Succeeded() []int
+
+	// Failed returns the error of each partition that failed to seek
+	Failed() map[int]error
+}
+
+type partitionedSeekError struct {
+	cause      error
+	partitions int
+	failed     map[int]error
+}
+
+func (e *partitionedSeekError) Succeeded() []int {
+	succeeded := make([]int, 0, e.partitions-len(e.failed))
+	for idx := 0; idx < e.partitions; idx++ {
+		if _, ok := e.failed[idx]; !ok {
+			succeeded = append(succeeded, idx)
+		}
+	}
+	return succeeded
+}
+
+func (e *partitionedSeekError) Failed() map[int]error {
+	return e.failed
+}
+
+func (e *partitionedSeekError) Error() string {
+	failed := make([]int, 0, len(e.failed))
+	for idx := range e.failed {
+		failed = append(failed, idx)
+	}
+	sort.Ints(failed)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, succeeded partitions %v", e.cause.Error(), e.Succeeded())
+	for _, idx := range failed {
+		fmt.Fprintf(&b, ", partition %d: %v", idx, e.failed[idx])
+	}
+	return b.String()
+}
+
+func (e *partitionedSeekError) Unwrap() error {
+	return e.cause
+}
+
+// MessageIDDecodeFailure tells why DeserializeMessageID rejected its data.
+type MessageIDDecodeFailure int
+
+const (
+	// MessageIDTruncated means the data ends in the middle of a field, or misses the ledger and entry ids
+	MessageIDTruncated MessageIDDecodeFailure = iota + 1
+	// MessageIDUnknownFields means the data holds fields that are not part of a message id
+	MessageIDUnknownFields
+	// MessageIDNegativePosition means the ledger or entry id is negative, other than the -1 of EarliestMessageID
+	MessageIDNegativePosition
+	// MessageIDMalformed means the data is not valid protobuf
+	MessageIDMalformed
+)
+
+func (f MessageIDDecodeFailure) String() string {
+	switch f {
+	case MessageIDTruncated:
+		return "truncated data"
+	case MessageIDUnknownFields:
+		return "unknown fields"
+	case MessageIDNegativePosition:
+		return "negative ledger or entry id"
+	case MessageIDMalformed:
+		return "malformed data"
+	default:
+		return fmt.Sprintf("MessageIDDecodeFailure(%d)", int(f))
+	}
+}
+
+// MessageIDDecodeError is the error of DeserializeMessageID when the data is not a serialized message id, e.g.
+// because it was corrupted in an external store. It wraps an *Error with the InvalidMessage result.
+type MessageIDDecodeError struct {
+	// Reason is the kind of corruption detected
+	Reason MessageIDDecodeFailure
+	// Size is the length of the data given to DeserializeMessageID
+	Size int
+	// Detail describes the faulty field, if any
+	Detail string
+}
+
+func (e *MessageIDDecodeError) Error() string {
+	msg := fmt.Sprintf("invalid serialized message id of %d bytes: %s", e.Size, e.Reason)
+	if e.Detail != "" {
+		msg += ", " + e.Detail
+	}
+	return msg
+}
+
+func (e *MessageIDDecodeError) Unwrap() error {
+	return newError(InvalidMessage, e.Error())
+}
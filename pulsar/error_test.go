@@ -34,3 +34,25 @@ func Test_joinErrors(t *testing.T) {
 	assert.True(t, errors.Is(err, err2))
 	assert.False(t, errors.Is(err, err3))
 }
+
+func TestPartitionedSeekError(t *testing.T) {
+	var err error = &partitionedSeekError{
+		cause:      newError(SeekFailed, "unable to SeekByTime"),
+		partitions: 4,
+		failed: map[int]error{
+			1: errors.New("broker unavailable"),
+			3: errors.New("connection closed"),
+		},
+	}
+
+	var seekErr PartitionedSeekError
+	assert.True(t, errors.As(err, &seekErr))
+	assert.Equal(t, []int{0, 2}, seekErr.Succeeded())
+	assert.Len(t, seekErr.Failed(), 2)
+	assert.Contains(t, err.Error(), "partition 1: broker unavailable")
+	assert.Contains(t, err.Error(), "partition 3: connection closed")
+
+	var pulsarErr *Error
+	assert.True(t, errors.As(err, &pulsarErr))
+	assert.Equal(t, SeekFailed, pulsarErr.Result())
+}
@@ -0,0 +1,401 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// filterExpression is a predicate over the properties of a message, parsed from the SQL-like syntax of
+// ReaderOptions.FilterExpression
+type filterExpression interface {
+	match(properties map[string]string) bool
+}
+
+type filterAnd struct{ left, right filterExpression }
+
+func (f *filterAnd) match(properties map[string]string) bool {
+	return f.left.match(properties) && f.right.match(properties)
+}
+
+type filterOr struct{ left, right filterExpression }
+
+func (f *filterOr) match(properties map[string]string) bool {
+	return f.left.match(properties) || f.right.match(properties)
+}
+
+type filterNot struct{ expr filterExpression }
+
+func (f *filterNot) match(properties map[string]string) bool {
+	return !f.expr.match(properties)
+}
+
+type filterIsNull struct {
+	property string
+	not      bool
+}
+
+func (f *filterIsNull) match(properties map[string]string) bool {
+	_, ok := properties[f.property]
+	return ok == f.not
+}
+
+type filterLiteral struct {
+	value    string
+	number   float64
+	isNumber bool
+}
+
+// compare returns the comparison of the property value with the literal, and false if they can not be compared
+func (l *filterLiteral) compare(value string) (int, bool) {
+	if !l.isNumber {
+		return strings.Compare(value, l.value), true
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case n < l.number:
+		return -1, true
+	case n > l.number:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+type filterComparison struct {
+	property string
+	op       string
+	literal  filterLiteral
+}
+
+func (f *filterComparison) match(properties map[string]string) bool {
+	value, ok := properties[f.property]
+	if !ok {
+		return false
+	}
+	cmp, ok := f.literal.compare(value)
+	if !ok {
+		return false
+	}
+	switch f.op {
+	case "=":
+		return cmp == 0
+	case "!=", "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+type filterIn struct {
+	property string
+	literals []filterLiteral
+	not      bool
+}
+
+func (f *filterIn) match(properties map[string]string) bool {
+	value, ok := properties[f.property]
+	if !ok {
+		return false
+	}
+	for _, l := range f.literals {
+		if cmp, ok := l.compare(value); ok && cmp == 0 {
+			return !f.not
+		}
+	}
+	return f.not
+}
+
+type filterTokenKind int
+
+const (
+	filterTokenEOF filterTokenKind = iota
+	filterTokenIdent
+	filterTokenString
+	filterTokenNumber
+	filterTokenOperator
+	filterTokenLParen
+	filterTokenRParen
+	filterTokenComma
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	value string
+	pos   int
+}
+
+// tokenizeFilterExpression splits the expression into tokens, whose positions are counted in characters
+func tokenizeFilterExpression(expr string) ([]filterToken, error) {
+	s := []rune(expr)
+	var tokens []filterToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{filterTokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{filterTokenRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, filterToken{filterTokenComma, ",", i})
+			i++
+		case strings.ContainsRune("=!<>", c):
+			op := string(c)
+			if i+1 < len(s) {
+				switch two := string(s[i : i+2]); two {
+				case "!=", "<>", "<=", ">=":
+					op = two
+				}
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, filterToken{filterTokenOperator, op, i})
+			i += len(op)
+		case c == '\'':
+			var sb strings.Builder
+			start := i
+			for i++; ; i++ {
+				if i >= len(s) {
+					return nil, fmt.Errorf("unterminated string at position %d", start)
+				}
+				if s[i] == '\'' {
+					// a quote is escaped by doubling it
+					if i+1 < len(s) && s[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				sb.WriteRune(s[i])
+			}
+			tokens = append(tokens, filterToken{filterTokenString, sb.String(), start})
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			start := i
+			i++
+			for i < len(s) && (s[i] == '.' || unicode.IsDigit(s[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{filterTokenNumber, string(s[start:i]), start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			i++
+			for i < len(s) && (s[i] == '_' || s[i] == '.' || s[i] == '-' || unicode.IsLetter(s[i]) || unicode.IsDigit(s[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{filterTokenIdent, string(s[start:i]), start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, filterToken{filterTokenEOF, "", len(s)}), nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// parseFilterExpression parses a SQL-like filter expression such as
+// `region = 'eu' AND (priority >= 3 OR vip IS NOT NULL)`.
+//
+// The identifiers refer to message properties. The supported operators are the comparisons
+// (=, !=, <>, <, <=, >, >=) against a single-quoted string or a number, [NOT] IN, IS [NOT] NULL,
+// NOT, AND, OR and parentheses. A comparison on a missing property is false.
+func parseFilterExpression(s string) (filterExpression, error) {
+	tokens, err := tokenizeFilterExpression(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != filterTokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+	}
+	return expr, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.tokens[p.pos]
+	if t.kind != filterTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) acceptKeyword(keyword string) bool {
+	if t := p.peek(); t.kind == filterTokenIdent && strings.EqualFold(t.value, keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(kind filterTokenKind, what string) error {
+	if t := p.next(); t.kind != kind {
+		return fmt.Errorf("expected %s at position %d", what, t.pos)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterExpression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterExpression, error) {
+	if p.acceptKeyword("NOT") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &filterNot{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterExpression, error) {
+	t := p.next()
+	switch t.kind {
+	case filterTokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(filterTokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case filterTokenIdent:
+		return p.parsePredicate(t.value)
+	default:
+		return nil, fmt.Errorf("expected a property name at position %d", t.pos)
+	}
+}
+
+func (p *filterParser) parsePredicate(property string) (filterExpression, error) {
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if !p.acceptKeyword("NULL") {
+			return nil, fmt.Errorf("expected NULL at position %d", p.peek().pos)
+		}
+		return &filterIsNull{property: property, not: not}, nil
+	}
+
+	not := p.acceptKeyword("NOT")
+	if p.acceptKeyword("IN") {
+		if err := p.expect(filterTokenLParen, "'('"); err != nil {
+			return nil, err
+		}
+		in := &filterIn{property: property, not: not}
+		for {
+			l, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			in.literals = append(in.literals, l)
+			if p.peek().kind != filterTokenComma {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(filterTokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return in, nil
+	}
+	if not {
+		return nil, fmt.Errorf("expected IN at position %d", p.peek().pos)
+	}
+
+	t := p.next()
+	if t.kind != filterTokenOperator {
+		return nil, fmt.Errorf("expected an operator at position %d", t.pos)
+	}
+	l, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	return &filterComparison{property: property, op: t.value, literal: l}, nil
+}
+
+func (p *filterParser) parseLiteral() (filterLiteral, error) {
+	t := p.next()
+	switch t.kind {
+	case filterTokenString:
+		return filterLiteral{value: t.value}, nil
+	case filterTokenNumber:
+		n, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return filterLiteral{}, fmt.Errorf("invalid number %q at position %d", t.value, t.pos)
+		}
+		return filterLiteral{value: t.value, number: n, isNumber: true}, nil
+	default:
+		return filterLiteral{}, fmt.Errorf("expected a string or a number at position %d", t.pos)
+	}
+}
@@ -0,0 +1,100 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterExpression(t *testing.T) {
+	props := map[string]string{
+		"region":   "eu",
+		"priority": "5",
+		"name":     "o'neil",
+		"städte":   "zürich",
+	}
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"region = 'eu'", true},
+		{"region != 'eu'", false},
+		{"region <> 'us'", true},
+		{"priority >= 5", true},
+		{"priority > 5", false},
+		{"priority < 10", true},
+		{"priority <= 4.5", false},
+		{"region = 'us' OR priority > 3", true},
+		{"region = 'eu' AND priority > 7", false},
+		{"NOT region = 'us'", true},
+		{"region = 'eu' AND (priority > 7 OR vip IS NOT NULL)", false},
+		{"vip IS NULL", true},
+		{"region IS NOT NULL", true},
+		{"region IN ('us', 'eu')", true},
+		{"region NOT IN ('us', 'eu')", false},
+		{"priority IN (1, 5)", true},
+		{"name = 'o''neil'", true},
+		{"region > 3", false},
+		{"missing = 'x'", false},
+		{"not region = 'us' and region in ('eu')", true},
+		{"städte = 'zürich'", true},
+		{"städte IN ('genève', 'zürich')", true},
+		{"städte = 'zurich'", false},
+	}
+	for _, test := range tests {
+		expr, err := parseFilterExpression(test.expr)
+		if assert.NoError(t, err, test.expr) {
+			assert.Equal(t, test.match, expr.match(props), test.expr)
+		}
+	}
+
+	_, err := parseFilterExpression("städte = 'zürich' §")
+	assert.EqualError(t, err, "unexpected character '§' at position 18")
+
+	for _, invalid := range []string{
+		"",
+		"region",
+		"region = ",
+		"region = 'eu",
+		"(region = 'eu'",
+		"region = 'eu')",
+		"region IS 'eu'",
+		"region NOT = 'eu'",
+		"region IN 'eu'",
+		"region = 'eu' AND",
+		"region ! 'eu'",
+		"region = eu",
+	} {
+		_, err := parseFilterExpression(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestReaderFiltered(t *testing.T) {
+	filter, err := parseFilterExpression("region = 'eu'")
+	assert.NoError(t, err)
+	r := &reader{filter: filter, producerName: "job"}
+
+	assert.False(t, r.filtered(&message{properties: map[string]string{"region": "eu"}, producerName: "job"}))
+	assert.True(t, r.filtered(&message{properties: map[string]string{"region": "us"}, producerName: "job"}))
+	assert.True(t, r.filtered(&message{properties: map[string]string{"region": "eu"}, producerName: "other"}))
+	assert.False(t, (&reader{}).filtered(&message{}))
+}
@@ -18,19 +18,31 @@
 package pulsar
 
 import (
+	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+	"github.com/apache/pulsar-client-go/pulsar/internal/compression"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
 	"github.com/bits-and-blooms/bitset"
 )
 
+// endOfStreamProperty marks the message sent on close by the producers with EmitEndMarkerOnClose
+const endOfStreamProperty = "__end_of_stream"
+
 type messageID struct {
 	ledgerID     int64
 	entryID      int64
@@ -184,14 +196,91 @@ func (id *messageID) BatchSize() int32 {
 }
 
 func (id *messageID) String() string {
-	return fmt.Sprintf("%d:%d:%d", id.ledgerID, id.entryID, id.partitionIdx)
+	if id.batchIdx < 0 {
+		return fmt.Sprintf("%d:%d:%d", id.ledgerID, id.entryID, id.partitionIdx)
+	}
+	return fmt.Sprintf("%d:%d:%d:%d", id.ledgerID, id.entryID, id.partitionIdx, id.batchIdx)
+}
+
+func parseMessageID(s string) (MessageID, error) {
+	if strings.Contains(s, ",") {
+		return parsePartitionedMessageID(s)
+	}
+	if first, last, ok := strings.Cut(s, ";"); ok {
+		firstChunkID, err := parseSingleMessageID(first)
+		if err != nil {
+			return nil, err
+		}
+		lastChunkID, err := parseSingleMessageID(last)
+		if err != nil {
+			return nil, err
+		}
+		return newChunkMessageID(firstChunkID, lastChunkID), nil
+	}
+	return parseSingleMessageID(s)
+}
+
+func parseSingleMessageID(s string) (*messageID, error) {
+	fields := strings.Split(s, ":")
+	if len(fields) != 3 && len(fields) != 4 {
+		return nil, newError(InvalidMessage, fmt.Sprintf("invalid message id %q, expected "+
+			"ledger:entry:partition[:batch]", s))
+	}
+	ledgerID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, joinErrors(ErrInvalidMessage, fmt.Errorf("invalid ledger id in message id %q: %w", s, err))
+	}
+	entryID, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, joinErrors(ErrInvalidMessage, fmt.Errorf("invalid entry id in message id %q: %w", s, err))
+	}
+	partitionIdx, err := strconv.ParseInt(fields[2], 10, 32)
+	if err != nil {
+		return nil, joinErrors(ErrInvalidMessage, fmt.Errorf("invalid partition in message id %q: %w", s, err))
+	}
+	batchIdx := int64(-1)
+	if len(fields) == 4 {
+		if batchIdx, err = strconv.ParseInt(fields[3], 10, 32); err != nil || batchIdx < 0 {
+			return nil, newError(InvalidMessage, fmt.Sprintf("invalid batch index in message id %q", s))
+		}
+	}
+	return &messageID{
+		ledgerID:     ledgerID,
+		entryID:      entryID,
+		batchIdx:     int32(batchIdx),
+		partitionIdx: int32(partitionIdx),
+	}, nil
 }
 
 func deserializeMessageID(data []byte) (MessageID, error) {
+	if isPartitionedMessageID(data) {
+		id, err := deserializePartitionedMessageID(data)
+		var decodeErr *MessageIDDecodeError
+		if errors.As(err, &decodeErr) {
+			// report the size of the whole id rather than the one of the partition
+			decodeErr.Size = len(data)
+		}
+		return id, err
+	}
+	if err := validateMessageIDData(data); err != nil {
+		return nil, err
+	}
 	msgID := &pb.MessageIdData{}
 	err := proto.Unmarshal(data, msgID)
 	if err != nil {
-		return nil, err
+		return nil, &MessageIDDecodeError{Reason: MessageIDTruncated, Size: len(data), Detail: err.Error()}
+	}
+	for _, pos := range []struct {
+		name  string
+		value int64
+	}{{"ledger", int64(msgID.GetLedgerId())}, {"entry", int64(msgID.GetEntryId())}} {
+		if pos.value < -1 {
+			return nil, &MessageIDDecodeError{
+				Reason: MessageIDNegativePosition,
+				Size:   len(data),
+				Detail: fmt.Sprintf("%s id %d", pos.name, pos.value),
+			}
+		}
 	}
 	id := newMessageID(
 		int64(msgID.GetLedgerId()),
@@ -203,6 +292,39 @@ func deserializeMessageID(data []byte) (MessageID, error) {
 	return id, nil
 }
 
+// validateMessageIDData checks the wire format of a serialized pb.MessageIdData, which proto.Unmarshal does not
+// report precisely
+func validateMessageIDData(data []byte) error {
+	for b := data; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return messageIDWireError(data, protowire.ParseError(n))
+		}
+		if fd := (&pb.MessageIdData{}).ProtoReflect().Descriptor().Fields().ByNumber(num); fd == nil {
+			return &MessageIDDecodeError{
+				Reason: MessageIDUnknownFields,
+				Size:   len(data),
+				Detail: fmt.Sprintf("field number %d", num),
+			}
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return messageIDWireError(data, protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func messageIDWireError(data []byte, err error) error {
+	reason := MessageIDMalformed
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		reason = MessageIDTruncated
+	}
+	return &MessageIDDecodeError{Reason: reason, Size: len(data), Detail: err.Error()}
+}
+
 func newMessageID(ledgerID int64, entryID int64, batchIdx int32, partitionIdx int32, batchSize int32) MessageID {
 	return &messageID{
 		ledgerID:     ledgerID,
@@ -290,6 +412,7 @@ type message struct {
 	publishTime         time.Time
 	eventTime           time.Time
 	key                 string
+	keyB64Encoded       bool
 	orderingKey         string
 	producerName        string
 	payLoad             []byte
@@ -305,6 +428,9 @@ type message struct {
 	encryptionContext   *EncryptionContext
 	index               *uint64
 	brokerPublishTime   *time.Time
+	brokerEntrySize     int64
+	payloadReader       *lazyPayloadReader
+	payloadDiscarded    bool
 }
 
 func (msg *message) Topic() string {
@@ -352,20 +478,56 @@ func (msg *message) GetReplicatedFrom() string {
 }
 
 func (msg *message) GetSchemaValue(v interface{}) error {
+	if msg.payloadDiscarded {
+		return newError(SchemaFailure, "message payload was discarded, the reader only keeps the metadata")
+	}
+	if msg.IsRawEncoded() {
+		return newError(SchemaFailure, "message is raw encoded, use Payload() instead")
+	}
+	schema := msg.schema
 	if msg.schemaVersion != nil {
-		schema, err := msg.schemaInfoCache.Get(msg.schemaVersion)
+		var err error
+		schema, err = msg.schemaInfoCache.Get(msg.schemaVersion)
+		if err != nil {
+			return err
+		}
+		if resolver, ok := msg.schema.(schemaResolver); ok {
+			return resolver.decodeFrom(schema, msg.payLoad, v)
+		}
+	}
+	if kvSchema, ok := schema.(*KeyValueSchema); ok && kvSchema.encoding == KeyValueSeparated {
+		key, err := msg.keyBytes()
 		if err != nil {
 			return err
 		}
-		return schema.Decode(msg.payLoad, v)
+		return kvSchema.decodeSeparated(key, msg.payLoad, v)
 	}
-	return msg.schema.Decode(msg.payLoad, v)
+	return schema.Decode(msg.payLoad, v)
+}
+
+// keyBytes returns the key of the message, base64 decoded if it was encoded by the producer
+func (msg *message) keyBytes() ([]byte, error) {
+	if !msg.keyB64Encoded {
+		if msg.key == "" {
+			return nil, nil
+		}
+		return []byte(msg.key), nil
+	}
+	key, err := base64.StdEncoding.DecodeString(msg.key)
+	if err != nil {
+		return nil, newError(InvalidMessage, fmt.Sprintf("invalid base64 encoded message key: %v", err))
+	}
+	return key, nil
 }
 
 func (msg *message) SchemaVersion() []byte {
 	return msg.schemaVersion
 }
 
+func (msg *message) IsRawEncoded() bool {
+	return isRawSchemaVersion(msg.schemaVersion)
+}
+
 func (msg *message) ProducerName() string {
 	return msg.producerName
 }
@@ -382,10 +544,114 @@ func (msg *message) BrokerPublishTime() *time.Time {
 	return msg.brokerPublishTime
 }
 
+func (msg *message) PayloadReader() io.ReadCloser {
+	if msg.payloadReader != nil {
+		return msg.payloadReader
+	}
+	return io.NopCloser(bytes.NewReader(msg.payLoad))
+}
+
+func (msg *message) BrokerEntrySize() int64 {
+	if msg.brokerEntrySize == 0 {
+		return -1
+	}
+	return msg.brokerEntrySize
+}
+
+func (msg *message) IsEndOfStream() bool {
+	return msg.properties[endOfStreamProperty] == "true"
+}
+
+func (msg *message) PartitionIndex() int {
+	tn, err := internal.ParseTopicName(msg.topic)
+	if err != nil {
+		return -1
+	}
+	return tn.Partition
+}
+
 func (msg *message) size() int {
+	if msg.payloadReader != nil {
+		// the compressed payload is what the message holds in memory
+		return len(msg.payloadReader.payload)
+	}
 	return len(msg.payLoad)
 }
 
+// fullPayload returns the payload of the message, decompressing it in memory if it is decompressed lazily
+func (msg *message) fullPayload() ([]byte, error) {
+	if msg.payloadReader != nil {
+		return msg.payloadReader.decompress()
+	}
+	return msg.payLoad, nil
+}
+
+// releasePayload releases the decompressor of the payload of a message dropped by the consumer
+func releasePayload(msg Message) {
+	if m, ok := msg.(*message); ok && m.payloadReader != nil {
+		m.payloadReader.Close()
+	}
+}
+
+// lazyPayloadReader decompresses the payload of a message while it is read. The decompressor is only created on
+// the first Read and is released at the end of the payload or on Close, so that the messages which are never
+// read do not hold one.
+type lazyPayloadReader struct {
+	sync.Mutex
+	provider compression.StreamProvider
+	payload  []byte
+	rc       io.ReadCloser
+	err      error
+}
+
+func (r *lazyPayloadReader) Read(p []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.rc == nil {
+		if r.rc, r.err = r.provider.DecompressReader(bytes.NewReader(r.payload)); r.err != nil {
+			return 0, r.err
+		}
+	}
+	n, err := r.rc.Read(p)
+	if err != nil {
+		r.release(err)
+	}
+	return n, err
+}
+
+func (r *lazyPayloadReader) Close() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.err == nil {
+		r.release(os.ErrClosed)
+	}
+	return nil
+}
+
+// release closes the decompressor, the next reads return err, must be called with the lock held
+func (r *lazyPayloadReader) release(err error) {
+	r.err = err
+	if r.rc != nil {
+		r.rc.Close()
+		r.rc = nil
+	}
+}
+
+// decompress returns the whole decompressed payload, independently of the reads
+func (r *lazyPayloadReader) decompress() ([]byte, error) {
+	rc, err := r.provider.DecompressReader(bytes.NewReader(r.payload))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
 func newAckTracker(size uint) *ackTracker {
 	batchIDs := bitset.New(size)
 	for i := uint(0); i < size; i++ {
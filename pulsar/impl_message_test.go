@@ -18,9 +18,17 @@
 package pulsar
 
 import (
+	"errors"
+	"io"
+	"math"
+	"os"
 	"testing"
 
+	"github.com/apache/pulsar-client-go/pulsar/internal/compression"
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestMessageId(t *testing.T) {
@@ -46,6 +54,40 @@ func TestMessageId(t *testing.T) {
 	assert.Nil(t, id)
 }
 
+func TestParseMessageID(t *testing.T) {
+	for _, id := range []MessageID{
+		newMessageID(1, 2, 3, 4, 5),
+		newMessageID(1, 2, -1, 4, 0),
+		newMessageID(1, 2, 0, -1, 10),
+		EarliestMessageID(),
+		LatestMessageID(),
+	} {
+		parsed, err := ParseMessageID(id.String())
+		assert.NoError(t, err)
+		assert.Equal(t, id.String(), parsed.String())
+		assert.Equal(t, id.LedgerID(), parsed.LedgerID())
+		assert.Equal(t, id.EntryID(), parsed.EntryID())
+		assert.Equal(t, id.BatchIdx(), parsed.BatchIdx())
+		assert.Equal(t, id.PartitionIdx(), parsed.PartitionIdx())
+	}
+	assert.Equal(t, "1:2:4:3", newMessageID(1, 2, 3, 4, 5).String())
+	assert.Equal(t, "1:2:4", newMessageID(1, 2, -1, 4, 0).String())
+
+	chunkID := newChunkMessageID(newMessageID(1, 2, -1, 0, 0).(*messageID), newMessageID(1, 5, -1, 0, 0).(*messageID))
+	parsed, err := ParseMessageID(chunkID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, chunkID.String(), parsed.String())
+	assert.Equal(t, int64(5), parsed.EntryID())
+
+	for _, s := range []string{"", "1", "1:2", "1:2:3:4:5", "a:2:3", "1:2:3:-2", "1:2:3:x", "1:2:3;4"} {
+		_, err := ParseMessageID(s)
+		assert.Error(t, err, s)
+		var e *Error
+		assert.ErrorAs(t, err, &e)
+		assert.Equal(t, InvalidMessage, e.Result(), s)
+	}
+}
+
 func TestMessageIdGetFuncs(t *testing.T) {
 	// test LedgerId,EntryId,BatchIdx,PartitionIdx
 	id := newMessageID(1, 2, 3, 4, 5)
@@ -56,6 +98,25 @@ func TestMessageIdGetFuncs(t *testing.T) {
 	assert.Equal(t, int32(5), id.BatchSize())
 }
 
+func TestMessageIsRawEncoded(t *testing.T) {
+	msg := &message{schema: NewStringSchema(nil), payLoad: []byte("hello")}
+	assert.False(t, msg.IsRawEncoded())
+
+	msg = &message{schema: NewStringSchema(nil), payLoad: []byte{0x00, 0x01}, schemaVersion: rawSchemaVersion}
+	assert.True(t, msg.IsRawEncoded())
+	var v string
+	assert.Error(t, msg.GetSchemaValue(&v))
+}
+
+func TestMessagePayloadDiscarded(t *testing.T) {
+	msg := &message{schema: NewStringSchema(nil), payloadDiscarded: true}
+	assert.Nil(t, msg.Payload())
+	var v string
+	err := msg.GetSchemaValue(&v)
+	assert.Error(t, err)
+	assert.Equal(t, SchemaFailure, err.(*Error).Result())
+}
+
 func TestAckTracker(t *testing.T) {
 	tracker := newAckTracker(1)
 	assert.Equal(t, true, tracker.ack(0))
@@ -129,3 +190,162 @@ func TestAckingMessageIDBatchTwo(t *testing.T) {
 	assert.Equal(t, true, ids[0].ack())
 	assert.Equal(t, true, tracker.completed())
 }
+
+func TestMessageBrokerEntrySize(t *testing.T) {
+	msg := &message{}
+	assert.Equal(t, int64(-1), msg.BrokerEntrySize())
+
+	msg = &message{brokerEntrySize: 128}
+	assert.Equal(t, int64(128), msg.BrokerEntrySize())
+}
+
+func TestMessagePartitionIndex(t *testing.T) {
+	msg := &message{topic: "persistent://public/default/my-topic-partition-3"}
+	assert.Equal(t, 3, msg.PartitionIndex())
+
+	msg = &message{topic: "persistent://public/default/my-topic"}
+	assert.Equal(t, -1, msg.PartitionIndex())
+
+	msg = &message{}
+	assert.Equal(t, -1, msg.PartitionIndex())
+}
+
+func TestPartitionedMessageIDRoundTrip(t *testing.T) {
+	id := &partitionedMessageID{ids: []*messageID{
+		{ledgerID: 1, entryID: 2, batchIdx: -1, partitionIdx: 0},
+		{ledgerID: 3, entryID: 4, batchIdx: 5, partitionIdx: 1, batchSize: 10},
+	}}
+
+	deserialized, err := DeserializeMessageID(id.Serialize())
+	assert.NoError(t, err)
+	assert.Equal(t, id, deserialized)
+
+	assert.Equal(t, "1:2:0,3:4:1:5", id.String())
+	parsed, err := ParseMessageID(id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, id.String(), parsed.String())
+
+	_, err = ParseMessageID("1:2:0,3:4:-1")
+	assert.Error(t, err)
+	_, err = DeserializeMessageID(append(id.Serialize(), 42))
+	assert.Error(t, err)
+}
+
+func TestDeserializeMessageIDErrors(t *testing.T) {
+	valid := newMessageID(1, 2, 3, 4, 5).Serialize()
+	negative, err := proto.Marshal(&pb.MessageIdData{
+		LedgerId: proto.Uint64(uint64(1)),
+		EntryId:  proto.Uint64(uint64(math.MaxUint64 - 4)),
+	})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		reason MessageIDDecodeFailure
+	}{
+		{"empty", nil, MessageIDTruncated},
+		{"truncated", valid[:len(valid)-1], MessageIDTruncated},
+		{"unknown field", append(append([]byte{}, valid...), protowire.AppendTag(nil, 42, protowire.VarintType)...),
+			MessageIDUnknownFields},
+		{"negative entry", negative, MessageIDNegativePosition},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DeserializeMessageID(tc.data)
+			var decodeErr *MessageIDDecodeError
+			if assert.True(t, errors.As(err, &decodeErr)) {
+				assert.Equal(t, tc.reason, decodeErr.Reason)
+				assert.Equal(t, len(tc.data), decodeErr.Size)
+			}
+			var pulsarErr *Error
+			if assert.True(t, errors.As(err, &pulsarErr)) {
+				assert.Equal(t, InvalidMessage, pulsarErr.Result())
+			}
+		})
+	}
+
+	// the earliest message id has -1 positions
+	id, err := DeserializeMessageID(EarliestMessageID().Serialize())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), id.LedgerID())
+}
+
+func TestMessageIDForPartition(t *testing.T) {
+	for _, tc := range []struct {
+		id       MessageID
+		sentinel *messageID
+	}{
+		{EarliestMessageIDForPartition(3), earliestMessageID},
+		{LatestMessageIDForPartition(3), latestMessageID},
+	} {
+		assert.Equal(t, int32(3), tc.id.PartitionIdx())
+
+		deserialized, err := DeserializeMessageID(tc.id.Serialize())
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), deserialized.PartitionIdx())
+		assert.Equal(t, tc.sentinel.LedgerID(), deserialized.LedgerID())
+		assert.Equal(t, tc.sentinel.EntryID(), deserialized.EntryID())
+		// still recognized as the sentinel
+		assert.True(t, fromMessageID(deserialized).equal(tc.sentinel))
+	}
+
+	parsed, err := ParseMessageID(EarliestMessageIDForPartition(0).String() + "," +
+		LatestMessageIDForPartition(1).String())
+	assert.NoError(t, err)
+	pid, ok := parsed.(*partitionedMessageID)
+	if assert.True(t, ok) {
+		assert.Len(t, pid.ids, 2)
+		assert.True(t, pid.ids[0].equal(earliestMessageID))
+		assert.True(t, pid.ids[1].equal(latestMessageID))
+	}
+}
+
+// countingStreamProvider counts the decompressors it creates
+type countingStreamProvider struct {
+	compression.StreamProvider
+	created int
+}
+
+func (p *countingStreamProvider) DecompressReader(src io.Reader) (io.ReadCloser, error) {
+	p.created++
+	return p.StreamProvider.DecompressReader(src)
+}
+
+func TestLazyPayloadReader(t *testing.T) {
+	zlib := compression.NewZLibProvider()
+	payload := []byte("lazily decompressed payload")
+	compressed := zlib.Compress(nil, payload)
+
+	provider := &countingStreamProvider{StreamProvider: zlib.(compression.StreamProvider)}
+	msg := &message{payloadReader: &lazyPayloadReader{provider: provider, payload: compressed}}
+	assert.Equal(t, len(compressed), msg.size())
+	assert.Nil(t, msg.Payload())
+	// no decompressor is held until the payload is read
+	assert.Equal(t, 0, provider.created)
+
+	full, err := msg.fullPayload()
+	assert.NoError(t, err)
+	assert.Equal(t, payload, full)
+
+	received, err := io.ReadAll(msg.PayloadReader())
+	assert.NoError(t, err)
+	assert.Equal(t, payload, received)
+	assert.Nil(t, msg.payloadReader.rc)
+	assert.Equal(t, 2, provider.created)
+
+	// a dropped message releases its decompressor and is no longer readable
+	dropped := &message{payloadReader: &lazyPayloadReader{provider: provider, payload: compressed}}
+	_, err = dropped.PayloadReader().Read(make([]byte, 1))
+	assert.NoError(t, err)
+	assert.NotNil(t, dropped.payloadReader.rc)
+	releasePayload(dropped)
+	assert.Nil(t, dropped.payloadReader.rc)
+	_, err = dropped.PayloadReader().Read(make([]byte, 1))
+	assert.ErrorIs(t, err, os.ErrClosed)
+
+	plain := &message{payLoad: payload}
+	received, err = io.ReadAll(plain.PayloadReader())
+	assert.NoError(t, err)
+	assert.Equal(t, payload, received)
+	assert.NoError(t, plain.PayloadReader().Close())
+}
@@ -40,6 +40,12 @@ type BatcherBuilderProvider func(
 	bufferPool BuffersPool, logger log.Logger, encryptor crypto.Encryptor,
 ) (BatchBuilder, error)
 
+// CompressionProviderSetter is implemented by the batch builders whose compression provider can be replaced by a
+// custom codec of the compression type of their batches
+type CompressionProviderSetter interface {
+	SetCompressionProvider(provider compression.Provider)
+}
+
 // BatchBuilder is a interface of batch builders
 type BatchBuilder interface {
 	// IsFull check if the size in the current batch exceeds the maximum size allowed by the batch
@@ -68,6 +74,9 @@ type BatchBuilder interface {
 	// Return the batch container batch message in multiple batches.
 	IsMultiBatches() bool
 
+	// Discard drops the messages of the current batch and returns their callbacks, in the order they were added.
+	Discard() (callbacks []interface{})
+
 	reset()
 	Close() error
 }
@@ -283,6 +292,13 @@ func (bc *batchContainer) Flush() (
 	return buffer, sequenceID, callbacks, err
 }
 
+// Discard drops the messages of the current batch and returns their callbacks.
+func (bc *batchContainer) Discard() []interface{} {
+	callbacks := bc.callbacks
+	bc.reset()
+	return callbacks
+}
+
 // FlushBatches only for multiple batches container
 func (bc *batchContainer) FlushBatches() (
 	batchData []Buffer, sequenceID []uint64, callbacks [][]interface{}, errors []error,
@@ -295,6 +311,12 @@ func (bc *batchContainer) IsMultiBatches() bool {
 	return false
 }
 
+// SetCompressionProvider replaces the compression provider of the batches
+func (bc *batchContainer) SetCompressionProvider(provider compression.Provider) {
+	bc.compressionProvider.Close()
+	bc.compressionProvider = provider
+}
+
 func (bc *batchContainer) Close() error {
 	return bc.compressionProvider.Close()
 }
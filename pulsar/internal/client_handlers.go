@@ -49,6 +49,17 @@ func (h *ClientHandlers) Val(c Closable) bool {
 	return h.handlers[c]
 }
 
+// Values returns a snapshot of the handlers
+func (h *ClientHandlers) Values() []Closable {
+	h.l.RLock()
+	defer h.l.RUnlock()
+	handlers := make([]Closable, 0, len(h.handlers))
+	for handler := range h.handlers {
+		handlers = append(handlers, handler)
+	}
+	return handlers
+}
+
 func (h *ClientHandlers) Close() {
 	h.l.Lock()
 	handlers := make([]Closable, 0, len(h.handlers))
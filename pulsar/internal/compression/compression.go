@@ -48,3 +48,11 @@ type Provider interface {
 	// Close the compressor
 	io.Closer
 }
+
+// StreamProvider is implemented by the providers able to decompress a payload incrementally,
+// without holding the whole decompressed content in memory.
+type StreamProvider interface {
+	// DecompressReader returns a reader yielding the decompressed content of src.
+	// The returned reader must be closed once it is no longer used.
+	DecompressReader(src io.Reader) (io.ReadCloser, error)
+}
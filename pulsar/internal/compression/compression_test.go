@@ -18,6 +18,8 @@
 package compression
 
 import (
+	"bytes"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -88,3 +90,24 @@ func TestDecompressionError(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamDecompression(t *testing.T) {
+	for _, provider := range providers {
+		p := provider
+		t.Run(p.name, func(t *testing.T) {
+			streamProvider, ok := p.provider.(StreamProvider)
+			if !ok {
+				t.Skipf("%s does not support stream decompression", p.name)
+			}
+
+			hello := []byte("test compression data")
+			compressed := p.provider.Compress(nil, hello)
+			r, err := streamProvider.DecompressReader(bytes.NewReader(compressed))
+			assert.Nil(t, err)
+			uncompressed, err := io.ReadAll(r)
+			assert.Nil(t, err)
+			assert.Nil(t, r.Close())
+			assert.Equal(t, hello, uncompressed)
+		})
+	}
+}
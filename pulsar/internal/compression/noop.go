@@ -19,6 +19,7 @@ package compression
 
 import (
 	"bytes"
+	"io"
 )
 
 type noopProvider struct{}
@@ -52,6 +53,10 @@ func (noopProvider) Decompress(dst, src []byte, originalSize int) ([]byte, error
 	return dst[:len(src)], nil
 }
 
+func (noopProvider) DecompressReader(src io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(src), nil
+}
+
 func (noopProvider) Close() error {
 	return nil
 }
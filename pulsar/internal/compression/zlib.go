@@ -72,6 +72,10 @@ func (zlibProvider) Decompress(dst, src []byte, originalSize int) ([]byte, error
 	return dst, nil
 }
 
+func (zlibProvider) DecompressReader(src io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(src)
+}
+
 func (zlibProvider) Clone() Provider {
 	return NewZLibProvider()
 }
@@ -25,6 +25,7 @@
 package compression
 
 import (
+	"io"
 	"sync"
 
 	"github.com/DataDog/zstd"
@@ -81,6 +82,10 @@ func (z *zstdCGoProvider) Decompress(dst, src []byte, originalSize int) ([]byte,
 	return ctx.Decompress(dst, src)
 }
 
+func (z *zstdCGoProvider) DecompressReader(src io.Reader) (io.ReadCloser, error) {
+	return zstd.NewReader(src), nil
+}
+
 func (z *zstdCGoProvider) Close() error {
 	return nil
 }
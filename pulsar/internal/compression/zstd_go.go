@@ -18,6 +18,8 @@
 package compression
 
 import (
+	"io"
+
 	"github.com/klauspost/compress/zstd"
 )
 
@@ -62,6 +64,15 @@ func (p *zstdProvider) Decompress(dst, src []byte, originalSize int) ([]byte, er
 	return p.decoder.DecodeAll(src, dst)
 }
 
+func (p *zstdProvider) DecompressReader(src io.Reader) (io.ReadCloser, error) {
+	// the shared decoder is used by Decompress, streams need their own
+	decoder, err := zstd.NewReader(src)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
 func (p *zstdProvider) Close() error {
 	p.decoder.Close()
 	return p.encoder.Close()
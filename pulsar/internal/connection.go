@@ -18,6 +18,7 @@
 package internal
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
@@ -47,6 +48,9 @@ type TLSOptions struct {
 	KeyFile                 string
 	CertFile                string
 	TrustCertsFilePath      string
+	CertPEM                 []byte
+	KeyPEM                  []byte
+	TrustCertsPEM           []byte
 	AllowInsecureConnection bool
 	ValidateHostname        bool
 	ServerName              string
@@ -55,6 +59,35 @@ type TLSOptions struct {
 	MaxVersion              uint16
 }
 
+// trustCerts returns the PEM encoded trusted certificates, nil if none is configured
+func (o *TLSOptions) trustCerts() ([]byte, error) {
+	if o.TrustCertsPEM != nil {
+		return o.TrustCertsPEM, nil
+	}
+	if o.TrustCertsFilePath != "" {
+		return os.ReadFile(o.TrustCertsFilePath)
+	}
+	return nil, nil
+}
+
+// keyPair returns the client certificate, nil if none is configured
+func (o *TLSOptions) keyPair() (*tls.Certificate, error) {
+	var cert tls.Certificate
+	var err error
+	switch {
+	case o.CertPEM != nil && o.KeyPEM != nil:
+		cert, err = tls.X509KeyPair(o.CertPEM, o.KeyPEM)
+	case o.CertFile != "" && o.KeyFile != "":
+		cert, err = tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
 var (
 	errConnectionClosed        = errors.New("connection closed")
 	errUnableRegisterListener  = errors.New("unable register listener when con closed")
@@ -85,6 +118,8 @@ type Connection interface {
 	GetMaxMessageSize() int32
 	Close()
 	IsProxied() bool
+	// Ping sends a PING command to the broker and waits for its PONG, or for the context to be done
+	Ping(ctx context.Context) error
 }
 
 type ConsumerHandler interface {
@@ -167,6 +202,10 @@ type connection struct {
 	consumerHandlersLock sync.RWMutex
 	consumerHandlers     map[uint64]ConsumerHandler
 
+	// pongWaitersLock guards pongWaiters, the channels closed on the next PONG received
+	pongWaitersLock sync.Mutex
+	pongWaiters     []chan struct{}
+
 	tlsOptions *TLSOptions
 	auth       auth.Provider
 
@@ -176,6 +215,11 @@ type connection struct {
 	keepAliveInterval time.Duration
 
 	lastActive time.Time
+
+	// stats counts the connection in the pool from its handshake to its close, counted tells whether it is
+	// not counted yet (0), counted (1) or closed (2)
+	stats   *connectionStats
+	counted int32
 }
 
 // connectionOptions defines configurations for creating connection.
@@ -188,6 +232,7 @@ type connectionOptions struct {
 	logger            log.Logger
 	metrics           *Metrics
 	keepAliveInterval time.Duration
+	stats             *connectionStats
 }
 
 func newConnection(opts connectionOptions) *connection {
@@ -202,6 +247,7 @@ func newConnection(opts connectionOptions) *connection {
 		lastDataReceivedTime: time.Now(),
 		tlsOptions:           opts.tls,
 		auth:                 opts.auth,
+		stats:                opts.stats,
 
 		closeCh:            make(chan interface{}),
 		incomingRequestsCh: make(chan *request, 10),
@@ -234,6 +280,9 @@ func (c *connection) start() {
 		if c.connect() {
 			if c.doHandshake() {
 				c.metrics.ConnectionsOpened.Inc()
+				if c.stats != nil && atomic.CompareAndSwapInt32(&c.counted, 0, 1) {
+					c.stats.opened(c.physicalAddr.Host)
+				}
 				c.run()
 			} else {
 				c.metrics.ConnectionsHandshakeErrors.Inc()
@@ -817,6 +866,35 @@ func (c *connection) sendPing() {
 
 func (c *connection) handlePong() {
 	c.log.Debug("Received PONG response")
+
+	c.pongWaitersLock.Lock()
+	waiters := c.pongWaiters
+	c.pongWaiters = nil
+	c.pongWaitersLock.Unlock()
+	for _, ch := range waiters {
+		close(ch)
+	}
+}
+
+func (c *connection) Ping(ctx context.Context) error {
+	if c.closed() {
+		return ErrConnectionClosed
+	}
+
+	pong := make(chan struct{})
+	c.pongWaitersLock.Lock()
+	c.pongWaiters = append(c.pongWaiters, pong)
+	c.pongWaitersLock.Unlock()
+	c.sendPing()
+
+	select {
+	case <-pong:
+		return nil
+	case <-c.closeCh:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (c *connection) handlePing() {
@@ -987,9 +1065,11 @@ func (c *connection) isIdle() bool {
 }
 
 func (c *connection) CheckIdle(maxIdleTime time.Duration) bool {
-	// We don't need to lock here because this method should only be
-	// called in a single goroutine of the connectionPool
-	if !c.isIdle() {
+	idle := c.isIdle()
+
+	c.Lock()
+	defer c.Unlock()
+	if !idle {
 		c.lastActive = time.Now()
 	}
 	return time.Since(c.lastActive) > maxIdleTime
@@ -1038,6 +1118,9 @@ func (c *connection) Close() {
 		}
 
 		c.metrics.ConnectionsClosed.Inc()
+		if c.stats != nil && atomic.SwapInt32(&c.counted, 2) == 1 {
+			c.stats.closed(c.physicalAddr.Host)
+		}
 	})
 }
 
@@ -1071,12 +1154,11 @@ func (c *connection) getTLSConfig() (*tls.Config, error) {
 		MaxVersion:         c.tlsOptions.MaxVersion,
 	}
 
-	if c.tlsOptions.TrustCertsFilePath != "" {
-		caCerts, err := os.ReadFile(c.tlsOptions.TrustCertsFilePath)
-		if err != nil {
-			return nil, err
-		}
-
+	caCerts, err := c.tlsOptions.trustCerts()
+	if err != nil {
+		return nil, err
+	}
+	if caCerts != nil {
 		tlsConfig.RootCAs = x509.NewCertPool()
 		ok := tlsConfig.RootCAs.AppendCertsFromPEM(caCerts)
 		if !ok {
@@ -1093,12 +1175,12 @@ func (c *connection) getTLSConfig() (*tls.Config, error) {
 		c.log.Debugf("getTLSConfig(): setting tlsConfig.ServerName = %+v", tlsConfig.ServerName)
 	}
 
-	if c.tlsOptions.CertFile != "" && c.tlsOptions.KeyFile != "" {
-		cert, err := tls.LoadX509KeyPair(c.tlsOptions.CertFile, c.tlsOptions.KeyFile)
-		if err != nil {
-			return nil, errors.New(err.Error())
-		}
-		tlsConfig.Certificates = []tls.Certificate{cert}
+	keyPair, err := c.tlsOptions.keyPair()
+	if err != nil {
+		return nil, errors.New(err.Error())
+	}
+	if keyPair != nil {
+		tlsConfig.Certificates = []tls.Certificate{*keyPair}
 	}
 
 	cert, err := c.auth.GetTLSCertificate()
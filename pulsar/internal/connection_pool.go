@@ -18,6 +18,7 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"sync"
@@ -29,15 +30,44 @@ import (
 	"github.com/apache/pulsar-client-go/pulsar/log"
 )
 
+// minIdleTimeBeforeClose is how long a connection must have been idle to be closed by CloseIdleConnections, so
+// that a connection just returned by GetConnection is not closed before its caller registers on it.
+const minIdleTimeBeforeClose = time.Second
+
 // ConnectionPool is a interface of connection pool.
 type ConnectionPool interface {
 	// GetConnection get a connection from ConnectionPool.
 	GetConnection(logicalAddr *url.URL, physicalAddr *url.URL) (Connection, error)
 
+	// Retain registers an additional owner of the pool, e.g. another client sharing its connections.
+	// The connections are only closed once Close has been called by every owner.
+	Retain() error
+
+	// IDGenerator returns the generator of the request, producer and consumer ids used on the pooled
+	// connections, so that the owners of the pool never send colliding ids on a shared connection.
+	IDGenerator() *IDGenerator
+
+	// CloseIdleConnections closes the ready connections that have had no producer, consumer or pending request
+	// for at least minIdleTimeBeforeClose, and returns how many were closed.
+	CloseIdleConnections() int
+
+	// Stats returns the number of connections of the pool and of the connections replacing a closed one.
+	Stats() ConnectionPoolStats
+
 	// Close all the connections in the pool
 	Close()
 }
 
+// IDGenerator holds the counters used to generate request, producer and consumer ids.
+type IDGenerator struct {
+	requestID  uint64
+	producerID uint64
+	consumerID uint64
+}
+
+// ErrConnectionPoolClosed happens when retaining a connection pool that was already closed.
+var ErrConnectionPoolClosed = errors.New("connection pool is closed")
+
 type connectionPool struct {
 	sync.Mutex
 	connections           map[string]*connection
@@ -48,6 +78,9 @@ type connectionPool struct {
 	roundRobinCnt         int32
 	keepAliveInterval     time.Duration
 	closeCh               chan struct{}
+	refCnt                int
+	ids                   IDGenerator
+	stats                 connectionStats
 
 	metrics *Metrics
 	log     log.Logger
@@ -73,6 +106,7 @@ func NewConnectionPool(
 		log:                   logger,
 		metrics:               metrics,
 		closeCh:               make(chan struct{}),
+		refCnt:                1,
 	}
 	go p.checkAndCleanIdleConnections(connectionMaxIdleTime)
 	return p
@@ -97,6 +131,7 @@ func (p *connectionPool) GetConnection(logicalAddr *url.URL, physicalAddr *url.U
 			delete(p.connections, key)
 			conn.Close()
 			conn = nil // set to nil so we create a new one
+			p.stats.reconnects.Inc()
 		}
 	}
 
@@ -110,6 +145,7 @@ func (p *connectionPool) GetConnection(logicalAddr *url.URL, physicalAddr *url.U
 			keepAliveInterval: p.keepAliveInterval,
 			logger:            p.log,
 			metrics:           p.metrics,
+			stats:             &p.stats,
 		})
 		p.connections[key] = conn
 		p.Unlock()
@@ -121,11 +157,53 @@ func (p *connectionPool) GetConnection(logicalAddr *url.URL, physicalAddr *url.U
 	}
 
 	err := conn.waitUntilReady()
+	if err == nil {
+		// the caller has not registered anything on the connection yet, it must not look idle for a while
+		conn.ResetLastActive()
+	}
 	return conn, err
 }
 
+func (p *connectionPool) Retain() error {
+	p.Lock()
+	defer p.Unlock()
+	if p.refCnt <= 0 {
+		return ErrConnectionPoolClosed
+	}
+	p.refCnt++
+	return nil
+}
+
+func (p *connectionPool) IDGenerator() *IDGenerator {
+	return &p.ids
+}
+
+func (p *connectionPool) CloseIdleConnections() int {
+	p.Lock()
+	defer p.Unlock()
+	closed := 0
+	for k, c := range p.connections {
+		if c.getState() == connectionReady && c.CheckIdle(minIdleTimeBeforeClose) {
+			p.log.Debugf("Closed idle connection from pool. logical_addr=%+v physical_addr=%+v",
+				c.logicalAddr, c.physicalAddr)
+			delete(p.connections, k)
+			c.Close()
+			closed++
+		}
+	}
+	return closed
+}
+
+func (p *connectionPool) Stats() ConnectionPoolStats {
+	return p.stats.snapshot()
+}
+
 func (p *connectionPool) Close() {
 	p.Lock()
+	if p.refCnt--; p.refCnt != 0 {
+		p.Unlock()
+		return
+	}
 	close(p.closeCh)
 	for k, c := range p.connections {
 		delete(p.connections, k)
@@ -0,0 +1,77 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package internal
+
+import (
+	"sync"
+
+	ua "go.uber.org/atomic"
+)
+
+// ConnectionPoolStats is a snapshot of the connections of a pool.
+type ConnectionPoolStats struct {
+	// Connections is the number of connections established with a broker and not closed yet
+	Connections int
+
+	// ConnectionsPerBroker is the number of these connections by broker host
+	ConnectionsPerBroker map[string]int
+
+	// Reconnects is the number of connections opened to replace a closed one since the pool was created
+	Reconnects uint64
+}
+
+// connectionStats counts the connections of a pool, it is updated by the connections as they open and close.
+type connectionStats struct {
+	connections ua.Int64
+	reconnects  ua.Uint64
+	// perBroker maps a broker host to the *ua.Int64 counting its connections
+	perBroker sync.Map
+}
+
+func (s *connectionStats) broker(host string) *ua.Int64 {
+	if cnt, ok := s.perBroker.Load(host); ok {
+		return cnt.(*ua.Int64)
+	}
+	cnt, _ := s.perBroker.LoadOrStore(host, ua.NewInt64(0))
+	return cnt.(*ua.Int64)
+}
+
+func (s *connectionStats) opened(host string) {
+	s.connections.Inc()
+	s.broker(host).Inc()
+}
+
+func (s *connectionStats) closed(host string) {
+	s.connections.Dec()
+	s.broker(host).Dec()
+}
+
+func (s *connectionStats) snapshot() ConnectionPoolStats {
+	stats := ConnectionPoolStats{
+		Connections:          int(s.connections.Load()),
+		ConnectionsPerBroker: make(map[string]int),
+		Reconnects:           s.reconnects.Load(),
+	}
+	s.perBroker.Range(func(host, cnt interface{}) bool {
+		if n := cnt.(*ua.Int64).Load(); n > 0 {
+			stats.ConnectionsPerBroker[host.(string)] = int(n)
+		}
+		return true
+	})
+	return stats
+}
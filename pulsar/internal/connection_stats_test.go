@@ -0,0 +1,47 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionStats(t *testing.T) {
+	var s connectionStats
+	assert.Equal(t, ConnectionPoolStats{ConnectionsPerBroker: map[string]int{}}, s.snapshot())
+
+	s.opened("broker-1:6650")
+	s.opened("broker-1:6650")
+	s.opened("broker-2:6650")
+	s.reconnects.Inc()
+	assert.Equal(t, ConnectionPoolStats{
+		Connections:          3,
+		ConnectionsPerBroker: map[string]int{"broker-1:6650": 2, "broker-2:6650": 1},
+		Reconnects:           1,
+	}, s.snapshot())
+
+	// the brokers without connections are left out
+	s.closed("broker-2:6650")
+	assert.Equal(t, ConnectionPoolStats{
+		Connections:          2,
+		ConnectionsPerBroker: map[string]int{"broker-1:6650": 2},
+		Reconnects:           1,
+	}, s.snapshot())
+}
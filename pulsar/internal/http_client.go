@@ -25,7 +25,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"path"
 	"time"
 
@@ -343,21 +342,21 @@ func getDefaultTransport(tlsConfig *TLSOptions) (http.RoundTripper, error) {
 			MinVersion:         tlsConfig.MinVersion,
 			MaxVersion:         tlsConfig.MaxVersion,
 		}
-		if len(tlsConfig.TrustCertsFilePath) > 0 {
-			rootCA, err := os.ReadFile(tlsConfig.TrustCertsFilePath)
-			if err != nil {
-				return nil, err
-			}
+		rootCA, err := tlsConfig.trustCerts()
+		if err != nil {
+			return nil, err
+		}
+		if rootCA != nil {
 			cfg.RootCAs = x509.NewCertPool()
 			cfg.RootCAs.AppendCertsFromPEM(rootCA)
 		}
 
-		if tlsConfig.CertFile != "" && tlsConfig.KeyFile != "" {
-			cert, err := tls.LoadX509KeyPair(tlsConfig.CertFile, tlsConfig.KeyFile)
-			if err != nil {
-				return nil, errors.New(err.Error())
-			}
-			cfg.Certificates = []tls.Certificate{cert}
+		keyPair, err := tlsConfig.keyPair()
+		if err != nil {
+			return nil, errors.New(err.Error())
+		}
+		if keyPair != nil {
+			cfg.Certificates = []tls.Certificate{*keyPair}
 		}
 		transport.TLSClientConfig = cfg
 	}
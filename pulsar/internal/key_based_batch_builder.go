@@ -53,6 +53,8 @@ type keyBasedBatchContainer struct {
 	batchContainer
 	compressionType pb.CompressionType
 	level           compression.Level
+	// customCompressionProvider replaces the provider of the compression type in the batches of every key
+	customCompressionProvider compression.Provider
 }
 
 // newKeyBasedBatches init a keyBasedBatches
@@ -157,6 +159,9 @@ func (bc *keyBasedBatchContainer) Add(
 			bc.maxMessages, bc.maxBatchSize, bc.maxMessageSize, bc.producerName, bc.producerID,
 			bc.compressionType, bc.level, bc.buffersPool, bc.log, bc.encryptor,
 		)
+		if bc.customCompressionProvider != nil {
+			t.SetCompressionProvider(bc.customCompressionProvider.Clone())
+		}
 		batchPart = &t
 		bc.batches.Add(msgKey, &t)
 	}
@@ -233,12 +238,25 @@ func (bc *keyBasedBatchContainer) FlushBatches() (
 	return batchesData, sequenceIDs, callbacks, errors
 }
 
+// Discard drops the messages of all the batches and returns their callbacks.
+func (bc *keyBasedBatchContainer) Discard() []interface{} {
+	callbacks := bc.callbacks
+	bc.reset()
+	return callbacks
+}
+
 func (bc *keyBasedBatchContainer) Flush() (
 	batchData Buffer, sequenceID uint64, callbacks []interface{}, err error,
 ) {
 	panic("multi batches container not support Flush(), please use FlushBatches() instead")
 }
 
+// SetCompressionProvider replaces the compression provider of the batches of every key
+func (bc *keyBasedBatchContainer) SetCompressionProvider(provider compression.Provider) {
+	bc.batchContainer.SetCompressionProvider(provider)
+	bc.customCompressionProvider = provider
+}
+
 func (bc *keyBasedBatchContainer) Close() error {
 	return bc.compressionProvider.Close()
 }
@@ -146,7 +146,7 @@ func (ls *lookupService) Lookup(topic string) (*LookupResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	ls.log.Debugf("Got topic{%s} lookup response: %+v", topic, res)
+	ls.log.WithField("topic", topic).Debugf("Got lookup response: %+v", res)
 
 	for i := 0; i < lookupResultMaxRedirect; i++ {
 		lr := res.Response.LookupTopicResponse
@@ -159,8 +159,8 @@ func (ls *lookupService) Lookup(topic string) (*LookupResult, error) {
 				return nil, err
 			}
 
-			ls.log.Debugf("Follow topic{%s} redirect to broker. %v / %v - Use proxy: %v",
-				topic, lr.BrokerServiceUrl, lr.BrokerServiceUrlTls, lr.ProxyThroughServiceUrl)
+			ls.log.WithField("topic", topic).Debugf("Follow redirect to broker. %v / %v - Use proxy: %v",
+				lr.BrokerServiceUrl, lr.BrokerServiceUrlTls, lr.ProxyThroughServiceUrl)
 
 			id := ls.rpcClient.NewRequestID()
 			res, err = ls.rpcClient.Request(lookupResult.LogicalAddr, lookupResult.PhysicalAddr, id, pb.BaseCommand_LOOKUP,
@@ -178,8 +178,8 @@ func (ls *lookupService) Lookup(topic string) (*LookupResult, error) {
 			continue
 
 		case pb.CommandLookupTopicResponse_Connect:
-			ls.log.Debugf("Successfully looked up topic{%s} on broker. %s / %s - Use proxy: %t",
-				topic, lr.GetBrokerServiceUrl(), lr.GetBrokerServiceUrlTls(), lr.GetProxyThroughServiceUrl())
+			ls.log.WithField("topic", topic).Debugf("Successfully looked up topic on broker. %s / %s - Use proxy: %t",
+				lr.GetBrokerServiceUrl(), lr.GetBrokerServiceUrlTls(), lr.GetProxyThroughServiceUrl())
 
 			brokerServiceURL := selectServiceURL(ls.tlsEnabled, lr.GetBrokerServiceUrl(), lr.GetBrokerServiceUrlTls())
 			return ls.GetBrokerAddress(brokerServiceURL, lr.GetProxyThroughServiceUrl())
@@ -213,7 +213,7 @@ func (ls *lookupService) GetPartitionedTopicMetadata(topic string) (*Partitioned
 	if err != nil {
 		return nil, err
 	}
-	ls.log.Debugf("Got topic{%s} partitioned metadata response: %+v", topic, res)
+	ls.log.WithField("topic", topic).Debugf("Got partitioned metadata response: %+v", res)
 
 	var partitionedTopicMetadata PartitionedTopicMetadata
 
@@ -305,8 +305,7 @@ func (h *httpLookupService) Lookup(topic string) (*LookupResult, error) {
 		return nil, err
 	}
 
-	h.log.Debugf("Successfully looked up topic{%s} on http broker. %+v",
-		topic, lookupData)
+	h.log.WithField("topic", topic).Debugf("Successfully looked up topic on http broker. %+v", lookupData)
 
 	brokerServiceURL := selectServiceURL(h.tlsEnabled, lookupData.BrokerURL, lookupData.BrokerURLTLS)
 	return h.GetBrokerAddress(brokerServiceURL, false /* ignored */)
@@ -333,7 +332,7 @@ func (h *httpLookupService) GetPartitionedTopicMetadata(topic string) (*Partitio
 		return nil, err
 	}
 
-	h.log.Debugf("Got topic{%s} partitioned metadata response: %+v", topic, tMetadata)
+	h.log.WithField("topic", topic).Debugf("Got partitioned metadata response: %+v", tMetadata)
 
 	return tMetadata, nil
 }
@@ -45,6 +45,7 @@ type Metrics struct {
 	producersReconnectFailure  *prometheus.CounterVec
 	producersReconnectMaxRetry *prometheus.CounterVec
 	producersPartitions        *prometheus.GaugeVec
+	producersClockSkewExceeded *prometheus.CounterVec
 	consumersOpened            *prometheus.CounterVec
 	consumersClosed            *prometheus.CounterVec
 	consumersReconnectFailure  *prometheus.CounterVec
@@ -59,6 +60,7 @@ type Metrics struct {
 	ConnectionsEstablishmentErrors        prometheus.Counter
 	ConnectionsHandshakeErrors            prometheus.Counter
 	LookupRequestsCount                   prometheus.Counter
+	LookupRequestsRejected                prometheus.Counter
 	PartitionedTopicMetadataRequestsCount prometheus.Counter
 	RPCRequestCount                       prometheus.Counter
 }
@@ -87,6 +89,7 @@ type LeveledMetrics struct {
 	ProducersReconnectFailure  prometheus.Counter
 	ProducersReconnectMaxRetry prometheus.Counter
 	ProducersPartitions        prometheus.Gauge
+	ProducersClockSkewExceeded prometheus.Counter
 	ConsumersOpened            prometheus.Counter
 	ConsumersClosed            prometheus.Counter
 	ConsumersReconnectFailure  prometheus.Counter
@@ -185,6 +188,12 @@ func NewMetricsProvider(metricsCardinality int, userDefinedLabels map[string]str
 			ConstLabels: constLabels,
 		}, metricsLevelLabels),
 
+		producersClockSkewExceeded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "pulsar_client_producers_clock_skew_exceeded",
+			Help:        "Counter of producer clock skew measurements exceeding the threshold",
+			ConstLabels: constLabels,
+		}, metricsLevelLabels),
+
 		producersReconnectFailure: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name:        "pulsar_client_producers_reconnect_failure",
 			Help:        "Counter of reconnect failure of producers",
@@ -318,6 +327,12 @@ func NewMetricsProvider(metricsCardinality int, userDefinedLabels map[string]str
 			ConstLabels: constLabels,
 		}),
 
+		LookupRequestsRejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "pulsar_client_lookup_rejected",
+			Help:        "Counter of lookup requests rejected because too many were pending on a connection",
+			ConstLabels: constLabels,
+		}),
+
 		PartitionedTopicMetadataRequestsCount: prometheus.NewCounter(prometheus.CounterOpts{
 			Name:        "pulsar_client_partitioned_topic_metadata_count",
 			Help:        "Counter of partitioned_topic_metadata requests made by the client",
@@ -451,6 +466,12 @@ func NewMetricsProvider(metricsCardinality int, userDefinedLabels map[string]str
 			metrics.producersPartitions = are.ExistingCollector.(*prometheus.GaugeVec)
 		}
 	}
+	err = registerer.Register(metrics.producersClockSkewExceeded)
+	if err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			metrics.producersClockSkewExceeded = are.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
 	err = registerer.Register(metrics.consumersOpened)
 	if err != nil {
 		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
@@ -523,6 +544,12 @@ func NewMetricsProvider(metricsCardinality int, userDefinedLabels map[string]str
 			metrics.LookupRequestsCount = are.ExistingCollector.(prometheus.Counter)
 		}
 	}
+	err = registerer.Register(metrics.LookupRequestsRejected)
+	if err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			metrics.LookupRequestsRejected = are.ExistingCollector.(prometheus.Counter)
+		}
+	}
 	err = registerer.Register(metrics.PartitionedTopicMetadataRequestsCount)
 	if err != nil {
 		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
@@ -580,6 +607,7 @@ func (mp *Metrics) GetLeveledMetrics(t string) *LeveledMetrics {
 		ProducersReconnectFailure:  mp.producersReconnectFailure.With(labels),
 		ProducersReconnectMaxRetry: mp.producersReconnectMaxRetry.With(labels),
 		ProducersPartitions:        mp.producersPartitions.With(labels),
+		ProducersClockSkewExceeded: mp.producersClockSkewExceeded.With(labels),
 		ConsumersOpened:            mp.consumersOpened.With(labels),
 		ConsumersClosed:            mp.consumersClosed.With(labels),
 		ConsumersReconnectFailure:  mp.consumersReconnectFailure.With(labels),
@@ -84,10 +84,17 @@ func (c *mockConsumer) AckIDCumulative(msgID pulsar.MessageID) error {
 	return nil
 }
 
-func (c *mockConsumer) ReconsumeLater(msg pulsar.Message, delay time.Duration) {}
+func (c *mockConsumer) AckAllFromTopic(topic string) error {
+	return nil
+}
+
+func (c *mockConsumer) ReconsumeLater(msg pulsar.Message, delay time.Duration) error {
+	return nil
+}
 
 func (c *mockConsumer) ReconsumeLaterWithCustomProperties(msg pulsar.Message, customProperties map[string]string,
-	delay time.Duration) {
+	delay time.Duration) error {
+	return nil
 }
 
 func (c *mockConsumer) Nack(msg pulsar.Message) {}
@@ -107,3 +114,7 @@ func (c *mockConsumer) SeekByTime(time time.Time) error {
 func (c *mockConsumer) Name() string {
 	return ""
 }
+
+func (c *mockConsumer) OldestUnackedAge() time.Duration {
+	return 0
+}
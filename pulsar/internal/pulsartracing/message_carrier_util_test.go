@@ -18,7 +18,9 @@
 package pulsartracing
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"testing"
 	"time"
 
@@ -80,6 +82,10 @@ func (msg *mockConsumerMessage) Payload() []byte {
 	return nil
 }
 
+func (msg *mockConsumerMessage) PayloadReader() io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(nil))
+}
+
 func (msg *mockConsumerMessage) ID() pulsar.MessageID {
 	return nil
 }
@@ -127,6 +133,10 @@ func (msg *mockConsumerMessage) GetEncryptionContext() *pulsar.EncryptionContext
 	return &pulsar.EncryptionContext{}
 }
 
+func (msg *mockConsumerMessage) IsRawEncoded() bool {
+	return false
+}
+
 func (msg *mockConsumerMessage) Index() *uint64 {
 	return nil
 }
@@ -134,3 +144,15 @@ func (msg *mockConsumerMessage) Index() *uint64 {
 func (msg *mockConsumerMessage) BrokerPublishTime() *time.Time {
 	return nil
 }
+
+func (msg *mockConsumerMessage) BrokerEntrySize() int64 {
+	return -1
+}
+
+func (msg *mockConsumerMessage) IsEndOfStream() bool {
+	return false
+}
+
+func (msg *mockConsumerMessage) PartitionIndex() int {
+	return -1
+}
@@ -20,6 +20,7 @@ package pulsartracing
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/opentracing/opentracing-go"
@@ -63,6 +64,22 @@ func (p *mockProducer) LastSequenceID() int64 {
 	return 0
 }
 
+func (p *mockProducer) AvgBatchSize() (float64, float64) {
+	return 0, 0
+}
+
+func (p *mockProducer) BatchingDelay() time.Duration {
+	return 0
+}
+
+func (p *mockProducer) MeasuredClockSkew() time.Duration {
+	return 0
+}
+
+func (p *mockProducer) RefreshEncryptionKeys() error {
+	return nil
+}
+
 func (p *mockProducer) Flush() error {
 	return nil
 }
@@ -71,4 +88,12 @@ func (p *mockProducer) FlushWithCtx(ctx context.Context) error {
 	return nil
 }
 
+func (p *mockProducer) Stats() pulsar.ProducerStats {
+	return pulsar.ProducerStats{}
+}
+
+func (p *mockProducer) FlushWithResults(ctx context.Context) ([]pulsar.FlushResult, error) {
+	return nil, nil
+}
+
 func (p *mockProducer) Close() {}
@@ -20,6 +20,7 @@ package internal
 import (
 	"errors"
 	"net/url"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -32,8 +33,21 @@ import (
 var (
 	// ErrRequestTimeOut happens when request not finished in given requestTimeout.
 	ErrRequestTimeOut = errors.New("request timed out")
+
+	// ErrTooManyLookupRequests happens when a lookup request is rejected because too many lookup requests are
+	// already in flight or waiting on the connection.
+	ErrTooManyLookupRequests = errors.New("too many lookup requests")
 )
 
+// LookupLimits bounds the lookup requests sent on each broker connection. A zero value disables the limit.
+type LookupLimits struct {
+	// MaxConcurrent is the number of lookup requests in flight, the next ones wait for a slot
+	MaxConcurrent int
+
+	// MaxPerConnection is the number of lookup requests in flight or waiting, the next ones are rejected
+	MaxPerConnection int
+}
+
 type result struct {
 	*RPCResult
 	error
@@ -67,21 +81,37 @@ type rpcClient struct {
 	serviceNameResolver ServiceNameResolver
 	pool                ConnectionPool
 	requestTimeout      time.Duration
-	requestIDGenerator  uint64
-	producerIDGenerator uint64
-	consumerIDGenerator uint64
+	requestTimeouts     map[pb.BaseCommand_Type]time.Duration
+	ids                 *IDGenerator
 	log                 log.Logger
 	metrics             *Metrics
+
+	lookupLimits LookupLimits
+	lookupsLock  sync.Mutex
+	lookups      map[Connection]*lookupPermits
+}
+
+// lookupPermits tracks the lookup requests of a connection
+type lookupPermits struct {
+	// pending counts the requests in flight or waiting for a slot
+	pending int
+	slots   chan struct{}
 }
 
+// NewRPCClient creates a RPCClient. The requestTimeouts override requestTimeout for the given command types.
 func NewRPCClient(serviceURL *url.URL, serviceNameResolver ServiceNameResolver, pool ConnectionPool,
-	requestTimeout time.Duration, logger log.Logger, metrics *Metrics) RPCClient {
+	requestTimeout time.Duration, requestTimeouts map[pb.BaseCommand_Type]time.Duration,
+	lookupLimits LookupLimits, logger log.Logger, metrics *Metrics) RPCClient {
 	return &rpcClient{
 		serviceNameResolver: serviceNameResolver,
 		pool:                pool,
 		requestTimeout:      requestTimeout,
+		requestTimeouts:     requestTimeouts,
+		ids:                 pool.IDGenerator(),
 		log:                 logger.SubLogger(log.Fields{"serviceURL": serviceURL}),
 		metrics:             metrics,
+		lookupLimits:        lookupLimits,
+		lookups:             make(map[Connection]*lookupPermits),
 	}
 }
 
@@ -91,10 +121,11 @@ func (c *rpcClient) RequestToAnyBroker(requestID uint64, cmdType pb.BaseCommand_
 	var host *url.URL
 	var rpcResult *RPCResult
 	startTime := time.Now()
+	requestTimeout := c.timeout(cmdType)
 	backoff := DefaultBackoff{100 * time.Millisecond}
 	// we can retry these requests because this kind of request is
 	// not specific to any particular broker
-	for time.Since(startTime) < c.requestTimeout {
+	for time.Since(startTime) < requestTimeout {
 		host, err = c.serviceNameResolver.ResolveHost()
 		if err != nil {
 			c.log.WithError(err).Errorf("rpc client failed to resolve host")
@@ -107,7 +138,7 @@ func (c *rpcClient) RequestToAnyBroker(requestID uint64, cmdType pb.BaseCommand_
 		}
 
 		retryTime := backoff.Next()
-		c.log.Debugf("Retrying request in {%v} with timeout in {%v}", retryTime, c.requestTimeout)
+		c.log.Debugf("Retrying request in {%v} with timeout in {%v}", retryTime, requestTimeout)
 		time.Sleep(retryTime)
 	}
 
@@ -122,6 +153,16 @@ func (c *rpcClient) Request(logicalAddr *url.URL, physicalAddr *url.URL, request
 		return nil, err
 	}
 
+	// the time spent waiting for a lookup slot counts in the request timeout
+	timeoutCh := time.After(c.timeout(cmdType))
+	if isLookupRequest(cmdType) {
+		release, err := c.acquireLookupPermit(cnx, timeoutCh)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
 	ch := make(chan result, 1)
 
 	cnx.SendRequest(requestID, baseCommand(cmdType, message), func(response *pb.BaseCommand, err error) {
@@ -131,7 +172,6 @@ func (c *rpcClient) Request(logicalAddr *url.URL, physicalAddr *url.URL, request
 		}, err}
 	})
 
-	timeoutCh := time.After(c.requestTimeout)
 	for {
 		select {
 		case res := <-ch:
@@ -150,6 +190,58 @@ func (c *rpcClient) Request(logicalAddr *url.URL, physicalAddr *url.URL, request
 	}
 }
 
+func isLookupRequest(cmdType pb.BaseCommand_Type) bool {
+	return cmdType == pb.BaseCommand_LOOKUP || cmdType == pb.BaseCommand_PARTITIONED_METADATA
+}
+
+// acquireLookupPermit waits until a lookup request can be sent on the connection, according to the
+// LookupLimits, and returns the function releasing the permit once the request completed.
+func (c *rpcClient) acquireLookupPermit(cnx Connection, timeoutCh <-chan time.Time) (func(), error) {
+	if c.lookupLimits.MaxConcurrent <= 0 && c.lookupLimits.MaxPerConnection <= 0 {
+		return func() {}, nil
+	}
+
+	c.lookupsLock.Lock()
+	permits, ok := c.lookups[cnx]
+	if !ok {
+		permits = &lookupPermits{}
+		if c.lookupLimits.MaxConcurrent > 0 {
+			permits.slots = make(chan struct{}, c.lookupLimits.MaxConcurrent)
+		}
+		c.lookups[cnx] = permits
+	}
+	if c.lookupLimits.MaxPerConnection > 0 && permits.pending >= c.lookupLimits.MaxPerConnection {
+		c.lookupsLock.Unlock()
+		c.metrics.LookupRequestsRejected.Inc()
+		return nil, ErrTooManyLookupRequests
+	}
+	permits.pending++
+	c.lookupsLock.Unlock()
+
+	done := func() {
+		c.lookupsLock.Lock()
+		defer c.lookupsLock.Unlock()
+		permits.pending--
+		if permits.pending == 0 {
+			delete(c.lookups, cnx)
+		}
+	}
+
+	if permits.slots == nil {
+		return done, nil
+	}
+	select {
+	case permits.slots <- struct{}{}:
+		return func() {
+			<-permits.slots
+			done()
+		}, nil
+	case <-timeoutCh:
+		done()
+		return nil, ErrRequestTimeOut
+	}
+}
+
 func (c *rpcClient) RequestOnCnx(cnx Connection, requestID uint64, cmdType pb.BaseCommand_Type,
 	message proto.Message) (*RPCResult, error) {
 	c.metrics.RPCRequestCount.Inc()
@@ -167,24 +259,31 @@ func (c *rpcClient) RequestOnCnx(cnx Connection, requestID uint64, cmdType pb.Ba
 	select {
 	case res := <-ch:
 		return res.RPCResult, res.error
-	case <-time.After(c.requestTimeout):
+	case <-time.After(c.timeout(cmdType)):
 		return nil, ErrRequestTimeOut
 	}
 }
 
+func (c *rpcClient) timeout(cmdType pb.BaseCommand_Type) time.Duration {
+	if timeout, ok := c.requestTimeouts[cmdType]; ok {
+		return timeout
+	}
+	return c.requestTimeout
+}
+
 func (c *rpcClient) RequestOnCnxNoWait(cnx Connection, cmdType pb.BaseCommand_Type, message proto.Message) error {
 	c.metrics.RPCRequestCount.Inc()
 	return cnx.SendRequestNoWait(baseCommand(cmdType, message))
 }
 
 func (c *rpcClient) NewRequestID() uint64 {
-	return atomic.AddUint64(&c.requestIDGenerator, 1)
+	return atomic.AddUint64(&c.ids.requestID, 1)
 }
 
 func (c *rpcClient) NewProducerID() uint64 {
-	return atomic.AddUint64(&c.producerIDGenerator, 1)
+	return atomic.AddUint64(&c.ids.producerID, 1)
 }
 
 func (c *rpcClient) NewConsumerID() uint64 {
-	return atomic.AddUint64(&c.consumerIDGenerator, 1)
+	return atomic.AddUint64(&c.ids.consumerID, 1)
 }
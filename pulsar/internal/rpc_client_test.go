@@ -0,0 +1,90 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type lookupMockCnx struct {
+	Connection
+}
+
+func newLookupTestRPCClient(limits LookupLimits) *rpcClient {
+	return &rpcClient{
+		metrics:      NewMetricsProvider(4, map[string]string{}, prometheus.DefaultRegisterer),
+		lookupLimits: limits,
+		lookups:      make(map[Connection]*lookupPermits),
+	}
+}
+
+func TestLookupPermitsConcurrency(t *testing.T) {
+	c := newLookupTestRPCClient(LookupLimits{MaxConcurrent: 2, MaxPerConnection: 3})
+	cnx := &lookupMockCnx{}
+
+	release1, err := c.acquireLookupPermit(cnx, nil)
+	assert.NoError(t, err)
+	release2, err := c.acquireLookupPermit(cnx, nil)
+	assert.NoError(t, err)
+
+	// the third request waits for a slot
+	acquired := make(chan func())
+	go func() {
+		release, err := c.acquireLookupPermit(cnx, nil)
+		assert.NoError(t, err)
+		acquired <- release
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("the lookup permit should not be acquired")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the fourth request is rejected, since 3 requests are in flight or waiting
+	_, err = c.acquireLookupPermit(cnx, nil)
+	assert.ErrorIs(t, err, ErrTooManyLookupRequests)
+
+	// the other connections are not limited by this one
+	release4, err := c.acquireLookupPermit(&lookupMockCnx{}, nil)
+	assert.NoError(t, err)
+	release4()
+
+	release1()
+	release3 := <-acquired
+	release2()
+	release3()
+	assert.Empty(t, c.lookups)
+}
+
+func TestLookupPermitsTimeout(t *testing.T) {
+	c := newLookupTestRPCClient(LookupLimits{MaxConcurrent: 1})
+	cnx := &lookupMockCnx{}
+
+	release, err := c.acquireLookupPermit(cnx, nil)
+	assert.NoError(t, err)
+
+	_, err = c.acquireLookupPermit(cnx, time.After(10*time.Millisecond))
+	assert.ErrorIs(t, err, ErrRequestTimeOut)
+
+	release()
+	assert.Empty(t, c.lookups)
+}
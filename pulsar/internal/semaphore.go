@@ -44,6 +44,9 @@ type Semaphore interface {
 	// Correct usage of a semaphore is established by programming convention
 	// in the application.
 	Release()
+
+	// Usage returns the fraction of the permits currently acquired, between 0 and 1.
+	Usage() float64
 }
 
 type semaphore struct {
@@ -103,3 +106,12 @@ func (s *semaphore) Release() {
 		s.ch <- true
 	}
 }
+
+func (s *semaphore) Usage() float64 {
+	permits := atomic.LoadInt32(&s.permits)
+	if permits > s.maxPermits {
+		// the acquirers waiting for a permit are not counted
+		permits = s.maxPermits
+	}
+	return float64(permits) / float64(s.maxPermits)
+}
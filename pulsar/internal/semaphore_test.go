@@ -60,6 +60,21 @@ func TestSemaphore_TryAcquire(t *testing.T) {
 	s.Release()
 }
 
+func TestSemaphore_Usage(t *testing.T) {
+	s := NewSemaphore(4)
+	assert.Equal(t, 0.0, s.Usage())
+
+	assert.True(t, s.TryAcquire())
+	assert.Equal(t, 0.25, s.Usage())
+	for i := 0; i < 3; i++ {
+		assert.True(t, s.TryAcquire())
+	}
+	assert.Equal(t, 1.0, s.Usage())
+
+	s.Release()
+	assert.Equal(t, 0.75, s.Usage())
+}
+
 func TestSemaphore_ContextExpire(t *testing.T) {
 	s := NewSemaphore(1)
 
@@ -17,7 +17,8 @@
 
 package log
 
-// DefaultNopLogger returns a nop logger.
+// DefaultNopLogger returns a nop logger, which drops all the logs without allocating, e.g. to silence the
+// client by setting it as ClientOptions.Logger.
 func DefaultNopLogger() Logger {
 	return nopLogger{}
 }
@@ -24,6 +24,11 @@
 // are good resources to learn how to implement a effective
 // logging library.
 //
+// The client passes structured key-value fields, such as the topic,
+// partition and subscription of a producer or consumer, to the
+// SubLogger and WithFields methods rather than formatting them into
+// the messages, so that a logger can emit them as is, e.g. in JSON.
+//
 // Besides the interfaces, this log library also provides an
 // implementation based on logrus, and a No-op one as well.
 package log
@@ -33,6 +38,7 @@ type Fields map[string]interface{}
 
 // Logger describes the interface that must be implemeted by all loggers.
 type Logger interface {
+	// SubLogger returns a logger attaching the fields to all its logs, in addition to the fields of this one.
 	SubLogger(fields Fields) Logger
 
 	WithFields(fields Fields) Entry
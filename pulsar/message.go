@@ -18,6 +18,7 @@
 package pulsar
 
 import (
+	"io"
 	"time"
 )
 
@@ -68,11 +69,35 @@ type ProducerMessage struct {
 
 	//Schema assign to the current message
 	//Note: messages may have a different schema from producer schema, use it instead of producer schema when assigned
+	//When batching, the messages with a different schema version are sent in separate batches, since the schema
+	//version is part of the batch metadata
 	Schema Schema
 
 	//Transaction assign to the current message
 	//Note: The message is not visible before the transaction is committed.
 	Transaction Transaction
+
+	// SkipSchema sends the Payload as-is, bypassing the producer schema, even if the topic has a schema.
+	// The message is tagged with a raw schema version marker so that consumers can detect it with
+	// `Message.IsRawEncoded()`. Value can not be set together with SkipSchema.
+	SkipSchema bool
+
+	// SchemaVersion stamps the message with a version of the schema registered on SchemaTopic, instead of the
+	// version of the producer schema: Value is still encoded with the producer schema, or the message schema if
+	// set. The version must exist on SchemaTopic, otherwise the send fails with ErrSchema. When SchemaTopic is
+	// empty, it is a version of the producer topic, such as a previous version during a schema rollout.
+	//
+	// The producers with an AutoPublishSchema forward the pre-encoded Payload of a received message with its
+	// SchemaVersion() and Topic() as SchemaTopic: the schema is registered on the producer topic if needed.
+	SchemaVersion []byte
+
+	// SchemaTopic is the topic on which SchemaVersion is registered, see SchemaVersion
+	SchemaTopic string
+
+	// Partition forces the message to be sent to the given partition of a partitioned topic, bypassing the
+	// MessageRouter. The index must be in [0, NumPartitions()), otherwise the send fails with ErrInvalidMessage.
+	// On a non-partitioned topic, only the index 0 is accepted.
+	Partition *int
 }
 
 // Message abstraction used in Pulsar
@@ -87,9 +112,15 @@ type Message interface {
 	// Returns the properties attached to the message.
 	Properties() map[string]string
 
-	// Payload returns the payload of the message
+	// Payload returns the payload of the message, nil when it was received by a `ReaderOptions.MetadataOnly` reader
 	Payload() []byte
 
+	// PayloadReader returns a reader over the payload of the message, to close once it is no longer read.
+	// For compressed chunked messages received with LazyChunkedPayloadDecompression enabled, the payload is
+	// decompressed while it is read and Payload returns nil. Such a reader can only be consumed once, it holds a
+	// decompressor from its first read until the end of the payload or until it is closed.
+	PayloadReader() io.ReadCloser
+
 	// ID returns the unique message ID associated with this message.
 	// The message id can be used to univocally refer to a message without having the keep the entire payload in memory.
 	ID() MessageID
@@ -115,6 +146,9 @@ type Message interface {
 	//
 	// Message redelivery increases monotonically in a broker, when topic switch ownership to a another broker
 	// redelivery count will be recalculated.
+	//
+	// It is also available on the messages returned by a Reader. It is 0 when the broker does not set it, and since
+	// the subscription of a reader is not durable, the count restarts whenever its cursor is recreated.
 	RedeliveryCount() uint32
 
 	// IsReplicated determines whether the message is replicated from another cluster.
@@ -129,6 +163,10 @@ type Message interface {
 	//SchemaVersion get the schema version of the message, if any
 	SchemaVersion() []byte
 
+	// IsRawEncoded returns true if the message was published with `ProducerMessage.SkipSchema`,
+	// meaning its payload was not encoded with the topic schema.
+	IsRawEncoded() bool
+
 	// GetEncryptionContext returns the ecryption context of the message.
 	// It will be used by the application to parse the undecrypted message.
 	GetEncryptionContext() *EncryptionContext
@@ -140,6 +178,23 @@ type Message interface {
 	// BrokerPublishTime returns broker publish time from broker entry metadata,
 	// or empty if the feature is not enabled in the broker.
 	BrokerPublishTime() *time.Time
+
+	// BrokerEntrySize returns the size in bytes of the entry holding this message, as stored by the broker,
+	// or -1 if the broker entry metadata feature is not enabled in the broker.
+	// The size covers the serialized message metadata, with its 4 bytes size prefix, and the payload as
+	// published (compressed and encrypted), but neither the broker entry metadata nor the checksum.
+	// All the messages of a batch share the same entry, and thus the same entry size. The size of a
+	// chunked message is the sum of the sizes of the entries holding its chunks.
+	BrokerEntrySize() int64
+
+	// IsEndOfStream returns true if the message is the end-of-stream marker sent by a producer closed with
+	// `ProducerOptions.EmitEndMarkerOnClose`. The marker has an empty payload and carries the "__end_of_stream"
+	// property.
+	IsEndOfStream() bool
+
+	// PartitionIndex returns the index of the partition the message was read from, as given by the name of its
+	// topic, or -1 if the topic is not partitioned. All the messages of a batch have the same partition index.
+	PartitionIndex() int
 }
 
 // MessageID identifier for a particular message
@@ -163,9 +218,22 @@ type MessageID interface {
 	BatchSize() int32
 
 	// String returns message id in string format
+	//
+	// The message ids of this library use the stable "ledger:entry:partition:batch" format, where the batch index is
+	// omitted for a message that is not part of a batch, and which can be turned back into a MessageID with
+	// ParseMessageID.
 	String() string
 }
 
+// ParseMessageID reconstructs a MessageID from its string representation, as returned by MessageID.String(), in the
+// "ledger:entry:partition:batch" format (e.g. "12:3:-1:0" or "12:3:-1" for a message not part of a batch).
+//
+// The batch size is not part of the string representation, so the returned MessageID can be used to seek or to
+// start a reader, but not to acknowledge an individual message of a batch.
+func ParseMessageID(s string) (MessageID, error) {
+	return parseMessageID(s)
+}
+
 // DeserializeMessageID reconstruct a MessageID object from its serialized representation
 func DeserializeMessageID(data []byte) (MessageID, error) {
 	return deserializeMessageID(data)
@@ -186,6 +254,21 @@ func LatestMessageID() MessageID {
 	return latestMessageID
 }
 
+// EarliestMessageIDForPartition returns the EarliestMessageID of a partition of a partitioned topic. The
+// partition index is kept by Serialize and DeserializeMessageID, so that the start position of each partition
+// can be persisted, e.g. to start a reader with the positions joined by ParseMessageID.
+func EarliestMessageIDForPartition(idx int) MessageID {
+	return newMessageID(earliestMessageID.ledgerID, earliestMessageID.entryID, earliestMessageID.batchIdx,
+		int32(idx), 0)
+}
+
+// LatestMessageIDForPartition returns the LatestMessageID of a partition of a partitioned topic, see
+// EarliestMessageIDForPartition.
+func LatestMessageIDForPartition(idx int) MessageID {
+	return newMessageID(latestMessageID.ledgerID, latestMessageID.entryID, latestMessageID.batchIdx,
+		int32(idx), 0)
+}
+
 func messageIDCompare(lhs MessageID, rhs MessageID) int {
 	if lhs.LedgerID() < rhs.LedgerID() {
 		return -1
@@ -0,0 +1,178 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"fmt"
+	"time"
+)
+
+// MessageBuilder builds a ProducerMessage with a fluent API, e.g.
+//
+//	msg, err := pulsar.NewMessage().
+//		Payload([]byte("hello")).
+//		Key("my-key").
+//		Property("origin", "example").
+//		DeliverAfter(10 * time.Second).
+//		Build()
+//
+// Incompatible combinations of fields are reported by Build.
+type MessageBuilder struct {
+	msg ProducerMessage
+}
+
+// NewMessage creates an empty MessageBuilder
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{}
+}
+
+// Payload sets the payload of the message
+func (b *MessageBuilder) Payload(payload []byte) *MessageBuilder {
+	b.msg.Payload = payload
+	return b
+}
+
+// Value sets the value of the message, to be encoded with the schema
+func (b *MessageBuilder) Value(value interface{}) *MessageBuilder {
+	b.msg.Value = value
+	return b
+}
+
+// Key sets the key of the message for routing policy
+func (b *MessageBuilder) Key(key string) *MessageBuilder {
+	b.msg.Key = key
+	return b
+}
+
+// OrderingKey sets the ordering key of the message
+func (b *MessageBuilder) OrderingKey(key string) *MessageBuilder {
+	b.msg.OrderingKey = key
+	return b
+}
+
+// Property adds an application defined property to the message
+func (b *MessageBuilder) Property(key, value string) *MessageBuilder {
+	if b.msg.Properties == nil {
+		b.msg.Properties = make(map[string]string)
+	}
+	b.msg.Properties[key] = value
+	return b
+}
+
+// Properties adds the given application defined properties to the message
+func (b *MessageBuilder) Properties(properties map[string]string) *MessageBuilder {
+	for k, v := range properties {
+		b.Property(k, v)
+	}
+	return b
+}
+
+// EventTime sets the event time of the message
+func (b *MessageBuilder) EventTime(eventTime time.Time) *MessageBuilder {
+	b.msg.EventTime = eventTime
+	return b
+}
+
+// ReplicationClusters overrides the replication clusters for the message
+func (b *MessageBuilder) ReplicationClusters(clusters []string) *MessageBuilder {
+	b.msg.ReplicationClusters = clusters
+	return b
+}
+
+// DisableReplication disables the replication for the message
+func (b *MessageBuilder) DisableReplication() *MessageBuilder {
+	b.msg.DisableReplication = true
+	return b
+}
+
+// SequenceID sets the sequence id to assign to the message
+func (b *MessageBuilder) SequenceID(sequenceID int64) *MessageBuilder {
+	b.msg.SequenceID = &sequenceID
+	return b
+}
+
+// DeliverAfter requests to deliver the message only after the specified relative delay
+func (b *MessageBuilder) DeliverAfter(delay time.Duration) *MessageBuilder {
+	b.msg.DeliverAfter = delay
+	return b
+}
+
+// DeliverAt requests to deliver the message only at or after the specified absolute timestamp
+func (b *MessageBuilder) DeliverAt(deliverAt time.Time) *MessageBuilder {
+	b.msg.DeliverAt = deliverAt
+	return b
+}
+
+// Partition forces the message to be sent to the given partition, bypassing the MessageRouter
+func (b *MessageBuilder) Partition(partition int) *MessageBuilder {
+	b.msg.Partition = &partition
+	return b
+}
+
+// Schema sets the schema of the message, overriding the producer schema
+func (b *MessageBuilder) Schema(schema Schema) *MessageBuilder {
+	b.msg.Schema = schema
+	return b
+}
+
+// Transaction sets the transaction the message is published in
+func (b *MessageBuilder) Transaction(txn Transaction) *MessageBuilder {
+	b.msg.Transaction = txn
+	return b
+}
+
+// SkipSchema sends the payload as-is, bypassing the producer schema
+func (b *MessageBuilder) SkipSchema() *MessageBuilder {
+	b.msg.SkipSchema = true
+	return b
+}
+
+// SchemaVersion stamps the message with a version of the schema registered on the producer topic
+func (b *MessageBuilder) SchemaVersion(schemaVersion []byte) *MessageBuilder {
+	b.msg.SchemaVersion = schemaVersion
+	return b
+}
+
+// Build validates the fields set so far and returns the resulting ProducerMessage.
+// The builder can be reused afterwards, the returned message does not share its properties map.
+func (b *MessageBuilder) Build() (*ProducerMessage, error) {
+	if b.msg.Payload != nil && b.msg.Value != nil {
+		return nil, joinErrors(ErrInvalidMessage, fmt.Errorf("can not set Value and Payload both"))
+	}
+	if b.msg.DeliverAfter < 0 {
+		return nil, joinErrors(ErrInvalidMessage, fmt.Errorf("DeliverAfter can not be negative"))
+	}
+	if b.msg.DeliverAfter > 0 && !b.msg.DeliverAt.IsZero() {
+		return nil, joinErrors(ErrInvalidMessage, fmt.Errorf("can not set DeliverAfter and DeliverAt both"))
+	}
+	if b.msg.SkipSchema && (b.msg.Value != nil || b.msg.Schema != nil) {
+		return nil, joinErrors(ErrInvalidMessage, fmt.Errorf("can not set Value or Schema with SkipSchema"))
+	}
+	if b.msg.SkipSchema && b.msg.SchemaVersion != nil {
+		return nil, joinErrors(ErrInvalidMessage, fmt.Errorf("can not set SchemaVersion with SkipSchema"))
+	}
+
+	msg := b.msg
+	if b.msg.Properties != nil {
+		msg.Properties = make(map[string]string, len(b.msg.Properties))
+		for k, v := range b.msg.Properties {
+			msg.Properties[k] = v
+		}
+	}
+	return &msg, nil
+}
@@ -0,0 +1,80 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBuilder(t *testing.T) {
+	eventTime := time.Now()
+	msg, err := NewMessage().
+		Payload([]byte("hello")).
+		Key("key").
+		OrderingKey("ordering-key").
+		Property("a", "1").
+		Properties(map[string]string{"b": "2"}).
+		EventTime(eventTime).
+		SequenceID(10).
+		DeliverAfter(time.Second).
+		Build()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hello"), msg.Payload)
+	assert.Equal(t, "key", msg.Key)
+	assert.Equal(t, "ordering-key", msg.OrderingKey)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, msg.Properties)
+	assert.Equal(t, eventTime, msg.EventTime)
+	assert.Equal(t, int64(10), *msg.SequenceID)
+	assert.Equal(t, time.Second, msg.DeliverAfter)
+}
+
+func TestMessageBuilderReuse(t *testing.T) {
+	builder := NewMessage().Property("a", "1")
+	msg1, err := builder.Build()
+	assert.Nil(t, err)
+	msg2, err := builder.Property("b", "2").Build()
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]string{"a": "1"}, msg1.Properties)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, msg2.Properties)
+}
+
+func TestMessageBuilderValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *MessageBuilder
+	}{
+		{"payload and value", NewMessage().Payload([]byte("a")).Value("a")},
+		{"deliver after and at", NewMessage().DeliverAfter(time.Second).DeliverAt(time.Now())},
+		{"negative deliver after", NewMessage().DeliverAfter(-time.Second)},
+		{"skip schema with value", NewMessage().Value("a").SkipSchema()},
+		{"skip schema with schema", NewMessage().Schema(NewStringSchema(nil)).SkipSchema()},
+		{"skip schema with schema version", NewMessage().SchemaVersion(make([]byte, 8)).SkipSchema()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := tt.builder.Build()
+			assert.Nil(t, msg)
+			assert.True(t, errors.Is(err, ErrInvalidMessage))
+		})
+	}
+}
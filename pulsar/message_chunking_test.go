@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"strings"
@@ -53,6 +54,9 @@ func TestInvalidChunkingConfig(t *testing.T) {
 	})
 
 	assert.Error(t, err, "producer creation should have fail")
+	var pulsarErr *Error
+	assert.True(t, errors.As(err, &pulsarErr))
+	assert.Equal(t, InvalidConfiguration, pulsarErr.Result())
 	assert.Nil(t, producer)
 }
 
@@ -541,6 +545,52 @@ func TestChunkBlockIfQueueFull(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestLazyChunkedPayloadDecompression(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:               topic,
+		DisableBatching:     true,
+		EnableChunking:      true,
+		ChunkMaxMessageSize: 100,
+		CompressionType:     ZLib,
+	})
+	assert.NoError(t, err)
+	defer producer.Close()
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:                           topic,
+		StartMessageID:                  EarliestMessageID(),
+		LazyChunkedPayloadDecompression: true,
+	})
+	assert.NoError(t, err)
+	defer reader.Close()
+
+	payload := createTestMessagePayload(10 * 1024)
+	_, err = producer.Send(context.Background(), &ProducerMessage{
+		Payload: payload,
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, err := reader.Next(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, msg.Payload())
+
+	payloadReader := msg.PayloadReader()
+	defer payloadReader.Close()
+	received, err := io.ReadAll(payloadReader)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, received)
+}
+
 func createTestMessagePayload(size int) []byte {
 	payload := make([]byte, size)
 	for i := range payload {
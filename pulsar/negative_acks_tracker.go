@@ -87,11 +87,22 @@ func (t *negativeAcksTracker) Add(msgID *messageID) {
 	t.negativeAcks[batchMsgID] = targetTime
 }
 
+// AddWithRedeliveryCount tracks a message nacked by id, delayed by the NackBackoffPolicy for the redelivery count
+// the message had when it was delivered, or by the fixed delay without policy
+func (t *negativeAcksTracker) AddWithRedeliveryCount(msgID *messageID, redeliveryCount uint32) {
+	if t.nackBackoff == nil {
+		t.Add(msgID)
+		return
+	}
+	t.addWithDelay(msgID, t.nackBackoff.Next(redeliveryCount))
+}
+
 func (t *negativeAcksTracker) AddMessage(msg Message) {
 	nackBackoffDelay := t.nackBackoff.Next(msg.RedeliveryCount())
+	t.addWithDelay(msg.ID(), nackBackoffDelay)
+}
 
-	msgID := msg.ID()
-
+func (t *negativeAcksTracker) addWithDelay(msgID MessageID, nackBackoffDelay time.Duration) {
 	// Always clear up the batch index since we want to track the nack
 	// for the entire batch
 	batchMsgID := messageID{
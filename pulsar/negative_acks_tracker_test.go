@@ -18,6 +18,8 @@
 package pulsar
 
 import (
+	"bytes"
+	"io"
 	"sort"
 	"sync"
 	"testing"
@@ -178,6 +180,28 @@ func TestNackBackoffTracker(t *testing.T) {
 	nacks.Close()
 }
 
+type countNackBackoffPolicy struct{}
+
+func (countNackBackoffPolicy) Next(redeliveryCount uint32) time.Duration {
+	return time.Duration(redeliveryCount+1) * time.Hour
+}
+
+func TestNackBackoffTrackerByID(t *testing.T) {
+	nmc := newNackMockedConsumer(countNackBackoffPolicy{})
+	nacks := newNegativeAcksTracker(nmc, testNackDelay, countNackBackoffPolicy{}, log.DefaultNopLogger())
+	defer nacks.Close()
+
+	now := time.Now()
+	nacks.AddWithRedeliveryCount(&messageID{ledgerID: 1, entryID: 1}, 0)
+	nacks.AddWithRedeliveryCount(&messageID{ledgerID: 1, entryID: 2}, 2)
+
+	nacks.Lock()
+	defer nacks.Unlock()
+	// every message advances its own backoff
+	assert.WithinDuration(t, now.Add(time.Hour), nacks.negativeAcks[messageID{ledgerID: 1, entryID: 1}], time.Minute)
+	assert.WithinDuration(t, now.Add(3*time.Hour), nacks.negativeAcks[messageID{ledgerID: 1, entryID: 2}], time.Minute)
+}
+
 type mockMessage1 struct {
 	properties map[string]string
 }
@@ -194,6 +218,10 @@ func (msg *mockMessage1) Payload() []byte {
 	return nil
 }
 
+func (msg *mockMessage1) PayloadReader() io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(nil))
+}
+
 func (msg *mockMessage1) ID() MessageID {
 	return &messageID{
 		ledgerID: 1,
@@ -246,6 +274,10 @@ func (msg *mockMessage1) GetEncryptionContext() *EncryptionContext {
 	return &EncryptionContext{}
 }
 
+func (msg *mockMessage1) IsRawEncoded() bool {
+	return false
+}
+
 func (msg *mockMessage1) Index() *uint64 {
 	return nil
 }
@@ -254,6 +286,18 @@ func (msg *mockMessage1) BrokerPublishTime() *time.Time {
 	return nil
 }
 
+func (msg *mockMessage1) BrokerEntrySize() int64 {
+	return -1
+}
+
+func (msg *mockMessage1) IsEndOfStream() bool {
+	return false
+}
+
+func (msg *mockMessage1) PartitionIndex() int {
+	return -1
+}
+
 type mockMessage2 struct {
 	properties map[string]string
 }
@@ -270,6 +314,10 @@ func (msg *mockMessage2) Payload() []byte {
 	return nil
 }
 
+func (msg *mockMessage2) PayloadReader() io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(nil))
+}
+
 func (msg *mockMessage2) ID() MessageID {
 	return &messageID{
 		ledgerID: 2,
@@ -322,6 +370,10 @@ func (msg *mockMessage2) GetEncryptionContext() *EncryptionContext {
 	return &EncryptionContext{}
 }
 
+func (msg *mockMessage2) IsRawEncoded() bool {
+	return false
+}
+
 func (msg *mockMessage2) Index() *uint64 {
 	return nil
 }
@@ -329,3 +381,15 @@ func (msg *mockMessage2) Index() *uint64 {
 func (msg *mockMessage2) BrokerPublishTime() *time.Time {
 	return nil
 }
+
+func (msg *mockMessage2) BrokerEntrySize() int64 {
+	return -1
+}
+
+func (msg *mockMessage2) IsEndOfStream() bool {
+	return false
+}
+
+func (msg *mockMessage2) PartitionIndex() int {
+	return -1
+}
@@ -0,0 +1,178 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+	"github.com/apache/pulsar-client-go/pulsar/log"
+)
+
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxMaxRetries   = 3
+)
+
+// OutboxProducerOptions configures an OutboxProducer.
+type OutboxProducerOptions struct {
+	// Fetch returns the next messages to publish, in order, e.g. the unsent rows of an outbox table.
+	// Returning no message means that the outbox is empty.
+	// This argument is required.
+	Fetch func() ([]*ProducerMessage, error)
+
+	// MarkSent is called with the ids of the messages returned by Fetch, in the same order, once they have all
+	// been published, e.g. to flag the rows as sent. If only a prefix of the messages could be published,
+	// MarkSent is called with the ids of that prefix.
+	// This argument is required.
+	MarkSent func([]MessageID) error
+
+	// PollInterval sets the delay before fetching again when the outbox is empty. (default: 1 second)
+	PollInterval time.Duration
+
+	// MaxRetries sets how many times a failed send or MarkSent call is retried, with an exponential backoff,
+	// before the cycle is given up. (default: 3)
+	MaxRetries int
+}
+
+// OutboxProducer implements the transactional outbox pattern on top of a Producer: the messages returned by
+// a fetch callback are published in order, then acknowledged to the application through a mark-sent callback.
+//
+// The delivery is at-least-once: if the process stops, or MarkSent keeps failing, between the publication
+// of the messages and their marking, they are fetched and published again. Setting
+// ProducerMessage.SequenceID from the outbox (e.g. the row id) lets a broker with deduplication enabled
+// discard the duplicates.
+type OutboxProducer interface {
+	// RunOnce fetches the pending messages, publishes them and marks them as sent.
+	// It returns the number of messages marked as sent.
+	RunOnce(ctx context.Context) (int, error)
+
+	// Run calls RunOnce until the context is done, waiting for the PollInterval when the outbox is empty
+	// or after a failed cycle. It returns the error of the context.
+	Run(ctx context.Context) error
+}
+
+type outboxProducer struct {
+	producer Producer
+	options  OutboxProducerOptions
+	log      log.Logger
+}
+
+// NewOutboxProducer creates an OutboxProducer publishing with the given producer.
+func NewOutboxProducer(p Producer, options OutboxProducerOptions) (OutboxProducer, error) {
+	if p == nil {
+		return nil, newError(InvalidConfiguration, "producer is required")
+	}
+	if options.Fetch == nil || options.MarkSent == nil {
+		return nil, newError(InvalidConfiguration, "Fetch and MarkSent are required")
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultOutboxPollInterval
+	}
+	if options.MaxRetries < 0 {
+		return nil, newError(InvalidConfiguration, "MaxRetries can not be negative")
+	} else if options.MaxRetries == 0 {
+		options.MaxRetries = defaultOutboxMaxRetries
+	}
+
+	logger := log.DefaultNopLogger()
+	if impl, ok := p.(*producer); ok {
+		logger = impl.log
+	}
+
+	return &outboxProducer{
+		producer: p,
+		options:  options,
+		log:      logger.SubLogger(log.Fields{"outbox": p.Topic()}),
+	}, nil
+}
+
+func (o *outboxProducer) RunOnce(ctx context.Context) (int, error) {
+	msgs, err := o.options.Fetch()
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch the outbox messages: %w", err)
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+
+	// the messages are sent one at a time so that a failure never lets a later message be published
+	// before an earlier one
+	msgIDs := make([]MessageID, 0, len(msgs))
+	var sendErr error
+	for _, msg := range msgs {
+		var msgID MessageID
+		sendErr = o.retry(ctx, func() (err error) {
+			msgID, err = o.producer.Send(ctx, msg)
+			return err
+		})
+		if sendErr != nil {
+			break
+		}
+		msgIDs = append(msgIDs, msgID)
+	}
+
+	if len(msgIDs) > 0 {
+		if err := o.retry(ctx, func() error { return o.options.MarkSent(msgIDs) }); err != nil {
+			return 0, fmt.Errorf("failed to mark %d outbox messages as sent: %w", len(msgIDs), err)
+		}
+	}
+	if sendErr != nil {
+		return len(msgIDs), fmt.Errorf("failed to send an outbox message: %w", sendErr)
+	}
+	return len(msgIDs), nil
+}
+
+func (o *outboxProducer) retry(ctx context.Context, op func() error) error {
+	backoff := &internal.DefaultBackoff{}
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= o.options.MaxRetries {
+			return err
+		}
+		delay := backoff.Next()
+		o.log.WithError(err).Warnf("Outbox operation failed, retrying in %s", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (o *outboxProducer) Run(ctx context.Context) error {
+	for {
+		n, err := o.RunOnce(ctx)
+		if err != nil {
+			o.log.WithError(err).Error("Outbox cycle failed")
+		}
+		if err == nil && n > 0 {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		select {
+		case <-time.After(o.options.PollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxProducer(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	_, err = NewOutboxProducer(producer, OutboxProducerOptions{})
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	var rows []*ProducerMessage
+	for i := 0; i < 5; i++ {
+		rows = append(rows, &ProducerMessage{Payload: []byte(fmt.Sprintf("row-%d", i))})
+	}
+	var marked []MessageID
+	outbox, err := NewOutboxProducer(producer, OutboxProducerOptions{
+		Fetch: func() ([]*ProducerMessage, error) {
+			return rows[len(marked):], nil
+		},
+		MarkSent: func(ids []MessageID) error {
+			marked = append(marked, ids...)
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+
+	n, err := outbox.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Len(t, marked, 5)
+
+	// the outbox is now empty
+	n, err = outbox.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	for i := 0; i < 5; i++ {
+		msg, err := consumer.Receive(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("row-%d", i)), msg.Payload())
+		assert.Equal(t, marked[i].Serialize(), msg.ID().Serialize())
+	}
+}
@@ -0,0 +1,116 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// partitionedMessageIDMagic prefixes the serialized partitionedMessageID. A serialized MessageIdData never
+// starts with 0xff, which is not a valid protobuf tag.
+var partitionedMessageIDMagic = []byte{0xff, 'P'}
+
+// partitionedMessageID is the position of a reader on each partition of a partitioned topic, as returned by
+// Reader.Tell. Used as ReaderOptions.StartMessageID, it starts a reader from these positions.
+type partitionedMessageID struct {
+	ids []*messageID
+}
+
+func (id *partitionedMessageID) Serialize() []byte {
+	buf := append([]byte{}, partitionedMessageIDMagic...)
+	size := make([]byte, binary.MaxVarintLen64)
+	for _, pid := range id.ids {
+		data := pid.Serialize()
+		buf = append(buf, size[:binary.PutUvarint(size, uint64(len(data)))]...)
+		buf = append(buf, data...)
+	}
+	return buf
+}
+
+// LedgerID returns -1, since the position of each partition differs
+func (id *partitionedMessageID) LedgerID() int64 {
+	return -1
+}
+
+// EntryID returns -1, since the position of each partition differs
+func (id *partitionedMessageID) EntryID() int64 {
+	return -1
+}
+
+func (id *partitionedMessageID) BatchIdx() int32 {
+	return -1
+}
+
+func (id *partitionedMessageID) PartitionIdx() int32 {
+	return -1
+}
+
+func (id *partitionedMessageID) BatchSize() int32 {
+	return 0
+}
+
+// String returns the ids of the partitions separated by commas, which ParseMessageID turns back into a
+// partitionedMessageID
+func (id *partitionedMessageID) String() string {
+	s := make([]string, len(id.ids))
+	for i, pid := range id.ids {
+		s[i] = pid.String()
+	}
+	return strings.Join(s, ",")
+}
+
+func deserializePartitionedMessageID(data []byte) (*partitionedMessageID, error) {
+	data = data[len(partitionedMessageIDMagic):]
+	id := &partitionedMessageID{}
+	for len(data) > 0 {
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			return nil, &MessageIDDecodeError{Reason: MessageIDTruncated, Detail: "invalid partitioned message id"}
+		}
+		data = data[n:]
+		pid, err := deserializeMessageID(data[:size])
+		if err != nil {
+			return nil, err
+		}
+		id.ids = append(id.ids, fromMessageID(pid))
+		data = data[size:]
+	}
+	return id, nil
+}
+
+func parsePartitionedMessageID(s string) (*partitionedMessageID, error) {
+	id := &partitionedMessageID{}
+	for _, field := range strings.Split(s, ",") {
+		pid, err := parseSingleMessageID(field)
+		if err != nil {
+			return nil, err
+		}
+		if pid.partitionIdx < 0 {
+			return nil, newError(InvalidMessage, fmt.Sprintf("missing partition in message id %q", field))
+		}
+		id.ids = append(id.ids, pid)
+	}
+	return id, nil
+}
+
+func isPartitionedMessageID(data []byte) bool {
+	return bytes.HasPrefix(data, partitionedMessageIDMagic)
+}
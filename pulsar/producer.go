@@ -75,6 +75,18 @@ type TopicMetadata interface {
 	NumPartitions() uint32
 }
 
+// FlushResult is the outcome of a message reported by Producer.FlushWithResults.
+type FlushResult struct {
+	// Message is the message given to Send or SendAsync.
+	Message *ProducerMessage
+
+	// MessageID is the id of the message if it was persisted, nil otherwise.
+	MessageID MessageID
+
+	// Err is the reason why the message failed, nil if it was persisted.
+	Err error
+}
+
 type ProducerOptions struct {
 	// Topic specifies the topic this producer will be publishing on.
 	// This argument is required when constructing the producer.
@@ -105,6 +117,12 @@ type ProducerOptions struct {
 	// acknowledgment from the broker.
 	MaxPendingMessages int
 
+	// MaxPendingMessagesAcrossPartitions limits the number of messages pending to receive an acknowledgment
+	// from the broker on all the partitions of the producer together, in addition to the MaxPendingMessages
+	// limit of every partition. Send and SendAsync block when it is reached, or fail with ErrSendQueueIsFull
+	// if DisableBlockIfQueueFull is set. Default is 0, no limit.
+	MaxPendingMessagesAcrossPartitions int
+
 	// HashingScheme is used to define the partition on where to publish a particular message.
 	// Standard hashing functions available are:
 	//
@@ -115,6 +133,11 @@ type ProducerOptions struct {
 	// Default is `JavaStringHash`.
 	HashingScheme
 
+	// CustomHasher overrides the HashingScheme with a custom hashing function of the message keys, e.g. to
+	// route the messages to the partitions an external system expects. It is used by the default router
+	// and by the router returned by NewSinglePartitionRouter.
+	CustomHasher func(key string) uint32
+
 	// CompressionType specifies the compression type for the producer.
 	// By default, message payloads are not compressed. Supported compression types are:
 	//  - LZ4
@@ -131,6 +154,11 @@ type ProducerOptions struct {
 	// - Better
 	CompressionLevel
 
+	// CompressionProvider, if set, compresses the payloads instead of the built-in codec of its Type, which
+	// overrides CompressionType, e.g. to use Zstd with a custom dictionary. The consumers need the matching
+	// provider in ClientOptions.CompressionProviders.
+	CompressionProvider CompressionProvider
+
 	// MessageRouter represents a custom message routing policy by passing an implementation of MessageRouter
 	// The router is a function that given a particular message and the topic metadata, returns the
 	// partition index where the message should be routed to
@@ -161,6 +189,24 @@ type ProducerOptions struct {
 	// BatchingMaxMessages (see above) has been reached or the batch interval has elapsed.
 	BatchingMaxSize uint
 
+	// AdaptiveBatching tunes the batching delay of each partition between 1ms and BatchingMaxPublishDelay, based
+	// on the number of messages sent and on the depth of the send queue: the delay is shortened while the messages
+	// are sent one at a time, to minimize their latency, and lengthened when the batches carry several messages or
+	// the queue builds up, to maximize the throughput. The current delay is returned by Producer.BatchingDelay.
+	// It has no effect when batching is disabled. (default: false)
+	AdaptiveBatching bool
+
+	// ClockSkewThreshold enables the measurement of the skew between the clock of the producer, which sets the
+	// publish time of the messages (and usually their event time), and the clock of the broker: once a minute,
+	// each partition producer reads back one of its messages, with a reader kept open for the lifetime of the
+	// producer, to compare the time at which it was sent with its broker publish time. A warning is logged, and
+	// the pulsar_client_producers_clock_skew_exceeded counter is incremented, when the skew exceeds the
+	// threshold. The last skew measured is returned by Producer.MeasuredClockSkew.
+	// The broker must add the broker entry metadata to the messages (with the
+	// AppendBrokerTimestampMetadataInterceptor), otherwise the skew can not be measured.
+	// Default: 0, the clock skew is not measured
+	ClockSkewThreshold time.Duration
+
 	// Interceptors is a chain of interceptors, These interceptors will be called at some points defined
 	// in ProducerInterceptor interface
 	Interceptors ProducerInterceptors
@@ -186,10 +232,37 @@ type ProducerOptions struct {
 	// Default is 1 minute
 	PartitionsAutoDiscoveryInterval time.Duration
 
+	// LazyStartPartitionedProducers makes the producer of a partitioned topic start the producer of a partition
+	// the first time a message is routed to it, instead of starting all of them on creation. Only the first
+	// partition is started eagerly, to validate the options. It can not be enabled with EmitEndMarkerOnClose.
+	// (default: false)
+	LazyStartPartitionedProducers bool
+
 	// Disable multiple Schame Version
 	// Default false
 	DisableMultiSchema bool
 
+	// PartitionFailoverOnSend makes the producer of a partitioned topic send a message on the next connected
+	// partition when its partition is reconnecting to its broker, and retry it on the other partitions, one after
+	// the other, when its partition fails to send it without writing it: its send queue is full, or its producer is
+	// closed or fenced. A send timeout is not retried, as the message may have been persisted: the messages already
+	// queued on a partition when it lost its broker still fail with ErrSendTimeout if it does not reconnect in time.
+	// Only the messages without Key, OrderingKey or Partition are rerouted, the others still fail to preserve
+	// their ordering. (default: false)
+	PartitionFailoverOnSend bool
+
+	// ValidateSchemaOnCreate makes CreateProducer fail with ErrSchema if the broker did not accept the producer
+	// schema, instead of failing on the first Send. (default: false)
+	ValidateSchemaOnCreate bool
+
+	// EmitEndMarkerOnClose makes Close send an empty end-of-stream marker message on every partition of the topic
+	// before closing the producer, so that the readers of a bounded stream know it is complete once they receive
+	// a message for which `Message.IsEndOfStream()` is true. The markers are sent on all the partitions in parallel
+	// and Close waits for them, for up to SendTimeout. No marker is sent when CreateProducer fails. It can not be
+	// enabled with LazyStartPartitionedProducers, as the partitions not started would not get the marker:
+	// CreateProducer fails with InvalidConfiguration. (default: false)
+	EmitEndMarkerOnClose bool
+
 	// Encryption specifies the fields required to encrypt a message
 	Encryption *ProducerEncryptionInfo
 
@@ -227,6 +300,9 @@ type Producer interface {
 	// This call is blocked when the `maxPendingMessages` becomes full (default: 1000)
 	// The callback will report back the message being published and
 	// the eventual error in publishing
+	// If the context is done before the message is written to the connection, e.g. while it waits in the current
+	// batch, the message is dropped and the callback receives an error matching both ErrContextExpired and the
+	// error of the context.
 	SendAsync(context.Context, *ProducerMessage, func(MessageID, *ProducerMessage, error))
 
 	// LastSequenceID get the last sequence id that was published by this producer.
@@ -237,6 +313,35 @@ type Producer interface {
 	// return the last sequence id published by this producer.
 	LastSequenceID() int64
 
+	// AvgBatchSize returns the average number of messages and bytes of the batches sent by this producer
+	// over the last minute, or zeros if no batch was sent. The bytes are counted after compression.
+	// A value close to 1 message means that BatchingMaxPublishDelay is too low for the publish rate.
+	AvgBatchSize() (msgs float64, bytes float64)
+
+	// Stats returns the number of messages, entries and bytes written by the producer on every partition since
+	// its creation, from which the realized compression ratio and the average batch size are derived. It only
+	// reads atomic counters and can be called frequently, e.g. by a metrics loop.
+	Stats() ProducerStats
+
+	// BatchingDelay returns the current delay within which the messages sent are batched, averaged over the
+	// partitions. It is BatchingMaxPublishDelay unless ProducerOptions.AdaptiveBatching is set, and zero if
+	// batching is disabled.
+	BatchingDelay() time.Duration
+
+	// MeasuredClockSkew returns how much the clock of the producer is ahead of the clock of the broker (negative
+	// if it is behind), as last measured on the partition with the largest skew, or zero if
+	// ProducerOptions.ClockSkewThreshold is not set or no measurement completed yet.
+	MeasuredClockSkew() time.Duration
+
+	// RefreshEncryptionKeys reads the public keys from ProducerEncryptionInfo.KeyReader again, e.g. after a key
+	// rotation, and generates a new data key encrypted with them. The messages encrypted once it returns use the
+	// new data key: as the batches are encrypted when they are flushed, the messages sent just before may use
+	// either key. On error, the producer keeps using the previous data key. Each message carries its encrypted
+	// data key, so the consumers decrypt the messages of both keys, in any order, as long as their KeyReader
+	// provides both private keys during the rotation (see crypto.MultiKeyReader).
+	// It returns an error if the producer does not encrypt the messages.
+	RefreshEncryptionKeys() error
+
 	// Deprecated: Use `FlushWithCtx()` instead.
 	Flush() error
 
@@ -244,6 +349,13 @@ type Producer interface {
 	// persisted.
 	FlushWithCtx(ctx context.Context) error
 
+	// FlushWithResults flushes like FlushWithCtx and returns the result of every message that was still pending
+	// when the flush started, in the order they were sent on each partition. The messages are also reported to
+	// their SendAsync callback as usual. The error is the one of the flush, the failures of the messages are
+	// only reported in their FlushResult. If the flush fails, e.g. when ctx is done, the messages not completed
+	// yet have neither MessageID nor Err.
+	FlushWithResults(ctx context.Context) ([]FlushResult, error)
+
 	// Close the producer and releases resources allocated
 	// No more writes will be accepted from this producer. Waits until all pending write request are persisted. In case
 	// of errors, pending writes will not be retried.
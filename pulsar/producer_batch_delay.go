@@ -0,0 +1,93 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	minAdaptiveBatchingDelay = time.Millisecond
+
+	// the queue usage above which the delay is lengthened, and below which it can be shortened
+	adaptiveBatchingHighQueueUsage = 0.5
+	adaptiveBatchingLowQueueUsage  = 0.1
+)
+
+// adaptiveBatchDelay tunes the batching delay of a partition producer between minAdaptiveBatchingDelay and
+// ProducerOptions.BatchingMaxPublishDelay: the delay is halved while the batches carry a single message and the
+// queue is nearly empty, so that a light load is not delayed for nothing, and doubled as soon as the batches
+// carry several messages or the queue builds up, so that a heavier load is sent in fewer and larger batches.
+//
+// onFlush and adjust must be called from the event loop of the producer, current can be called from anywhere.
+type adaptiveBatchDelay struct {
+	min, max time.Duration
+	delay    int64
+
+	// the number of messages and batches flushed since the last adjustment
+	messages, batches int
+}
+
+func newAdaptiveBatchDelay(max time.Duration) *adaptiveBatchDelay {
+	min := minAdaptiveBatchingDelay
+	if min > max {
+		min = max
+	}
+	return &adaptiveBatchDelay{
+		min:   min,
+		max:   max,
+		delay: int64(max),
+	}
+}
+
+func (d *adaptiveBatchDelay) current() time.Duration {
+	return time.Duration(atomic.LoadInt64(&d.delay))
+}
+
+func (d *adaptiveBatchDelay) onFlush(messages int) {
+	d.messages += messages
+	d.batches++
+}
+
+// adjust computes the delay to use for the next batches, given the batches flushed during the last delay and
+// the usage of the queue (between 0 and 1), in messages. It returns the new delay and whether it changed.
+func (d *adaptiveBatchDelay) adjust(queueUsage float64) (time.Duration, bool) {
+	messages, batches := d.messages, d.batches
+	d.messages, d.batches = 0, 0
+
+	delay := d.current()
+	next := delay
+	switch {
+	case queueUsage >= adaptiveBatchingHighQueueUsage || messages > batches:
+		next = delay * 2
+		if next > d.max {
+			next = d.max
+		}
+	case queueUsage < adaptiveBatchingLowQueueUsage:
+		next = delay / 2
+		if next < d.min {
+			next = d.min
+		}
+	}
+	if next == delay {
+		return delay, false
+	}
+	atomic.StoreInt64(&d.delay, int64(next))
+	return next, true
+}
@@ -0,0 +1,73 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveBatchDelay(t *testing.T) {
+	d := newAdaptiveBatchDelay(8 * time.Millisecond)
+	assert.Equal(t, 8*time.Millisecond, d.current())
+
+	// a light load shortens the delay down to the minimum
+	for _, expected := range []time.Duration{4, 2, 1} {
+		d.onFlush(1)
+		delay, changed := d.adjust(0)
+		assert.True(t, changed)
+		assert.Equal(t, expected*time.Millisecond, delay)
+	}
+	d.onFlush(1)
+	_, changed := d.adjust(0)
+	assert.False(t, changed)
+	assert.Equal(t, time.Millisecond, d.current())
+
+	// single message batches keep the delay while the queue is not empty
+	d.onFlush(1)
+	d.onFlush(1)
+	_, changed = d.adjust(0.2)
+	assert.False(t, changed)
+
+	// a moderate load, batching a few messages, lengthens the delay up to the maximum
+	for _, expected := range []time.Duration{2, 4, 8, 8} {
+		d.onFlush(3)
+		d.onFlush(2)
+		delay, _ := d.adjust(0)
+		assert.Equal(t, expected*time.Millisecond, delay)
+	}
+
+	// so does a deep queue, even with single message batches
+	d = newAdaptiveBatchDelay(8 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		d.adjust(0)
+	}
+	assert.Equal(t, time.Millisecond, d.current())
+	d.onFlush(1)
+	delay, changed := d.adjust(0.8)
+	assert.True(t, changed)
+	assert.Equal(t, 2*time.Millisecond, delay)
+
+	// the minimum never exceeds the maximum
+	d = newAdaptiveBatchDelay(100 * time.Microsecond)
+	_, changed = d.adjust(0)
+	assert.False(t, changed)
+	assert.Equal(t, 100*time.Microsecond, d.current())
+}
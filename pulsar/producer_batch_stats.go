@@ -0,0 +1,156 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"sync"
+	"time"
+
+	uAtomic "go.uber.org/atomic"
+)
+
+const (
+	batchStatsWindow  = time.Minute
+	batchStatsBuckets = 12
+)
+
+type batchStatsBucket struct {
+	start    int64
+	batches  int64
+	messages int64
+	bytes    int64
+}
+
+// batchStats accumulates the size of the batches flushed by a producer over a rolling window, split in
+// buckets so that old batches expire without having to keep track of each of them.
+type batchStats struct {
+	sync.Mutex
+	buckets [batchStatsBuckets]batchStatsBucket
+}
+
+func (s *batchStats) bucketDuration() int64 {
+	return int64(batchStatsWindow / batchStatsBuckets)
+}
+
+func (s *batchStats) add(now time.Time, messages, bytes int) {
+	start := now.UnixNano() / s.bucketDuration()
+
+	s.Lock()
+	defer s.Unlock()
+	b := &s.buckets[start%batchStatsBuckets]
+	if b.start != start {
+		*b = batchStatsBucket{start: start}
+	}
+	b.batches++
+	b.messages += int64(messages)
+	b.bytes += int64(bytes)
+}
+
+// totals returns the number of batches, messages and bytes flushed within the window ending at now.
+func (s *batchStats) totals(now time.Time) (batches, messages, bytes int64) {
+	oldest := now.UnixNano()/s.bucketDuration() - batchStatsBuckets
+
+	s.Lock()
+	defer s.Unlock()
+	for _, b := range s.buckets {
+		if b.start > oldest {
+			batches += b.batches
+			messages += b.messages
+			bytes += b.bytes
+		}
+	}
+	return
+}
+
+func averageBatchSize(batches, messages, bytes int64) (float64, float64) {
+	if batches == 0 {
+		return 0, 0
+	}
+	return float64(messages) / float64(batches), float64(bytes) / float64(batches)
+}
+
+// ProducerStats holds the statistics of a producer since its creation, see Producer.Stats. The embedded
+// ProducerPartitionStats are the totals over the partitions.
+type ProducerStats struct {
+	ProducerPartitionStats
+
+	// Partitions are the statistics of every partition producer started
+	Partitions []ProducerPartitionStats
+}
+
+// ProducerPartitionStats holds the statistics of a partition producer since its creation
+type ProducerPartitionStats struct {
+	// Topic is the partition topic
+	Topic string
+	// Messages is the number of messages written
+	Messages int64
+	// Batches is the number of entries written: the batches, and the messages sent without batching or as chunks
+	Batches int64
+	// UncompressedBytes is the size of the payloads of the messages written, before compression
+	UncompressedBytes int64
+	// WireBytes is the size of the entries written on the connection, after compression and with their headers
+	WireBytes int64
+}
+
+// CompressionRatio returns UncompressedBytes divided by WireBytes, or zero if nothing was written
+func (s ProducerPartitionStats) CompressionRatio() float64 {
+	if s.WireBytes == 0 {
+		return 0
+	}
+	return float64(s.UncompressedBytes) / float64(s.WireBytes)
+}
+
+// AvgBatchSize returns the average number of messages and bytes on the wire of the entries written, or zeros if
+// nothing was written
+func (s ProducerPartitionStats) AvgBatchSize() (msgs float64, bytes float64) {
+	return averageBatchSize(s.Batches, s.Messages, s.WireBytes)
+}
+
+func (s *ProducerStats) add(partition ProducerPartitionStats) {
+	s.Partitions = append(s.Partitions, partition)
+	s.Messages += partition.Messages
+	s.Batches += partition.Batches
+	s.UncompressedBytes += partition.UncompressedBytes
+	s.WireBytes += partition.WireBytes
+}
+
+// producerStatsCounters are the counters behind the ProducerPartitionStats, updated by the producer goroutine and
+// read by Stats
+type producerStatsCounters struct {
+	messages          uAtomic.Int64
+	batches           uAtomic.Int64
+	uncompressedBytes uAtomic.Int64
+	wireBytes         uAtomic.Int64
+}
+
+func (c *producerStatsCounters) record(messages int, uncompressedBytes int64, wireBytes int) {
+	c.messages.Add(int64(messages))
+	c.batches.Inc()
+	c.uncompressedBytes.Add(uncompressedBytes)
+	c.wireBytes.Add(int64(wireBytes))
+}
+
+func (c *producerStatsCounters) stats(topic string) ProducerPartitionStats {
+	return ProducerPartitionStats{
+		Topic:             topic,
+		Messages:          c.messages.Load(),
+		Batches:           c.batches.Load(),
+		UncompressedBytes: c.uncompressedBytes.Load(),
+		WireBytes:         c.wireBytes.Load(),
+	}
+}
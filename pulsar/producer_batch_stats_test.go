@@ -0,0 +1,83 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchStats(t *testing.T) {
+	var s batchStats
+	now := time.Now()
+
+	msgs, bytes := averageBatchSize(s.totals(now))
+	assert.Equal(t, 0.0, msgs)
+	assert.Equal(t, 0.0, bytes)
+
+	s.add(now, 10, 1000)
+	s.add(now, 20, 3000)
+	msgs, bytes = averageBatchSize(s.totals(now))
+	assert.Equal(t, 15.0, msgs)
+	assert.Equal(t, 2000.0, bytes)
+
+	// batches older than the window are no longer accounted
+	later := now.Add(batchStatsWindow + time.Second)
+	s.add(later, 1, 100)
+	msgs, bytes = averageBatchSize(s.totals(later))
+	assert.Equal(t, 1.0, msgs)
+	assert.Equal(t, 100.0, bytes)
+
+	msgs, _ = averageBatchSize(s.totals(later.Add(batchStatsWindow)))
+	assert.Equal(t, 0.0, msgs)
+}
+
+func TestProducerStatsCounters(t *testing.T) {
+	var c producerStatsCounters
+	stats := c.stats("topic-partition-0")
+	assert.Equal(t, 0.0, stats.CompressionRatio())
+	msgs, _ := stats.AvgBatchSize()
+	assert.Equal(t, 0.0, msgs)
+
+	c.record(10, 4000, 1000)
+	c.record(1, 1000, 1000)
+	stats = c.stats("topic-partition-0")
+	assert.Equal(t, ProducerPartitionStats{
+		Topic:             "topic-partition-0",
+		Messages:          11,
+		Batches:           2,
+		UncompressedBytes: 5000,
+		WireBytes:         2000,
+	}, stats)
+	assert.Equal(t, 2.5, stats.CompressionRatio())
+	msgs, bytes := stats.AvgBatchSize()
+	assert.Equal(t, 5.5, msgs)
+	assert.Equal(t, 1000.0, bytes)
+
+	var total ProducerStats
+	total.add(stats)
+	total.add(ProducerPartitionStats{Topic: "topic-partition-1", Messages: 1, Batches: 1, UncompressedBytes: 10,
+		WireBytes: 50})
+	assert.Len(t, total.Partitions, 2)
+	assert.Equal(t, int64(12), total.Messages)
+	assert.Equal(t, int64(3), total.Batches)
+	assert.Equal(t, int64(5010), total.UncompressedBytes)
+	assert.Equal(t, int64(2050), total.WireBytes)
+}
@@ -0,0 +1,174 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	uAtomic "go.uber.org/atomic"
+)
+
+const clockSkewProbeInterval = time.Minute
+
+// clockSkewProbe measures the skew between the clock of a partition producer and the one of the broker, by
+// reading back a message sent by the producer to compare the time at which it was sent with the broker publish
+// time of its entry.
+type clockSkewProbe struct {
+	threshold time.Duration
+
+	// the time of the next probe, only accessed when receiving the send receipts
+	next time.Time
+
+	running     uAtomic.Bool
+	unsupported uAtomic.Bool
+	skew        uAtomic.Duration
+
+	// ctx is canceled when the producer is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// reader reads back the probed messages, it is created by the first probe and seeks for the next ones
+	mu     sync.Mutex
+	reader Reader
+	closed bool
+}
+
+func newClockSkewProbe(threshold time.Duration) *clockSkewProbe {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &clockSkewProbe{threshold: threshold, ctx: ctx, cancel: cancel}
+}
+
+// readBack returns the first message of the entry of the given id
+func (probe *clockSkewProbe) readBack(p *partitionProducer, id *messageID) (Message, error) {
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+	if probe.closed {
+		return nil, ErrProducerClosed
+	}
+
+	entryID := &messageID{ledgerID: id.ledgerID, entryID: id.entryID, batchIdx: -1, partitionIdx: id.partitionIdx}
+	if probe.reader == nil {
+		reader, err := p.client.CreateReader(ReaderOptions{
+			Topic:                   p.topic,
+			StartMessageID:          entryID,
+			StartMessageIDInclusive: true,
+			ReceiverQueueSize:       1,
+			MetadataOnly:            true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		probe.reader = reader
+	} else if err := probe.reader.Seek(entryID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(probe.ctx, p.client.operationTimeout)
+	defer cancel()
+	return probe.reader.Next(ctx)
+}
+
+// close stops the probe in progress, if any, and closes the reader without waiting for it
+func (probe *clockSkewProbe) close() {
+	probe.cancel()
+	go func() {
+		probe.mu.Lock()
+		defer probe.mu.Unlock()
+		probe.closed = true
+		if probe.reader != nil {
+			probe.reader.Close()
+			probe.reader = nil
+		}
+	}()
+}
+
+// estimateClockSkew returns the skew of the local clock ahead of the broker's, given the local times at which a
+// message was sent and its receipt was received, and the broker time at which it was published. As in NTP, the
+// message is assumed to reach the broker halfway through the round trip.
+func estimateClockSkew(sentAt, receivedAt, brokerPublishTime time.Time) time.Duration {
+	return sentAt.Add(receivedAt.Sub(sentAt) / 2).Sub(brokerPublishTime)
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// maybeProbeClockSkew starts a measurement of the clock skew with the message of a send receipt, if the previous
+// one is older than clockSkewProbeInterval
+func (p *partitionProducer) maybeProbeClockSkew(pi *pendingItem, id *messageID, receivedAt time.Time) {
+	probe := p.clockSkew
+	if probe == nil || probe.unsupported.Load() || receivedAt.Before(probe.next) {
+		return
+	}
+	for _, i := range pi.sendRequests {
+		if sr, ok := i.(*sendRequest); ok && sr.totalChunks > 1 {
+			// a chunk can not be read back on its own
+			return
+		}
+	}
+	if !probe.running.CAS(false, true) {
+		return
+	}
+	probe.next = receivedAt.Add(clockSkewProbeInterval)
+	go p.probeClockSkew(id, pi.sentAt, receivedAt)
+}
+
+func (p *partitionProducer) probeClockSkew(id *messageID, sentAt, receivedAt time.Time) {
+	probe := p.clockSkew
+	defer probe.running.Store(false)
+
+	msg, err := probe.readBack(p, id)
+	if err != nil {
+		if probe.ctx.Err() == nil {
+			p.log.WithError(err).Warn("Failed to read the message measuring the clock skew with the broker")
+		}
+		return
+	}
+	if msgID := msg.ID(); msgID.LedgerID() != id.ledgerID || msgID.EntryID() != id.entryID {
+		p.log.WithField("messageID", msgID).Warn("Read back another message than the one measuring the clock skew")
+		return
+	}
+	brokerPublishTime := msg.BrokerPublishTime()
+	if brokerPublishTime == nil {
+		probe.unsupported.Store(true)
+		probe.close()
+		p.log.Warn("The clock skew with the broker can not be measured, since the broker does not add the " +
+			"broker entry metadata to the messages")
+		return
+	}
+
+	skew := estimateClockSkew(sentAt, receivedAt, *brokerPublishTime)
+	probe.skew.Store(skew)
+	if absDuration(skew) > probe.threshold {
+		p.metrics.ProducersClockSkewExceeded.Inc()
+		p.log.Warnf("The clock of the producer is %v ahead of the broker's, which exceeds the threshold of %v: "+
+			"the publish and event times of its messages are shifted accordingly", skew, probe.threshold)
+	}
+}
+
+func (p *partitionProducer) MeasuredClockSkew() time.Duration {
+	if p.clockSkew == nil {
+		return 0
+	}
+	return p.clockSkew.skew.Load()
+}
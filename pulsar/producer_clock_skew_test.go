@@ -0,0 +1,54 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateClockSkew(t *testing.T) {
+	sentAt := time.Now()
+	receivedAt := sentAt.Add(10 * time.Millisecond)
+
+	assert.Equal(t, time.Duration(0), estimateClockSkew(sentAt, receivedAt, sentAt.Add(5*time.Millisecond)))
+	assert.Equal(t, 2*time.Second, estimateClockSkew(sentAt, receivedAt, sentAt.Add(-2*time.Second+5*time.Millisecond)))
+	assert.Equal(t, -time.Minute, estimateClockSkew(sentAt, receivedAt, sentAt.Add(time.Minute+5*time.Millisecond)))
+
+	assert.Equal(t, time.Second, absDuration(-time.Second))
+	assert.Equal(t, time.Second, absDuration(time.Second))
+}
+
+func TestClockSkewProbeClose(t *testing.T) {
+	probe := newClockSkewProbe(time.Second)
+	probe.close()
+	assert.NotNil(t, probe.ctx.Err())
+
+	assert.Eventually(t, func() bool {
+		probe.mu.Lock()
+		defer probe.mu.Unlock()
+		return probe.closed
+	}, time.Second, 10*time.Millisecond)
+
+	// the probes after the producer was closed do not create a reader
+	_, err := probe.readBack(nil, &messageID{ledgerID: 1, entryID: 2})
+	assert.ErrorIs(t, err, ErrProducerClosed)
+	assert.Nil(t, probe.reader)
+}
@@ -19,6 +19,7 @@ package pulsar
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -54,12 +55,18 @@ type producer struct {
 	topic         string
 	producers     []Producer
 	producersPtr  unsafe.Pointer
+	partitions    []string
 	numPartitions uint32
+	closed        bool
 	messageRouter func(*ProducerMessage, TopicMetadata) int
 	closeOnce     sync.Once
 	stopDiscovery func()
 	log           log.Logger
 	metrics       *internal.LeveledMetrics
+
+	// pendingAcrossPartitions enforces MaxPendingMessagesAcrossPartitions, shared by the partition producers,
+	// nil when there is no limit
+	pendingAcrossPartitions internal.Semaphore
 }
 
 func getHashingFunction(s HashingScheme) func(string) uint32 {
@@ -94,8 +101,26 @@ func newProducer(client *client, options *ProducerOptions) (*producer, error) {
 		options.PartitionsAutoDiscoveryInterval = defaultPartitionsAutoDiscoveryInterval
 	}
 
+	if options.MaxPendingMessagesAcrossPartitions < 0 {
+		return nil, newError(InvalidConfiguration, "MaxPendingMessagesAcrossPartitions can not be negative")
+	}
+
+	if options.CompressionProvider != nil {
+		if !validCustomCompressionType(options.CompressionProvider.Type()) {
+			return nil, newError(InvalidConfiguration, fmt.Sprintf("unsupported type %d of CompressionProvider",
+				options.CompressionProvider.Type()))
+		}
+		options.CompressionType = options.CompressionProvider.Type()
+	}
+
 	if !options.DisableBatching && options.EnableChunking {
-		return nil, fmt.Errorf("batching and chunking can not be enabled together")
+		return nil, newError(InvalidConfiguration,
+			"batching and chunking can not be enabled together, set DisableBatching to enable chunking")
+	}
+	if options.LazyStartPartitionedProducers && options.EmitEndMarkerOnClose {
+		// the end marker must reach every partition, the partitions not started would not get it
+		return nil, newError(InvalidConfiguration,
+			"LazyStartPartitionedProducers and EmitEndMarkerOnClose can not be enabled together")
 	}
 
 	p := &producer{
@@ -105,14 +130,21 @@ func newProducer(client *client, options *ProducerOptions) (*producer, error) {
 		log:     client.log.SubLogger(log.Fields{"topic": options.Topic}),
 		metrics: client.metrics.GetLeveledMetrics(options.Topic),
 	}
+	if options.MaxPendingMessagesAcrossPartitions > 0 {
+		p.pendingAcrossPartitions = internal.NewSemaphore(int32(options.MaxPendingMessagesAcrossPartitions))
+	}
 
 	if options.Interceptors == nil {
 		options.Interceptors = defaultProducerInterceptors
 	}
 
 	if options.MessageRouter == nil {
+		hashFunc := options.CustomHasher
+		if hashFunc == nil {
+			hashFunc = getHashingFunction(options.HashingScheme)
+		}
 		internalRouter := NewDefaultRouter(
-			getHashingFunction(options.HashingScheme),
+			hashFunc,
 			options.BatchingMaxMessages,
 			options.BatchingMaxSize,
 			options.BatchingMaxPublishDelay,
@@ -214,6 +246,7 @@ func (p *producer) internalCreatePartitionsProducers() error {
 	}
 
 	p.producers = make([]Producer, newNumPartitions)
+	p.partitions = partitions
 
 	// When for some reason (eg: forced deletion of sub partition) causes oldNumPartitions> newNumPartitions,
 	// we need to rebuild the cache of new producers, otherwise the array will be out of bounds.
@@ -236,13 +269,22 @@ func (p *producer) internalCreatePartitionsProducers() error {
 		partitionsToAdd = newNumPartitions
 		startPartition = 0
 	}
+	if p.options.LazyStartPartitionedProducers {
+		// the other partitions are started on their first message, see startPartition
+		if startPartition == 0 && newNumPartitions > 0 {
+			partitionsToAdd = 1
+		} else {
+			partitionsToAdd = 0
+		}
+	}
 	c := make(chan ProducerError, partitionsToAdd)
 
-	for partitionIdx := startPartition; partitionIdx < newNumPartitions; partitionIdx++ {
+	for partitionIdx := startPartition; partitionIdx < startPartition+partitionsToAdd; partitionIdx++ {
 		partition := partitions[partitionIdx]
 
 		go func(partitionIdx int, partition string) {
-			prod, e := newPartitionProducer(p.client, partition, p.options, partitionIdx, p.metrics)
+			prod, e := newPartitionProducer(p.client, partition, p.options, partitionIdx, p.metrics,
+				p.pendingAcrossPartitions)
 			c <- ProducerError{
 				partition: partitionIdx,
 				prod:      prod,
@@ -273,7 +315,7 @@ func (p *producer) internalCreatePartitionsProducers() error {
 	}
 
 	if newNumPartitions < oldNumPartitions {
-		p.metrics.ProducersPartitions.Set(float64(newNumPartitions))
+		p.metrics.ProducersPartitions.Set(float64(len(p.startedProducers())))
 	} else {
 		p.metrics.ProducersPartitions.Add(float64(partitionsToAdd))
 	}
@@ -282,6 +324,74 @@ func (p *producer) internalCreatePartitionsProducers() error {
 	return nil
 }
 
+// emitEndMarkers sends the end-of-stream marker on all the partitions in parallel, and waits for them to be sent.
+// It is only called by Close, the producers closed because CreateProducer failed do not end the stream.
+func (p *producer) emitEndMarkers() {
+	p.RLock()
+	producers := p.producers
+	p.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, pp := range producers {
+		wg.Add(1)
+		go func(pp Producer) {
+			defer wg.Done()
+			_, err := pp.Send(context.Background(), &ProducerMessage{
+				Properties: map[string]string{endOfStreamProperty: "true"},
+			})
+			if err != nil {
+				p.log.WithError(err).WithField("topic", pp.Topic()).Warn("Failed to send the end-of-stream marker")
+			}
+		}(pp)
+	}
+	wg.Wait()
+}
+
+// startPartition returns the producer of the partition, starting it if it was not yet
+func (p *producer) startPartition(partition int) (Producer, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return nil, ErrProducerClosed
+	}
+	if partition >= len(p.producers) {
+		return nil, joinErrors(ErrInvalidMessage,
+			fmt.Errorf("partition %d is out of range, the topic has %d partitions", partition, len(p.producers)))
+	}
+	if pp := p.producers[partition]; pp != nil {
+		return pp, nil
+	}
+
+	pp, err := newPartitionProducer(p.client, p.partitions[partition], p.options, partition, p.metrics,
+		p.pendingAcrossPartitions)
+	if err != nil {
+		return nil, err
+	}
+	// copy on write, the list is read without lock
+	producers := make([]Producer, len(p.producers))
+	copy(producers, p.producers)
+	producers[partition] = pp
+	p.producers = producers
+	atomic.StorePointer(&p.producersPtr, unsafe.Pointer(&producers))
+	p.metrics.ProducersPartitions.Inc()
+	return pp, nil
+}
+
+// startedProducers returns the producers of the partitions started, must be called with the lock held
+func (p *producer) startedProducers() []Producer {
+	if !p.options.LazyStartPartitionedProducers {
+		return p.producers
+	}
+	started := make([]Producer, 0, len(p.producers))
+	for _, pp := range p.producers {
+		if pp != nil {
+			started = append(started, pp)
+		}
+	}
+	return started
+}
+
 func (p *producer) Topic() string {
 	return p.topic
 }
@@ -297,26 +407,150 @@ func (p *producer) NumPartitions() uint32 {
 	return atomic.LoadUint32(&p.numPartitions)
 }
 
+// keyHasher returns the ProducerOptions.CustomHasher, if any
+func (p *producer) keyHasher() func(string) uint32 {
+	return p.options.CustomHasher
+}
+
 func (p *producer) Send(ctx context.Context, msg *ProducerMessage) (MessageID, error) {
-	return p.getPartition(msg).Send(ctx, msg)
+	partition, err := p.getPartition(msg)
+	if err != nil {
+		return nil, err
+	}
+	partition = p.connectedPartition(ctx, msg, partition)
+	msgID, err := partition.Send(ctx, msg)
+	if err == nil || !p.canFailover(ctx, msg, err) {
+		return msgID, err
+	}
+
+	for _, other := range p.failoverPartitions(partition) {
+		p.log.WithError(err).Warnf("Failed to send message on %s, retrying on %s", partition.Topic(), other.Topic())
+		partition = other
+		msgID, err = partition.Send(ctx, msg)
+		if err == nil || !p.canFailover(ctx, msg, err) {
+			break
+		}
+	}
+	return msgID, err
 }
 
 func (p *producer) SendAsync(ctx context.Context, msg *ProducerMessage,
 	callback func(MessageID, *ProducerMessage, error)) {
-	p.getPartition(msg).SendAsync(ctx, msg, callback)
+	partition, err := p.getPartition(msg)
+	if err != nil {
+		callback(nil, msg, err)
+		return
+	}
+	if !p.options.PartitionFailoverOnSend {
+		partition.SendAsync(ctx, msg, callback)
+		return
+	}
+	p.sendAsyncWithFailover(ctx, msg, p.connectedPartition(ctx, msg, partition), nil, callback)
+}
+
+// sendAsyncWithFailover sends the message on the partition, then on the failover partitions one after the other
+// as long as it fails; failover is nil until the first failure
+func (p *producer) sendAsyncWithFailover(ctx context.Context, msg *ProducerMessage, partition Producer,
+	failover []Producer, callback func(MessageID, *ProducerMessage, error)) {
+	partition.SendAsync(ctx, msg, func(msgID MessageID, m *ProducerMessage, err error) {
+		if err != nil && p.canFailover(ctx, msg, err) {
+			if failover == nil {
+				failover = p.failoverPartitions(partition)
+			}
+			if len(failover) > 0 {
+				p.log.WithError(err).Warnf("Failed to send message on %s, retrying on %s",
+					partition.Topic(), failover[0].Topic())
+				// not retried in the callback, which must not block the partition producer
+				go p.sendAsyncWithFailover(ctx, msg, failover[0], failover[1:], callback)
+				return
+			}
+		}
+		callback(msgID, m, err)
+	})
 }
 
-func (p *producer) getPartition(msg *ProducerMessage) Producer {
+// canReroute reports whether msg can be sent on another partition than the one it was routed to: the ordering of
+// the messages with Key, OrderingKey or Partition is preserved
+func (p *producer) canReroute(ctx context.Context, msg *ProducerMessage) bool {
+	if !p.options.PartitionFailoverOnSend || ctx.Err() != nil {
+		return false
+	}
+	return msg.Key == "" && msg.OrderingKey == "" && msg.Partition == nil
+}
+
+// canFailover reports whether a message that failed to be sent with err can be retried on another partition. Only
+// the errors telling that the message was not written are retried: a message whose send timed out may have been
+// persisted, retrying it would duplicate it.
+func (p *producer) canFailover(ctx context.Context, msg *ProducerMessage, err error) bool {
+	if !p.canReroute(ctx, msg) {
+		return false
+	}
+	return errors.Is(err, ErrSendQueueIsFull) || errors.Is(err, ErrProducerClosed) ||
+		errors.Is(err, ErrProducerFenced) || errors.Is(err, ErrTopicNotfound)
+}
+
+// connectedPartition returns the partition to send msg on: the given one, unless it is reconnecting to its broker,
+// then the first failover partition which is connected. The message fails over before it is written, instead of
+// waiting for the send timeout on the disconnected partition.
+func (p *producer) connectedPartition(ctx context.Context, msg *ProducerMessage, partition Producer) Producer {
+	if pp, ok := partition.(*partitionProducer); !ok || !pp.disconnected.Load() || !p.canReroute(ctx, msg) {
+		return partition
+	}
+	for _, other := range p.failoverPartitions(partition) {
+		if pp, ok := other.(*partitionProducer); ok && !pp.disconnected.Load() {
+			p.log.Warnf("Partition %s is reconnecting, sending message on %s", partition.Topic(), other.Topic())
+			return other
+		}
+	}
+	return partition
+}
+
+// failoverPartitions returns the other partitions to retry a message that failed on the given partition,
+// starting from the next one
+func (p *producer) failoverPartitions(failed Producer) []Producer {
+	producers := *(*[]Producer)(atomic.LoadPointer(&p.producersPtr))
+	for i, pp := range producers {
+		if pp == failed {
+			failover := make([]Producer, 0, len(producers)-1)
+			for j := 1; j < len(producers); j++ {
+				// the partitions not started yet are skipped
+				if other := producers[(i+j)%len(producers)]; other != nil {
+					failover = append(failover, other)
+				}
+			}
+			return failover
+		}
+	}
+	return []Producer{}
+}
+
+func (p *producer) getPartition(msg *ProducerMessage) (Producer, error) {
 	// Since partitions can only increase, it's ok if the producers list
 	// is updated in between. The numPartition is updated only after the list.
-	partition := p.messageRouter(msg, p)
 	producers := *(*[]Producer)(atomic.LoadPointer(&p.producersPtr))
+	if msg != nil && msg.Partition != nil {
+		partition := *msg.Partition
+		if partition < 0 || partition >= len(producers) {
+			return nil, joinErrors(ErrInvalidMessage,
+				fmt.Errorf("partition %d is out of range, the topic has %d partitions", partition, len(producers)))
+		}
+		return p.partitionProducer(producers, partition)
+	}
+
+	partition := p.messageRouter(msg, p)
 	if partition >= len(producers) {
 		// We read the old producers list while the count was already
 		// updated
 		partition %= len(producers)
 	}
-	return producers[partition]
+	return p.partitionProducer(producers, partition)
+}
+
+func (p *producer) partitionProducer(producers []Producer, partition int) (Producer, error) {
+	if pp := producers[partition]; pp != nil {
+		return pp, nil
+	}
+	return p.startPartition(partition)
 }
 
 func (p *producer) LastSequenceID() int64 {
@@ -324,7 +558,7 @@ func (p *producer) LastSequenceID() int64 {
 	defer p.RUnlock()
 
 	var maxSeq int64 = -1
-	for _, pp := range p.producers {
+	for _, pp := range p.startedProducers() {
 		s := pp.LastSequenceID()
 		if s > maxSeq {
 			maxSeq = s
@@ -333,6 +567,81 @@ func (p *producer) LastSequenceID() int64 {
 	return maxSeq
 }
 
+func (p *producer) AvgBatchSize() (float64, float64) {
+	p.RLock()
+	defer p.RUnlock()
+
+	now := time.Now()
+	var batches, messages, bytes int64
+	for _, pp := range p.startedProducers() {
+		partition, ok := pp.(*partitionProducer)
+		if !ok {
+			continue
+		}
+		b, m, s := partition.batchStats.totals(now)
+		batches += b
+		messages += m
+		bytes += s
+	}
+	return averageBatchSize(batches, messages, bytes)
+}
+
+func (p *producer) Stats() ProducerStats {
+	p.RLock()
+	defer p.RUnlock()
+
+	stats := ProducerStats{ProducerPartitionStats: ProducerPartitionStats{Topic: p.topic}}
+	for _, pp := range p.startedProducers() {
+		if partition, ok := pp.(*partitionProducer); ok {
+			stats.add(partition.statsCounters.stats(partition.topic))
+		}
+	}
+	return stats
+}
+
+func (p *producer) BatchingDelay() time.Duration {
+	p.RLock()
+	defer p.RUnlock()
+
+	producers := p.startedProducers()
+	if len(producers) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, pp := range producers {
+		total += pp.BatchingDelay()
+	}
+	return total / time.Duration(len(producers))
+}
+
+func (p *producer) MeasuredClockSkew() time.Duration {
+	p.RLock()
+	defer p.RUnlock()
+
+	var skew time.Duration
+	for _, pp := range p.startedProducers() {
+		if s := pp.MeasuredClockSkew(); absDuration(s) > absDuration(skew) {
+			skew = s
+		}
+	}
+	return skew
+}
+
+func (p *producer) RefreshEncryptionKeys() error {
+	// the message crypto is shared by all the partitions
+	return refreshEncryptionKeys(p.options.Encryption)
+}
+
+func refreshEncryptionKeys(encryption *ProducerEncryptionInfo) error {
+	if encryption == nil {
+		return newError(InvalidConfiguration, "the producer does not encrypt the messages")
+	}
+	if err := encryption.MessageCrypto.AddPublicKeyCipher(encryption.Keys, encryption.KeyReader); err != nil {
+		return newError(CryptoError, fmt.Sprintf("failed to refresh the encryption keys: %v", err))
+	}
+	return nil
+}
+
 func (p *producer) Flush() error {
 	return p.FlushWithCtx(context.Background())
 }
@@ -341,7 +650,8 @@ func (p *producer) FlushWithCtx(ctx context.Context) error {
 	p.RLock()
 	defer p.RUnlock()
 
-	for _, pp := range p.producers {
+	// the partitions not started have nothing to flush
+	for _, pp := range p.startedProducers() {
 		if err := pp.FlushWithCtx(ctx); err != nil {
 			return err
 		}
@@ -350,18 +660,39 @@ func (p *producer) FlushWithCtx(ctx context.Context) error {
 	return nil
 }
 
+func (p *producer) FlushWithResults(ctx context.Context) ([]FlushResult, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	var results []FlushResult
+	for _, pp := range p.startedProducers() {
+		partitionResults, err := pp.FlushWithResults(ctx)
+		results = append(results, partitionResults...)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
 func (p *producer) Close() {
 	p.closeOnce.Do(func() {
 		p.stopDiscovery()
 
+		if p.options.EmitEndMarkerOnClose {
+			p.emitEndMarkers()
+		}
+
 		p.Lock()
 		defer p.Unlock()
 
-		for _, pp := range p.producers {
+		p.closed = true
+		producers := p.startedProducers()
+		for _, pp := range producers {
 			pp.Close()
 		}
 		p.client.handlers.Del(p)
-		p.metrics.ProducersPartitions.Sub(float64(len(p.producers)))
+		p.metrics.ProducersPartitions.Sub(float64(len(producers)))
 		p.metrics.ProducersClosed.Inc()
 	})
 }
@@ -19,6 +19,7 @@ package pulsar
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"math"
@@ -67,7 +68,6 @@ var (
 	ErrProducerBlockedQuotaExceeded = newError(ProducerBlockedQuotaExceededException, "producer blocked")
 	ErrProducerFenced               = newError(ProducerFenced, "producer fenced")
 
-	buffersPool     sync.Pool
 	sendRequestPool *sync.Pool
 )
 
@@ -93,6 +93,8 @@ type partitionProducer struct {
 	log    log.Logger
 
 	conn uAtomic.Value
+	// set while the producer reconnects to its broker, see producer.connectedPartition
+	disconnected uAtomic.Bool
 
 	options                  *ProducerOptions
 	producerName             string
@@ -110,6 +112,7 @@ type partitionProducer struct {
 	connectClosedCh chan *connectionClosed
 
 	publishSemaphore internal.Semaphore
+	sharedSemaphore  internal.Semaphore
 	pendingQueue     internal.BlockingQueue
 	lastSequenceID   int64
 	schemaInfo       *SchemaInfo
@@ -118,6 +121,13 @@ type partitionProducer struct {
 	epoch            uint64
 	schemaCache      *schemaCache
 	topicEpoch       *uint64
+	batchStats       batchStats
+	statsCounters    producerStatsCounters
+	batchDelay       *adaptiveBatchDelay
+	clockSkew        *clockSkewProbe
+
+	// number of messages in the current batch whose context can be canceled, see removeCanceledFromBatch
+	batchCancelable int
 }
 
 type schemaCache struct {
@@ -142,7 +152,7 @@ func (s *schemaCache) Get(schema *SchemaInfo) (schemaVersion []byte) {
 }
 
 func newPartitionProducer(client *client, topic string, options *ProducerOptions, partitionIdx int,
-	metrics *internal.LeveledMetrics) (
+	metrics *internal.LeveledMetrics, pendingAcrossPartitions internal.Semaphore) (
 	*partitionProducer, error) {
 	var batchingMaxPublishDelay time.Duration
 	if options.BatchingMaxPublishDelay != 0 {
@@ -158,7 +168,7 @@ func newPartitionProducer(client *client, topic string, options *ProducerOptions
 		maxPendingMessages = options.MaxPendingMessages
 	}
 
-	logger := client.log.SubLogger(log.Fields{"topic": topic})
+	logger := client.log.SubLogger(log.Fields{"topic": topic, "partition": partitionIdx})
 
 	p := &partitionProducer{
 		client:           client,
@@ -170,9 +180,8 @@ func newPartitionProducer(client *client, topic string, options *ProducerOptions
 		cmdChan:          make(chan interface{}, 10),
 		connectClosedCh:  make(chan *connectionClosed, 10),
 		batchFlushTicker: time.NewTicker(batchingMaxPublishDelay),
-		compressionProvider: internal.GetCompressionProvider(pb.CompressionType(options.CompressionType),
-			compression.Level(options.CompressionLevel)),
 		publishSemaphore: internal.NewSemaphore(int32(maxPendingMessages)),
+		sharedSemaphore:  pendingAcrossPartitions,
 		pendingQueue:     internal.NewBlockingQueue(maxPendingMessages),
 		lastSequenceID:   -1,
 		partitionIdx:     int32(partitionIdx),
@@ -182,10 +191,25 @@ func newPartitionProducer(client *client, topic string, options *ProducerOptions
 	}
 	if p.options.DisableBatching {
 		p.batchFlushTicker.Stop()
+	} else if p.options.AdaptiveBatching {
+		p.batchDelay = newAdaptiveBatchDelay(batchingMaxPublishDelay)
+	}
+	if p.options.ClockSkewThreshold > 0 {
+		p.clockSkew = newClockSkewProbe(p.options.ClockSkewThreshold)
 	}
 	p.setProducerState(producerInit)
 
-	if options.Schema != nil && options.Schema.GetSchemaInfo() != nil {
+	if options.CompressionProvider != nil {
+		p.compressionProvider = customCompressionProvider{provider: options.CompressionProvider}
+	} else {
+		p.compressionProvider = internal.GetCompressionProvider(pb.CompressionType(options.CompressionType),
+			compression.Level(options.CompressionLevel))
+	}
+
+	if _, ok := options.Schema.(*AutoPublishSchema); ok {
+		// the schemas are those of the forwarded messages
+		p.schemaInfo = nil
+	} else if options.Schema != nil && options.Schema.GetSchemaInfo() != nil {
 		p.schemaInfo = options.Schema.GetSchemaInfo()
 	} else {
 		p.schemaInfo = nil
@@ -212,6 +236,13 @@ func newPartitionProducer(client *client, topic string, options *ProducerOptions
 	p.log.WithField("cnx", p._getConn().ID()).Info("Created producer")
 	p.setProducerState(producerReady)
 
+	if p.options.ValidateSchemaOnCreate {
+		if err := p.validateSchema(); err != nil {
+			p.doClose(err)
+			return nil, err
+		}
+	}
+
 	if p.options.SendTimeout > 0 {
 		go p.failTimeoutMessages()
 	}
@@ -338,6 +369,9 @@ func (p *partitionProducer) grabCnx(assignedBrokerURL string) error {
 		if err != nil {
 			return err
 		}
+		if setter, ok := p.batchBuilder.(internal.CompressionProviderSetter); ok && p.options.CompressionProvider != nil {
+			setter.SetCompressionProvider(p.compressionProvider)
+		}
 	}
 
 	p.log.WithFields(log.Fields{
@@ -383,16 +417,13 @@ func (cc *connectionClosed) HasURL() bool {
 }
 
 func (p *partitionProducer) GetBuffer() internal.Buffer {
-	b, ok := buffersPool.Get().(internal.Buffer)
-	if ok {
-		b.Clear()
-	}
-	return b
+	return getPooledBuffer(p.client.bufferPool)
 }
 
 func (p *partitionProducer) ConnectionClosed(closeProducer *pb.CommandCloseProducer) {
 	// Trigger reconnection in the produce goroutine
 	p.log.WithField("cnx", p._getConn().ID()).Warn("Connection was closed")
+	p.disconnected.Store(true)
 	var assignedBrokerURL string
 	if closeProducer != nil {
 		assignedBrokerURL = p.client.selectServiceURL(
@@ -403,6 +434,21 @@ func (p *partitionProducer) ConnectionClosed(closeProducer *pb.CommandCloseProdu
 	}
 }
 
+// validateSchema makes sure the broker accepted the producer schema, registering it now
+// if the broker did not return its version when the producer was created
+func (p *partitionProducer) validateSchema() error {
+	if p.schemaInfo == nil || p.schemaCache.Get(p.schemaInfo) != nil {
+		return nil
+	}
+	schemaVersion, err := p.getOrCreateSchema(p.schemaInfo)
+	if err != nil {
+		p.log.WithError(err).Error("Failed to validate the producer schema")
+		return joinErrors(ErrSchema, fmt.Errorf("the broker rejected the producer schema: %w", err))
+	}
+	p.schemaCache.Put(p.schemaInfo, schemaVersion)
+	return nil
+}
+
 func (p *partitionProducer) getOrCreateSchema(schemaInfo *SchemaInfo) (schemaVersion []byte, err error) {
 
 	tmpSchemaType := pb.Schema_Type(int32(schemaInfo.Type))
@@ -482,6 +528,7 @@ func (p *partitionProducer) reconnectToBroker(connectionClosed *connectionClosed
 		err := p.grabCnx(assignedBrokerURL)
 		if err == nil {
 			// Successfully reconnected
+			p.disconnected.Store(false)
 			p.log.WithField("cnx", p._getConn().ID()).Info("Reconnected producer to broker")
 			return
 		}
@@ -548,6 +595,7 @@ func (p *partitionProducer) runEventsLoop() {
 			p.reconnectToBroker(connectionClosed)
 		case <-p.batchFlushTicker.C:
 			p.internalFlushCurrentBatch()
+			p.adjustBatchDelay()
 		}
 	}
 }
@@ -571,8 +619,18 @@ func runCallback(cb func(MessageID, *ProducerMessage, error), id MessageID, msg
 func (p *partitionProducer) internalSend(sr *sendRequest) {
 	p.log.Debug("Received send request: ", *sr.msg)
 
+	if err := sr.ctx.Err(); err != nil {
+		sr.done(nil, joinErrors(ErrContextExpired, err))
+		return
+	}
+
 	if sr.sendAsBatch {
 		smm := p.genSingleMessageMetadataInBatch(sr.msg, int(sr.uncompressedSize))
+		if sr.schemaKey != nil {
+			smm.PartitionKey = proto.String(base64.StdEncoding.EncodeToString(sr.schemaKey))
+			smm.PartitionKeyB64Encoded = proto.Bool(true)
+		}
+		sr.smm = smm
 		multiSchemaEnabled := !p.options.DisableMultiSchema
 
 		added := addRequestToBatch(
@@ -592,6 +650,9 @@ func (p *partitionProducer) internalSend(sr *sendRequest) {
 				return
 			}
 		}
+		if sr.ctx.Done() != nil {
+			p.batchCancelable++
+		}
 
 		if sr.flushImmediately {
 			p.internalFlushCurrentBatch()
@@ -634,6 +695,7 @@ func (p *partitionProducer) internalSend(sr *sendRequest) {
 			transaction:         sr.transaction,
 			memLimit:            sr.memLimit,
 			semaphore:           sr.semaphore,
+			sharedSemaphore:     sr.sharedSemaphore,
 			reservedMem:         int64(rhs - lhs),
 			sendAsBatch:         sr.sendAsBatch,
 			schema:              sr.schema,
@@ -796,9 +858,16 @@ func (p *partitionProducer) internalSingleSend(
 		return
 	}
 
+	// a chunked message is counted with its last chunk
+	if sr.totalChunks <= 1 || sr.chunkID == sr.totalChunks-1 {
+		p.statsCounters.record(1, sr.uncompressedSize, int(buffer.ReadableBytes()))
+	} else {
+		p.statsCounters.record(0, 0, int(buffer.ReadableBytes()))
+	}
 	p.pendingQueue.Put(&pendingItem{
 		sentAt:       time.Now(),
 		buffer:       buffer,
+		bufferPool:   p.client.bufferPool,
 		sequenceID:   sid,
 		sendRequests: []interface{}{sr},
 	})
@@ -808,6 +877,7 @@ func (p *partitionProducer) internalSingleSend(
 type pendingItem struct {
 	sync.Mutex
 	buffer        internal.Buffer
+	bufferPool    BufferPool
 	sequenceID    uint64
 	sentAt        time.Time
 	sendRequests  []interface{}
@@ -823,6 +893,9 @@ func (p *partitionProducer) internalFlushCurrentBatch() {
 		// so we add check to prevent the flow continues on a nil batchBuilder
 		return
 	}
+	if p.batchCancelable > 0 {
+		p.removeCanceledFromBatch()
+	}
 	if p.batchBuilder.IsMultiBatches() {
 		p.internalFlushCurrentBatches()
 		return
@@ -849,15 +922,41 @@ func (p *partitionProducer) internalFlushCurrentBatch() {
 		return
 	}
 
+	p.recordBatch(callbacks, int(batchData.ReadableBytes()))
 	p.pendingQueue.Put(&pendingItem{
 		sentAt:       time.Now(),
 		buffer:       batchData,
+		bufferPool:   p.client.bufferPool,
 		sequenceID:   sequenceID,
 		sendRequests: callbacks,
 	})
 	p._getConn().WriteData(batchData)
 }
 
+// removeCanceledFromBatch fails the messages of the current batch whose context is done, and rebuilds the batch
+// with the others
+func (p *partitionProducer) removeCanceledFromBatch() {
+	p.batchCancelable = 0
+	callbacks := p.batchBuilder.Discard()
+	multiSchemaEnabled := !p.options.DisableMultiSchema
+	for _, cb := range callbacks {
+		sr, ok := cb.(*sendRequest)
+		if !ok {
+			continue
+		}
+		if err := sr.ctx.Err(); err != nil {
+			p.log.Debugf("Removing message from batch, its context is done: %v", err)
+			sr.done(nil, joinErrors(ErrContextExpired, err))
+			continue
+		}
+		// the messages fitted in the batch before, so they still fit without the ones removed
+		if !addRequestToBatch(
+			sr.smm, p, sr.uncompressedPayload, sr, sr.msg, sr.deliverAt, sr.schemaVersion, multiSchemaEnabled) {
+			sr.done(nil, ErrFailAddToBatch)
+		}
+	}
+}
+
 func (p *partitionProducer) failTimeoutMessages() {
 	diff := func(sentAt time.Time) time.Duration {
 		return p.options.SendTimeout - time.Since(sentAt)
@@ -976,9 +1075,11 @@ func (p *partitionProducer) internalFlushCurrentBatches() {
 		if batchesData[i] == nil {
 			continue
 		}
+		p.recordBatch(callbacks[i], int(batchesData[i].ReadableBytes()))
 		p.pendingQueue.Put(&pendingItem{
 			sentAt:       time.Now(),
 			buffer:       batchesData[i],
+			bufferPool:   p.client.bufferPool,
 			sequenceID:   sequenceIDs[i],
 			sendRequests: callbacks[i],
 		})
@@ -1002,6 +1103,10 @@ func (p *partitionProducer) internalFlush(fr *flushRequest) {
 		p.internalFlushCurrentBatch()
 	}
 
+	if fr.results != nil {
+		fr.results.track(p.pendingQueue.ReadableSlice())
+	}
+
 	pi, ok := p.pendingQueue.PeekLast().(*pendingItem)
 	if !ok {
 		close(fr.doneCh)
@@ -1067,10 +1172,30 @@ func (p *partitionProducer) validateMsg(msg *ProducerMessage) error {
 		return joinErrors(ErrInvalidMessage, fmt.Errorf("can not set Value and Payload both"))
 	}
 
+	if msg.SkipSchema {
+		if msg.Value != nil || msg.Schema != nil {
+			return joinErrors(ErrInvalidMessage, fmt.Errorf("can not set Value or Schema with SkipSchema"))
+		}
+		if p.options.DisableMultiSchema {
+			p.log.Error("The producer is disabled the `MultiSchema`")
+			return joinErrors(ErrSchema, fmt.Errorf("SkipSchema can not be used when MultiSchema is disabled"))
+		}
+	}
+
+	if msg.SchemaVersion != nil {
+		if msg.SkipSchema {
+			return joinErrors(ErrInvalidMessage, fmt.Errorf("can not set SchemaVersion with SkipSchema"))
+		}
+		if len(msg.SchemaVersion) != schemaVersionSize {
+			return joinErrors(ErrSchema, fmt.Errorf("invalid SchemaVersion of %d bytes, expected %d",
+				len(msg.SchemaVersion), schemaVersionSize))
+		}
+	}
+
 	if p.options.DisableMultiSchema {
 		if msg.Schema != nil && p.options.Schema != nil &&
 			msg.Schema.GetSchemaInfo().hash() != p.options.Schema.GetSchemaInfo().hash() {
-			p.log.Errorf("The producer %s of the topic %s is disabled the `MultiSchema`", p.producerName, p.topic)
+			p.log.Error("The producer is disabled the `MultiSchema`")
 			return joinErrors(ErrSchema, fmt.Errorf("msg schema can not match with producer schema"))
 		}
 	}
@@ -1108,16 +1233,33 @@ func (p *partitionProducer) updateSchema(sr *sendRequest) error {
 	var schemaVersion []byte
 	var err error
 
+	if sr.msg.SkipSchema {
+		// the payload is sent as-is and tagged so that consumers know it was not schema encoded
+		sr.schemaVersion = rawSchemaVersion
+		return nil
+	}
+
 	if sr.msg.Schema != nil {
 		schema = sr.msg.Schema
 	} else if p.options.Schema != nil {
 		schema = p.options.Schema
 	}
 
+	if sr.msg.SchemaVersion != nil {
+		sr.schema = schema
+		return p.updateMessageSchemaVersion(sr)
+	}
+
 	if schema == nil {
 		return nil
 	}
 
+	if _, ok := schema.(*AutoPublishSchema); ok {
+		// the forwarded payload has no schema version
+		sr.schema = schema
+		return nil
+	}
+
 	schemaVersion = p.schemaCache.Get(schema.GetSchemaInfo())
 	if schemaVersion == nil {
 		schemaVersion, err = p.getOrCreateSchema(schema.GetSchemaInfo())
@@ -1132,6 +1274,36 @@ func (p *partitionProducer) updateSchema(sr *sendRequest) error {
 	return nil
 }
 
+// updateMessageSchemaVersion stamps the message with its ProducerMessage.SchemaVersion, which must exist on the
+// ProducerMessage.SchemaTopic, if any, or on the producer topic. The schema of another topic is registered on the
+// producer topic if needed.
+func (p *partitionProducer) updateMessageSchemaVersion(sr *sendRequest) error {
+	topic := sr.msg.SchemaTopic
+	if topic == "" {
+		topic = p.topic
+	}
+	schema, err := newSchemaInfoCache(p.client, topic).Get(sr.msg.SchemaVersion)
+	if err != nil {
+		return joinErrors(ErrSchema, fmt.Errorf("get schema of version %x on topic %s fail, err: %w",
+			sr.msg.SchemaVersion, topic, err))
+	}
+	if sr.msg.SchemaTopic == "" {
+		sr.schemaVersion = sr.msg.SchemaVersion
+		return nil
+	}
+
+	schemaVersion := p.schemaCache.Get(schema.GetSchemaInfo())
+	if schemaVersion == nil {
+		schemaVersion, err = p.getOrCreateSchema(schema.GetSchemaInfo())
+		if err != nil {
+			return joinErrors(ErrSchema, fmt.Errorf("get schema version fail, err: %w", err))
+		}
+		p.schemaCache.Put(schema.GetSchemaInfo(), schemaVersion)
+	}
+	sr.schemaVersion = schemaVersion
+	return nil
+}
+
 func (p *partitionProducer) updateUncompressedPayload(sr *sendRequest) error {
 	// read payload from message
 	sr.uncompressedPayload = sr.msg.Payload
@@ -1144,7 +1316,13 @@ func (p *partitionProducer) updateUncompressedPayload(sr *sendRequest) error {
 
 		// payload and schema are mutually exclusive
 		// try to get payload from schema value only if payload is not set
-		schemaPayload, err := sr.schema.Encode(sr.msg.Value)
+		var schemaPayload []byte
+		var err error
+		if kvSchema, ok := sr.schema.(*KeyValueSchema); ok && kvSchema.encoding == KeyValueSeparated {
+			sr.schemaKey, schemaPayload, err = kvSchema.encodeSeparated(sr.msg.Value)
+		} else {
+			schemaPayload, err = sr.schema.Encode(sr.msg.Value)
+		}
 		if err != nil {
 			p.log.WithError(err).Errorf("Schema encode message failed %s", sr.msg.Value)
 			return joinErrors(ErrSchema, err)
@@ -1169,6 +1347,13 @@ func (p *partitionProducer) updateMetaData(sr *sendRequest) {
 	}
 
 	sr.mm = p.genMetadata(sr.msg, int(sr.uncompressedSize), deliverAt)
+	if sr.schemaKey != nil {
+		sr.mm.PartitionKey = proto.String(base64.StdEncoding.EncodeToString(sr.schemaKey))
+		sr.mm.PartitionKeyB64Encoded = proto.Bool(true)
+	}
+	if sr.schemaVersion != nil {
+		sr.mm.SchemaVersion = sr.schemaVersion
+	}
 
 	sr.sendAsBatch = !p.options.DisableBatching &&
 		sr.msg.ReplicationClusters == nil &&
@@ -1369,6 +1554,13 @@ func (p *partitionProducer) ReceivedSendReceipt(response *pb.CommandSendReceipt)
 
 		// Mark this pending item as done
 		pi.done(nil)
+
+		p.maybeProbeClockSkew(pi, &messageID{
+			ledgerID:     int64(response.MessageId.GetLedgerId()),
+			entryID:      int64(response.MessageId.GetEntryId()),
+			batchIdx:     -1,
+			partitionIdx: p.partitionIdx,
+		}, time.Unix(0, now))
 	}
 }
 
@@ -1399,6 +1591,7 @@ func (p *partitionProducer) doClose(reason error) {
 		p.log.Info("Closed producer")
 	}
 	p.failPendingMessages(reason)
+	p.failQueuedMessages(reason)
 
 	if p.batchBuilder != nil {
 		if err = p.batchBuilder.Close(); err != nil {
@@ -1406,11 +1599,31 @@ func (p *partitionProducer) doClose(reason error) {
 		}
 	}
 
+	if p.clockSkew != nil {
+		p.clockSkew.close()
+	}
+
 	p.setProducerState(producerClosed)
 	p._getConn().UnregisterListener(p.producerID)
 	p.batchFlushTicker.Stop()
 }
 
+// failQueuedMessages fails the messages not sent yet, waiting in the current batch or in the data channel, so
+// that they release their permits
+func (p *partitionProducer) failQueuedMessages(err error) {
+	if p.batchBuilder != nil {
+		for _, cb := range p.batchBuilder.Discard() {
+			if sr, ok := cb.(*sendRequest); ok {
+				sr.done(nil, err)
+			}
+		}
+	}
+	for len(p.dataChan) > 0 {
+		sr := <-p.dataChan
+		sr.done(nil, err)
+	}
+}
+
 func (p *partitionProducer) failPendingMessages(err error) {
 	curViewItems := p.pendingQueue.ReadableSlice()
 	viewSize := len(curViewItems)
@@ -1451,18 +1664,84 @@ func (p *partitionProducer) failPendingMessages(err error) {
 	}
 }
 
+func (p *partitionProducer) recordBatch(callbacks []interface{}, bytes int) {
+	messages := len(callbacks)
+	var uncompressedBytes int64
+	for _, cb := range callbacks {
+		if sr, ok := cb.(*sendRequest); ok {
+			uncompressedBytes += sr.uncompressedSize
+		}
+	}
+	p.statsCounters.record(messages, uncompressedBytes, bytes)
+	p.batchStats.add(time.Now(), messages, bytes)
+	if p.batchDelay != nil {
+		p.batchDelay.onFlush(messages)
+	}
+}
+
+// adjustBatchDelay updates the batching delay after a flush triggered by the ticker, when adaptive batching is
+// enabled.
+func (p *partitionProducer) adjustBatchDelay() {
+	if p.batchDelay == nil {
+		return
+	}
+	// the messages waiting in dataChan or for their receipt all hold a permit of the publish semaphore
+	delay, changed := p.batchDelay.adjust(p.publishSemaphore.Usage())
+	if changed {
+		p.log.Debugf("Adjusted the batching delay to %v", delay)
+		p.batchFlushTicker.Reset(delay)
+	}
+}
+
 func (p *partitionProducer) LastSequenceID() int64 {
 	return atomic.LoadInt64(&p.lastSequenceID)
 }
 
+func (p *partitionProducer) AvgBatchSize() (float64, float64) {
+	return averageBatchSize(p.batchStats.totals(time.Now()))
+}
+
+func (p *partitionProducer) Stats() ProducerStats {
+	var stats ProducerStats
+	stats.Topic = p.topic
+	stats.add(p.statsCounters.stats(p.topic))
+	return stats
+}
+
+func (p *partitionProducer) BatchingDelay() time.Duration {
+	switch {
+	case p.options.DisableBatching:
+		return 0
+	case p.batchDelay != nil:
+		return p.batchDelay.current()
+	default:
+		return p.options.BatchingMaxPublishDelay
+	}
+}
+
+func (p *partitionProducer) RefreshEncryptionKeys() error {
+	return refreshEncryptionKeys(p.options.Encryption)
+}
+
 func (p *partitionProducer) Flush() error {
 	return p.FlushWithCtx(context.Background())
 }
 
 func (p *partitionProducer) FlushWithCtx(ctx context.Context) error {
+	return p.flush(ctx, nil)
+}
+
+func (p *partitionProducer) FlushWithResults(ctx context.Context) ([]FlushResult, error) {
+	results := &flushResults{slots: make(map[*sync.Once]int)}
+	err := p.flush(ctx, results)
+	return results.get(), err
+}
+
+func (p *partitionProducer) flush(ctx context.Context, results *flushResults) error {
 	flushReq := &flushRequest{
-		doneCh: make(chan struct{}),
-		err:    nil,
+		doneCh:  make(chan struct{}),
+		err:     nil,
+		results: results,
 	}
 	select {
 	case <-ctx.Done():
@@ -1526,13 +1805,17 @@ type sendRequest struct {
 	reservedMem       int64
 	semaphore         internal.Semaphore
 	reservedSemaphore int
+	// sharedSemaphore is the ProducerOptions.MaxPendingMessagesAcrossPartitions semaphore, if reserved
+	sharedSemaphore internal.Semaphore
 
 	/// convey settable state
 
 	sendAsBatch         bool
+	smm                 *pb.SingleMessageMetadata
 	transaction         *transaction
 	schema              Schema
 	schemaVersion       []byte
+	schemaKey           []byte
 	uncompressedPayload []byte
 	uncompressedSize    int64
 	compressedPayload   []byte
@@ -1575,6 +1858,10 @@ func (sr *sendRequest) done(msgID MessageID, err error) {
 		sr.producer.metrics.MessagesPending.Dec()
 	}
 
+	if sr.sharedSemaphore != nil {
+		sr.sharedSemaphore.Release()
+	}
+
 	if sr.memLimit != nil {
 		sr.memLimit.ReleaseMemory(sr.reservedMem)
 		sr.producer.metrics.BytesPending.Sub(float64(sr.reservedMem))
@@ -1583,6 +1870,9 @@ func (sr *sendRequest) done(msgID MessageID, err error) {
 	// sr.chunkID == -1 means a chunked message is not yet prepared, so that we should fail it immediately
 	if sr.totalChunks <= 1 || sr.chunkID == -1 || sr.chunkID == sr.totalChunks-1 {
 		sr.callbackOnce.Do(func() {
+			if sink := sr.producer.client.metricsSink; sink != nil {
+				sink.ObserveSendLatency(sr.producer.topic, time.Since(sr.publishTime), err)
+			}
 			runCallback(sr.callback, msgID, sr.msg, err)
 		})
 
@@ -1616,6 +1906,13 @@ func (p *partitionProducer) reserveSemaphore(sr *sendRequest) error {
 			sr.semaphore = p.publishSemaphore
 			sr.reservedSemaphore++
 			p.metrics.MessagesPending.Inc()
+
+			if shared := p.sharedSemaphore; shared != nil {
+				if !shared.Acquire(sr.ctx) {
+					return ErrContextExpired
+				}
+				sr.sharedSemaphore = shared
+			}
 		} else {
 			if !p.publishSemaphore.TryAcquire() {
 				return ErrSendQueueIsFull
@@ -1626,6 +1923,13 @@ func (p *partitionProducer) reserveSemaphore(sr *sendRequest) error {
 			sr.semaphore = p.publishSemaphore
 			sr.reservedSemaphore++
 			p.metrics.MessagesPending.Inc()
+
+			if shared := p.sharedSemaphore; shared != nil {
+				if !shared.TryAcquire() {
+					return ErrSendQueueIsFull
+				}
+				sr.sharedSemaphore = shared
+			}
 		}
 	}
 
@@ -1669,8 +1973,60 @@ type closeProducer struct {
 }
 
 type flushRequest struct {
-	doneCh chan struct{}
-	err    error
+	doneCh  chan struct{}
+	err     error
+	results *flushResults
+}
+
+// flushResults collects the results of the messages pending when a flush starts
+type flushResults struct {
+	sync.Mutex
+	results []FlushResult
+	// slots maps the messages, identified by the callbackOnce shared by their chunks, to their result
+	slots map[*sync.Once]int
+}
+
+// track records the results of the messages of the pending items, called from the event loop
+func (r *flushResults) track(items []interface{}) {
+	for _, item := range items {
+		pi := item.(*pendingItem)
+		// the requests are completed with the lock held, see ReceivedSendReceipt
+		pi.Lock()
+		if !pi.isDone {
+			for _, i := range pi.sendRequests {
+				r.wrapCallback(i.(*sendRequest))
+			}
+		}
+		pi.Unlock()
+	}
+}
+
+func (r *flushResults) wrapCallback(sr *sendRequest) {
+	r.Lock()
+	slot, ok := r.slots[sr.callbackOnce]
+	if !ok {
+		slot = len(r.results)
+		r.slots[sr.callbackOnce] = slot
+		r.results = append(r.results, FlushResult{Message: sr.msg})
+	}
+	r.Unlock()
+
+	callback := sr.callback
+	sr.callback = func(msgID MessageID, msg *ProducerMessage, err error) {
+		r.Lock()
+		r.results[slot].MessageID = msgID
+		r.results[slot].Err = err
+		r.Unlock()
+		if callback != nil {
+			callback(msgID, msg, err)
+		}
+	}
+}
+
+func (r *flushResults) get() []FlushResult {
+	r.Lock()
+	defer r.Unlock()
+	return append([]FlushResult(nil), r.results...)
 }
 
 func (i *pendingItem) done(err error) {
@@ -1678,7 +2034,7 @@ func (i *pendingItem) done(err error) {
 		return
 	}
 	i.isDone = true
-	buffersPool.Put(i.buffer)
+	releasePooledBuffer(i.bufferPool, i.buffer)
 	if i.flushCallback != nil {
 		i.flushCallback(err)
 	}
@@ -28,6 +28,7 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/proto"
@@ -1833,6 +1834,223 @@ func TestMultipleSchemaProducerConsumer(t *testing.T) {
 	}
 }
 
+func TestMultipleSchemaCompressedBatchProducerConsumer(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	schema1 := NewAvroSchema(`{"fields":
+		[
+			{"name":"id","type":"int"}
+		],
+		"name":"MyAvro4","namespace":"PulsarTestCase","type":"record"}`, nil)
+	schema2 := NewAvroSchema(`{"fields":
+		[
+			{"name":"id","type":"int"},{"default":null,"name":"age","type":["null","int"]}
+		],
+		"name":"MyAvro4","namespace":"PulsarTestCase","type":"record"}`, nil)
+	topic := newTopicName()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:                   topic,
+		Schema:                  schema1,
+		CompressionType:         LZ4,
+		BatchingMaxPublishDelay: time.Minute,
+	})
+	assert.NoError(t, err)
+	defer producer.Close()
+
+	payload1, err := schema1.Encode(map[string]interface{}{"id": 1})
+	assert.NoError(t, err)
+	payload2, err := schema2.Encode(map[string]interface{}{"id": 2, "age": map[string]interface{}{"int": 10}})
+	assert.NoError(t, err)
+
+	// both messages are in the same batching window, with a different schema version
+	ids := make(chan MessageID, 2)
+	for i, payload := range [][]byte{payload1, payload2} {
+		schema := schema1
+		if i == 1 {
+			schema = schema2
+		}
+		producer.SendAsync(context.Background(), &ProducerMessage{
+			Payload: payload,
+			Schema:  schema,
+		}, func(id MessageID, _ *ProducerMessage, err error) {
+			assert.NoError(t, err)
+			ids <- id
+		})
+	}
+	assert.NoError(t, producer.Flush())
+
+	// the schema version is carried by the batch, so a new one is started for the second schema
+	id1, id2 := <-ids, <-ids
+	assert.NotEqual(t, id1.EntryID(), id2.EntryID())
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            "my-sub",
+		Schema:                      schema1,
+		SubscriptionInitialPosition: SubscriptionPositionEarliest,
+	})
+	assert.NoError(t, err)
+	defer consumer.Close()
+
+	var versions [][]byte
+	for i := 1; i <= 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		msg, err := consumer.Receive(ctx)
+		cancel()
+		if !assert.NoError(t, err) {
+			return
+		}
+		var v struct {
+			ID int `json:"id"`
+		}
+		assert.NoError(t, msg.GetSchemaValue(&v))
+		assert.Equal(t, i, v.ID)
+		versions = append(versions, msg.SchemaVersion())
+	}
+	assert.NotEqual(t, versions[0], versions[1])
+}
+
+func TestSkipSchemaProducerConsumer(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	schema := NewStringSchema(nil)
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:  topic,
+		Schema: schema,
+	})
+	assert.NoError(t, err)
+	defer producer.Close()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "my-sub",
+		Schema:           schema,
+	})
+	assert.NoError(t, err)
+	defer consumer.Close()
+
+	_, err = producer.Send(context.Background(), &ProducerMessage{
+		Value: "hello",
+	})
+	assert.NoError(t, err)
+	_, err = producer.Send(context.Background(), &ProducerMessage{
+		Payload:    []byte{0x00, 0x01, 0x02},
+		SkipSchema: true,
+	})
+	assert.NoError(t, err)
+
+	// Value and SkipSchema are mutually exclusive
+	_, err = producer.Send(context.Background(), &ProducerMessage{
+		Value:      "invalid",
+		SkipSchema: true,
+	})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	msg, err := consumer.Receive(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, msg.IsRawEncoded())
+	var v string
+	assert.NoError(t, msg.GetSchemaValue(&v))
+	assert.Equal(t, "hello", v)
+
+	msg, err = consumer.Receive(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, msg.IsRawEncoded())
+	assert.Equal(t, []byte{0x00, 0x01, 0x02}, msg.Payload())
+	assert.Error(t, msg.GetSchemaValue(&v))
+}
+
+func TestProducerStats(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	assert.NoError(t, createPartitionedTopic(topic, 2))
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		CompressionType: ZLib,
+	})
+	assert.NoError(t, err)
+	defer producer.Close()
+
+	payload := make([]byte, 1024)
+	for i := 0; i < 10; i++ {
+		producer.SendAsync(context.Background(), &ProducerMessage{Payload: payload}, nil)
+	}
+	assert.NoError(t, producer.FlushWithCtx(context.Background()))
+
+	stats := producer.Stats()
+	assert.Len(t, stats.Partitions, 2)
+	assert.Equal(t, int64(10), stats.Messages)
+	assert.Equal(t, int64(10*1024), stats.UncompressedBytes)
+	assert.Greater(t, stats.CompressionRatio(), 1.0)
+	msgs, _ := stats.AvgBatchSize()
+	assert.GreaterOrEqual(t, msgs, 1.0)
+}
+
+func TestProducerMessageSchemaVersion(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+	oldSchema := NewJSONSchema(exampleSchemaDef, nil)
+	oldProducer, err := client.CreateProducer(ProducerOptions{Topic: topic, Schema: oldSchema})
+	assert.NoError(t, err)
+	defer oldProducer.Close()
+	_, err = oldProducer.Send(ctx, &ProducerMessage{Value: testJSON{ID: 1, Name: "old"}})
+	assert.NoError(t, err)
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            "my-sub",
+		SubscriptionInitialPosition: SubscriptionPositionEarliest,
+	})
+	assert.NoError(t, err)
+	defer consumer.Close()
+	msg, err := consumer.Receive(ctx)
+	assert.NoError(t, err)
+	oldVersion := msg.SchemaVersion()
+
+	newSchema := NewJSONSchema(`{"type":"record","name":"Example","namespace":"test","fields":[`+
+		`{"name":"ID","type":"int"},{"name":"Name","type":"string"},{"name":"Age","type":"int","default":0}]}`, nil)
+	producer, err := client.CreateProducer(ProducerOptions{Topic: topic, Schema: newSchema})
+	assert.NoError(t, err)
+	defer producer.Close()
+
+	_, err = producer.Send(ctx, &ProducerMessage{Value: testJSON{ID: 2, Name: "new"}, SchemaVersion: oldVersion})
+	assert.NoError(t, err)
+	msg, err = consumer.Receive(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, oldVersion, msg.SchemaVersion())
+
+	// malformed and unknown versions
+	_, err = producer.Send(ctx, &ProducerMessage{Value: testJSON{ID: 3}, SchemaVersion: []byte{1}})
+	assert.ErrorIs(t, err, ErrSchema)
+	_, err = producer.Send(ctx, &ProducerMessage{
+		Value:         testJSON{ID: 4},
+		SchemaVersion: []byte{0, 0, 0, 0, 0, 0, 0x10, 0},
+	})
+	assert.ErrorIs(t, err, ErrSchema)
+}
+
 func TestProducerWithSchemaAndConsumerSchemaNotFound(t *testing.T) {
 	client, err := NewClient(ClientOptions{
 		URL: lookupURL,
@@ -2474,3 +2692,666 @@ func TestDisableReplication(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"__local__"}, msgMetadata.GetReplicateTo())
 }
+
+func TestProducerMessagePartitionOverride(t *testing.T) {
+	topicName := "public/default/" + newTopicName()
+	numberOfPartitions := 3
+
+	// call admin api to make it partitioned
+	url := adminURL + "/" + "admin/v2/persistent/" + topicName + "/partitions"
+	makeHTTPCall(t, http.MethodPut, url, strconv.Itoa(numberOfPartitions))
+
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topicName,
+		DisableBatching: true,
+		MessageRouter: func(msg *ProducerMessage, tm TopicMetadata) int {
+			return 0
+		},
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	ctx := context.Background()
+	partition := 2
+	msgID, err := producer.Send(ctx, &ProducerMessage{
+		Payload:   []byte("hello"),
+		Partition: &partition,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(partition), msgID.PartitionIdx())
+
+	partition = numberOfPartitions
+	_, err = producer.Send(ctx, &ProducerMessage{
+		Payload:   []byte("hello"),
+		Partition: &partition,
+	})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestProducerMessagePartitionOverrideNonPartitioned(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: newTopicName(),
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	ctx := context.Background()
+	partition := 0
+	_, err = producer.Send(ctx, &ProducerMessage{
+		Payload:   []byte("hello"),
+		Partition: &partition,
+	})
+	assert.Nil(t, err)
+
+	partition = 1
+	_, err = producer.Send(ctx, &ProducerMessage{
+		Payload:   []byte("hello"),
+		Partition: &partition,
+	})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestProducerLazyStartPartitionedProducers(t *testing.T) {
+	topicName := "public/default/" + newTopicName()
+	numberOfPartitions := 4
+
+	url := adminURL + "/" + "admin/v2/persistent/" + topicName + "/partitions"
+	makeHTTPCall(t, http.MethodPut, url, strconv.Itoa(numberOfPartitions))
+
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	p, err := client.CreateProducer(ProducerOptions{
+		Topic:                         topicName,
+		LazyStartPartitionedProducers: true,
+	})
+	assert.Nil(t, err)
+	defer p.Close()
+
+	started := func() []Producer {
+		impl := p.(*producer)
+		impl.RLock()
+		defer impl.RUnlock()
+		return impl.startedProducers()
+	}
+	assert.Len(t, started(), 1)
+	assert.NotEmpty(t, p.Name())
+
+	ctx := context.Background()
+	partition := 2
+	msgID, err := p.Send(ctx, &ProducerMessage{
+		Payload:   []byte("hello"),
+		Partition: &partition,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(partition), msgID.PartitionIdx())
+	assert.Len(t, started(), 2)
+
+	// the partition is started once
+	_, err = p.Send(ctx, &ProducerMessage{
+		Payload:   []byte("hello"),
+		Partition: &partition,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, started(), 2)
+
+	assert.Nil(t, p.Flush())
+	assert.Equal(t, uint32(numberOfPartitions), p.(*producer).NumPartitions())
+}
+
+func TestProducerFailoverPartitionsSkipsLazyPartitions(t *testing.T) {
+	p0, p2, p3 := &partitionProducer{}, &partitionProducer{}, &partitionProducer{}
+	producers := []Producer{p0, nil, p2, p3}
+	p := &producer{}
+	p.producersPtr = unsafe.Pointer(&producers)
+
+	assert.Equal(t, []Producer{p3, p0}, p.failoverPartitions(p2))
+	assert.Equal(t, []Producer{p2, p3}, p.failoverPartitions(p0))
+}
+
+func TestProducerConnectedPartition(t *testing.T) {
+	p0, p1, p2 := &partitionProducer{topic: "p0"}, &partitionProducer{topic: "p1"}, &partitionProducer{topic: "p2"}
+	producers := []Producer{p0, p1, p2}
+	p := &producer{options: &ProducerOptions{PartitionFailoverOnSend: true}, log: plog.DefaultNopLogger()}
+	p.producersPtr = unsafe.Pointer(&producers)
+	ctx := context.Background()
+	msg := &ProducerMessage{Payload: []byte("hello")}
+
+	assert.Equal(t, p0, p.connectedPartition(ctx, msg, p0))
+
+	// the message fails over to the next connected partition before it is written
+	p0.disconnected.Store(true)
+	p1.disconnected.Store(true)
+	assert.Equal(t, p2, p.connectedPartition(ctx, msg, p0))
+	// the ordering of keyed messages is preserved
+	assert.Equal(t, p0, p.connectedPartition(ctx, &ProducerMessage{Key: "my-key"}, p0))
+
+	// no partition is connected
+	p2.disconnected.Store(true)
+	assert.Equal(t, p0, p.connectedPartition(ctx, msg, p0))
+
+	p.options.PartitionFailoverOnSend = false
+	p2.disconnected.Store(false)
+	assert.Equal(t, p0, p.connectedPartition(ctx, msg, p0))
+}
+
+func TestProducerValidateSchemaOnCreate(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:                  topic,
+		Schema:                 NewStringSchema(nil),
+		ValidateSchemaOnCreate: true,
+	})
+	assert.NoError(t, err)
+	defer producer.Close()
+
+	_, err = producer.Send(context.Background(), &ProducerMessage{
+		Value: "hello",
+	})
+	assert.NoError(t, err)
+
+	// an int64 schema is not compatible with the string schema of the topic
+	_, err = client.CreateProducer(ProducerOptions{
+		Topic:                  topic,
+		Schema:                 NewInt64Schema(nil),
+		ValidateSchemaOnCreate: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestProducerPartitionFailoverOnSend(t *testing.T) {
+	topicName := "public/default/" + newTopicName()
+	numberOfPartitions := 3
+
+	// call admin api to make it partitioned
+	url := adminURL + "/" + "admin/v2/persistent/" + topicName + "/partitions"
+	makeHTTPCall(t, http.MethodPut, url, strconv.Itoa(numberOfPartitions))
+
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	p, err := client.CreateProducer(ProducerOptions{
+		Topic:                   topicName,
+		DisableBatching:         true,
+		PartitionFailoverOnSend: true,
+		MessageRouter: func(msg *ProducerMessage, tm TopicMetadata) int {
+			return 0
+		},
+	})
+	assert.Nil(t, err)
+	defer p.Close()
+
+	// simulate the failure of the first partition
+	p.(*producer).producers[0].Close()
+
+	ctx := context.Background()
+	msgID, err := p.Send(ctx, &ProducerMessage{
+		Payload: []byte("hello"),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), msgID.PartitionIdx())
+
+	done := make(chan struct{})
+	p.SendAsync(ctx, &ProducerMessage{
+		Payload: []byte("hello"),
+	}, func(id MessageID, _ *ProducerMessage, err error) {
+		assert.Nil(t, err)
+		assert.Equal(t, int32(1), id.PartitionIdx())
+		close(done)
+	})
+	<-done
+
+	// keyed messages are not rerouted
+	_, err = p.Send(ctx, &ProducerMessage{
+		Payload: []byte("hello"),
+		Key:     "my-key",
+	})
+	assert.ErrorIs(t, err, ErrProducerClosed)
+}
+
+func TestProducerClockSkewWithoutBrokerEntryMetadata(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	p, err := client.CreateProducer(ProducerOptions{
+		Topic:              newTopicName(),
+		ClockSkewThreshold: time.Second,
+	})
+	assert.Nil(t, err)
+	defer p.Close()
+
+	_, err = p.Send(context.Background(), &ProducerMessage{
+		Payload: []byte("hello"),
+	})
+	assert.Nil(t, err)
+
+	// the test broker does not add the broker entry metadata, so the measurement is given up
+	probe := p.(*producer).producers[0].(*partitionProducer).clockSkew
+	assert.Eventually(t, probe.unsupported.Load, 10*time.Second, 100*time.Millisecond)
+	assert.Equal(t, time.Duration(0), p.MeasuredClockSkew())
+
+	// the reader of the probe is not needed anymore
+	assert.Eventually(t, func() bool {
+		probe.mu.Lock()
+		defer probe.mu.Unlock()
+		return probe.closed && probe.reader == nil
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestProducerEmitEndMarkerOnClose(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	assert.Nil(t, createPartitionedTopic(topic, 2))
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:                topic,
+		EmitEndMarkerOnClose: true,
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{Payload: []byte(fmt.Sprintf("msg-%d", i))})
+		assert.NoError(t, err)
+	}
+	producer.Close()
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.NoError(t, err)
+	defer reader.Close()
+
+	// every partition ends with a marker
+	var messages, markers int
+	for reader.HasNext() {
+		msg, err := reader.Next(ctx)
+		assert.NoError(t, err)
+		if msg.IsEndOfStream() {
+			markers++
+			assert.Empty(t, msg.Payload())
+		} else {
+			messages++
+		}
+	}
+	assert.Equal(t, 4, messages)
+	assert.Equal(t, 2, markers)
+}
+
+func TestTypedProducer(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	_, err = NewTypedProducer[testJSON](client, ProducerOptions{
+		Topic: topic,
+	})
+	assert.Error(t, err)
+
+	producer, err := NewTypedProducer[testJSON](client, ProducerOptions{
+		Topic:  topic,
+		Schema: NewJSONSchema(exampleSchemaDef, nil),
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	eventTime := time.Now().Truncate(time.Millisecond)
+	_, err = producer.Send(ctx, testJSON{ID: 1, Name: "pulsar"},
+		WithMessageKey("key"),
+		WithMessageProperties(map[string]string{"a": "b"}),
+		WithMessageEventTime(eventTime))
+	assert.NoError(t, err)
+
+	r, err := NewTypedReader[testJSON](client, ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+		Schema:         NewJSONSchema(exampleSchemaDef, nil),
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	value, msg, err := r.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testJSON{ID: 1, Name: "pulsar"}, value)
+	assert.Equal(t, "key", msg.Key())
+	assert.Equal(t, map[string]string{"a": "b"}, msg.Properties())
+	assert.True(t, eventTime.Equal(msg.EventTime()))
+
+	// the raw bytes are sent without a schema
+	rawProducer, err := NewTypedProducer[[]byte](client, ProducerOptions{
+		Topic: newTopicName(),
+	})
+	assert.Nil(t, err)
+	defer rawProducer.Close()
+	_, err = rawProducer.Send(ctx, []byte("hello"))
+	assert.NoError(t, err)
+}
+
+type sendLatencySink struct {
+	mu        sync.Mutex
+	topics    []string
+	latencies []time.Duration
+	errs      []error
+}
+
+func (s *sendLatencySink) ObserveSendLatency(topic string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.topics = append(s.topics, topic)
+	s.latencies = append(s.latencies, d)
+	s.errs = append(s.errs, err)
+}
+
+func TestProducerMetricsSink(t *testing.T) {
+	sink := &sendLatencySink{}
+	client, err := NewClient(ClientOptions{
+		URL:         serviceURL,
+		MetricsSink: sink,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.NoError(t, err)
+	defer producer.Close()
+
+	_, err = producer.Send(context.Background(), &ProducerMessage{Payload: []byte("sync")})
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	producer.SendAsync(context.Background(), &ProducerMessage{Payload: []byte("async")},
+		func(_ MessageID, _ *ProducerMessage, err error) {
+			assert.NoError(t, err)
+			close(done)
+		})
+	<-done
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Len(t, sink.topics, 2)
+	for i := range sink.topics {
+		assert.Contains(t, sink.topics[i], topic)
+		assert.Greater(t, sink.latencies[i], time.Duration(0))
+		assert.NoError(t, sink.errs[i])
+	}
+}
+
+func TestFlushResultsTrack(t *testing.T) {
+	var called []*ProducerMessage
+	callback := func(_ MessageID, msg *ProducerMessage, _ error) {
+		called = append(called, msg)
+	}
+	msg1, msg2, msg3 := &ProducerMessage{}, &ProducerMessage{}, &ProducerMessage{}
+	chunked := &sync.Once{}
+	sr1 := &sendRequest{msg: msg1, callback: callback, callbackOnce: &sync.Once{}}
+	// the chunks of msg2 share the callbackOnce
+	sr2a := &sendRequest{msg: msg2, callback: callback, callbackOnce: chunked}
+	sr2b := &sendRequest{msg: msg2, callback: callback, callbackOnce: chunked}
+	sr3 := &sendRequest{msg: msg3, callback: callback, callbackOnce: &sync.Once{}}
+
+	results := &flushResults{slots: make(map[*sync.Once]int)}
+	results.track([]interface{}{
+		&pendingItem{sendRequests: []interface{}{sr1}, isDone: true},
+		&pendingItem{sendRequests: []interface{}{sr2a, sr2b}},
+		&pendingItem{sendRequests: []interface{}{sr3}},
+	})
+
+	msgID := &messageID{ledgerID: 1, entryID: 2}
+	sr2b.callback(msgID, msg2, nil)
+	sr3.callback(nil, msg3, ErrSendTimeout)
+
+	assert.Equal(t, []FlushResult{
+		{Message: msg2, MessageID: msgID},
+		{Message: msg3, Err: ErrSendTimeout},
+	}, results.get())
+	assert.Equal(t, []*ProducerMessage{msg2, msg3}, called)
+}
+
+func TestProducerFlushWithResults(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	p, err := client.CreateProducer(ProducerOptions{
+		Topic:                   newTopicName(),
+		BatchingMaxPublishDelay: time.Hour,
+	})
+	assert.NoError(t, err)
+	defer p.Close()
+
+	var msgs []*ProducerMessage
+	for i := 0; i < 5; i++ {
+		msg := &ProducerMessage{Payload: []byte(fmt.Sprintf("msg-%d", i))}
+		msgs = append(msgs, msg)
+		p.SendAsync(context.Background(), msg, func(MessageID, *ProducerMessage, error) {})
+	}
+
+	results, err := p.FlushWithResults(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, results, len(msgs))
+	for i, r := range results {
+		assert.Same(t, msgs[i], r.Message)
+		assert.NotNil(t, r.MessageID)
+		assert.NoError(t, r.Err)
+	}
+
+	// nothing pending
+	results, err = p.FlushWithResults(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestProducerSendAsyncCanceledInBatch(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	p, err := client.CreateProducer(ProducerOptions{
+		Topic:                   topic,
+		BatchingMaxPublishDelay: time.Hour,
+	})
+	assert.NoError(t, err)
+	defer p.Close()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "sub",
+	})
+	assert.NoError(t, err)
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceledErr := make(chan error, 1)
+	p.SendAsync(ctx, &ProducerMessage{Payload: []byte("canceled")},
+		func(_ MessageID, _ *ProducerMessage, err error) {
+			canceledErr <- err
+		})
+	sentErr := make(chan error, 1)
+	p.SendAsync(context.Background(), &ProducerMessage{Payload: []byte("sent")},
+		func(_ MessageID, _ *ProducerMessage, err error) {
+			sentErr <- err
+		})
+	cancel()
+
+	assert.NoError(t, p.Flush())
+	err = <-canceledErr
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, ErrContextExpired)
+	var pulsarErr *Error
+	assert.True(t, errors.As(err, &pulsarErr))
+	assert.NoError(t, <-sentErr)
+
+	receiveCtx, receiveCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer receiveCancel()
+	msg, err := consumer.Receive(receiveCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("sent"), msg.Payload())
+}
+
+func TestProducerMaxPendingMessagesAcrossPartitions(t *testing.T) {
+	topicName := "public/default/" + newTopicName()
+	url := adminURL + "/" + "admin/v2/persistent/" + topicName + "/partitions"
+	makeHTTPCall(t, http.MethodPut, url, "3")
+
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	p, err := client.CreateProducer(ProducerOptions{
+		Topic:                              topicName,
+		MaxPendingMessagesAcrossPartitions: 2,
+		DisableBlockIfQueueFull:            true,
+		BatchingMaxPublishDelay:            time.Hour,
+	})
+	assert.NoError(t, err)
+
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		partition := i
+		p.SendAsync(context.Background(), &ProducerMessage{
+			Payload:   []byte("hello"),
+			Partition: &partition,
+		}, func(_ MessageID, _ *ProducerMessage, err error) {
+			errs <- err
+		})
+	}
+	// the third message exceeds the limit of the producer, although its partition is empty
+	assert.ErrorIs(t, <-errs, ErrSendQueueIsFull)
+
+	shared := p.(*producer).pendingAcrossPartitions
+	assert.False(t, shared.TryAcquire())
+
+	// closing releases the permits of the messages still batched
+	p.Close()
+	assert.ErrorIs(t, <-errs, ErrProducerClosed)
+	assert.ErrorIs(t, <-errs, ErrProducerClosed)
+	assert.True(t, shared.TryAcquire())
+	assert.True(t, shared.TryAcquire())
+}
+
+func TestProducerRefreshEncryptionKeys(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: "crypto-subscription",
+		Decryption: &MessageDecryptionInfo{
+			KeyReader:                   crypto.NewFileKeyReader("", "crypto/testdata/pri_key_rsa.pem"),
+			ConsumerCryptoFailureAction: crypto.ConsumerCryptoFailureActionFail,
+		},
+	})
+	assert.Nil(t, err)
+	defer consumer.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+		Encryption: &ProducerEncryptionInfo{
+			KeyReader: crypto.NewFileKeyReader("crypto/testdata/pub_key_rsa.pem", ""),
+			Keys:      []string{"client-rsa.pem"},
+		},
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	_, err = producer.Send(ctx, &ProducerMessage{Payload: []byte("before")})
+	assert.Nil(t, err)
+	assert.Nil(t, producer.RefreshEncryptionKeys())
+	_, err = producer.Send(ctx, &ProducerMessage{Payload: []byte("after")})
+	assert.Nil(t, err)
+
+	for _, expected := range []string{"before", "after"} {
+		msg, err := consumer.Receive(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(msg.Payload()))
+		consumer.Ack(msg)
+	}
+
+	plainProducer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+	defer plainProducer.Close()
+	err = plainProducer.RefreshEncryptionKeys()
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+}
+
+func TestProducerCanFailover(t *testing.T) {
+	p := &producer{options: &ProducerOptions{PartitionFailoverOnSend: true}}
+	ctx := context.Background()
+	msg := &ProducerMessage{Payload: []byte("hello")}
+
+	assert.True(t, p.canFailover(ctx, msg, ErrSendQueueIsFull))
+	assert.True(t, p.canFailover(ctx, msg, ErrProducerClosed))
+	assert.True(t, p.canFailover(ctx, msg, joinErrors(ErrProducerFenced, errors.New("fenced"))))
+	// the message may have been persisted
+	assert.False(t, p.canFailover(ctx, msg, ErrSendTimeout))
+	// the ordering of keyed messages is preserved
+	assert.False(t, p.canFailover(ctx, &ProducerMessage{Key: "my-key"}, ErrProducerClosed))
+}
+
+func TestProducerLazyStartWithEndMarker(t *testing.T) {
+	_, err := newProducer(nil, &ProducerOptions{
+		Topic:                         newTopicName(),
+		LazyStartPartitionedProducers: true,
+		EmitEndMarkerOnClose:          true,
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+}
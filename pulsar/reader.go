@@ -28,14 +28,36 @@ import (
 type ReaderMessage struct {
 	Reader
 	Message
+
+	// Err is the error returned by Next instead of a message, see Reader.MessageChannel
+	Err error
 }
 
 // ReaderOptions represents Reader options to use.
 type ReaderOptions struct {
 	// Topic specifies the topic this consumer will subscribe on.
-	// This argument is required when constructing the reader.
+	// This argument is required when constructing the reader, unless Topics or TopicsPattern is set.
 	Topic string
 
+	// Interceptors is a chain of interceptors called with the messages returned by `Reader.Next()` and
+	// `Reader.NextBatch()`. The panics of the interceptors are recovered and logged.
+	Interceptors ReaderInterceptors
+
+	// Topics specifies a list of topics the reader reads at once, the messages of all the topics being merged by
+	// `Reader.Next()`. Every topic is read from StartMessageID (or StartMessageTime), which can not be a specific
+	// message id. Such a reader has no single position: GetLastMessageID, Seek, SeekByTimeResolved, Tell and
+	// SaveState return an error, and it can not be combined with ReadReverse. It can not be used with Topic.
+	Topics []string
+
+	// TopicsPattern specifies a regular expression matching the topics of a namespace that the reader reads at once,
+	// as with Topics. The topics created after the reader are read from StartMessageID once they are discovered.
+	// It can not be used with Topic or Topics.
+	TopicsPattern string
+
+	// TopicsPatternAutoDiscoveryInterval specifies the interval in which to poll for the topics matching
+	// TopicsPattern. (Default: 1 minute)
+	TopicsPatternAutoDiscoveryInterval time.Duration
+
 	// Name set the reader name.
 	Name string
 
@@ -56,8 +78,13 @@ type ReaderOptions struct {
 	// Default is `false` and the reader will start from the "next" message
 	StartMessageIDInclusive bool
 
-	// MessageChannel sets a `MessageChannel` for the consumer
-	// When a message is received, it will be pushed to the channel for consumption
+	// StartMessageTime, if set, makes the reader start at the first message published at or after this time,
+	// resolved by the broker when the reader subscribes instead of with a separate SeekByTime. It can not be
+	// used together with StartMessageID.
+	StartMessageTime time.Time
+
+	// MessageChannel sets the channel returned by `Reader.MessageChannel()`, e.g. to share it between readers.
+	// Unlike the channel created by default, it is not closed when the reader is closed.
 	MessageChannel chan ReaderMessage
 
 	// ReceiverQueueSize sets the size of the consumer receive queue.
@@ -67,6 +94,21 @@ type ReaderOptions struct {
 	// Default value is {@code 1000} messages and should be good for most use cases.
 	ReceiverQueueSize int
 
+	// EnableAutoScaledReceiverQueueSize, if enabled, scales the receive queue of the Reader with the rate at which
+	// the application reads the messages, up to ReceiverQueueSize: it starts with a single message and doubles
+	// as the application drains it, so that slow readers do not prefetch more messages than they need. It is
+	// halved when the client memory limit is reached, or when the application does not read any message for
+	// 30 seconds.
+	// Default is false.
+	EnableAutoScaledReceiverQueueSize bool
+
+	// MaxReceiverQueueSizeBytes, if positive, caps the cumulative size of the payloads accumulated by the Reader:
+	// the Reader stops asking the broker for messages while it holds this many bytes, and resumes as the
+	// application reads them, whichever of ReceiverQueueSize and MaxReceiverQueueSizeBytes is reached first.
+	// The broker may still deliver the messages it was already asked for, up to ReceiverQueueSize, so the cap
+	// can be exceeded by them. Default value is 0, which does not limit the bytes.
+	MaxReceiverQueueSizeBytes int
+
 	// SubscriptionRolePrefix sets the subscription role prefix. The default prefix is "reader".
 	SubscriptionRolePrefix string
 
@@ -74,6 +116,13 @@ type ReaderOptions struct {
 	// If subscriptionRolePrefix is set at the same time, this configuration will prevail
 	SubscriptionName string
 
+	// EnableBatchIndexAcknowledgment makes the reader acknowledge the messages of a batch individually as they
+	// are returned, instead of the whole batch once all its messages are returned, so that the broker only
+	// redelivers the remaining messages of a partially read batch, e.g. on reconnection. The broker must have
+	// batch index acknowledgment enabled (acknowledgmentAtBatchIndexLevelEnabled), otherwise the messages are
+	// acknowledged as usual. (default: false)
+	EnableBatchIndexAcknowledgment bool
+
 	// ReadCompacted, if enabled, the reader will read messages from the compacted topic rather than reading the
 	// full message backlog of the topic. This means that, if the topic has been compacted, the reader will only
 	// see the latest value for each key in the topic, up until the point in the topic message backlog that has
@@ -102,6 +151,82 @@ type ReaderOptions struct {
 	// AutoAckIncompleteChunk sets whether reader auto acknowledges incomplete chunked message when it should
 	// be removed (e.g.the chunked message pending queue is full). (default: false)
 	AutoAckIncompleteChunk bool
+
+	// LazyChunkedPayloadDecompression sets whether the payload of compressed chunked messages is only decompressed
+	// while it is read through `Message.PayloadReader()`, instead of being decompressed in memory before delivery, so
+	// that the uncompressed payload is never held in memory as a whole. `Message.Payload()` returns nil for these
+	// messages, and their payload reader must be closed when it is not read to the end. This does not stream the
+	// chunks: they are still received and reassembled in memory, in their compressed form, before the message is
+	// delivered. Uncompressed and LZ4 payloads are delivered as usual. (default: false)
+	LazyChunkedPayloadDecompression bool
+
+	// MetadataOnly sets whether the reader only keeps the metadata of the messages. The payload of each message is
+	// released right after its metadata is parsed, so that `Message.Payload()` returns nil and
+	// `Message.GetSchemaValue()` returns an error. This reduces the memory used when scanning large topics, the
+	// payloads are still transferred by the broker though. (default: false)
+	MetadataOnly bool
+
+	// BarrierProperty sets the name of a message property marking a barrier message. When the reader
+	// delivers a message carrying this property, it pauses and `Reader.Next()` blocks until
+	// `Reader.ResumeFromBarrier()` is called. (default: "", barriers disabled)
+	BarrierProperty string
+
+	// FilterExpression sets a SQL-like expression over the message properties, e.g.
+	// `region = 'eu' AND (priority >= 3 OR vip IS NOT NULL)`, so that only the matching messages are returned
+	// by `Reader.Next()`.
+	//
+	// The filter is only evaluated by the reader: the broker still dispatches every message, so it does not save
+	// bandwidth. It supports the comparisons (=, !=, <>, <, <=, >, >=) against a single-quoted string or a number,
+	// [NOT] IN, IS [NOT] NULL, NOT, AND, OR and parentheses. The messages skipped are not returned by
+	// `Reader.SeekByTimeResolved()` either. Since they are not known in advance, `Reader.HasNext()` may return
+	// true even though no remaining message matches. (default: "", no filtering)
+	FilterExpression string
+
+	// ProducerNameFilter sets the name of the producer whose messages are returned by `Reader.Next()`, the
+	// messages of the other producers being skipped. As with FilterExpression, `Reader.HasNext()` may return
+	// true even though no remaining message matches. (default: "", no filtering)
+	ProducerNameFilter string
+
+	// ProducerNameFilterOnBroker passes ProducerNameFilter to the broker in the "filter.producer.name"
+	// subscription property, for an entry filter plugin to skip the messages of the other producers before they
+	// are dispatched. The reader still filters the messages itself, in case the broker lacks the plugin.
+	// (default: false)
+	ProducerNameFilterOnBroker bool
+
+	// OnReachedEndOfTopic is called when the reader has caught up with the last message available, i.e. when
+	// `Reader.HasNext()` first returns false after at least one message was read, and again each time the reader
+	// catches up after more messages were published. It is checked after each call to `Reader.Next()` and
+	// `Reader.NextBatch()`, and is called from the goroutine of the call, which may query the broker for the last
+	// message id once the reader is at the end of the topic. (default: nil)
+	OnReachedEndOfTopic func(reader Reader)
+
+	// EndMessageID bounds the reader: once the message with this id has been read, `Reader.HasNext()` returns false
+	// and `Reader.Next()` returns ErrEndMessageIDReached. The messages after it are not returned. On a partitioned
+	// topic, the end of each partition is given by an id returned by `Reader.Tell()` or `ParseMessageID()`; the
+	// partitions that are not part of the id are not read. With EndMessageIDInclusive false, HasNext can only
+	// report the end of a partition once the message at EndMessageID has been received. It is not supported with
+	// Topics, TopicsPattern or ReadReverse. (default: nil, the reader keeps tailing the topic)
+	EndMessageID MessageID
+
+	// EndMessageIDInclusive, if true, makes the reader return the message at EndMessageID before ending.
+	// (default: false)
+	EndMessageIDInclusive bool
+
+	// ReadReverse makes `Reader.Next()` return the messages in descending order, from StartMessageID towards the
+	// earliest message of the topic, after which `Reader.HasNext()` returns false and `Reader.Next()` returns a
+	// StopMessageIDReached error. With LatestMessageID, the reader starts from the last message published when it
+	// is created, and ignores the messages published afterwards.
+	//
+	// The messages are read forward window by window, seeking backwards in between, and about ReceiverQueueSize
+	// messages are buffered per window. Seeking is not supported, and neither are partitioned topics (use a reader
+	// per partition) and MaxReadRate. (default: false)
+	ReadReverse bool
+
+	// MaxReadRate caps the number of messages per second returned by `Reader.Next()`, which then blocks until
+	// the rate allows the next message, e.g. to pace the replay of a topic into a slower downstream.
+	// The ReceiverQueueSize is lowered to MaxReadRate if larger, so that the reader does not fetch more than
+	// about a second of messages ahead of the application. (default: 0, no limit)
+	MaxReadRate int
 }
 
 // Reader can be used to scan through all the messages currently available in a topic.
@@ -112,10 +237,33 @@ type Reader interface {
 	// Next reads the next message in the topic, blocking until a message is available
 	Next(context.Context) (Message, error)
 
+	// NextBatch blocks until a message is available, and then returns it along with up to maxMessages-1 of the
+	// messages that are already available, without waiting for more. The messages are in the order in which
+	// Next would return them.
+	//
+	// If the context is done while draining the available messages, the messages gathered so far are returned
+	// along with the context error.
+	NextBatch(ctx context.Context, maxMessages int) ([]Message, error)
+
 	// HasNext checks if there is any message available to read from the current position
 	// If there is any errors, it will return false
 	HasNext() bool
 
+	// HasNextWithContext checks if there is any message available to read from the current position, like
+	// HasNext. Fetching the last message id from the broker is abandoned when the context is done, and the
+	// error is returned instead of being reported as false.
+	HasNextWithContext(ctx context.Context) (bool, error)
+
+	// Stats returns the number of messages and bytes read and the number of reconnections to the brokers since
+	// the reader was created, e.g. to detect a stalled replay. It only reads atomic counters.
+	Stats() ReaderStats
+
+	// MessageChannel returns a channel delivering the messages that Next would return, for select loops. The
+	// messages are read from a goroutine started on the first call, so Next must not be called concurrently.
+	// When Next fails, the error is delivered as a ReaderMessage with Err set and no more messages are delivered.
+	// The channel is closed once the reader is closed or after an error, unless it is ReaderOptions.MessageChannel.
+	MessageChannel() <-chan ReaderMessage
+
 	// Close the reader and stop the broker to push more messages
 	Close()
 
@@ -136,7 +284,46 @@ type Reader interface {
 	//
 	SeekByTime(time time.Time) error
 
+	// SeekByTimeWithContext is SeekByTime, which stops waiting for the partitions to seek when the context is done.
+	// The error then wraps an *Error with the TimeoutError result, and the partitions may still seek afterwards.
+	// The partitions are seeked concurrently, the error being a PartitionedSeekError when some of them fail.
+	SeekByTimeWithContext(ctx context.Context, time time.Time) error
+
+	// SeekByTimeResolved resets the subscription associated with this reader to a specific message publish time,
+	// and returns the id of the first message published at or after that time, e.g. to persist it as a checkpoint.
+	// The returned message is the next one delivered by Next.
+	//
+	// LatestMessageID is returned when no message was published at or after that time, which the broker tells from
+	// the position of the subscription. Otherwise, the message is waited for up to the client operation timeout.
+	//
+	// Note: this operation can only be done on non-partitioned topics.
+	SeekByTimeResolved(time time.Time) (MessageID, error)
+
 	// GetLastMessageID get the last message id available for consume.
 	// It only works for single topic reader. It will return an error when the reader is the multi-topic reader.
 	GetLastMessageID() (MessageID, error)
+
+	// ResumeFromBarrier resumes the delivery of messages after the reader has paused on a barrier message
+	// (see `ReaderOptions.BarrierProperty`). It has no effect if the reader is not paused.
+	ResumeFromBarrier()
+
+	// Tell returns the id of the last message returned by Next, or the start position if no message has been
+	// returned yet, e.g. to resume a reader from it with StartMessageIDInclusive set to false.
+	//
+	// For a partitioned topic, the returned MessageID holds the position of every partition. Its Serialize and
+	// String methods can be reversed with DeserializeMessageID and ParseMessageID, and a reader created with it
+	// as StartMessageID starts from these positions.
+	Tell() (MessageID, error)
+
+	// BatchIndexAcknowledgmentEnabled reports whether the reader acknowledges the messages of the batches
+	// individually, see ReaderOptions.EnableBatchIndexAcknowledgment. The protocol does not tell whether the broker
+	// supports it, which depends on its acknowledgmentAtBatchIndexLevelEnabled setting.
+	BatchIndexAcknowledgmentEnabled() bool
+
+	// SaveState returns an opaque blob capturing the position of the reader on every partition, from which
+	// Client.RestoreReader creates a reader returning the messages that follow the ones returned by Next.
+	//
+	// The chunks of a chunked message not returned yet are read again by the restored reader when they were
+	// interleaved with the messages already returned, so that the message is reassembled.
+	SaveState() ([]byte, error)
 }
@@ -0,0 +1,52 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+
+	uAtomic "go.uber.org/atomic"
+)
+
+// catchUpNotifier calls ReaderOptions.OnReachedEndOfTopic when a reader catches up with the last message
+// available
+type catchUpNotifier struct {
+	onReachedEnd func(reader Reader)
+	// behind is set when a message has been read since the reader last reached the end of the topic
+	behind uAtomic.Bool
+}
+
+// read records that the reader returned messages, and checks whether it has now reached the end of the topic
+func (n *catchUpNotifier) read(ctx context.Context, reader Reader, count int) {
+	if n.onReachedEnd == nil || count == 0 {
+		return
+	}
+	n.behind.Store(true)
+	// HasNextWithContext calls checked
+	_, _ = reader.HasNextWithContext(ctx)
+}
+
+// checked calls the callback if the reader has no more messages while it has read some since the last call
+func (n *catchUpNotifier) checked(reader Reader, hasNext bool, err error) {
+	if n.onReachedEnd == nil || err != nil || hasNext {
+		return
+	}
+	if n.behind.CAS(true, false) {
+		n.onReachedEnd(reader)
+	}
+}
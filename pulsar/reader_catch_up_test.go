@@ -0,0 +1,49 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatchUpNotifier(t *testing.T) {
+	calls := 0
+	n := &catchUpNotifier{onReachedEnd: func(Reader) { calls++ }}
+
+	// nothing was read yet
+	n.checked(nil, false, nil)
+	assert.Equal(t, 0, calls)
+
+	n.behind.Store(true)
+	n.checked(nil, true, nil)
+	assert.Equal(t, 0, calls)
+	n.checked(nil, false, errors.New("failed"))
+	assert.Equal(t, 0, calls)
+	n.checked(nil, false, nil)
+	assert.Equal(t, 1, calls)
+
+	// called once until more messages are read
+	n.checked(nil, false, nil)
+	assert.Equal(t, 1, calls)
+	n.behind.Store(true)
+	n.checked(nil, false, nil)
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,90 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"sync"
+)
+
+// readerChannel feeds the channel returned by Reader.MessageChannel with the messages returned by Next, from a
+// goroutine started on the first call
+type readerChannel struct {
+	ch chan ReaderMessage
+	// owned is false when the channel is ReaderOptions.MessageChannel, which is not closed
+	owned  bool
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+	doneCh chan struct{}
+}
+
+func newReaderChannel(ch chan ReaderMessage) *readerChannel {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &readerChannel{
+		ch:     ch,
+		ctx:    ctx,
+		cancel: cancel,
+		doneCh: make(chan struct{}),
+	}
+	if c.ch == nil {
+		c.ch = make(chan ReaderMessage)
+		c.owned = true
+	}
+	return c
+}
+
+func (c *readerChannel) get(r Reader) <-chan ReaderMessage {
+	c.once.Do(func() {
+		go c.run(r)
+	})
+	return c.ch
+}
+
+func (c *readerChannel) run(r Reader) {
+	defer c.stop()
+	for {
+		msg, err := r.Next(c.ctx)
+		if c.ctx.Err() != nil {
+			// the reader is closing
+			return
+		}
+		select {
+		case c.ch <- ReaderMessage{Reader: r, Message: msg, Err: err}:
+		case <-c.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *readerChannel) stop() {
+	if c.owned {
+		close(c.ch)
+	}
+	close(c.doneCh)
+}
+
+// close stops the goroutine, if started, and waits for it to close the channel
+func (c *readerChannel) close() {
+	c.cancel()
+	c.once.Do(c.stop)
+	<-c.doneCh
+}
@@ -0,0 +1,104 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nextReader returns the messages of its channel from Next
+type nextReader struct {
+	Reader
+	msgs chan Message
+	err  error
+}
+
+func (r *nextReader) Next(ctx context.Context) (Message, error) {
+	select {
+	case msg, ok := <-r.msgs:
+		if !ok {
+			return nil, r.err
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestReaderChannel(t *testing.T) {
+	r := &nextReader{msgs: make(chan Message, 2), err: errors.New("next failed")}
+	r.msgs <- &message{payLoad: []byte("1")}
+	r.msgs <- &message{payLoad: []byte("2")}
+	close(r.msgs)
+
+	c := newReaderChannel(nil)
+	ch := c.get(r)
+	for _, payload := range []string{"1", "2"} {
+		rm := <-ch
+		assert.NoError(t, rm.Err)
+		assert.Equal(t, []byte(payload), rm.Payload())
+		assert.Equal(t, r, rm.Reader)
+	}
+	rm := <-ch
+	assert.EqualError(t, rm.Err, "next failed")
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	c.close()
+}
+
+func TestReaderChannelClose(t *testing.T) {
+	// closed while Next is blocked
+	r := &nextReader{msgs: make(chan Message)}
+	c := newReaderChannel(nil)
+	ch := c.get(r)
+	c.close()
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	// closed while the message is not received
+	r = &nextReader{msgs: make(chan Message, 1)}
+	r.msgs <- &message{}
+	c = newReaderChannel(nil)
+	ch = c.get(r)
+	time.Sleep(10 * time.Millisecond)
+	c.close()
+	_, ok = <-ch
+	assert.False(t, ok)
+
+	// closed before being used
+	c = newReaderChannel(nil)
+	c.close()
+	_, ok = <-c.get(r)
+	assert.False(t, ok)
+
+	// the channel of the options is not closed
+	userCh := make(chan ReaderMessage, 1)
+	c = newReaderChannel(userCh)
+	c.close()
+	select {
+	case <-userCh:
+		assert.Fail(t, "the channel of the options must not be closed")
+	default:
+	}
+}
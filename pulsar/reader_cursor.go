@@ -0,0 +1,113 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"time"
+)
+
+// partitionCursor is a partition whose subscription cursor precedes the last message, so that it has messages to
+// deliver after the time the reader seeked to
+type partitionCursor struct {
+	pc        *partitionConsumer
+	lastMsgID *trackingMessageID
+	// reported is false when the broker does not report the position of the cursor, which is then assumed to
+	// precede the last message
+	reported bool
+}
+
+// cursorsBeforeLastMessage returns the partitions whose cursor precedes their last message. The partitions that
+// are empty, or whose cursor is at the end, can not deliver any message before a new one is published.
+func cursorsBeforeLastMessage(ctx context.Context, consumers []*partitionConsumer) ([]partitionCursor, error) {
+	cursors := make([]partitionCursor, 0, len(consumers))
+	for _, pc := range consumers {
+		lastMsgID, markDeleteID, err := pc.getLastMessageIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !lastMsgID.isEntryIDValid() {
+			continue
+		}
+		if markDeleteID != nil && compareEntries(markDeleteID.messageID, lastMsgID.messageID) >= 0 {
+			continue
+		}
+		cursors = append(cursors, partitionCursor{pc: pc, lastMsgID: lastMsgID, reported: markDeleteID != nil})
+	}
+	return cursors, nil
+}
+
+// startTimePollInterval is how often waitFirstMessage checks whether the partitions skipped all their messages
+const startTimePollInterval = 100 * time.Millisecond
+
+// waitFirstMessage receives the first message after the cursors, and reports false when there is none. The broker
+// positions a reader starting at a time a bit before it, so a partition may only deliver messages that it skips as
+// published before the start time, or that the filters of the reader skip: once it skipped its last message, it is
+// not waited for anymore. As the other
+// partitions are known to hold messages, not receiving any within the timeout is a TimeoutError rather than the
+// end of the topic, unless the broker did not report the position of the cursor.
+func waitFirstMessage(ctx context.Context, cursors []partitionCursor, timeout time.Duration,
+	receive func(ctx context.Context) (ConsumerMessage, bool, error)) (ConsumerMessage, bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		pending, reported := false, false
+		for _, c := range cursors {
+			if !c.pc.skippedUpTo(c.lastMsgID) {
+				pending = true
+				reported = reported || c.reported
+			}
+		}
+		if !pending {
+			return ConsumerMessage{}, false, nil
+		}
+
+		receiveCtx, cancel := context.WithTimeout(ctx, startTimePollInterval)
+		cm, _, err := receive(receiveCtx)
+		cancel()
+		if err == nil {
+			return cm, true, nil
+		} else if err != context.DeadlineExceeded || ctx.Err() != nil {
+			return ConsumerMessage{}, false, err
+		}
+
+		if time.Now().After(deadline) {
+			if reported {
+				return ConsumerMessage{}, false,
+					newError(TimeoutError, "no message received although the broker holds messages after the cursor")
+			}
+			// the broker can not tell whether there are messages after the cursor, assume there are none
+			return ConsumerMessage{}, false, nil
+		}
+	}
+}
+
+// compareEntries compares the entries holding two messages, regardless of their batch indexes
+func compareEntries(lhs *messageID, rhs *messageID) int {
+	switch {
+	case lhs.ledgerID != rhs.ledgerID:
+		if lhs.ledgerID < rhs.ledgerID {
+			return -1
+		}
+		return 1
+	case lhs.entryID < rhs.entryID:
+		return -1
+	case lhs.entryID > rhs.entryID:
+		return 1
+	}
+	return 0
+}
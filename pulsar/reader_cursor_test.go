@@ -0,0 +1,119 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCursorConsumer returns a partition consumer answering the requests for its last message id with the
+// given ids
+func newTestCursorConsumer(t *testing.T, lastMsgID, markDeleteID *trackingMessageID) *partitionConsumer {
+	eventsCh := make(chan interface{})
+	pc := &partitionConsumer{
+		eventsCh: eventsCh,
+		client:   &client{operationTimeout: time.Second},
+		options:  &partitionConsumerOpts{},
+		log:      log.DefaultNopLogger(),
+	}
+	go func() {
+		for e := range eventsCh {
+			req := e.(*getLastMsgIDRequest)
+			req.msgID, req.markDeleteID = lastMsgID, markDeleteID
+			close(req.doneCh)
+		}
+	}()
+	t.Cleanup(func() { close(eventsCh) })
+	return pc
+}
+
+func TestCursorsBeforeLastMessage(t *testing.T) {
+	empty := newTestCursorConsumer(t, newTrackingMessageID(-1, -1, -1, 0, 0, nil), nil)
+	atEnd := newTestCursorConsumer(t, newTrackingMessageID(1, 5, 2, 0, 0, nil),
+		newTrackingMessageID(1, 5, 0, 0, 0, nil))
+	pending := newTestCursorConsumer(t, newTrackingMessageID(1, 5, -1, 0, 0, nil),
+		newTrackingMessageID(1, 3, 0, 0, 0, nil))
+	unreported := newTestCursorConsumer(t, newTrackingMessageID(1, 5, -1, 0, 0, nil), nil)
+
+	cursors, err := cursorsBeforeLastMessage(context.Background(),
+		[]*partitionConsumer{empty, atEnd, pending, unreported})
+	assert.Nil(t, err)
+	assert.Len(t, cursors, 2)
+	assert.Equal(t, pending, cursors[0].pc)
+	assert.True(t, cursors[0].reported)
+	assert.Equal(t, unreported, cursors[1].pc)
+	assert.False(t, cursors[1].reported)
+}
+
+func TestWaitFirstMessage(t *testing.T) {
+	never := func(ctx context.Context) (ConsumerMessage, bool, error) {
+		<-ctx.Done()
+		return ConsumerMessage{}, false, ctx.Err()
+	}
+	ctx := context.Background()
+	lastMsgID := newTrackingMessageID(1, 5, -1, 0, 0, nil)
+
+	// nothing after the cursors, no need to wait
+	_, ok, err := waitFirstMessage(ctx, nil, time.Hour, never)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// the broker holds messages after the cursor, not receiving them is not the end of the topic
+	cursor := partitionCursor{pc: &partitionConsumer{}, lastMsgID: lastMsgID, reported: true}
+	_, _, err = waitFirstMessage(ctx, []partitionCursor{cursor}, 10*time.Millisecond, never)
+	assert.Equal(t, TimeoutError, err.(*Error).Result())
+
+	// the broker did not report its cursor, assume there are no messages after it
+	unreported := partitionCursor{pc: &partitionConsumer{}, lastMsgID: lastMsgID}
+	_, ok, err = waitFirstMessage(ctx, []partitionCursor{unreported}, 10*time.Millisecond, never)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// the partition skips its last message as published before the start time
+	start := time.Now()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cursor.pc.lastSkipped.set(newTrackingMessageID(1, 5, 2, 0, 3, nil))
+	}()
+	_, ok, err = waitFirstMessage(ctx, []partitionCursor{cursor}, time.Hour, never)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Less(t, time.Since(start), time.Second)
+
+	msg := &message{msgID: newMessageID(1, 5, -1, 0, 0)}
+	cursor = partitionCursor{pc: &partitionConsumer{}, lastMsgID: lastMsgID, reported: true}
+	cm, ok, err := waitFirstMessage(ctx, []partitionCursor{cursor}, time.Hour,
+		func(ctx context.Context) (ConsumerMessage, bool, error) {
+			return ConsumerMessage{Message: msg}, true, nil
+		})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, msg, cm.Message)
+}
+
+func TestCompareEntries(t *testing.T) {
+	assert.Equal(t, 0, compareEntries(&messageID{ledgerID: 1, entryID: 2, batchIdx: 3},
+		&messageID{ledgerID: 1, entryID: 2}))
+	assert.Equal(t, -1, compareEntries(&messageID{ledgerID: 1, entryID: 2}, &messageID{ledgerID: 1, entryID: 3}))
+	assert.Equal(t, 1, compareEntries(&messageID{ledgerID: 2, entryID: 0}, &messageID{ledgerID: 1, entryID: 3}))
+}
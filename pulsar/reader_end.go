@@ -0,0 +1,119 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ErrEndMessageIDReached is returned by `Reader.Next()` once the reader has read every partition up to
+// ReaderOptions.EndMessageID
+var ErrEndMessageIDReached = newError(StopMessageIDReached, "reader reached its end message id")
+
+// readerEnd bounds a reader to the messages up to an end message id on every partition
+type readerEnd struct {
+	sync.Mutex
+	inclusive bool
+	// endMsgIDs holds, for each partition, the end message id, or nil once the partition has been read up to it
+	endMsgIDs []MessageID
+	remaining int
+}
+
+// newReaderEnd resolves the end message id of each of the partitions of a topic. The partitions that are not
+// part of the id are not read at all.
+func newReaderEnd(endMsgID MessageID, inclusive bool, partitions int) (*readerEnd, error) {
+	ids := []MessageID{endMsgID}
+	if pid, ok := endMsgID.(*partitionedMessageID); ok {
+		ids = make([]MessageID, 0, len(pid.ids))
+		for _, id := range pid.ids {
+			ids = append(ids, id)
+		}
+	}
+
+	end := &readerEnd{
+		inclusive: inclusive,
+		endMsgIDs: make([]MessageID, partitions),
+	}
+	for _, id := range ids {
+		mid := fromMessageID(id)
+		if mid.equal(earliestMessageID) || mid.equal(latestMessageID) {
+			return nil, newError(InvalidConfiguration, "EndMessageID must be the id of a message")
+		}
+		partition := int(mid.partitionIdx)
+		if partition < 0 && partitions == 1 {
+			partition = 0
+		}
+		if partition < 0 || partition >= partitions {
+			return nil, newError(InvalidConfiguration,
+				fmt.Sprintf("EndMessageID %v does not belong to a partition of the topic", id))
+		}
+		if end.endMsgIDs[partition] == nil {
+			end.remaining++
+		}
+		end.endMsgIDs[partition] = id
+	}
+	return end, nil
+}
+
+// ended reports whether all the partitions have been read up to their end message id
+func (e *readerEnd) ended() bool {
+	e.Lock()
+	defer e.Unlock()
+	return e.remaining == 0
+}
+
+// accept reports whether the message is within the bounds, and ends its partition when it reaches the end
+func (e *readerEnd) accept(msg Message) bool {
+	e.Lock()
+	defer e.Unlock()
+
+	partition := int(msg.ID().PartitionIdx())
+	if partition < 0 || partition >= len(e.endMsgIDs) {
+		partition = 0
+	}
+	endMsgID := e.endMsgIDs[partition]
+	if endMsgID == nil {
+		return false
+	}
+
+	cmp := messageIDCompare(msg.ID(), endMsgID)
+	if cmp >= 0 {
+		e.endMsgIDs[partition] = nil
+		e.remaining--
+	}
+	return cmp < 0 || (cmp == 0 && e.inclusive)
+}
+
+// hasNext reports whether one of the partitions that have not reached their end has more messages
+func (e *readerEnd) hasNext(ctx context.Context, consumers []*partitionConsumer) (bool, error) {
+	for idx, pc := range consumers {
+		e.Lock()
+		ended := idx >= len(e.endMsgIDs) || e.endMsgIDs[idx] == nil
+		e.Unlock()
+		if ended {
+			continue
+		}
+		hasNext, err := pc.hasNext(ctx)
+		if err != nil || hasNext {
+			return hasNext, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,87 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderEnd(t *testing.T) {
+	msgAt := func(entryID int64, partition int32) Message {
+		return &message{msgID: newMessageID(1, entryID, -1, partition, 0)}
+	}
+
+	end, err := newReaderEnd(newMessageID(1, 2, -1, -1, 0), false, 1)
+	assert.Nil(t, err)
+	assert.True(t, end.accept(msgAt(1, -1)))
+	assert.False(t, end.ended())
+	assert.False(t, end.accept(msgAt(2, -1)))
+	assert.True(t, end.ended())
+	assert.False(t, end.accept(msgAt(3, -1)))
+
+	end, err = newReaderEnd(newMessageID(1, 2, -1, -1, 0), true, 1)
+	assert.Nil(t, err)
+	assert.True(t, end.accept(msgAt(2, -1)))
+	assert.True(t, end.ended())
+
+	// a message past the end ends the partition as well
+	end, err = newReaderEnd(newMessageID(1, 2, -1, -1, 0), true, 1)
+	assert.Nil(t, err)
+	assert.False(t, end.accept(msgAt(3, -1)))
+	assert.True(t, end.ended())
+}
+
+func TestReaderEndPartitioned(t *testing.T) {
+	msgAt := func(entryID int64, partition int32) Message {
+		return &message{msgID: newMessageID(1, entryID, -1, partition, 0)}
+	}
+
+	id := &partitionedMessageID{ids: []*messageID{
+		{ledgerID: 1, entryID: 2, batchIdx: -1, partitionIdx: 0},
+		{ledgerID: 1, entryID: 4, batchIdx: -1, partitionIdx: 2},
+	}}
+	end, err := newReaderEnd(id, true, 3)
+	assert.Nil(t, err)
+
+	// the partition 1 is not part of the end id
+	assert.False(t, end.accept(msgAt(1, 1)))
+
+	assert.True(t, end.accept(msgAt(2, 0)))
+	assert.False(t, end.ended())
+	assert.False(t, end.accept(msgAt(3, 0)))
+	assert.True(t, end.accept(msgAt(3, 2)))
+	assert.True(t, end.accept(msgAt(4, 2)))
+	assert.True(t, end.ended())
+}
+
+func TestReaderEndInvalid(t *testing.T) {
+	_, err := newReaderEnd(EarliestMessageID(), false, 1)
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	_, err = newReaderEnd(LatestMessageID(), false, 1)
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	// the partition of the id is required on a partitioned topic
+	_, err = newReaderEnd(newMessageID(1, 2, -1, -1, 0), false, 3)
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	_, err = newReaderEnd(newMessageID(1, 2, -1, 3, 0), false, 3)
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+}
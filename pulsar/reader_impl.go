@@ -26,10 +26,14 @@ import (
 	"github.com/apache/pulsar-client-go/pulsar/crypto"
 	"github.com/apache/pulsar-client-go/pulsar/internal"
 	"github.com/apache/pulsar-client-go/pulsar/log"
+	"golang.org/x/time/rate"
 )
 
 const (
 	defaultReceiverQueueSize = 1000
+
+	// producerNameFilterProperty is the subscription property carrying ReaderOptions.ProducerNameFilter
+	producerNameFilterProperty = "filter.producer.name"
 )
 
 type reader struct {
@@ -39,17 +43,111 @@ type reader struct {
 	log       log.Logger
 	metrics   *internal.LeveledMetrics
 	c         *consumer
+
+	barrierProperty string
+	barrierMu       sync.Mutex
+	// barrierCh is not nil while the reader is paused on a barrier, it is closed on resume
+	barrierCh chan struct{}
+
+	// peekedMsg holds the message received by SeekByTimeResolved, to be returned by the next call to Next
+	peekedMu  sync.Mutex
+	peekedMsg *ConsumerMessage
+	// startTime is ReaderOptions.StartMessageTime until a message is received or the reader seeks, while the
+	// start position is not known by the client
+	startTime time.Time
+
+	filter       filterExpression
+	producerName string
+
+	// limiter paces Next when ReaderOptions.MaxReadRate is set
+	limiter *rate.Limiter
+
+	// reverse is set when ReaderOptions.ReadReverse is enabled
+	reverse *reverseReader
+
+	// restoredUpTo holds, for the readers created by Client.RestoreReader, the last message returned on each
+	// partition before the state was saved
+	restoredUpTo map[int32]*messageID
+
+	// snapshot is set on the readers created by CreateReaderAtTime
+	snapshot *readerSnapshot
+
+	// end is set when ReaderOptions.EndMessageID is set
+	end *readerEnd
+
+	interceptors ReaderInterceptors
+
+	channel *readerChannel
+
+	statsCounters readerStatsCounters
+	catchUp       catchUpNotifier
 }
 
 func newReader(client *client, options ReaderOptions) (Reader, error) {
+	if len(options.Topics) > 0 || options.TopicsPattern != "" {
+		m, err := newMultiTopicReader(client, options)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	r, err := newReaderWithStarts(client, options, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// newReaderWithStarts creates a reader starting from the given positions on some partitions, instead of
+// options.StartMessageID, and skipping the messages up to upTo on each partition.
+func newReaderWithStarts(client *client, options ReaderOptions, starts map[int]startPosition,
+	upTo map[int32]*messageID) (*reader, error) {
+	return newTopicReader(client, options, starts, upTo, nil)
+}
+
+// newTopicReader creates a reader of options.Topic, pushing its messages to messageCh if it is not nil, for the
+// readers of multiple topics.
+func newTopicReader(client *client, options ReaderOptions, starts map[int]startPosition,
+	upTo map[int32]*messageID, messageCh chan ConsumerMessage) (*reader, error) {
 	if options.Topic == "" {
 		return nil, newError(InvalidConfiguration, "Topic is required")
 	}
+	if len(options.Topics) > 0 || options.TopicsPattern != "" {
+		return nil, newError(InvalidConfiguration, "Topic can not be used with Topics or TopicsPattern")
+	}
+
+	if !options.StartMessageTime.IsZero() {
+		if options.StartMessageID != nil {
+			return nil, newError(InvalidConfiguration, "StartMessageID and StartMessageTime are mutually exclusive")
+		}
+		if options.ReadReverse {
+			return nil, newError(InvalidConfiguration, "StartMessageTime can not be used with ReadReverse")
+		}
+		// the broker rolls the subscription back from the latest message
+		options.StartMessageID = LatestMessageID()
+		options.StartMessageIDInclusive = false
+	}
 
 	if options.StartMessageID == nil {
 		return nil, newError(InvalidConfiguration, "StartMessageID is required")
 	}
 
+	if pid, ok := options.StartMessageID.(*partitionedMessageID); ok {
+		// the partitions that are not part of the id are read from the beginning
+		if starts == nil {
+			starts = make(map[int]startPosition, len(pid.ids))
+		}
+		for _, id := range pid.ids {
+			if _, ok := starts[int(id.partitionIdx)]; !ok {
+				starts[int(id.partitionIdx)] = startPosition{
+					id:        toTrackingMessageID(id),
+					inclusive: options.StartMessageIDInclusive,
+				}
+			}
+		}
+		options.StartMessageID = EarliestMessageID()
+	}
+
 	var startMessageID *trackingMessageID
 	if !checkMessageIDType(options.StartMessageID) {
 		// a custom type satisfying MessageID may not be a messageID or trackingMessageID
@@ -73,10 +171,27 @@ func newReader(client *client, options ReaderOptions) (Reader, error) {
 		subscriptionName += "-" + generateRandomName()
 	}
 
+	if options.MaxReadRate < 0 {
+		return nil, newError(InvalidConfiguration, "MaxReadRate can not be negative")
+	}
+	if options.MaxReceiverQueueSizeBytes < 0 {
+		return nil, newError(InvalidConfiguration, "MaxReceiverQueueSizeBytes can not be negative")
+	}
+	if options.ReadReverse && options.MaxReadRate > 0 {
+		return nil, newError(InvalidConfiguration, "MaxReadRate can not be used with ReadReverse")
+	}
+
 	receiverQueueSize := options.ReceiverQueueSize
 	if receiverQueueSize <= 0 {
 		receiverQueueSize = defaultReceiverQueueSize
 	}
+	var limiter *rate.Limiter
+	if options.MaxReadRate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(options.MaxReadRate), 1)
+		if receiverQueueSize > options.MaxReadRate {
+			receiverQueueSize = options.MaxReadRate
+		}
+	}
 
 	// decryption is enabled, use default message crypto if not provided
 	if options.Decryption != nil && options.Decryption.MessageCrypto == nil {
@@ -97,32 +212,65 @@ func newReader(client *client, options ReaderOptions) (Reader, error) {
 		options.ExpireTimeOfIncompleteChunk = time.Minute
 	}
 
+	var filter filterExpression
+	if options.FilterExpression != "" {
+		var err error
+		if filter, err = parseFilterExpression(options.FilterExpression); err != nil {
+			return nil, newError(InvalidConfiguration, fmt.Sprintf("invalid FilterExpression: %v", err))
+		}
+	}
+	var subscriptionProperties map[string]string
+	if options.ProducerNameFilter != "" && options.ProducerNameFilterOnBroker {
+		subscriptionProperties = map[string]string{producerNameFilterProperty: options.ProducerNameFilter}
+	}
+
 	consumerOptions := &ConsumerOptions{
-		Topic:                       options.Topic,
-		Name:                        options.Name,
-		SubscriptionName:            subscriptionName,
-		Type:                        Exclusive,
-		ReceiverQueueSize:           receiverQueueSize,
-		SubscriptionMode:            NonDurable,
-		ReadCompacted:               options.ReadCompacted,
-		Properties:                  options.Properties,
-		NackRedeliveryDelay:         defaultNackRedeliveryDelay,
-		ReplicateSubscriptionState:  false,
-		Decryption:                  options.Decryption,
-		Schema:                      options.Schema,
-		BackoffPolicy:               options.BackoffPolicy,
-		MaxPendingChunkedMessage:    options.MaxPendingChunkedMessage,
-		ExpireTimeOfIncompleteChunk: options.ExpireTimeOfIncompleteChunk,
-		AutoAckIncompleteChunk:      options.AutoAckIncompleteChunk,
-		startMessageID:              startMessageID,
-		StartMessageIDInclusive:     options.StartMessageIDInclusive,
+		Topic:                             options.Topic,
+		Name:                              options.Name,
+		SubscriptionName:                  subscriptionName,
+		Type:                              Exclusive,
+		ReceiverQueueSize:                 receiverQueueSize,
+		EnableAutoScaledReceiverQueueSize: options.EnableAutoScaledReceiverQueueSize,
+		SubscriptionMode:                  NonDurable,
+		ReadCompacted:                     options.ReadCompacted,
+		Properties:                        options.Properties,
+		SubscriptionProperties:            subscriptionProperties,
+		NackRedeliveryDelay:               defaultNackRedeliveryDelay,
+		ReplicateSubscriptionState:        false,
+		Decryption:                        options.Decryption,
+		Schema:                            options.Schema,
+		BackoffPolicy:                     options.BackoffPolicy,
+		MaxPendingChunkedMessage:          options.MaxPendingChunkedMessage,
+		ExpireTimeOfIncompleteChunk:       options.ExpireTimeOfIncompleteChunk,
+		AutoAckIncompleteChunk:            options.AutoAckIncompleteChunk,
+		LazyChunkedPayloadDecompression:   options.LazyChunkedPayloadDecompression,
+		EnableBatchIndexAcknowledgment:    options.EnableBatchIndexAcknowledgment,
+		metadataOnly:                      options.MetadataOnly,
+		maxReceiverQueueSizeBytes:         options.MaxReceiverQueueSizeBytes,
+		startMessageID:                    startMessageID,
+		StartMessageIDInclusive:           options.StartMessageIDInclusive,
+		startMessageTime:                  options.StartMessageTime,
+		startPositions:                    starts,
 	}
 
+	ownMessageCh := messageCh == nil
+	if ownMessageCh {
+		messageCh = make(chan ConsumerMessage)
+	}
 	reader := &reader{
-		client:    client,
-		messageCh: make(chan ConsumerMessage),
-		log:       client.log.SubLogger(log.Fields{"topic": options.Topic}),
-		metrics:   client.metrics.GetLeveledMetrics(options.Topic),
+		client:          client,
+		messageCh:       messageCh,
+		log:             client.log.SubLogger(log.Fields{"topic": options.Topic}),
+		metrics:         client.metrics.GetLeveledMetrics(options.Topic),
+		barrierProperty: options.BarrierProperty,
+		filter:          filter,
+		producerName:    options.ProducerNameFilter,
+		limiter:         limiter,
+		restoredUpTo:    upTo,
+		startTime:       options.StartMessageTime,
+		interceptors:    options.Interceptors,
+		channel:         newReaderChannel(options.MessageChannel),
+		catchUp:         catchUpNotifier{onReachedEnd: options.OnReachedEndOfTopic},
 	}
 
 	// Provide dummy dlq router with not dlq policy
@@ -138,11 +286,38 @@ func newReader(client *client, options ReaderOptions) (Reader, error) {
 
 	c, err := newInternalConsumer(client, *consumerOptions, options.Topic, reader.messageCh, dlq, rlq, false)
 	if err != nil {
-		close(reader.messageCh)
+		if ownMessageCh {
+			close(reader.messageCh)
+		}
 		return nil, err
 	}
 	reader.c = c
 
+	if options.EndMessageID != nil {
+		if options.ReadReverse {
+			c.Close()
+			return nil, newError(InvalidConfiguration, "EndMessageID can not be used with ReadReverse")
+		}
+		reader.end, err = newReaderEnd(options.EndMessageID, options.EndMessageIDInclusive, len(c.consumers))
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	if options.ReadReverse {
+		if len(c.consumers) > 1 {
+			c.Close()
+			return nil, newError(InvalidConfiguration, "ReadReverse is not supported for partitioned topics")
+		}
+		reader.reverse, err = newReverseReader(reader, startMessageID.messageID, options.StartMessageIDInclusive,
+			receiverQueueSize)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
 	reader.metrics.ReadersOpened.Inc()
 	return reader, nil
 }
@@ -152,36 +327,322 @@ func (r *reader) Topic() string {
 }
 
 func (r *reader) Next(ctx context.Context) (Message, error) {
+	msg, err := r.read(ctx)
+	if err == nil {
+		r.statsCounters.record(msg)
+		r.catchUp.read(ctx, r, 1)
+	}
+	r.interceptors.onRead(r.log, r, msg, err)
+	return msg, err
+}
+
+func (r *reader) NextBatch(ctx context.Context, maxMessages int) ([]Message, error) {
+	msgs, err := r.readBatch(ctx, maxMessages)
+	r.statsCounters.record(msgs...)
+	r.catchUp.read(ctx, r, len(msgs))
+	r.interceptors.onReadBatch(r.log, r, msgs, err)
+	return msgs, err
+}
+
+func (r *reader) BatchIndexAcknowledgmentEnabled() bool {
+	return r.c.options.EnableBatchIndexAcknowledgment
+}
+
+func (r *reader) Stats() ReaderStats {
+	return r.statsCounters.stats(r.c)
+}
+
+// read returns the next message, see Next
+func (r *reader) read(ctx context.Context) (Message, error) {
+	if err := r.waitForBarrier(ctx); err != nil {
+		return nil, err
+	}
+	if r.reverse != nil {
+		return r.reverse.next(ctx)
+	}
+	if r.limiter != nil {
+		// wait before receiving, so that no message is lost if the context expires
+		if err := r.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return r.next(ctx, true)
+}
+
+// readBatch returns the messages available, see NextBatch
+func (r *reader) readBatch(ctx context.Context, maxMessages int) ([]Message, error) {
+	if maxMessages <= 0 {
+		return nil, newError(InvalidConfiguration, "maxMessages must be positive")
+	}
+
+	msg, err := r.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msgs := []Message{msg}
+
+	for len(msgs) < maxMessages {
+		if err := ctx.Err(); err != nil {
+			return msgs, err
+		}
+		if r.pausedOnBarrier() || (r.snapshot != nil && r.snapshot.ended()) || (r.end != nil && r.end.ended()) {
+			break
+		}
+
+		var reservation *rate.Reservation
+		if r.limiter != nil {
+			if reservation = r.limiter.Reserve(); reservation.Delay() > 0 {
+				reservation.Cancel()
+				break
+			}
+		}
+		if r.reverse != nil {
+			msg := r.reverse.tryNext()
+			if msg == nil {
+				break
+			}
+			msgs = append(msgs, msg)
+			continue
+		}
+		msg, err := r.next(ctx, false)
+		if err != nil {
+			return msgs, err
+		}
+		if msg == nil {
+			if reservation != nil {
+				reservation.Cancel()
+			}
+			break
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// next returns the next message to deliver. If block is false, it returns a nil message instead of waiting
+// when no message is available.
+func (r *reader) next(ctx context.Context, block bool) (Message, error) {
 	for {
+		if r.snapshot != nil && r.snapshot.ended() {
+			return nil, r.snapshot.endError()
+		}
+		if r.end != nil && r.end.ended() {
+			return nil, ErrEndMessageIDReached
+		}
+
+		cm, ok, err := r.receive(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, nil
+		}
+		r.clearPeekedMsg()
+
+		deliver, err := r.accept(cm)
+		if err != nil {
+			return nil, err
+		}
+		if deliver {
+			return cm.Message, nil
+		}
+		releasePayload(cm.Message)
+	}
+}
+
+// accept acknowledges a received message, and reports whether it is to be returned to the application
+func (r *reader) accept(cm ConsumerMessage) (bool, error) {
+	// Acknowledge message immediately because the reader is based on non-durable subscription. When it
+	// reconnects, it will specify the subscription position anyway
+	msgID := cm.Message.ID()
+	if r.alreadyDelivered(msgID) {
+		return false, r.c.AckID(msgID)
+	}
+	if err := r.c.setLastDequeuedMsg(msgID); err != nil {
+		return false, err
+	}
+	if err := r.c.AckID(msgID); err != nil {
+		return false, err
+	}
+	if r.snapshot != nil && !r.snapshot.accept(cm.Message) {
+		return false, nil
+	}
+	if r.end != nil && !r.end.accept(cm.Message) {
+		return false, nil
+	}
+	if r.filtered(cm.Message) {
+		return false, nil
+	}
+	r.pauseIfBarrier(cm.Message)
+	return true, nil
+}
+
+// filtered reports whether the message is skipped by FilterExpression or ProducerNameFilter
+func (r *reader) filtered(msg Message) bool {
+	if r.filter != nil && !r.filter.match(msg.Properties()) {
+		return true
+	}
+	return r.producerName != "" && msg.ProducerName() != r.producerName
+}
+
+// receiveMatching waits for the next message passing the filters of the reader, so that the message peeked by
+// SeekByTimeResolved or for StartMessageTime is one that Next returns. The other messages are skipped.
+func (r *reader) receiveMatching(ctx context.Context) (ConsumerMessage, bool, error) {
+	for {
+		cm, ok, err := r.receive(ctx, true)
+		if err != nil || !ok || !r.filtered(cm.Message) {
+			return cm, ok, err
+		}
+		if err := r.skipFiltered(cm); err != nil {
+			return ConsumerMessage{}, false, err
+		}
+	}
+}
+
+// skipFiltered acknowledges a message filtered out while peeking, and records it as skipped by its partition
+// for waitFirstMessage not to wait for the partition once its last message is filtered out
+func (r *reader) skipFiltered(cm ConsumerMessage) error {
+	if _, err := r.accept(cm); err != nil {
+		return err
+	}
+	if mid := r.c.messageID(cm.Message.ID()); mid != nil {
+		r.c.consumers[mid.partitionIdx].lastSkipped.advance(mid)
+	}
+	releasePayload(cm.Message)
+	return nil
+}
+
+// receive returns the message peeked by SeekByTimeResolved if any, or waits for the next message. If block is
+// false, it returns false instead of waiting when no message is available.
+func (r *reader) receive(ctx context.Context, block bool) (ConsumerMessage, bool, error) {
+	r.peekedMu.Lock()
+	if cm := r.peekedMsg; cm != nil {
+		r.peekedMsg = nil
+		r.peekedMu.Unlock()
+		return *cm, true, nil
+	}
+	r.peekedMu.Unlock()
+
+	if !block {
 		select {
 		case cm, ok := <-r.messageCh:
 			if !ok {
-				return nil, newError(ConsumerClosed, "consumer closed")
+				return ConsumerMessage{}, false, newError(ConsumerClosed, "consumer closed")
 			}
+			return cm, true, nil
+		default:
+			return ConsumerMessage{}, false, nil
+		}
+	}
 
-			// Acknowledge message immediately because the reader is based on non-durable subscription. When it reconnects,
-			// it will specify the subscription position anyway
-			msgID := cm.Message.ID()
-			err := r.c.setLastDequeuedMsg(msgID)
-			if err != nil {
-				return nil, err
-			}
-			err = r.c.AckID(msgID)
-			if err != nil {
-				return nil, err
-			}
-			return cm.Message, nil
-		case <-ctx.Done():
-			return nil, ctx.Err()
+	select {
+	case cm, ok := <-r.messageCh:
+		if !ok {
+			return ConsumerMessage{}, false, newError(ConsumerClosed, "consumer closed")
 		}
+		return cm, true, nil
+	case <-ctx.Done():
+		return ConsumerMessage{}, false, ctx.Err()
+	}
+}
+
+// waitForBarrier blocks while the reader is paused on a barrier message
+func (r *reader) waitForBarrier(ctx context.Context) error {
+	r.barrierMu.Lock()
+	barrierCh := r.barrierCh
+	r.barrierMu.Unlock()
+
+	if barrierCh == nil {
+		return nil
+	}
+
+	select {
+	case <-barrierCh:
+		return nil
+	case <-r.c.closeCh:
+		return newError(ConsumerClosed, "consumer closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *reader) pausedOnBarrier() bool {
+	r.barrierMu.Lock()
+	defer r.barrierMu.Unlock()
+	return r.barrierCh != nil
+}
+
+func (r *reader) pauseIfBarrier(msg Message) {
+	if r.barrierProperty == "" {
+		return
+	}
+	if _, ok := msg.Properties()[r.barrierProperty]; !ok {
+		return
+	}
+
+	r.barrierMu.Lock()
+	defer r.barrierMu.Unlock()
+	if r.barrierCh == nil {
+		r.log.WithField("msgID", msg.ID()).Debug("Reader paused on barrier message")
+		r.barrierCh = make(chan struct{})
+	}
+}
+
+func (r *reader) ResumeFromBarrier() {
+	r.barrierMu.Lock()
+	defer r.barrierMu.Unlock()
+	if r.barrierCh != nil {
+		close(r.barrierCh)
+		r.barrierCh = nil
 	}
 }
 
 func (r *reader) HasNext() bool {
-	return r.c.hasNext()
+	hasNext, err := r.HasNextWithContext(context.Background())
+	if err != nil {
+		r.log.WithError(err).Warn("Failed to check if there are more messages")
+	}
+	return hasNext
+}
+
+func (r *reader) HasNextWithContext(ctx context.Context) (bool, error) {
+	hasNext, err := r.hasNext(ctx)
+	r.catchUp.checked(r, hasNext, err)
+	return hasNext, err
+}
+
+func (r *reader) hasNext(ctx context.Context) (bool, error) {
+	if r.snapshot != nil && r.snapshot.ended() {
+		return false, nil
+	}
+	if r.end != nil && r.end.ended() {
+		return false, nil
+	}
+	if r.reverse != nil {
+		return r.reverse.hasNext(ctx)
+	}
+	r.peekedMu.Lock()
+	peeked := r.peekedMsg != nil
+	resolving := !r.startTime.IsZero()
+	r.peekedMu.Unlock()
+	if peeked {
+		return true, nil
+	}
+	if resolving {
+		return r.resolveStartTime(ctx)
+	}
+	if r.end != nil {
+		return r.end.hasNext(ctx, r.c.consumers)
+	}
+	return r.c.hasNext(ctx)
+}
+
+func (r *reader) MessageChannel() <-chan ReaderMessage {
+	return r.channel.get(r)
 }
 
 func (r *reader) Close() {
+	r.channel.close()
 	r.c.Close()
 	r.client.handlers.Del(r)
 	r.metrics.ReadersClosed.Inc()
@@ -201,6 +662,9 @@ func (r *reader) messageID(msgID MessageID) *trackingMessageID {
 }
 
 func (r *reader) Seek(msgID MessageID) error {
+	if r.reverse != nil {
+		return newError(OperationNotSupported, "seek is not supported by the readers with ReadReverse")
+	}
 	r.Lock()
 	defer r.Unlock()
 
@@ -214,14 +678,87 @@ func (r *reader) Seek(msgID MessageID) error {
 		return nil
 	}
 
+	r.clearPeekedMsg()
 	return r.c.Seek(mid)
 }
 
 func (r *reader) SeekByTime(time time.Time) error {
+	return r.SeekByTimeWithContext(context.Background(), time)
+}
+
+func (r *reader) SeekByTimeWithContext(ctx context.Context, time time.Time) error {
+	if r.reverse != nil {
+		return newError(OperationNotSupported, "seek is not supported by the readers with ReadReverse")
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	return r.seekByTime(ctx, time)
+}
+
+func (r *reader) seekByTime(ctx context.Context, time time.Time) error {
+	r.clearPeekedMsg()
+	return r.c.seekByTime(ctx, time)
+}
+
+func (r *reader) clearPeekedMsg() {
+	r.peekedMu.Lock()
+	r.peekedMsg = nil
+	r.startTime = time.Time{}
+	r.peekedMu.Unlock()
+}
+
+// resolveStartTime peeks the first message published after ReaderOptions.StartMessageTime, as the partitions
+// can not tell whether there is one before any message is received. It only waits for the partitions whose
+// subscription cursor precedes their last message, and that did not skip it as published before the start time.
+func (r *reader) resolveStartTime(ctx context.Context) (bool, error) {
+	cursors, err := cursorsBeforeLastMessage(ctx, r.c.consumers)
+	if err != nil {
+		return false, err
+	}
+	cm, ok, err := waitFirstMessage(ctx, cursors, r.client.operationTimeout, r.receiveMatching)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	r.peekedMu.Lock()
+	r.peekedMsg = &cm
+	r.startTime = time.Time{}
+	r.peekedMu.Unlock()
+	return true, nil
+}
+
+func (r *reader) SeekByTimeResolved(time time.Time) (MessageID, error) {
+	if r.reverse != nil {
+		return nil, newError(OperationNotSupported, "seek is not supported by the readers with ReadReverse")
+	}
+	if len(r.c.consumers) > 1 {
+		return nil, newError(SeekFailed, "SeekByTimeResolved is not supported for partitioned topics")
+	}
+
 	r.Lock()
 	defer r.Unlock()
 
-	return r.c.SeekByTime(time)
+	if err := r.seekByTime(context.Background(), time); err != nil {
+		return nil, err
+	}
+	// only wait for a message when the broker holds some after the position it seeked to
+	cursors, err := cursorsBeforeLastMessage(context.Background(), r.c.consumers)
+	if err != nil {
+		return nil, err
+	}
+	cm, ok, err := waitFirstMessage(context.Background(), cursors, r.client.operationTimeout, r.receiveMatching)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		// no message was published after the given time, the reader is at the end of the topic
+		return LatestMessageID(), nil
+	}
+
+	r.peekedMu.Lock()
+	r.peekedMsg = &cm
+	r.peekedMu.Unlock()
+	return cm.Message.ID(), nil
 }
 
 func (r *reader) GetLastMessageID() (MessageID, error) {
@@ -0,0 +1,73 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import "github.com/apache/pulsar-client-go/pulsar/log"
+
+type ReaderInterceptor interface {
+	// BeforeRead This is called just before the message is returned by the reader.
+	BeforeRead(reader Reader, message Message)
+
+	// OnReadComplete This is called once a read of the reader completes, with the message returned or the error.
+	OnReadComplete(reader Reader, message Message, err error)
+}
+
+type ReaderInterceptors []ReaderInterceptor
+
+func (x ReaderInterceptors) BeforeRead(reader Reader, message Message) {
+	for i := range x {
+		x[i].BeforeRead(reader, message)
+	}
+}
+
+func (x ReaderInterceptors) OnReadComplete(reader Reader, message Message, err error) {
+	for i := range x {
+		x[i].OnReadComplete(reader, message, err)
+	}
+}
+
+// onRead calls the interceptors with the result of Next, recovering from their panics
+func (x ReaderInterceptors) onRead(logger log.Logger, reader Reader, message Message, err error) {
+	if message != nil {
+		for i := range x {
+			x.call(logger, func() { x[i].BeforeRead(reader, message) })
+		}
+	}
+	for i := range x {
+		x.call(logger, func() { x[i].OnReadComplete(reader, message, err) })
+	}
+}
+
+// onReadBatch calls the interceptors with every message of a batch, and with the error if any
+func (x ReaderInterceptors) onReadBatch(logger log.Logger, reader Reader, messages []Message, err error) {
+	for _, msg := range messages {
+		x.onRead(logger, reader, msg, nil)
+	}
+	if err != nil {
+		x.onRead(logger, reader, nil, err)
+	}
+}
+
+func (x ReaderInterceptors) call(logger log.Logger, f func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			logger.Errorf("Reader interceptor panicked: %v", p)
+		}
+	}()
+	f()
+}
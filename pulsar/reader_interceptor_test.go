@@ -0,0 +1,81 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingReaderInterceptor struct {
+	before    int
+	completed int
+	errs      int
+}
+
+func (c *countingReaderInterceptor) BeforeRead(_ Reader, _ Message) {
+	c.before++
+}
+
+func (c *countingReaderInterceptor) OnReadComplete(_ Reader, _ Message, err error) {
+	c.completed++
+	if err != nil {
+		c.errs++
+	}
+}
+
+type panickingReaderInterceptor struct{}
+
+func (panickingReaderInterceptor) BeforeRead(_ Reader, _ Message) {
+	panic("before read")
+}
+
+func (panickingReaderInterceptor) OnReadComplete(_ Reader, _ Message, _ error) {
+	panic("on read complete")
+}
+
+func TestReaderInterceptorsRecoverPanics(t *testing.T) {
+	counter := &countingReaderInterceptor{}
+	interceptors := ReaderInterceptors{panickingReaderInterceptor{}, counter}
+
+	assert.NotPanics(t, func() {
+		interceptors.onRead(log.DefaultNopLogger(), nil, &message{}, nil)
+	})
+	assert.Equal(t, 1, counter.before)
+	assert.Equal(t, 1, counter.completed)
+
+	assert.NotPanics(t, func() {
+		interceptors.onRead(log.DefaultNopLogger(), nil, nil, errors.New("read failed"))
+	})
+	assert.Equal(t, 1, counter.before)
+	assert.Equal(t, 2, counter.completed)
+	assert.Equal(t, 1, counter.errs)
+}
+
+func TestReaderInterceptorsBatch(t *testing.T) {
+	counter := &countingReaderInterceptor{}
+	interceptors := ReaderInterceptors{counter}
+
+	interceptors.onReadBatch(log.DefaultNopLogger(), nil, []Message{&message{}, &message{}}, nil)
+	assert.Equal(t, 2, counter.before)
+	assert.Equal(t, 2, counter.completed)
+	assert.Equal(t, 0, counter.errs)
+}
@@ -0,0 +1,566 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"golang.org/x/time/rate"
+)
+
+// multiTopicReader merges the messages of the readers of ReaderOptions.Topics, or of the topics matching
+// ReaderOptions.TopicsPattern, which push them to a shared channel
+type multiTopicReader struct {
+	client    *client
+	options   ReaderOptions
+	messageCh chan ConsumerMessage
+	log       log.Logger
+
+	mu      sync.RWMutex
+	topics  []string
+	readers []*reader
+	owners  map[Consumer]*reader
+
+	// the topics matching the pattern are discovered in the namespace periodically
+	namespace string
+	pattern   *regexp.Regexp
+	ticker    *time.Ticker
+	closeCh   chan struct{}
+	closeOnce sync.Once
+
+	// limiter paces Next when ReaderOptions.MaxReadRate is set
+	limiter *rate.Limiter
+
+	// peekedMsg holds the message received while resolving ReaderOptions.StartMessageTime
+	peekedMu  sync.Mutex
+	peekedMsg *ConsumerMessage
+	startTime time.Time
+
+	channel *readerChannel
+
+	statsCounters readerStatsCounters
+	catchUp       catchUpNotifier
+}
+
+func newMultiTopicReader(client *client, options ReaderOptions) (*multiTopicReader, error) {
+	if options.Topic != "" {
+		return nil, newError(InvalidConfiguration, "Topic and Topics can not be used together")
+	}
+	if len(options.Topics) > 0 && options.TopicsPattern != "" {
+		return nil, newError(InvalidConfiguration, "Topics and TopicsPattern can not be used together")
+	}
+	if options.ReadReverse {
+		return nil, newError(InvalidConfiguration, "ReadReverse is not supported for multiple topics")
+	}
+	if options.EndMessageID != nil {
+		return nil, newError(InvalidConfiguration, "EndMessageID is not supported for multiple topics")
+	}
+	if options.StartMessageID != nil {
+		start := fromMessageID(options.StartMessageID)
+		if !start.equal(earliestMessageID) && !start.equal(latestMessageID) {
+			return nil, newError(InvalidConfiguration,
+				"StartMessageID must be EarliestMessageID or LatestMessageID for multiple topics")
+		}
+	}
+
+	m := &multiTopicReader{
+		client:    client,
+		options:   options,
+		owners:    make(map[Consumer]*reader, len(options.Topics)),
+		messageCh: make(chan ConsumerMessage),
+		closeCh:   make(chan struct{}),
+		startTime: options.StartMessageTime,
+		channel:   newReaderChannel(options.MessageChannel),
+		catchUp:   catchUpNotifier{onReachedEnd: options.OnReachedEndOfTopic},
+	}
+	if options.MaxReadRate > 0 {
+		m.limiter = rate.NewLimiter(rate.Limit(options.MaxReadRate), 1)
+	}
+
+	topics := options.Topics
+	if options.TopicsPattern != "" {
+		tn, err := internal.ParseTopicName(options.TopicsPattern)
+		if err != nil {
+			return nil, err
+		}
+		if m.pattern, err = extractTopicPattern(tn); err != nil {
+			return nil, err
+		}
+		m.namespace = tn.Namespace
+		m.log = client.log.SubLogger(log.Fields{"topic": tn.Name})
+		if topics, err = m.discoverTopics(); err != nil {
+			return nil, err
+		}
+	} else {
+		m.log = client.log.SubLogger(log.Fields{"topics": options.Topics})
+	}
+
+	for _, topic := range topics {
+		if err := m.addTopic(topic); err != nil {
+			m.closeReaders()
+			return nil, err
+		}
+	}
+
+	if m.pattern != nil {
+		interval := options.TopicsPatternAutoDiscoveryInterval
+		if interval <= 0 {
+			interval = defaultAutoDiscoveryDuration
+		}
+		m.ticker = time.NewTicker(interval)
+		go m.monitor()
+	}
+	return m, nil
+}
+
+// addTopic creates the reader of a topic, starting from the StartMessageID of the options
+func (m *multiTopicReader) addTopic(topic string) error {
+	topicOptions := m.options
+	topicOptions.Topic = topic
+	topicOptions.Topics = nil
+	topicOptions.TopicsPattern = ""
+	topicOptions.OnReachedEndOfTopic = nil
+	r, err := newTopicReader(m.client, topicOptions, nil, nil, m.messageCh)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.closeCh:
+		r.Close()
+		return newError(AlreadyClosedError, "the reader is closed")
+	default:
+	}
+	m.topics = append(m.topics, topic)
+	m.readers = append(m.readers, r)
+	m.owners[r.c] = r
+	return nil
+}
+
+// removeTopic closes the reader of a topic that no longer matches the pattern
+func (m *multiTopicReader) removeTopic(topic string) {
+	m.mu.Lock()
+	var removed *reader
+	for i, t := range m.topics {
+		if t == topic {
+			removed = m.readers[i]
+			m.topics = append(m.topics[:i:i], m.topics[i+1:]...)
+			m.readers = append(m.readers[:i:i], m.readers[i+1:]...)
+			delete(m.owners, removed.c)
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if removed != nil {
+		removed.Close()
+	}
+}
+
+func (m *multiTopicReader) discoverTopics() ([]string, error) {
+	topics, err := m.client.lookupService.GetTopicsOfNamespace(m.namespace, internal.Persistent)
+	if err != nil {
+		return nil, err
+	}
+	return filterTopics(topics, m.pattern), nil
+}
+
+func (m *multiTopicReader) monitor() {
+	for {
+		select {
+		case <-m.closeCh:
+			return
+		case <-m.ticker.C:
+			m.log.Debug("Auto discovering topics")
+			m.discover()
+		}
+	}
+}
+
+func (m *multiTopicReader) discover() {
+	topics, err := m.discoverTopics()
+	if err != nil {
+		m.log.WithError(err).Errorf("Failed to discover topics")
+		return
+	}
+	known := m.knownTopics()
+	newTopics := topicsDiff(topics, known)
+	staleTopics := topicsDiff(known, topics)
+
+	m.log.
+		WithFields(log.Fields{
+			"new_topics": newTopics,
+			"old_topics": staleTopics,
+		}).
+		Debug("discover topics")
+
+	for _, topic := range staleTopics {
+		m.removeTopic(topic)
+	}
+	for _, topic := range newTopics {
+		select {
+		case <-m.closeCh:
+			return
+		default:
+		}
+		if err := m.addTopic(topic); err != nil {
+			m.log.WithError(err).WithField("topic", topic).Warn("Failed to create the reader of the topic")
+		}
+	}
+}
+
+func (m *multiTopicReader) knownTopics() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.topics...)
+}
+
+// topicReaders returns the readers of the current topics
+func (m *multiTopicReader) topicReaders() []*reader {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*reader(nil), m.readers...)
+}
+
+func (m *multiTopicReader) Topic() string {
+	if m.pattern != nil {
+		return m.options.TopicsPattern
+	}
+	return strings.Join(m.options.Topics, ",")
+}
+
+func (m *multiTopicReader) Next(ctx context.Context) (Message, error) {
+	msg, err := m.read(ctx)
+	if err == nil {
+		m.statsCounters.record(msg)
+		m.catchUp.read(ctx, m, 1)
+	}
+	m.options.Interceptors.onRead(m.log, m, msg, err)
+	return msg, err
+}
+
+func (m *multiTopicReader) NextBatch(ctx context.Context, maxMessages int) ([]Message, error) {
+	msgs, err := m.readBatch(ctx, maxMessages)
+	m.statsCounters.record(msgs...)
+	m.catchUp.read(ctx, m, len(msgs))
+	m.options.Interceptors.onReadBatch(m.log, m, msgs, err)
+	return msgs, err
+}
+
+func (m *multiTopicReader) BatchIndexAcknowledgmentEnabled() bool {
+	return m.options.EnableBatchIndexAcknowledgment
+}
+
+func (m *multiTopicReader) Stats() ReaderStats {
+	readers := m.topicReaders()
+	consumers := make([]*consumer, len(readers))
+	for i, r := range readers {
+		consumers[i] = r.c
+	}
+	return m.statsCounters.stats(consumers...)
+}
+
+// read returns the next message of any topic, see Next
+func (m *multiTopicReader) read(ctx context.Context) (Message, error) {
+	for _, r := range m.topicReaders() {
+		if err := r.waitForBarrier(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if m.limiter != nil {
+		if err := m.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return m.next(ctx, true)
+}
+
+// readBatch returns the messages available on any topic, see NextBatch
+func (m *multiTopicReader) readBatch(ctx context.Context, maxMessages int) ([]Message, error) {
+	if maxMessages <= 0 {
+		return nil, newError(InvalidConfiguration, "maxMessages must be positive")
+	}
+
+	msg, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msgs := []Message{msg}
+
+	for len(msgs) < maxMessages {
+		if err := ctx.Err(); err != nil {
+			return msgs, err
+		}
+		if m.pausedOnBarrier() {
+			break
+		}
+
+		var reservation *rate.Reservation
+		if m.limiter != nil {
+			if reservation = m.limiter.Reserve(); reservation.Delay() > 0 {
+				reservation.Cancel()
+				break
+			}
+		}
+		msg, err := m.next(ctx, false)
+		if err != nil {
+			return msgs, err
+		}
+		if msg == nil {
+			if reservation != nil {
+				reservation.Cancel()
+			}
+			break
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// next returns the next message of any topic, see reader.next
+func (m *multiTopicReader) next(ctx context.Context, block bool) (Message, error) {
+	for {
+		cm, ok, err := m.receive(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, nil
+		}
+		m.clearPeekedMsg()
+
+		m.mu.RLock()
+		r, found := m.owners[cm.Consumer]
+		m.mu.RUnlock()
+		if !found {
+			// the topic is no longer read
+			releasePayload(cm.Message)
+			continue
+		}
+		deliver, err := r.accept(cm)
+		if err != nil {
+			return nil, err
+		}
+		if deliver {
+			return cm.Message, nil
+		}
+		releasePayload(cm.Message)
+	}
+}
+
+// receiveMatching waits for the next message passing the filters of its topic reader, see reader.receiveMatching
+func (m *multiTopicReader) receiveMatching(ctx context.Context) (ConsumerMessage, bool, error) {
+	for {
+		cm, ok, err := m.receive(ctx, true)
+		if err != nil || !ok {
+			return cm, ok, err
+		}
+		m.mu.RLock()
+		r, found := m.owners[cm.Consumer]
+		m.mu.RUnlock()
+		if !found || !r.filtered(cm.Message) {
+			return cm, true, nil
+		}
+		if err := r.skipFiltered(cm); err != nil {
+			return ConsumerMessage{}, false, err
+		}
+	}
+}
+
+// receive returns the peeked message if any, or waits for the next message, see reader.receive
+func (m *multiTopicReader) receive(ctx context.Context, block bool) (ConsumerMessage, bool, error) {
+	m.peekedMu.Lock()
+	if cm := m.peekedMsg; cm != nil {
+		m.peekedMsg = nil
+		m.peekedMu.Unlock()
+		return *cm, true, nil
+	}
+	m.peekedMu.Unlock()
+
+	if !block {
+		select {
+		case cm := <-m.messageCh:
+			return cm, true, nil
+		default:
+			return ConsumerMessage{}, false, nil
+		}
+	}
+
+	select {
+	case cm := <-m.messageCh:
+		return cm, true, nil
+	case <-ctx.Done():
+		return ConsumerMessage{}, false, ctx.Err()
+	}
+}
+
+func (m *multiTopicReader) clearPeekedMsg() {
+	m.peekedMu.Lock()
+	m.peekedMsg = nil
+	m.startTime = time.Time{}
+	m.peekedMu.Unlock()
+}
+
+func (m *multiTopicReader) pausedOnBarrier() bool {
+	for _, r := range m.topicReaders() {
+		if r.pausedOnBarrier() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiTopicReader) ResumeFromBarrier() {
+	for _, r := range m.topicReaders() {
+		r.ResumeFromBarrier()
+	}
+}
+
+func (m *multiTopicReader) HasNext() bool {
+	hasNext, err := m.HasNextWithContext(context.Background())
+	if err != nil {
+		m.log.WithError(err).Warn("Failed to check if there are more messages")
+	}
+	return hasNext
+}
+
+func (m *multiTopicReader) HasNextWithContext(ctx context.Context) (bool, error) {
+	hasNext, err := m.hasNext(ctx)
+	m.catchUp.checked(m, hasNext, err)
+	return hasNext, err
+}
+
+// hasNext returns true as soon as one of the topics has more messages. If none has, the first error
+// encountered is returned.
+func (m *multiTopicReader) hasNext(ctx context.Context) (bool, error) {
+	m.peekedMu.Lock()
+	peeked := m.peekedMsg != nil
+	resolving := !m.startTime.IsZero()
+	m.peekedMu.Unlock()
+	if peeked {
+		return true, nil
+	}
+	if resolving {
+		return m.resolveStartTime(ctx)
+	}
+
+	var firstErr error
+	for _, r := range m.topicReaders() {
+		hasNext, err := r.c.hasNext(ctx)
+		if hasNext {
+			return true, nil
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return false, firstErr
+}
+
+// resolveStartTime peeks the first message published after ReaderOptions.StartMessageTime, see
+// reader.resolveStartTime
+func (m *multiTopicReader) resolveStartTime(ctx context.Context) (bool, error) {
+	var cursors []partitionCursor
+	for _, r := range m.topicReaders() {
+		topicCursors, err := cursorsBeforeLastMessage(ctx, r.c.consumers)
+		if err != nil {
+			return false, err
+		}
+		cursors = append(cursors, topicCursors...)
+	}
+	cm, ok, err := waitFirstMessage(ctx, cursors, m.client.operationTimeout, m.receiveMatching)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	m.peekedMu.Lock()
+	m.peekedMsg = &cm
+	m.startTime = time.Time{}
+	m.peekedMu.Unlock()
+	return true, nil
+}
+
+func (m *multiTopicReader) MessageChannel() <-chan ReaderMessage {
+	return m.channel.get(m)
+}
+
+func (m *multiTopicReader) Close() {
+	m.channel.close()
+	m.closeOnce.Do(func() {
+		close(m.closeCh)
+		if m.ticker != nil {
+			m.ticker.Stop()
+		}
+	})
+	m.closeReaders()
+	m.client.handlers.Del(m)
+}
+
+func (m *multiTopicReader) closeReaders() {
+	m.mu.Lock()
+	readers := m.readers
+	m.topics, m.readers = nil, nil
+	m.owners = make(map[Consumer]*reader)
+	m.mu.Unlock()
+
+	for _, r := range readers {
+		r.Close()
+	}
+}
+
+func (m *multiTopicReader) Seek(MessageID) error {
+	return newError(SeekFailed, "seek command not allowed for multi topic reader")
+}
+
+func (m *multiTopicReader) SeekByTime(time time.Time) error {
+	return m.SeekByTimeWithContext(context.Background(), time)
+}
+
+func (m *multiTopicReader) SeekByTimeWithContext(ctx context.Context, time time.Time) error {
+	m.clearPeekedMsg()
+	for _, r := range m.topicReaders() {
+		if err := r.SeekByTimeWithContext(ctx, time); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *multiTopicReader) SeekByTimeResolved(time.Time) (MessageID, error) {
+	return nil, newError(SeekFailed, "SeekByTimeResolved is not supported for multi topic reader")
+}
+
+func (m *multiTopicReader) GetLastMessageID() (MessageID, error) {
+	return nil, newError(OperationNotSupported, "GetLastMessageID is not supported for multi topic reader")
+}
+
+func (m *multiTopicReader) Tell() (MessageID, error) {
+	return nil, newError(OperationNotSupported, "Tell is not supported for multi topic reader")
+}
+
+func (m *multiTopicReader) SaveState() ([]byte, error) {
+	return nil, newError(OperationNotSupported, "SaveState is not supported for multi topic reader")
+}
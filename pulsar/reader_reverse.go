@@ -0,0 +1,323 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// reverseInitialTimeWindow is the first time window searched for the messages of the previous ledger
+	reverseInitialTimeWindow = time.Second
+	defaultReverseWindow     = 100
+)
+
+// reverseReader returns the messages of a non-partitioned topic in descending order. The protocol only delivers
+// the messages in ascending order, so the reader seeks backwards window by window, reads each window forward
+// and returns its messages in reverse.
+//
+// Within a ledger, a window is a range of entries before the last message returned. Since the ids of the
+// previous ledger are unknown, its messages are then found with a seek by publish time, looking further back
+// in time until a message before the last one returned is found or the earliest message is reached. A time
+// window holding more than window messages is narrowed, so that only about window messages are buffered.
+//
+// After each seek, the position of the subscription cursor tells whether any message is to be received, so that
+// the end of a window is never detected by waiting for a message that does not come.
+type reverseReader struct {
+	sync.Mutex
+	r *reader
+
+	// upper is the message before which the next window is read, included if inclusive is true
+	upper     *messageID
+	inclusive bool
+	// upperTime is the publish time of upper
+	upperTime time.Time
+
+	// ledgerStart is true when the ledger of upper has no message before it
+	ledgerStart bool
+	// earliest is the id of the earliest message of the topic, once needed
+	earliest *messageID
+
+	timeWindow time.Duration
+	// timeWindowEmpty is the largest time window known to hold no message, and timeWindowFull the smallest one
+	// known to hold more than window messages, or 0, to narrow the time window by bisection
+	timeWindowEmpty time.Duration
+	timeWindowFull  time.Duration
+	window          int64
+
+	// pending holds the messages of the last window, in ascending order
+	pending []Message
+	done    bool
+}
+
+func newReverseReader(r *reader, start *messageID, inclusive bool, window int) (*reverseReader, error) {
+	rr := &reverseReader{
+		r:          r,
+		upper:      start,
+		inclusive:  inclusive,
+		upperTime:  time.Now(),
+		timeWindow: reverseInitialTimeWindow,
+		window:     int64(window),
+	}
+	if rr.window <= 0 {
+		rr.window = defaultReverseWindow
+	}
+
+	if start.equal(latestMessageID) {
+		// the messages published after the reader is created are ignored
+		last, err := r.c.consumers[0].getLastMessageID()
+		if err != nil {
+			return nil, err
+		}
+		if !last.isEntryIDValid() {
+			rr.done = true
+			return rr, nil
+		}
+		rr.upper, rr.inclusive = last.messageID, true
+	} else if start.equal(earliestMessageID) {
+		rr.done = true
+	}
+	return rr, nil
+}
+
+func (rr *reverseReader) next(ctx context.Context) (Message, error) {
+	rr.Lock()
+	defer rr.Unlock()
+
+	for {
+		if len(rr.pending) == 0 {
+			if err := rr.fill(ctx); err != nil {
+				return nil, err
+			}
+			if len(rr.pending) == 0 {
+				return nil, newError(StopMessageIDReached, "reader reached the earliest message of the topic")
+			}
+		}
+
+		msg := rr.pop()
+		if rr.r.filtered(msg) {
+			releasePayload(msg)
+			continue
+		}
+		return msg, nil
+	}
+}
+
+// tryNext returns the next message of the current window, or nil if the window has been fully returned
+func (rr *reverseReader) tryNext() Message {
+	rr.Lock()
+	defer rr.Unlock()
+
+	for len(rr.pending) > 0 {
+		msg := rr.pop()
+		if rr.r.filtered(msg) {
+			releasePayload(msg)
+			continue
+		}
+		return msg
+	}
+	return nil
+}
+
+func (rr *reverseReader) hasNext(ctx context.Context) (bool, error) {
+	rr.Lock()
+	defer rr.Unlock()
+
+	if len(rr.pending) == 0 {
+		ctx, cancel := context.WithTimeout(ctx, rr.r.client.operationTimeout)
+		defer cancel()
+		if err := rr.fill(ctx); err != nil {
+			return false, err
+		}
+	}
+	return len(rr.pending) > 0, nil
+}
+
+func (rr *reverseReader) pop() Message {
+	msg := rr.pending[len(rr.pending)-1]
+	rr.pending = rr.pending[:len(rr.pending)-1]
+	return msg
+}
+
+// fill reads the messages before upper, until some are found or the earliest message is reached
+func (rr *reverseReader) fill(ctx context.Context) error {
+	for len(rr.pending) == 0 && !rr.done {
+		var msgs []Message
+		var err error
+		if !rr.ledgerStart {
+			from := &messageID{
+				ledgerID:     rr.upper.ledgerID,
+				entryID:      rr.upper.entryID - rr.window,
+				batchIdx:     -1,
+				partitionIdx: rr.r.c.consumers[0].partitionIdx,
+			}
+			if from.entryID < 0 {
+				from.entryID = 0
+			}
+			if msgs, _, err = rr.readWindow(ctx, func() error { return rr.r.c.Seek(from) }, -1); err != nil {
+				return err
+			}
+			if len(msgs) == 0 && from.entryID == 0 {
+				rr.ledgerStart = true
+			}
+		} else {
+			if rr.earliest == nil {
+				if rr.earliest, err = rr.earliestID(ctx); err != nil {
+					return err
+				}
+			}
+			if rr.earliest == nil || !rr.upper.greater(rr.earliest) {
+				rr.done = true
+				break
+			}
+
+			limit := int(rr.window)
+			if rr.timeWindowNarrowest() {
+				// read the smallest window known to hold messages, only keeping its last ones
+				rr.timeWindow, limit = rr.timeWindowFull, -1
+			}
+			from := rr.upperTime.Add(-rr.timeWindow)
+			earliest := !from.After(time.Unix(0, 0))
+			if earliest {
+				from = time.Unix(0, 0)
+			}
+			var overflow bool
+			msgs, overflow, err = rr.readWindow(ctx, func() error { return rr.r.c.SeekByTime(from) }, limit)
+			if err != nil {
+				return err
+			}
+			if overflow {
+				rr.resizeTimeWindow(true)
+				msgs = nil
+			} else if len(msgs) == 0 {
+				rr.done = earliest
+				rr.resizeTimeWindow(false)
+			}
+		}
+
+		if len(msgs) > 0 {
+			first := msgs[0]
+			rr.pending = msgs
+			rr.upper, rr.inclusive = fromMessageID(first.ID()), false
+			rr.upperTime = first.PublishTime()
+			rr.ledgerStart = false
+			rr.timeWindow = reverseInitialTimeWindow
+			rr.timeWindowEmpty, rr.timeWindowFull = 0, 0
+		}
+	}
+	return nil
+}
+
+// resizeTimeWindow narrows the time window after it overflowed, or widens it after it was empty. The window is
+// doubled until one overflows, and then bisected between the largest empty one and the smallest overflowing one.
+func (rr *reverseReader) resizeTimeWindow(overflow bool) {
+	if overflow {
+		rr.timeWindowFull = rr.timeWindow
+		rr.timeWindow = (rr.timeWindowEmpty + rr.timeWindow) / 2
+		return
+	}
+	rr.timeWindowEmpty = rr.timeWindow
+	if rr.timeWindowFull <= rr.timeWindow {
+		// the messages of the overflowing window were removed meanwhile, e.g. by the retention
+		rr.timeWindowFull = 0
+	}
+	if rr.timeWindowFull > 0 {
+		rr.timeWindow = (rr.timeWindow + rr.timeWindowFull) / 2
+	} else {
+		rr.timeWindow *= 2
+	}
+}
+
+// timeWindowNarrowest reports whether the bisection of the time window is over, as the smallest window holding
+// too many messages only extends the largest empty one by the precision of the publish times
+func (rr *reverseReader) timeWindowNarrowest() bool {
+	return rr.timeWindowFull > 0 && rr.timeWindowFull-rr.timeWindowEmpty <= time.Millisecond
+}
+
+// seek seeks the reader and returns the last message of the topic, or nil if there is no message after the
+// subscription cursor
+func (rr *reverseReader) seek(ctx context.Context, seek func() error) (*messageID, error) {
+	if err := seek(); err != nil {
+		return nil, err
+	}
+	cursors, err := cursorsBeforeLastMessage(ctx, rr.r.c.consumers)
+	if err != nil || len(cursors) == 0 {
+		return nil, err
+	}
+	return cursors[0].lastMsgID.messageID, nil
+}
+
+// earliestID returns the id of the earliest message of the topic, or nil if the topic is empty
+func (rr *reverseReader) earliestID(ctx context.Context) (*messageID, error) {
+	last, err := rr.seek(ctx, func() error { return rr.r.c.SeekByTime(time.Unix(0, 0)) })
+	if err != nil || last == nil {
+		return nil, err
+	}
+
+	cm, _, err := rr.r.receive(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := rr.r.c.AckID(cm.Message.ID()); err != nil {
+		return nil, err
+	}
+	return fromMessageID(cm.Message.ID()), nil
+}
+
+// readWindow seeks the reader and reads forward the messages before upper. Once more than limit messages are
+// read, it stops and reports that the window overflowed, or only keeps the last window messages if limit is
+// negative.
+func (rr *reverseReader) readWindow(ctx context.Context, seek func() error, limit int) ([]Message, bool, error) {
+	last, err := rr.seek(ctx, seek)
+	if err != nil || last == nil {
+		return nil, false, err
+	}
+
+	// the window ends with upper, or with the last message of the topic if upper was removed by the retention
+	var msgs []Message
+	for {
+		cm, _, err := rr.r.receive(ctx, true)
+		if err != nil {
+			return nil, false, err
+		}
+
+		msgID := cm.Message.ID()
+		id := fromMessageID(msgID)
+		if err := rr.r.c.AckID(msgID); err != nil {
+			return nil, false, err
+		}
+		if id.greater(rr.upper) || (id.equal(rr.upper) && !rr.inclusive) {
+			return msgs, false, nil
+		}
+		msgs = append(msgs, cm.Message)
+		if limit >= 0 && len(msgs) > limit {
+			return msgs, true, nil
+		}
+		if limit < 0 && len(msgs) > 2*int(rr.window) {
+			msgs = append(msgs[:0], msgs[len(msgs)-int(rr.window):]...)
+		}
+		if id.equal(rr.upper) {
+			return msgs, false, nil
+		}
+		if c := compareEntries(id, last); c > 0 || (c == 0 && msgID.BatchIdx() == msgID.BatchSize()-1) {
+			return msgs, false, nil
+		}
+	}
+}
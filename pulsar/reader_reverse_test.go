@@ -0,0 +1,45 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseReaderResizeTimeWindow(t *testing.T) {
+	rr := &reverseReader{timeWindow: reverseInitialTimeWindow}
+
+	// widened until a window holds messages
+	rr.resizeTimeWindow(false)
+	rr.resizeTimeWindow(false)
+	assert.Equal(t, 4*time.Second, rr.timeWindow)
+
+	// the messages are published between 2.5s and 3s before upper, too many for a window
+	for i := 0; !rr.timeWindowNarrowest(); i++ {
+		if !assert.Less(t, i, 100, "the time window does not converge") {
+			return
+		}
+		rr.resizeTimeWindow(rr.timeWindow >= 2500*time.Millisecond)
+	}
+	assert.GreaterOrEqual(t, rr.timeWindowFull, 2500*time.Millisecond)
+	assert.Less(t, rr.timeWindowEmpty, 2500*time.Millisecond)
+	assert.LessOrEqual(t, rr.timeWindowFull-rr.timeWindowEmpty, time.Millisecond)
+}
@@ -0,0 +1,112 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// readerSnapshot bounds a reader to the messages published at or before a point in time on every partition
+type readerSnapshot struct {
+	sync.Mutex
+	time time.Time
+	// lastMsgIDs holds, for each partition, the last message published before the reader was created,
+	// or nil once the partition has been read up to the snapshot time
+	lastMsgIDs []*trackingMessageID
+	remaining  int
+}
+
+func newReaderAtTime(client *client, options ReaderOptions, t time.Time) (Reader, error) {
+	if !options.StartMessageTime.IsZero() {
+		return nil, newError(InvalidConfiguration, "StartMessageTime is not supported by CreateReaderAtTime")
+	}
+	if len(options.Topics) > 0 || options.TopicsPattern != "" {
+		return nil, newError(InvalidConfiguration, "Topics and TopicsPattern are not supported by CreateReaderAtTime")
+	}
+	if options.StartMessageID == nil {
+		options.StartMessageID = EarliestMessageID()
+	}
+	r, err := newReader(client, options)
+	if err != nil {
+		return nil, err
+	}
+	reader := r.(*reader)
+
+	snapshot := &readerSnapshot{time: t}
+	start := reader.c.options.startMessageID
+	for _, pc := range reader.c.consumers {
+		lastMsgID, err := pc.getLastMessageID()
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+		// the partition is empty, or the reader starts after its last message
+		if !lastMsgID.isEntryIDValid() || start.greater(lastMsgID.messageID) ||
+			(!options.StartMessageIDInclusive && start.equal(lastMsgID.messageID)) {
+			lastMsgID = nil
+		} else {
+			snapshot.remaining++
+		}
+		snapshot.lastMsgIDs = append(snapshot.lastMsgIDs, lastMsgID)
+	}
+	reader.snapshot = snapshot
+	return reader, nil
+}
+
+// ended reports whether all the partitions have been read up to the snapshot time
+func (s *readerSnapshot) ended() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.remaining == 0
+}
+
+// accept reports whether the message belongs to the snapshot, and ends its partition when it is the last one
+func (s *readerSnapshot) accept(msg Message) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	partition := int(msg.ID().PartitionIdx())
+	if partition < 0 || partition >= len(s.lastMsgIDs) {
+		partition = 0
+	}
+	lastMsgID := s.lastMsgIDs[partition]
+	if lastMsgID == nil {
+		return false
+	}
+
+	mid := toTrackingMessageID(msg.ID())
+	if msg.PublishTime().After(s.time) {
+		s.endPartition(partition)
+		return false
+	}
+	if mid != nil && mid.greaterEqual(lastMsgID.messageID) {
+		s.endPartition(partition)
+	}
+	return true
+}
+
+func (s *readerSnapshot) endPartition(partition int) {
+	s.lastMsgIDs[partition] = nil
+	s.remaining--
+}
+
+func (s *readerSnapshot) endError() error {
+	return newError(StopMessageIDReached, fmt.Sprintf("reader reached its snapshot time %s", s.time))
+}
@@ -0,0 +1,194 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+)
+
+const readerStateVersion = 1
+
+// readerState is the position of a reader saved by Reader.SaveState
+type readerState struct {
+	Version    int                    `json:"version"`
+	Topic      string                 `json:"topic"`
+	Partitions []readerPartitionState `json:"partitions"`
+}
+
+// readerPartitionState is the position of a reader on a partition: the reader restarts from Start, and skips
+// the messages up to Delivered that it already returned. Start is before Delivered when the chunks of a
+// message not returned yet were interleaved with the returned messages, so that the message can be
+// reassembled again.
+type readerPartitionState struct {
+	Partition int32  `json:"partition"`
+	Start     []byte `json:"start"`
+	Inclusive bool   `json:"inclusive"`
+	Delivered []byte `json:"delivered,omitempty"`
+}
+
+// startPosition is the position of a partition consumer overriding ConsumerOptions.startMessageID
+type startPosition struct {
+	id        *trackingMessageID
+	inclusive bool
+}
+
+func (r *reader) SaveState() ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	state := readerState{
+		Version: readerStateVersion,
+		Topic:   r.c.topic,
+	}
+	for _, pc := range r.c.consumers {
+		start, inclusive, delivered := r.position(pc)
+		if delivered != nil {
+			start, inclusive = delivered, false
+			if first := pc.earliestPendingChunk(); first != nil && !first.greater(delivered) {
+				start, inclusive = first, true
+			}
+		}
+
+		ps := readerPartitionState{
+			Partition: pc.partitionIdx,
+			Start:     start.Serialize(),
+			Inclusive: inclusive,
+		}
+		if delivered != nil {
+			ps.Delivered = delivered.Serialize()
+		}
+		state.Partitions = append(state.Partitions, ps)
+	}
+	return json.Marshal(state)
+}
+
+// position returns the position from which the reader started on a partition, and the last message it returned
+// on it, if any
+func (r *reader) position(pc *partitionConsumer) (start *messageID, inclusive bool, delivered *messageID) {
+	start, inclusive = earliestMessageID, pc.options.startMessageIDInclusive
+	if pc.options.startMessageID != nil {
+		start = pc.options.startMessageID.messageID
+	}
+
+	if pc.lastDequeuedMsg != nil {
+		delivered = pc.lastDequeuedMsg.messageID
+	}
+	if upTo := r.restoredUpTo[pc.partitionIdx]; upTo != nil && (delivered == nil || upTo.greater(delivered)) {
+		delivered = upTo
+	}
+	return start, inclusive, delivered
+}
+
+func (r *reader) Tell() (MessageID, error) {
+	if r.reverse != nil {
+		return nil, newError(OperationNotSupported, "Tell is not supported by the readers with ReadReverse")
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	ids := make([]*messageID, len(r.c.consumers))
+	for i, pc := range r.c.consumers {
+		start, _, delivered := r.position(pc)
+		id := delivered
+		if id == nil {
+			id = start
+		}
+		ids[i] = &messageID{
+			ledgerID:     id.ledgerID,
+			entryID:      id.entryID,
+			batchIdx:     id.batchIdx,
+			partitionIdx: pc.partitionIdx,
+			batchSize:    id.batchSize,
+		}
+	}
+	if len(ids) == 1 {
+		return ids[0], nil
+	}
+	return &partitionedMessageID{ids: ids}, nil
+}
+
+// alreadyDelivered returns true if the message was returned by the reader whose state was restored
+func (r *reader) alreadyDelivered(msgID MessageID) bool {
+	upTo := r.restoredUpTo[msgID.PartitionIdx()]
+	return upTo != nil && !fromMessageID(msgID).greater(upTo)
+}
+
+func restoreReader(client *client, options ReaderOptions, data []byte) (*reader, error) {
+	var state readerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, newError(InvalidConfiguration, fmt.Sprintf("invalid reader state: %v", err))
+	}
+	if state.Version != readerStateVersion {
+		return nil, newError(InvalidConfiguration, fmt.Sprintf("unsupported reader state version %d", state.Version))
+	}
+	if options.Topic == "" {
+		options.Topic = state.Topic
+	} else if tn, err := internal.ParseTopicName(options.Topic); err != nil {
+		return nil, err
+	} else if tn.Name != state.Topic {
+		return nil, newError(InvalidConfiguration,
+			fmt.Sprintf("the reader state is for the topic %s, not %s", state.Topic, tn.Name))
+	}
+	if options.StartMessageID == nil && options.StartMessageTime.IsZero() {
+		// the partitions created after the state was saved are read from the beginning
+		options.StartMessageID = EarliestMessageID()
+	}
+
+	starts := make(map[int]startPosition, len(state.Partitions))
+	upTo := make(map[int32]*messageID)
+	for _, ps := range state.Partitions {
+		start, err := deserializeMessageID(ps.Start)
+		if err != nil {
+			return nil, newError(InvalidConfiguration, fmt.Sprintf("invalid reader state: %v", err))
+		}
+		starts[int(ps.Partition)] = startPosition{id: toTrackingMessageID(start), inclusive: ps.Inclusive}
+		if ps.Delivered != nil {
+			delivered, err := deserializeMessageID(ps.Delivered)
+			if err != nil {
+				return nil, newError(InvalidConfiguration, fmt.Sprintf("invalid reader state: %v", err))
+			}
+			upTo[ps.Partition] = fromMessageID(delivered)
+		}
+	}
+	return newReaderWithStarts(client, options, starts, upTo)
+}
+
+// earliestPendingChunk returns the id of the first chunk of the oldest chunked message received, in part or in
+// full, but not acknowledged yet, or nil if there is none.
+func (pc *partitionConsumer) earliestPendingChunk() *messageID {
+	var earliest *messageID
+	consider := func(id *messageID) {
+		if id != nil && (earliest == nil || earliest.greater(id)) {
+			earliest = id
+		}
+	}
+
+	for _, id := range pc.chunkedMsgCtxMap.firstChunkIDs() {
+		consider(id)
+	}
+	pc.unAckChunksTracker.mu.Lock()
+	for cmid := range pc.unAckChunksTracker.chunkIDs {
+		consider(cmid.firstChunkID)
+	}
+	pc.unAckChunksTracker.mu.Unlock()
+	return earliest
+}
@@ -0,0 +1,65 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	uAtomic "go.uber.org/atomic"
+)
+
+// ReaderStats holds the statistics of a reader since its creation, see Reader.Stats
+type ReaderStats struct {
+	// MessagesReceived is the number of messages returned by Next and NextBatch
+	MessagesReceived int64
+	// BytesReceived is the size of the payloads of the messages returned by Next and NextBatch
+	BytesReceived int64
+	// Reconnects is the number of times the reader reconnected to a broker, over its current partitions
+	Reconnects int64
+}
+
+// readerStatsCounters are the counters behind the ReaderStats messages
+type readerStatsCounters struct {
+	messages uAtomic.Int64
+	bytes    uAtomic.Int64
+}
+
+func (c *readerStatsCounters) record(msgs ...Message) {
+	var bytes int
+	for _, msg := range msgs {
+		if msg != nil {
+			bytes += len(msg.Payload())
+		}
+	}
+	c.messages.Add(int64(len(msgs)))
+	c.bytes.Add(int64(bytes))
+}
+
+// stats returns the ReaderStats of the counters and of the partition consumers of the reader
+func (c *readerStatsCounters) stats(consumers ...*consumer) ReaderStats {
+	stats := ReaderStats{
+		MessagesReceived: c.messages.Load(),
+		BytesReceived:    c.bytes.Load(),
+	}
+	for _, cons := range consumers {
+		cons.Lock()
+		for _, pc := range cons.consumers {
+			stats.Reconnects += pc.reconnects.Load()
+		}
+		cons.Unlock()
+	}
+	return stats
+}
@@ -0,0 +1,38 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderStatsCounters(t *testing.T) {
+	var c readerStatsCounters
+	assert.Equal(t, ReaderStats{}, c.stats())
+
+	c.record(&message{payLoad: []byte("hello")})
+	c.record(&message{payLoad: []byte("a")}, &message{payLoad: []byte("bc")})
+	c.record()
+
+	pc := &partitionConsumer{}
+	pc.reconnects.Add(2)
+	cons := &consumer{consumers: []*partitionConsumer{pc, {}}}
+	assert.Equal(t, ReaderStats{MessagesReceived: 3, BytesReceived: 8, Reconnects: 2}, c.stats(cons))
+}
@@ -20,6 +20,7 @@ package pulsar
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -779,6 +780,61 @@ func TestProducerReaderRSAEncryption(t *testing.T) {
 	}
 }
 
+func TestProducerReaderECEncryption(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	// create reader
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+		Decryption: &MessageDecryptionInfo{
+			KeyReader: crypto.NewFileKeyReader("crypto/testdata/pub_key_ec.pem",
+				"crypto/testdata/pri_key_ec.pem"),
+			ConsumerCryptoFailureAction: crypto.ConsumerCryptoFailureActionFail,
+		},
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	// create producer
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+		Encryption: &ProducerEncryptionInfo{
+			KeyReader: crypto.NewFileKeyReader("crypto/testdata/pub_key_ec.pem",
+				"crypto/testdata/pri_key_ec.pem"),
+			ProducerCryptoFailureAction: crypto.ProducerCryptoFailureActionFail,
+			Keys:                        []string{"client-ec.pem"},
+		},
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	// send 10 messages
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	// receive 10 messages
+	for i := 0; i < 10; i++ {
+		msg, err := reader.Next(context.Background())
+		assert.NoError(t, err)
+
+		expectMsg := fmt.Sprintf("hello-%d", i)
+		assert.Equal(t, []byte(expectMsg), msg.Payload())
+	}
+}
+
 func TestReaderWithSchema(t *testing.T) {
 	client, err := NewClient(ClientOptions{
 		URL: lookupURL,
@@ -1035,3 +1091,1367 @@ func TestReaderHasNextRetryFailed(t *testing.T) {
 	}
 
 }
+
+func TestReaderHasNextWithContext(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL:              serviceURL,
+		OperationTimeout: 30 * time.Second,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+	topic := newTopicName()
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+
+	hasNext, err := r.HasNextWithContext(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, hasNext)
+
+	c := make(chan interface{})
+	defer close(c)
+
+	// Close the consumer events loop and assign a mock eventsCh
+	pc := r.(*reader).c.consumers[0]
+	pc.Close()
+	pc.state.Store(consumerReady)
+	pc.eventsCh = c
+
+	go func() {
+		for e := range c {
+			req, ok := e.(*getLastMsgIDRequest)
+			assert.True(t, ok, "unexpected event type")
+			req.err = errors.New("expected error")
+			close(req.doneCh)
+		}
+	}()
+
+	// the deadline of the context bounds the retries, well before the operation timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	hasNext, err = r.HasNextWithContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, hasNext)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestReaderBarrier(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:           topic,
+		StartMessageID:  EarliestMessageID(),
+		BarrierProperty: "barrier",
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 4; i++ {
+		msg := &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		}
+		if i == 1 {
+			msg.Properties = map[string]string{"barrier": "epoch-1"}
+		}
+		_, err := producer.Send(ctx, msg)
+		assert.NoError(t, err)
+	}
+
+	// messages are delivered up to and including the barrier
+	for i := 0; i < 2; i++ {
+		msg, err := reader.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+
+	// the reader is paused until it is resumed
+	timeoutCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	msg, err := reader.Next(timeoutCtx)
+	assert.Nil(t, msg)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	reader.ResumeFromBarrier()
+	for i := 2; i < 4; i++ {
+		msg, err := reader.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+}
+
+func TestReaderSeekByTimeResolved(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	// the topic is empty
+	msgID, err := reader.SeekByTimeResolved(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, LatestMessageID(), msgID)
+
+	for i := 0; i < 5; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	seekTime := time.Now()
+	time.Sleep(100 * time.Millisecond)
+
+	var expectedIDs []MessageID
+	for i := 5; i < 10; i++ {
+		msgID, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+		expectedIDs = append(expectedIDs, msgID)
+	}
+
+	msgID, err = reader.SeekByTimeResolved(seekTime)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedIDs[0].Serialize(), msgID.Serialize())
+
+	// the resolved message is still delivered by Next
+	assert.True(t, reader.HasNext())
+	for i := 5; i < 10; i++ {
+		msg, err := reader.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+}
+
+func TestReaderFilterExpression(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	_, err = client.CreateReader(ReaderOptions{
+		Topic:            newTopicName(),
+		StartMessageID:   EarliestMessageID(),
+		FilterExpression: "region =",
+	})
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		region := "us"
+		if i%3 == 0 {
+			region = "eu"
+		}
+		producer.SendAsync(ctx, &ProducerMessage{
+			Payload:    []byte(fmt.Sprintf("hello-%d", i)),
+			Properties: map[string]string{"region": region},
+		}, func(_ MessageID, _ *ProducerMessage, err error) {
+			assert.NoError(t, err)
+		})
+	}
+	assert.NoError(t, producer.Flush())
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:            topic,
+		StartMessageID:   EarliestMessageID(),
+		FilterExpression: "region = 'eu'",
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	for i := 0; i < 10; i += 3 {
+		msg, err := reader.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+}
+
+func TestReaderProducerNameFilter(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	var producers []Producer
+	for _, name := range []string{"producer-a", "producer-b"} {
+		p, err := client.CreateProducer(ProducerOptions{
+			Topic: topic,
+			Name:  name,
+		})
+		assert.Nil(t, err)
+		defer p.Close()
+		producers = append(producers, p)
+	}
+
+	for i := 0; i < 10; i++ {
+		_, err := producers[i%2].Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:                      topic,
+		StartMessageID:             EarliestMessageID(),
+		ProducerNameFilter:         "producer-b",
+		ProducerNameFilterOnBroker: true,
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	for i := 1; i < 10; i += 2 {
+		msg, err := reader.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "producer-b", msg.ProducerName())
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+}
+
+func TestReaderMaxReadRate(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	_, err = client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+		MaxReadRate:    -1,
+	})
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+		MaxReadRate:    10,
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+	assert.Equal(t, 10, r.(*reader).c.options.ReceiverQueueSize)
+
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		msg, err := r.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+	// the first message is returned right away, the 19 others at 10 messages per second
+	assert.GreaterOrEqual(t, time.Since(start), 1800*time.Millisecond)
+}
+
+func TestReaderAtTime(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	assert.Nil(t, createPartitionedTopic(topic, 3))
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	send := func(i int) {
+		partition := i % 2
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload:   []byte(fmt.Sprintf("hello-%d", i)),
+			Partition: &partition,
+		})
+		assert.NoError(t, err)
+	}
+	for i := 0; i < 6; i++ {
+		send(i)
+	}
+	time.Sleep(100 * time.Millisecond)
+	snapshotTime := time.Now()
+	time.Sleep(100 * time.Millisecond)
+	for i := 6; i < 9; i++ {
+		send(i)
+	}
+
+	reader, err := client.CreateReaderAtTime(ReaderOptions{
+		Topic: topic,
+	}, snapshotTime)
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	// the third partition is empty, the two others are read up to the snapshot time
+	received := map[string]bool{}
+	for i := 0; i < 6; i++ {
+		msg, err := reader.Next(ctx)
+		assert.NoError(t, err)
+		received[string(msg.Payload())] = true
+	}
+	for i := 0; i < 6; i++ {
+		assert.True(t, received[fmt.Sprintf("hello-%d", i)])
+	}
+
+	assert.False(t, reader.HasNext())
+	msg, err := reader.Next(ctx)
+	assert.Nil(t, msg)
+	assert.Equal(t, StopMessageIDReached, err.(*Error).Result())
+}
+
+func TestReaderSaveAndRestoreState(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	for i := 0; i < 4; i++ {
+		msg, err := r.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+	state, err := r.SaveState()
+	assert.NoError(t, err)
+	r.Close()
+
+	_, err = client.RestoreReader(ReaderOptions{Topic: newTopicName()}, state)
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+	_, err = client.RestoreReader(ReaderOptions{}, []byte("not a state"))
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	restored, err := client.RestoreReader(ReaderOptions{}, state)
+	assert.Nil(t, err)
+	defer restored.Close()
+	for i := 4; i < 10; i++ {
+		msg, err := restored.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+	assert.False(t, restored.HasNext())
+}
+
+func TestReaderNextBatch(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	_, err = r.NextBatch(ctx, 0)
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+
+	// the batches do not skip or replay messages, and mix with Next
+	var payloads []string
+	for len(payloads) < 10 {
+		msgs, err := r.NextBatch(ctx, 3)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, msgs)
+		assert.LessOrEqual(t, len(msgs), 3)
+		for _, msg := range msgs {
+			payloads = append(payloads, string(msg.Payload()))
+		}
+		if len(payloads) == 3 {
+			msg, err := r.Next(ctx)
+			assert.NoError(t, err)
+			payloads = append(payloads, string(msg.Payload()))
+		}
+	}
+	for i, payload := range payloads {
+		assert.Equal(t, fmt.Sprintf("hello-%d", i), payload)
+	}
+
+	// the first message is awaited
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = r.NextBatch(timeoutCtx, 3)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestReaderReadReverse(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	var ids []MessageID
+	for i := 0; i < 10; i++ {
+		id, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: LatestMessageID(),
+		ReadReverse:    true,
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	// the messages published after the reader creation are ignored
+	_, err = producer.Send(ctx, &ProducerMessage{Payload: []byte("hello-10")})
+	assert.NoError(t, err)
+
+	for i := 9; i >= 0; i-- {
+		assert.True(t, r.HasNext())
+		msg, err := r.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), msg.Payload())
+	}
+	assert.False(t, r.HasNext())
+	_, err = r.Next(ctx)
+	assert.Equal(t, StopMessageIDReached, err.(*Error).Result())
+
+	r2, err := client.CreateReader(ReaderOptions{
+		Topic:                   topic,
+		StartMessageID:          ids[5],
+		StartMessageIDInclusive: true,
+		ReadReverse:             true,
+	})
+	assert.Nil(t, err)
+	defer r2.Close()
+	msgs, err := r2.NextBatch(ctx, 10)
+	assert.NoError(t, err)
+	for i, msg := range msgs {
+		assert.Equal(t, []byte(fmt.Sprintf("hello-%d", 5-i)), msg.Payload())
+	}
+	assert.Equal(t, OperationNotSupported, r2.Seek(ids[0]).(*Error).Result())
+
+	partitionedTopic := newTopicName()
+	assert.Nil(t, createPartitionedTopic(partitionedTopic, 2))
+	_, err = client.CreateReader(ReaderOptions{
+		Topic:          partitionedTopic,
+		StartMessageID: LatestMessageID(),
+		ReadReverse:    true,
+	})
+	assert.Equal(t, InvalidConfiguration, err.(*Error).Result())
+}
+
+func TestReaderTell(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	assert.Nil(t, createPartitionedTopic(topic, 3))
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 30; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	seen := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		msg, err := r.Next(ctx)
+		assert.NoError(t, err)
+		seen[string(msg.Payload())] = true
+	}
+	pos, err := r.Tell()
+	assert.NoError(t, err)
+	r.Close()
+
+	// the position survives a round-trip through its serialized form
+	pos, err = DeserializeMessageID(pos.Serialize())
+	assert.NoError(t, err)
+
+	resumed, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: pos,
+	})
+	assert.Nil(t, err)
+	defer resumed.Close()
+	for i := 0; i < 20; i++ {
+		msg, err := resumed.Next(ctx)
+		assert.NoError(t, err)
+		assert.False(t, seen[string(msg.Payload())])
+		seen[string(msg.Payload())] = true
+	}
+	assert.Len(t, seen, 30)
+	assert.False(t, resumed.HasNext())
+}
+
+func TestReaderStartMessageTime(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	_, err = client.CreateReader(ReaderOptions{
+		Topic:            topic,
+		StartMessageID:   EarliestMessageID(),
+		StartMessageTime: time.Now(),
+	})
+	assert.Error(t, err)
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("before-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	startTime := time.Now()
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("after-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:            topic,
+		StartMessageTime: startTime,
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	assert.True(t, r.HasNext())
+	for i := 0; i < 10; i++ {
+		msg, err := r.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("after-%d", i), string(msg.Payload()))
+	}
+	assert.False(t, r.HasNext())
+}
+
+func TestTypedReader(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	_, err = NewTypedReader[testJSON](client, ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Error(t, err)
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:  topic,
+		Schema: NewJSONSchema(exampleSchemaDef, nil),
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{
+			Value: &testJSON{ID: i, Name: "pulsar"},
+			Key:   fmt.Sprintf("key-%d", i),
+		})
+		assert.NoError(t, err)
+	}
+
+	r, err := NewTypedReader[testJSON](client, ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+		Schema:         NewJSONSchema(exampleSchemaDef, nil),
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	for i := 0; i < 5; i++ {
+		value, msg, err := r.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testJSON{ID: i, Name: "pulsar"}, value)
+		assert.Equal(t, fmt.Sprintf("key-%d", i), msg.Key())
+	}
+	assert.False(t, r.HasNext())
+}
+
+func TestReaderMultipleTopics(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topics := []string{newTopicName(), newTopicName()}
+	ctx := context.Background()
+
+	_, err = client.CreateReader(ReaderOptions{
+		Topic:          topics[0],
+		Topics:         topics,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Error(t, err)
+
+	expected := make(map[string]bool)
+	for _, topic := range topics {
+		producer, err := client.CreateProducer(ProducerOptions{
+			Topic:           topic,
+			DisableBatching: true,
+		})
+		assert.Nil(t, err)
+		for i := 0; i < 5; i++ {
+			payload := fmt.Sprintf("%s-%d", topic, i)
+			_, err := producer.Send(ctx, &ProducerMessage{
+				Payload: []byte(payload),
+			})
+			assert.NoError(t, err)
+			expected[payload] = true
+		}
+		producer.Close()
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topics:         topics,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	received := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		assert.True(t, r.HasNext())
+		msg, err := r.Next(ctx)
+		assert.NoError(t, err)
+		received[string(msg.Payload())] = true
+	}
+	assert.Equal(t, expected, received)
+	assert.False(t, r.HasNext())
+
+	_, err = r.GetLastMessageID()
+	assert.Error(t, err)
+}
+
+func TestReaderTopicsPattern(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	prefix := fmt.Sprintf("persistent://public/default/reader-pattern-%d", time.Now().UnixNano())
+	ctx := context.Background()
+	send := func(topic, payload string) {
+		producer, err := client.CreateProducer(ProducerOptions{
+			Topic: topic,
+		})
+		assert.Nil(t, err)
+		defer producer.Close()
+		_, err = producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(payload),
+		})
+		assert.NoError(t, err)
+	}
+
+	send(prefix+"-a", "a")
+
+	r, err := client.CreateReader(ReaderOptions{
+		TopicsPattern:                      prefix + "-.*",
+		TopicsPatternAutoDiscoveryInterval: time.Second,
+		StartMessageID:                     EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	msg, err := r.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(msg.Payload()))
+
+	// the topic created after the reader is discovered, and read from the earliest message
+	send(prefix+"-b", "b")
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	msg, err = r.Next(timeoutCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(msg.Payload()))
+
+	_, err = client.CreateReader(ReaderOptions{
+		Topics:         []string{prefix + "-a"},
+		TopicsPattern:  prefix + "-.*",
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Error(t, err)
+}
+
+func TestReaderSeekByTimeWithContext(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: serviceURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          newTopicName(),
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+
+	assert.NoError(t, r.SeekByTimeWithContext(context.Background(), time.Now()))
+
+	// Close the consumer events loop and assign an eventsCh that is never read
+	pc := r.(*reader).c.consumers[0]
+	pc.Close()
+	pc.state.Store(consumerReady)
+	pc.eventsCh = make(chan interface{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = r.SeekByTimeWithContext(ctx, time.Now())
+	var pulsarErr *Error
+	assert.True(t, errors.As(err, &pulsarErr))
+	assert.Equal(t, TimeoutError, pulsarErr.Result())
+	var seekErr PartitionedSeekError
+	assert.True(t, errors.As(err, &seekErr))
+	assert.Empty(t, seekErr.Succeeded())
+}
+
+func TestReaderMessageChannel(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic: topic,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := producer.Send(context.Background(), &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+
+	ch := r.MessageChannel()
+	for i := 0; i < 5; i++ {
+		select {
+		case rm := <-ch:
+			assert.NoError(t, rm.Err)
+			assert.Equal(t, []byte(fmt.Sprintf("hello-%d", i)), rm.Payload())
+			assert.Equal(t, r, rm.Reader)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "message not received")
+		}
+	}
+
+	r.Close()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestReaderStats(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{Payload: []byte("hello")})
+		assert.Nil(t, err)
+	}
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	_, err = reader.Next(ctx)
+	assert.Nil(t, err)
+	msgs, err := reader.NextBatch(ctx, 4)
+	assert.Nil(t, err)
+
+	stats := reader.Stats()
+	assert.Equal(t, int64(1+len(msgs)), stats.MessagesReceived)
+	assert.Equal(t, int64(5*(1+len(msgs))), stats.BytesReceived)
+	assert.Equal(t, int64(0), stats.Reconnects)
+}
+
+func TestReaderMetadataOnly(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		Schema:          NewStringSchema(nil),
+		CompressionType: LZ4,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		producer.SendAsync(ctx, &ProducerMessage{
+			Value:      fmt.Sprintf("hello-%d", i),
+			Key:        fmt.Sprintf("key-%d", i),
+			Properties: map[string]string{"idx": fmt.Sprint(i)},
+		}, nil)
+	}
+	assert.Nil(t, producer.Flush())
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+		Schema:         NewStringSchema(nil),
+		MetadataOnly:   true,
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	for i := 0; i < 10; i++ {
+		msg, err := reader.Next(ctx)
+		assert.Nil(t, err)
+		assert.Nil(t, msg.Payload())
+		assert.Equal(t, fmt.Sprintf("key-%d", i), msg.Key())
+		assert.Equal(t, fmt.Sprint(i), msg.Properties()["idx"])
+
+		var v string
+		assert.Error(t, msg.GetSchemaValue(&v))
+	}
+}
+
+func TestReaderEndMessageID(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	var msgIDs []MessageID
+	for i := 0; i < 10; i++ {
+		msgID, err := producer.Send(ctx, &ProducerMessage{
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		})
+		assert.Nil(t, err)
+		msgIDs = append(msgIDs, msgID)
+	}
+
+	for _, inclusive := range []bool{false, true} {
+		reader, err := client.CreateReader(ReaderOptions{
+			Topic:                 topic,
+			StartMessageID:        EarliestMessageID(),
+			EndMessageID:          msgIDs[5],
+			EndMessageIDInclusive: inclusive,
+		})
+		assert.Nil(t, err)
+
+		expected := 5
+		if inclusive {
+			expected = 6
+		}
+		for i := 0; i < expected; i++ {
+			assert.True(t, reader.HasNext())
+			msg, err := reader.Next(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, fmt.Sprintf("hello-%d", i), string(msg.Payload()))
+		}
+
+		msg, err := reader.Next(ctx)
+		assert.Nil(t, msg)
+		assert.True(t, errors.Is(err, ErrEndMessageIDReached))
+		assert.False(t, reader.HasNext())
+		reader.Close()
+	}
+}
+
+func TestReaderEndMessageIDPartitioned(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	assert.Nil(t, createPartitionedTopic(topic, 2))
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	ends := &partitionedMessageID{}
+	for i := 0; i < 10; i++ {
+		partition := i % 2
+		msgID, err := producer.Send(ctx, &ProducerMessage{
+			Payload:   []byte(fmt.Sprintf("hello-%d", i)),
+			Partition: &partition,
+		})
+		assert.Nil(t, err)
+		// end at the third message of each partition
+		if i/2 == 2 {
+			ends.ids = append(ends.ids, fromMessageID(msgID))
+		}
+	}
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:                 topic,
+		StartMessageID:        EarliestMessageID(),
+		EndMessageID:          ends,
+		EndMessageIDInclusive: true,
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	received := map[string]bool{}
+	for reader.HasNext() {
+		msg, err := reader.Next(ctx)
+		if errors.Is(err, ErrEndMessageIDReached) {
+			break
+		}
+		assert.Nil(t, err)
+		received[string(msg.Payload())] = true
+	}
+	assert.Len(t, received, 6)
+	for i := 0; i < 6; i++ {
+		assert.True(t, received[fmt.Sprintf("hello-%d", i)])
+	}
+}
+
+func TestReaderOnReachedEndOfTopic(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			_, err := producer.Send(ctx, &ProducerMessage{Payload: []byte("hello")})
+			assert.Nil(t, err)
+		}
+	}
+	send(3)
+
+	reached := make(chan Reader, 10)
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+		OnReachedEndOfTopic: func(r Reader) {
+			reached <- r
+		},
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	for i := 0; i < 3; i++ {
+		assert.Len(t, reached, 0)
+		_, err := reader.Next(ctx)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, reached, 1)
+	assert.Equal(t, reader, <-reached)
+
+	// polling HasNext does not call it again
+	assert.False(t, reader.HasNext())
+	assert.Len(t, reached, 0)
+
+	// it is called again once the reader catches up
+	send(2)
+	msgs, err := reader.NextBatch(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, msgs, 1)
+	assert.Len(t, reached, 0)
+	_, err = reader.Next(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, reached, 1)
+}
+
+func TestReaderBatchIndexAcknowledgment(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:                   topic,
+		BatchingMaxPublishDelay: time.Second,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		producer.SendAsync(ctx, &ProducerMessage{Payload: []byte(fmt.Sprintf("hello-%d", i))}, nil)
+	}
+	assert.Nil(t, producer.Flush())
+
+	reader, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	assert.False(t, reader.BatchIndexAcknowledgmentEnabled())
+	reader.Close()
+
+	reader, err = client.CreateReader(ReaderOptions{
+		Topic:                          topic,
+		StartMessageID:                 EarliestMessageID(),
+		SubscriptionName:               "batch-index-ack-reader",
+		EnableBatchIndexAcknowledgment: true,
+	})
+	assert.Nil(t, err)
+	defer reader.Close()
+	assert.True(t, reader.BatchIndexAcknowledgmentEnabled())
+
+	for i := 0; i < 10; i++ {
+		msg, err := reader.Next(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("hello-%d", i), string(msg.Payload()))
+	}
+}
+
+func TestReaderMaxReceiverQueueSizeBytes(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	_, err = client.CreateReader(ReaderOptions{
+		Topic:                     topic,
+		StartMessageID:            EarliestMessageID(),
+		MaxReceiverQueueSizeBytes: -1,
+	})
+	assert.Error(t, err)
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	payload := make([]byte, 100*1024)
+	for i := 0; i < 20; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{Payload: payload})
+		assert.Nil(t, err)
+	}
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:                     topic,
+		StartMessageID:            EarliestMessageID(),
+		ReceiverQueueSize:         4,
+		MaxReceiverQueueSizeBytes: 200 * 1024,
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	// the flow pauses and resumes as the messages are read
+	for i := 0; i < 20; i++ {
+		msg, err := r.Next(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, msg.Payload(), len(payload))
+
+		pc := r.(*reader).c.consumers[0]
+		assert.LessOrEqual(t, pc.queuedBytes.Load(), int64(200*1024+4*len(payload)))
+	}
+	assert.False(t, r.HasNext())
+}
+
+func TestReaderAutoScaledReceiverQueueSize(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	ctx := context.Background()
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:                             topic,
+		StartMessageID:                    EarliestMessageID(),
+		ReceiverQueueSize:                 4,
+		EnableAutoScaledReceiverQueueSize: true,
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+	pc := r.(*reader).c.consumers[0]
+	assert.Equal(t, int32(1), pc.currentQueueSize.Load())
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:           topic,
+		DisableBatching: true,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := producer.Send(ctx, &ProducerMessage{Payload: []byte(fmt.Sprintf("hello-%d", i))})
+		assert.Nil(t, err)
+	}
+
+	// the queue grows up to ReceiverQueueSize as the messages are read
+	for i := 0; i < 10; i++ {
+		msg, err := r.Next(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("hello-%d", i), string(msg.Payload()))
+	}
+	retryAssert(t, 5, 200, func() {}, func(t assert.TestingT) bool {
+		return assert.Greater(t, pc.currentQueueSize.Load(), int32(1))
+	})
+	assert.LessOrEqual(t, pc.currentQueueSize.Load(), int32(4))
+}
+
+func TestReaderMessagePartitionIndex(t *testing.T) {
+	client, err := NewClient(ClientOptions{
+		URL: lookupURL,
+	})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	assert.Nil(t, createPartitionedTopic(topic, 3))
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:                   topic,
+		BatchingMaxPublishDelay: time.Second,
+	})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	for i := 0; i < 30; i++ {
+		producer.SendAsync(ctx, &ProducerMessage{
+			Key:     fmt.Sprintf("key-%d", i),
+			Payload: []byte(fmt.Sprintf("hello-%d", i)),
+		}, nil)
+	}
+	assert.Nil(t, producer.Flush())
+
+	r, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	for i := 0; i < 30; i++ {
+		msg, err := r.Next(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, int(msg.ID().PartitionIdx()), msg.PartitionIndex())
+		assert.True(t, strings.HasSuffix(msg.Topic(), fmt.Sprintf("%s-partition-%d", topic, msg.PartitionIndex())))
+	}
+
+	// a non-partitioned topic has no partition index
+	topic = newTopicName()
+	single, err := client.CreateProducer(ProducerOptions{Topic: topic})
+	assert.Nil(t, err)
+	defer single.Close()
+	_, err = single.Send(ctx, &ProducerMessage{Payload: []byte("hello")})
+	assert.Nil(t, err)
+
+	r2, err := client.CreateReader(ReaderOptions{
+		Topic:          topic,
+		StartMessageID: EarliestMessageID(),
+	})
+	assert.Nil(t, err)
+	defer r2.Close()
+	msg, err := r2.Next(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, -1, msg.PartitionIndex())
+}
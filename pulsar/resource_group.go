@@ -0,0 +1,151 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ErrResourceGroupClosed is returned when creating a resource through a ResourceGroup that was closed
+var ErrResourceGroupClosed = newError(AlreadyClosedError, "resource group already closed")
+
+// ResourceGroup creates producers, consumers and readers through its client and closes all of them at once,
+// to tie their lifecycle to the component that uses them.
+type ResourceGroup interface {
+	// CreateProducer creates a producer as Client.CreateProducer does, and adds it to the group
+	CreateProducer(ProducerOptions) (Producer, error)
+
+	// Subscribe creates a consumer as Client.Subscribe does, and adds it to the group
+	Subscribe(ConsumerOptions) (Consumer, error)
+
+	// CreateReader creates a reader as Client.CreateReader does, and adds it to the group
+	CreateReader(ReaderOptions) (Reader, error)
+
+	// Close closes the producers, consumers and readers created by the group, in the reverse order of their
+	// creation, and returns the errors of the ones that failed to close. The client itself is not closed.
+	//
+	// Once closed, the group can no longer create resources and returns ErrResourceGroupClosed.
+	Close() error
+}
+
+// groupResource is either a producer, a consumer or a reader
+type groupResource interface {
+	Close()
+}
+
+type resourceGroup struct {
+	client    *client
+	mu        sync.Mutex
+	resources []groupResource
+	closed    bool
+}
+
+func newResourceGroup(client *client) *resourceGroup {
+	return &resourceGroup{client: client}
+}
+
+func (g *resourceGroup) CreateProducer(options ProducerOptions) (Producer, error) {
+	if g.isClosed() {
+		return nil, ErrResourceGroupClosed
+	}
+	producer, err := g.client.CreateProducer(options)
+	if err != nil {
+		return nil, err
+	}
+	return producer, g.add(producer)
+}
+
+func (g *resourceGroup) Subscribe(options ConsumerOptions) (Consumer, error) {
+	if g.isClosed() {
+		return nil, ErrResourceGroupClosed
+	}
+	consumer, err := g.client.Subscribe(options)
+	if err != nil {
+		return nil, err
+	}
+	return consumer, g.add(consumer)
+}
+
+func (g *resourceGroup) CreateReader(options ReaderOptions) (Reader, error) {
+	if g.isClosed() {
+		return nil, ErrResourceGroupClosed
+	}
+	reader, err := g.client.CreateReader(options)
+	if err != nil {
+		return nil, err
+	}
+	return reader, g.add(reader)
+}
+
+func (g *resourceGroup) isClosed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.closed
+}
+
+// add registers a resource created by the group. If the group was closed in the meantime, the resource is
+// closed right away and an error is returned, so that it is not leaked.
+func (g *resourceGroup) add(r groupResource) error {
+	g.mu.Lock()
+	if !g.closed {
+		g.resources = append(g.resources, r)
+		g.mu.Unlock()
+		return nil
+	}
+	g.mu.Unlock()
+
+	if err := closeResource(r); err != nil {
+		return joinErrors(ErrResourceGroupClosed, err)
+	}
+	return ErrResourceGroupClosed
+}
+
+func (g *resourceGroup) Close() error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return nil
+	}
+	g.closed = true
+	resources := g.resources
+	g.resources = nil
+	g.mu.Unlock()
+
+	var errs *multierror.Error
+	for i := len(resources) - 1; i >= 0; i-- {
+		if err := closeResource(resources[i]); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// closeResource closes a producer, a consumer or a reader, turning a panic during the close into an error so
+// that the other resources of the group are still closed.
+func closeResource(r groupResource) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = newError(UnknownError, fmt.Sprintf("failed to close %T: %v", r, p))
+		}
+	}()
+	r.Close()
+	return nil
+}
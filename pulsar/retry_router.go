@@ -38,6 +38,56 @@ const (
 	PropertyOriginMessageID    = "ORIGIN_MESSAGE_ID"
 )
 
+// DLQTopicName returns the dead letter topic of a consumer created with the options: DLQPolicy.DeadLetterTopic when
+// it is set, else, when RetryEnable is set, the "<topic>-<subscription>-DLQ" topic of the first topic of the
+// options. It returns an InvalidConfiguration error when the options do not configure a dead letter topic.
+//
+// When RetryEnable is set, a consumer uses instead the "<namespace>/<subscription>-DLQ" topic of older clients if
+// it exists as a partitioned topic, which this function cannot check: Client.CreateDLQReader does.
+func DLQTopicName(options ConsumerOptions) (string, error) {
+	return dlqTopicName(options, nil)
+}
+
+// dlqTopicName returns the dead letter topic of a consumer created with the options. The legacy namespace-level
+// topic is only considered when the lookup service is set.
+func dlqTopicName(options ConsumerOptions, lookupService internal.LookupService) (string, error) {
+	if options.DLQ != nil && options.DLQ.DeadLetterTopic != "" {
+		return options.DLQ.DeadLetterTopic, nil
+	}
+	if !options.RetryEnable {
+		return "", newError(InvalidConfiguration, "no dead letter topic is configured")
+	}
+	if options.SubscriptionName == "" {
+		return "", newError(SubscriptionNotFound, "subscription name is required for consumer")
+	}
+	return retryLetterTopicName(options, DlqTopicSuffix, lookupService)
+}
+
+// retryLetterTopicName returns the default retry or dead letter topic, depending on the suffix, of the
+// subscription on the first topic of the options. It returns the namespace-level topic of older clients instead
+// if the lookup service is set and finds it as a partitioned topic.
+func retryLetterTopicName(options ConsumerOptions, suffix string, lookupService internal.LookupService) (string,
+	error) {
+	usingTopic := options.Topic
+	if usingTopic == "" && len(options.Topics) > 0 {
+		usingTopic = options.Topics[0]
+	}
+	tn, err := internal.ParseTopicName(usingTopic)
+	if err != nil {
+		return "", err
+	}
+
+	if lookupService != nil {
+		oldTopic := tn.Domain + "://" + tn.Namespace + "/" + options.SubscriptionName + suffix
+		if r, err := lookupService.GetPartitionedTopicMetadata(oldTopic); err == nil &&
+			r != nil &&
+			r.Partitions > 0 {
+			return oldTopic, nil
+		}
+	}
+	return internal.TopicNameWithoutPartitionPart(tn) + "-" + options.SubscriptionName + suffix, nil
+}
+
 type RetryMessage struct {
 	producerMsg ProducerMessage
 	consumerMsg ConsumerMessage
@@ -19,11 +19,14 @@ package pulsar
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"hash/maphash"
+	"math"
 	"reflect"
 	"sync"
+	"time"
 	"unsafe"
 
 	log "github.com/sirupsen/logrus"
@@ -50,9 +53,9 @@ const (
 	INT64                         //A 64-byte integer.
 	FLOAT                         //A float number.
 	DOUBLE                        //A double number
+	DATE                          //A date, as days since the epoch.
 	_                             //
-	_                             //
-	_                             //
+	TIMESTAMP                     //A timestamp, as milliseconds since the epoch.
 	KeyValue                      //A Schema that contains Key Schema and Value Schema.
 	BYTES       = 0               //A bytes array.
 	AUTO        = -2              //
@@ -61,6 +64,17 @@ const (
 	ProtoNative = 20              //Protobuf native message encoding and decoding
 )
 
+// schemaVersionSize is the size of the schema versions, which are big-endian 64 bits integers
+const schemaVersionSize = 8
+
+// rawSchemaVersion is the schema version marker attached to messages published with
+// `ProducerMessage.SkipSchema`. It encodes the version -1, which the broker never assigns.
+var rawSchemaVersion = []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+func isRawSchemaVersion(schemaVersion []byte) bool {
+	return bytes.Equal(schemaVersion, rawSchemaVersion)
+}
+
 // Encapsulates data around the schema definition
 type SchemaInfo struct {
 	Name       string
@@ -89,6 +103,12 @@ type Schema interface {
 	GetSchemaInfo() *SchemaInfo
 }
 
+// schemaResolver is implemented by the schemas decoding the data written with the schema of another version
+// into their own shape
+type schemaResolver interface {
+	decodeFrom(writer Schema, data []byte, v interface{}) error
+}
+
 func NewSchema(schemaType SchemaType, schemaData []byte, properties map[string]string) (schema Schema, err error) {
 	var schemaDef = string(schemaData)
 	var s Schema
@@ -115,8 +135,14 @@ func NewSchema(schemaType SchemaType, schemaData []byte, properties map[string]s
 		s = NewFloatSchema(properties)
 	case DOUBLE:
 		s = NewDoubleSchema(properties)
+	case DATE:
+		s = NewDateSchema(properties)
+	case TIMESTAMP:
+		s = NewTimestampSchema(properties)
 	case ProtoNative:
 		s = newProtoNativeSchema(schemaDef, properties)
+	case KeyValue:
+		s, err = newKeyValueSchemaFromInfo(schemaData, properties)
 	default:
 		err = fmt.Errorf("not support schema type of %v", schemaType)
 	}
@@ -144,6 +170,8 @@ func initAvroCodec(codec string) (*goavro.Codec, error) {
 type JSONSchema struct {
 	AvroCodec
 	SchemaInfo
+	// strict rejects the data with fields unknown to the decoded value
+	strict bool
 }
 
 // NewJSONSchema creates a new JSONSchema
@@ -171,12 +199,37 @@ func NewJSONSchemaWithValidation(jsonAvroSchemaDef string, properties map[string
 	return js, nil
 }
 
+// NewJSONSchemaStrict creates a new JSONSchema whose decoding fails when the data has fields that the decoded
+// value does not define, the error naming the unknown field.
+// Note: the function will panic if creation of codec fails
+func NewJSONSchemaStrict(jsonAvroSchemaDef string, properties map[string]string) *JSONSchema {
+	js := NewJSONSchema(jsonAvroSchemaDef, properties)
+	js.strict = true
+	return js
+}
+
 func (js *JSONSchema) Encode(data interface{}) ([]byte, error) {
 	return json.Marshal(data)
 }
 
 func (js *JSONSchema) Decode(data []byte, v interface{}) error {
-	return json.Unmarshal(data, v)
+	if !js.strict {
+		return json.Unmarshal(data, v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return newError(SchemaFailure, fmt.Sprintf("JSON data does not match the strict schema: %v", err))
+	}
+	return nil
+}
+
+func (js *JSONSchema) decodeFrom(writer Schema, data []byte, v interface{}) error {
+	if js.strict {
+		// the data is decoded by its own fields, the schema it was written with does not matter
+		return js.Decode(data, v)
+	}
+	return writer.Decode(data, v)
 }
 
 func (js *JSONSchema) Validate(message []byte) error {
@@ -317,6 +370,9 @@ func (ps *ProtoNativeSchema) GetSchemaInfo() *SchemaInfo {
 type AvroSchema struct {
 	AvroCodec
 	SchemaInfo
+	// writer is the codec of the data decoded without a schema version, when the schema resolves the data
+	// written with other schemas into its own
+	writer *goavro.Codec
 }
 
 // NewAvroSchema creates a new AvroSchema
@@ -345,6 +401,21 @@ func NewAvroSchemaWithValidation(avroSchemaDef string, properties map[string]str
 	return as, nil
 }
 
+// NewAvroSchemaWithReaderWriter creates an AvroSchema decoding the data written with another schema into the
+// reader schema, applying the defaults of the reader schema for the fields missing from the data. The writer
+// schema of a message is resolved from its schema version, writerDef only being used for the data without a
+// schema version. The reader schema is the one registered, and used to encode the values.
+func NewAvroSchemaWithReaderWriter(writerDef, readerDef string, properties map[string]string) (*AvroSchema, error) {
+	as, err := NewAvroSchemaWithValidation(readerDef, properties)
+	if err != nil {
+		return nil, err
+	}
+	if as.writer, err = initAvroCodec(writerDef); err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
 func (as *AvroSchema) Encode(data interface{}) ([]byte, error) {
 	textual, err := json.Marshal(data)
 	if err != nil {
@@ -360,6 +431,13 @@ func (as *AvroSchema) Encode(data interface{}) ([]byte, error) {
 }
 
 func (as *AvroSchema) Decode(data []byte, v interface{}) error {
+	if as.writer != nil {
+		return as.decodeWithWriter(as.writer, data, v)
+	}
+	return as.decode(data, v)
+}
+
+func (as *AvroSchema) decode(data []byte, v interface{}) error {
 	native, _, err := as.Codec.NativeFromBinary(data)
 	if err != nil {
 		log.Errorf("convert binary Avro data back to native Go form error:%s", err.Error())
@@ -378,6 +456,30 @@ func (as *AvroSchema) Decode(data []byte, v interface{}) error {
 	return nil
 }
 
+func (as *AvroSchema) decodeFrom(writer Schema, data []byte, v interface{}) error {
+	writerSchema, ok := writer.(*AvroSchema)
+	if as.writer == nil || !ok {
+		return writer.Decode(data, v)
+	}
+	return as.decodeWithWriter(writerSchema.Codec, data, v)
+}
+
+// decodeWithWriter re-encodes the data with the reader schema, which drops the fields it does not have and
+// sets the missing ones to their default, before decoding it
+func (as *AvroSchema) decodeWithWriter(writer *goavro.Codec, data []byte, v interface{}) error {
+	native, _, err := writer.NativeFromBinary(data)
+	if err != nil {
+		log.Errorf("convert binary Avro data back to native Go form error:%s", err.Error())
+		return err
+	}
+	resolved, err := as.Codec.BinaryFromNative(nil, native)
+	if err != nil {
+		log.Errorf("resolve Avro data with the reader schema error:%s", err.Error())
+		return err
+	}
+	return as.decode(resolved, v)
+}
+
 func (as *AvroSchema) Validate(message []byte) error {
 	return as.Decode(message, nil)
 }
@@ -663,3 +765,517 @@ func (ds *DoubleSchema) Validate(message []byte) error {
 func (ds *DoubleSchema) GetSchemaInfo() *SchemaInfo {
 	return &ds.SchemaInfo
 }
+
+type TimestampSchema struct {
+	SchemaInfo
+}
+
+// NewTimestampSchema creates a schema encoding a time.Time (or *time.Time) as the number of milliseconds since the
+// epoch, which is decoded into a *time.Time.
+func NewTimestampSchema(properties map[string]string) *TimestampSchema {
+	timestampSchema := new(TimestampSchema)
+	timestampSchema.SchemaInfo.Properties = properties
+	timestampSchema.SchemaInfo.Type = TIMESTAMP
+	timestampSchema.SchemaInfo.Name = "Timestamp"
+	timestampSchema.SchemaInfo.Schema = ""
+	return timestampSchema
+}
+
+func (ts *TimestampSchema) Encode(value interface{}) ([]byte, error) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return nil, newError(SchemaFailure, "TimestampSchema can not encode a nil *time.Time")
+		}
+		t = *v
+	default:
+		return nil, newError(SchemaFailure, fmt.Sprintf("TimestampSchema can not encode a value of type %T", value))
+	}
+	var buf bytes.Buffer
+	// unlike UnixNano, does not overflow for the times outside of the years 1678 to 2262
+	err := WriteElements(&buf, t.Unix()*1000+int64(t.Nanosecond())/int64(time.Millisecond))
+	return buf.Bytes(), err
+}
+
+func (ts *TimestampSchema) Decode(data []byte, v interface{}) error {
+	t, ok := v.(*time.Time)
+	if !ok || t == nil {
+		return newError(SchemaFailure, fmt.Sprintf("TimestampSchema can not decode into a value of type %T", v))
+	}
+	var millis int64
+	if err := ReadElements(bytes.NewReader(data), &millis); err != nil {
+		return err
+	}
+	*t = time.Unix(millis/1000, (millis%1000)*int64(time.Millisecond))
+	return nil
+}
+
+func (ts *TimestampSchema) Validate(message []byte) error {
+	if len(message) != 8 {
+		return newError(InvalidMessage, "size of data received by TimestampSchema is not 8")
+	}
+	return nil
+}
+
+func (ts *TimestampSchema) GetSchemaInfo() *SchemaInfo {
+	return &ts.SchemaInfo
+}
+
+type DateSchema struct {
+	SchemaInfo
+}
+
+const millisPerDay = int64(24 * time.Hour / time.Millisecond)
+
+// NewDateSchema creates a schema encoding the day of a time.Time (or *time.Time) as the number of days since the
+// epoch, which is decoded into a *time.Time at midnight UTC. The dates encoded as milliseconds since the epoch,
+// as published by the Java client, are decoded as well.
+func NewDateSchema(properties map[string]string) *DateSchema {
+	dateSchema := new(DateSchema)
+	dateSchema.SchemaInfo.Properties = properties
+	dateSchema.SchemaInfo.Type = DATE
+	dateSchema.SchemaInfo.Name = "Date"
+	dateSchema.SchemaInfo.Schema = ""
+	return dateSchema
+}
+
+func (ds *DateSchema) Encode(value interface{}) ([]byte, error) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return nil, newError(SchemaFailure, "DateSchema can not encode a nil *time.Time")
+		}
+		t = *v
+	default:
+		return nil, newError(SchemaFailure, fmt.Sprintf("DateSchema can not encode a value of type %T", value))
+	}
+	// the day of the date in its own location
+	year, month, day := t.Date()
+	seconds := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix()
+	var buf bytes.Buffer
+	err := WriteElements(&buf, int32(seconds*1000/millisPerDay))
+	return buf.Bytes(), err
+}
+
+func (ds *DateSchema) Decode(data []byte, v interface{}) error {
+	t, ok := v.(*time.Time)
+	if !ok || t == nil {
+		return newError(SchemaFailure, fmt.Sprintf("DateSchema can not decode into a value of type %T", v))
+	}
+	var days int64
+	if len(data) == 8 {
+		var millis int64
+		if err := ReadElements(bytes.NewReader(data), &millis); err != nil {
+			return err
+		}
+		days = millis / millisPerDay
+		if millis%millisPerDay < 0 {
+			days--
+		}
+	} else {
+		var d int32
+		if err := ReadElements(bytes.NewReader(data), &d); err != nil {
+			return err
+		}
+		days = int64(d)
+	}
+	*t = time.Unix(days*millisPerDay/1000, 0).UTC()
+	return nil
+}
+
+func (ds *DateSchema) Validate(message []byte) error {
+	if len(message) != 4 && len(message) != 8 {
+		return newError(InvalidMessage, "size of data received by DateSchema is not 4 or 8")
+	}
+	return nil
+}
+
+func (ds *DateSchema) GetSchemaInfo() *SchemaInfo {
+	return &ds.SchemaInfo
+}
+
+// KeyValueEncodingType is the way a KeyValueSchema encodes the key of its values
+type KeyValueEncodingType int
+
+const (
+	// KeyValueInline encodes the key and the value together in the payload of the messages
+	KeyValueInline KeyValueEncodingType = iota
+	// KeyValueSeparated encodes the value in the payload of the messages and the key in their key, base64 encoded
+	KeyValueSeparated
+)
+
+func (e KeyValueEncodingType) String() string {
+	if e == KeyValueSeparated {
+		return "SEPARATED"
+	}
+	return "INLINE"
+}
+
+const (
+	kvEncodingTypeProperty = "kv.encoding.type"
+	kvKeySchemaPrefix      = "key.schema."
+	kvValueSchemaPrefix    = "value.schema."
+)
+
+// schemaTypeNames are the names of the schema types in the properties of the KeyValue schemas
+var schemaTypeNames = map[SchemaType]string{
+	BYTES:       "BYTES",
+	STRING:      "STRING",
+	JSON:        "JSON",
+	PROTOBUF:    "PROTOBUF",
+	AVRO:        "AVRO",
+	BOOLEAN:     "BOOLEAN",
+	INT8:        "INT8",
+	INT16:       "INT16",
+	INT32:       "INT32",
+	INT64:       "INT64",
+	FLOAT:       "FLOAT",
+	DOUBLE:      "DOUBLE",
+	DATE:        "DATE",
+	TIMESTAMP:   "TIMESTAMP",
+	KeyValue:    "KEY_VALUE",
+	ProtoNative: "PROTOBUF_NATIVE",
+}
+
+// KeyValuePair is the value of the messages produced and consumed with a KeyValueSchema. To decode it with
+// Message.GetSchemaValue, Key and Value are set to pointers to the types the key and value schemas decode into;
+// when left nil, they are decoded into an interface{}.
+type KeyValuePair struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// KeyValueSchema is the schema of the messages whose key and value have their own schema
+type KeyValueSchema struct {
+	SchemaInfo
+	keySchema   Schema
+	valueSchema Schema
+	encoding    KeyValueEncodingType
+}
+
+// NewKeyValueSchema creates a schema encoding the KeyValuePair values with the key and value schemas. With
+// KeyValueInline, the encoded key and value are both in the payload of the messages. With KeyValueSeparated, the
+// payload only holds the encoded value while the encoded key is the key of the messages, which overrides
+// ProducerMessage.Key; as the key is encoded after the partition of a message is chosen, such messages are not
+// routed by their key.
+func NewKeyValueSchema(keySchema, valueSchema Schema, encodingType KeyValueEncodingType) *KeyValueSchema {
+	keyInfo := keySchema.GetSchemaInfo()
+	valueInfo := valueSchema.GetSchemaInfo()
+	properties := map[string]string{kvEncodingTypeProperty: encodingType.String()}
+	addKeyValueSchemaProperties(properties, kvKeySchemaPrefix, keyInfo)
+	addKeyValueSchemaProperties(properties, kvValueSchemaPrefix, valueInfo)
+
+	kvSchema := &KeyValueSchema{
+		keySchema:   keySchema,
+		valueSchema: valueSchema,
+		encoding:    encodingType,
+	}
+	kvSchema.SchemaInfo.Properties = properties
+	kvSchema.SchemaInfo.Type = KeyValue
+	kvSchema.SchemaInfo.Name = "KeyValue"
+	kvSchema.SchemaInfo.Schema = string(encodeKeyValue([]byte(keyInfo.Schema), []byte(valueInfo.Schema)))
+	return kvSchema
+}
+
+func addKeyValueSchemaProperties(properties map[string]string, prefix string, info *SchemaInfo) {
+	properties[prefix+"name"] = info.Name
+	properties[prefix+"type"] = schemaTypeNames[info.Type]
+	schemaProperties := info.Properties
+	if schemaProperties == nil {
+		schemaProperties = map[string]string{}
+	}
+	encoded, _ := json.Marshal(schemaProperties)
+	properties[prefix+"properties"] = string(encoded)
+}
+
+// newKeyValueSchemaFromInfo creates the KeyValueSchema of a schema registered on a topic
+func newKeyValueSchemaFromInfo(schemaData []byte, properties map[string]string) (*KeyValueSchema, error) {
+	keyData, valueData, err := decodeKeyValue(schemaData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KeyValue schema data: %w", err)
+	}
+	keySchema, err := newKeyValueComponentSchema(keyData, properties, kvKeySchemaPrefix)
+	if err != nil {
+		return nil, err
+	}
+	valueSchema, err := newKeyValueComponentSchema(valueData, properties, kvValueSchemaPrefix)
+	if err != nil {
+		return nil, err
+	}
+	encoding := KeyValueInline
+	if properties[kvEncodingTypeProperty] == KeyValueSeparated.String() {
+		encoding = KeyValueSeparated
+	}
+	kvSchema := NewKeyValueSchema(keySchema, valueSchema, encoding)
+	kvSchema.SchemaInfo.Schema = string(schemaData)
+	return kvSchema, nil
+}
+
+func newKeyValueComponentSchema(data []byte, properties map[string]string, prefix string) (Schema, error) {
+	schemaType := SchemaType(BYTES)
+	if name, ok := properties[prefix+"type"]; ok {
+		found := false
+		for t, n := range schemaTypeNames {
+			if n == name {
+				schemaType, found = t, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("not support schema type of %v in KeyValue schema", name)
+		}
+	}
+	var schemaProperties map[string]string
+	if encoded := properties[prefix+"properties"]; encoded != "" {
+		if err := json.Unmarshal([]byte(encoded), &schemaProperties); err != nil {
+			return nil, fmt.Errorf("invalid properties of KeyValue schema: %w", err)
+		}
+	}
+	return NewSchema(schemaType, data, schemaProperties)
+}
+
+// encodeKeyValue encodes a key and a value as their 4 bytes big-endian length followed by their bytes, a nil
+// key or value having a length of -1
+func encodeKeyValue(key, value []byte) []byte {
+	buf := make([]byte, 0, 8+len(key)+len(value))
+	var size [4]byte
+	for _, part := range [][]byte{key, value} {
+		if part == nil {
+			binary.BigEndian.PutUint32(size[:], math.MaxUint32)
+		} else {
+			binary.BigEndian.PutUint32(size[:], uint32(len(part)))
+		}
+		buf = append(buf, size[:]...)
+		buf = append(buf, part...)
+	}
+	return buf
+}
+
+// decodeKeyValue decodes the key and value encoded by encodeKeyValue
+func decodeKeyValue(data []byte) (key, value []byte, err error) {
+	parts := make([][]byte, 2)
+	for i := range parts {
+		if len(data) < 4 {
+			return nil, nil, fmt.Errorf("missing length of %d bytes", 4-len(data))
+		}
+		size := int32(binary.BigEndian.Uint32(data))
+		data = data[4:]
+		if size < 0 {
+			continue
+		}
+		if int(size) > len(data) {
+			return nil, nil, fmt.Errorf("length %d exceeds the remaining %d bytes", size, len(data))
+		}
+		parts[i], data = data[:size], data[size:]
+	}
+	return parts[0], parts[1], nil
+}
+
+// keyValuePair returns the pair given to Encode
+func keyValuePair(value interface{}) (*KeyValuePair, error) {
+	switch v := value.(type) {
+	case KeyValuePair:
+		return &v, nil
+	case *KeyValuePair:
+		if v != nil {
+			return v, nil
+		}
+	}
+	return nil, newError(SchemaFailure, fmt.Sprintf("KeyValueSchema can not encode a value of type %T", value))
+}
+
+func (kvs *KeyValueSchema) Encode(v interface{}) ([]byte, error) {
+	key, value, err := kvs.encodeSeparated(v)
+	if err != nil {
+		return nil, err
+	}
+	if kvs.encoding == KeyValueSeparated {
+		return value, nil
+	}
+	return encodeKeyValue(key, value), nil
+}
+
+// encodeSeparated returns the encoded key and value of the KeyValuePair, the key being nil if the pair has none
+func (kvs *KeyValueSchema) encodeSeparated(v interface{}) (key, value []byte, err error) {
+	pair, err := keyValuePair(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pair.Key != nil {
+		if key, err = kvs.keySchema.Encode(pair.Key); err != nil {
+			return nil, nil, err
+		}
+		if key == nil {
+			key = []byte{}
+		}
+	}
+	if pair.Value != nil {
+		if value, err = kvs.valueSchema.Encode(pair.Value); err != nil {
+			return nil, nil, err
+		}
+	}
+	return key, value, nil
+}
+
+// Decode decodes the payload of a message into a *KeyValuePair. With KeyValueSeparated, only its value is
+// decoded, the key being in the message key: Message.GetSchemaValue decodes both.
+func (kvs *KeyValueSchema) Decode(data []byte, v interface{}) error {
+	if kvs.encoding == KeyValueSeparated {
+		return kvs.decodeSeparated(nil, data, v)
+	}
+	key, value, err := decodeKeyValue(data)
+	if err != nil {
+		return newError(InvalidMessage, fmt.Sprintf("invalid data received by KeyValueSchema: %v", err))
+	}
+	return kvs.decodeSeparated(key, value, v)
+}
+
+// decodeSeparated decodes the encoded key and value into a *KeyValuePair, leaving its Key or Value untouched when
+// the encoded one is nil
+func (kvs *KeyValueSchema) decodeSeparated(key, value []byte, v interface{}) error {
+	pair, ok := v.(*KeyValuePair)
+	if !ok || pair == nil {
+		return newError(SchemaFailure, fmt.Sprintf("KeyValueSchema can not decode into a value of type %T", v))
+	}
+	if key != nil {
+		if err := decodeKeyValuePart(kvs.keySchema, key, &pair.Key); err != nil {
+			return err
+		}
+	}
+	if value != nil {
+		if err := decodeKeyValuePart(kvs.valueSchema, value, &pair.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeKeyValuePart(schema Schema, data []byte, target *interface{}) error {
+	if *target != nil {
+		return schema.Decode(data, *target)
+	}
+	return schema.Decode(data, target)
+}
+
+// Validate checks the payload holds a key and a value, the KeyValueSeparated payloads being only a value
+func (kvs *KeyValueSchema) Validate(message []byte) error {
+	if kvs.encoding == KeyValueSeparated {
+		return nil
+	}
+	if _, _, err := decodeKeyValue(message); err != nil {
+		return newError(InvalidMessage, fmt.Sprintf("invalid data received by KeyValueSchema: %v", err))
+	}
+	return nil
+}
+
+func (kvs *KeyValueSchema) GetSchemaInfo() *SchemaInfo {
+	return &kvs.SchemaInfo
+}
+
+// AutoConsumeSchema is the schema of the consumers decoding the messages with the schema registered on the topic
+// for their version, without knowing it upfront. Message.GetSchemaValue decodes their value into a *interface{}:
+// the Avro and JSON values are decoded as their JSON representation, a map[string]interface{} for the records,
+// the other values are returned as their raw bytes.
+type AutoConsumeSchema struct {
+	SchemaInfo
+}
+
+// NewAutoConsumeSchema creates a schema for the consumers decoding the messages of any schema. It can not be used
+// to produce messages.
+func NewAutoConsumeSchema() *AutoConsumeSchema {
+	autoConsumeSchema := new(AutoConsumeSchema)
+	autoConsumeSchema.SchemaInfo.Type = AutoConsume
+	autoConsumeSchema.SchemaInfo.Name = "AutoConsume"
+	return autoConsumeSchema
+}
+
+func (acs *AutoConsumeSchema) Encode(v interface{}) ([]byte, error) {
+	return nil, newError(SchemaFailure, "AutoConsumeSchema can not encode values")
+}
+
+// Decode decodes the data into a *interface{} as its raw bytes, the schema of the data being unknown:
+// Message.GetSchemaValue decodes it with the schema of the message.
+func (acs *AutoConsumeSchema) Decode(data []byte, v interface{}) error {
+	return acs.decodeFrom(nil, data, v)
+}
+
+// decodeFrom decodes the data written with the writer schema, which is nil if unknown, into a *interface{}
+func (acs *AutoConsumeSchema) decodeFrom(writer Schema, data []byte, v interface{}) error {
+	target, ok := v.(*interface{})
+	if !ok || target == nil {
+		return newError(SchemaFailure, fmt.Sprintf("AutoConsumeSchema can not decode into a value of type %T", v))
+	}
+	var value interface{}
+	switch w := writer.(type) {
+	case *AvroSchema:
+		native, _, err := w.Codec.NativeFromBinary(data)
+		if err != nil {
+			return err
+		}
+		textual, err := w.Codec.TextualFromNative(nil, native)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(textual, &value); err != nil {
+			return err
+		}
+	case *JSONSchema:
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	default:
+		value = append([]byte(nil), data...)
+	}
+	*target = value
+	return nil
+}
+
+func (acs *AutoConsumeSchema) Validate(message []byte) error {
+	return nil
+}
+
+func (acs *AutoConsumeSchema) GetSchemaInfo() *SchemaInfo {
+	return &acs.SchemaInfo
+}
+
+// AutoPublishSchema is the schema of the producers forwarding payloads already encoded with the schema of another
+// topic, such as the payloads of the messages received by a consumer. The payloads are published untouched with
+// the schema given by ProducerMessage.SchemaVersion and ProducerMessage.SchemaTopic, which is registered on the
+// topic of the producer if needed.
+type AutoPublishSchema struct {
+	SchemaInfo
+}
+
+// NewAutoPublishSchema creates a schema for the producers forwarding pre-encoded payloads
+func NewAutoPublishSchema() *AutoPublishSchema {
+	autoPublishSchema := new(AutoPublishSchema)
+	autoPublishSchema.SchemaInfo.Type = AutoPublish
+	autoPublishSchema.SchemaInfo.Name = "AutoPublish"
+	return autoPublishSchema
+}
+
+func (aps *AutoPublishSchema) Encode(v interface{}) ([]byte, error) {
+	data, ok := v.([]byte)
+	if !ok {
+		return nil, newError(SchemaFailure, fmt.Sprintf("AutoPublishSchema can not encode a value of type %T", v))
+	}
+	return data, nil
+}
+
+func (aps *AutoPublishSchema) Decode(data []byte, v interface{}) error {
+	return newError(SchemaFailure, "AutoPublishSchema can not decode values")
+}
+
+func (aps *AutoPublishSchema) Validate(message []byte) error {
+	return nil
+}
+
+func (aps *AutoPublishSchema) GetSchemaInfo() *SchemaInfo {
+	return &aps.SchemaInfo
+}
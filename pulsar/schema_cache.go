@@ -0,0 +1,172 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"container/list"
+	"encoding/hex"
+	"sync"
+
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+)
+
+// SchemaCache stores the schemas resolved from the brokers by the consumers, identified by the topic name
+// (without the partition suffix) and the schema version. Implementations must be safe for concurrent use.
+type SchemaCache interface {
+	// Get returns the schema of the topic with the given version, if it is cached
+	Get(topic string, schemaVersion []byte) (Schema, bool)
+
+	// Put stores the schema of the topic with the given version
+	Put(topic string, schemaVersion []byte, schema Schema)
+}
+
+// NewSchemaCache creates an unbounded, in-memory SchemaCache.
+func NewSchemaCache() SchemaCache {
+	return &memorySchemaCache{}
+}
+
+type memorySchemaCache struct {
+	schemas sync.Map
+}
+
+func (c *memorySchemaCache) key(topic string, schemaVersion []byte) string {
+	return topic + "/" + hex.EncodeToString(schemaVersion)
+}
+
+func (c *memorySchemaCache) Get(topic string, schemaVersion []byte) (Schema, bool) {
+	schema, ok := c.schemas.Load(c.key(topic, schemaVersion))
+	if !ok {
+		return nil, false
+	}
+	return schema.(Schema), true
+}
+
+func (c *memorySchemaCache) Put(topic string, schemaVersion []byte, schema Schema) {
+	c.schemas.Store(c.key(topic, schemaVersion), schema)
+}
+
+var globalSchemaCache struct {
+	sync.RWMutex
+	cache SchemaCache
+}
+
+// SetGlobalSchemaCache sets a process-wide SchemaCache, shared by all the clients, which is looked up by the
+// consumers before asking the broker for a schema, so that the clients of the same cluster resolve each
+// schema only once. Since the topics are only identified by name, the clients sharing the cache must be
+// connected to the same cluster. Passing nil disables the global cache, which is the default.
+func SetGlobalSchemaCache(cache SchemaCache) {
+	globalSchemaCache.Lock()
+	defer globalSchemaCache.Unlock()
+	globalSchemaCache.cache = cache
+}
+
+func getGlobalSchemaCache() SchemaCache {
+	globalSchemaCache.RLock()
+	defer globalSchemaCache.RUnlock()
+	return globalSchemaCache.cache
+}
+
+// schemaCacheTopic returns the name under which the schemas of a topic are stored in the global cache,
+// the schemas being shared by all the partitions of a topic
+func schemaCacheTopic(topic string) string {
+	tn, err := internal.ParseTopicName(topic)
+	if err != nil {
+		return topic
+	}
+	return internal.TopicNameWithoutPartitionPart(tn)
+}
+
+// schemaVersionCache is a LRU cache of the schemas resolved from the brokers, shared by the consumers of a client.
+// The concurrent lookups of a schema missing from the cache wait for a single request to the broker.
+type schemaVersionCache struct {
+	sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+	pending map[string]*schemaFetch
+}
+
+type schemaVersionEntry struct {
+	key    string
+	schema Schema
+}
+
+// schemaFetch is a request for a schema missing from the cache, done is closed once it completes
+type schemaFetch struct {
+	done   chan struct{}
+	schema Schema
+	err    error
+}
+
+func newSchemaVersionCache(size int) *schemaVersionCache {
+	return &schemaVersionCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		pending: make(map[string]*schemaFetch),
+	}
+}
+
+// get returns the cached schema, or resolves it with fetch if it is missing
+func (c *schemaVersionCache) get(key string, fetch func() (Schema, error)) (Schema, error) {
+	c.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.Unlock()
+		return e.Value.(*schemaVersionEntry).schema, nil
+	}
+	if f, ok := c.pending[key]; ok {
+		c.Unlock()
+		<-f.done
+		return f.schema, f.err
+	}
+	f := &schemaFetch{done: make(chan struct{})}
+	c.pending[key] = f
+	c.Unlock()
+
+	f.schema, f.err = fetch()
+
+	c.Lock()
+	delete(c.pending, key)
+	if f.err == nil {
+		c.addLocked(key, f.schema)
+	}
+	c.Unlock()
+	close(f.done)
+	return f.schema, f.err
+}
+
+func (c *schemaVersionCache) add(key string, schema Schema) {
+	c.Lock()
+	defer c.Unlock()
+	c.addLocked(key, schema)
+}
+
+func (c *schemaVersionCache) addLocked(key string, schema Schema) {
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*schemaVersionEntry).schema = schema
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&schemaVersionEntry{key: key, schema: schema})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*schemaVersionEntry).key)
+	}
+}
@@ -0,0 +1,107 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobalSchemaCache(t *testing.T) {
+	cache := NewSchemaCache()
+	SetGlobalSchemaCache(cache)
+	defer SetGlobalSchemaCache(nil)
+
+	schema := NewStringSchema(nil)
+	version := []byte{0, 0, 0, 0, 0, 0, 0, 1}
+	cache.Put("persistent://public/default/my-topic", version, schema)
+
+	_, ok := cache.Get("persistent://public/default/my-topic", []byte{0, 0, 0, 0, 0, 0, 0, 2})
+	assert.False(t, ok)
+
+	// the schema is resolved from the global cache, without asking the broker, for every partition
+	s := newSchemaInfoCache(nil, "persistent://public/default/my-topic-partition-1")
+	resolved, err := s.Get(version)
+	assert.NoError(t, err)
+	assert.Equal(t, schema, resolved)
+}
+
+func TestSchemaVersionCacheEviction(t *testing.T) {
+	cache := newSchemaVersionCache(2)
+	fetched := 0
+	fetch := func() (Schema, error) {
+		fetched++
+		return NewStringSchema(nil), nil
+	}
+
+	_, err := cache.get("a", fetch)
+	assert.NoError(t, err)
+	_, err = cache.get("b", fetch)
+	assert.NoError(t, err)
+	// a is now more recently used than b
+	_, err = cache.get("a", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetched)
+
+	_, err = cache.get("c", fetch)
+	assert.NoError(t, err)
+	_, err = cache.get("a", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fetched)
+	_, err = cache.get("b", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, fetched)
+
+	// the failed lookups are not cached
+	_, err = cache.get("d", func() (Schema, error) {
+		return nil, newError(LookupError, "expected error")
+	})
+	assert.Error(t, err)
+	_, err = cache.get("d", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, fetched)
+}
+
+func TestSchemaVersionCacheConcurrentMiss(t *testing.T) {
+	cache := newSchemaVersionCache(defaultSchemaCacheSize)
+	schema := NewStringSchema(nil)
+	release := make(chan struct{})
+	var fetched int32
+	fetch := func() (Schema, error) {
+		atomic.AddInt32(&fetched, 1)
+		<-release
+		return schema, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resolved, err := cache.get("version", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, schema, resolved)
+		}()
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+}
@@ -19,6 +19,8 @@ package pulsar
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"testing"
@@ -556,3 +558,400 @@ func TestDoubleSchema(t *testing.T) {
 	assert.Equal(t, res, float64(1))
 	defer consumer.Close()
 }
+
+func TestTimestampSchema(t *testing.T) {
+	client := createClient()
+	defer client.Close()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:  "timestampTopic",
+		Schema: NewTimestampSchema(nil),
+	})
+	assert.Nil(t, err)
+	ctx := context.Background()
+	now := time.Now()
+	if _, err := producer.Send(ctx, &ProducerMessage{
+		Value: now,
+	}); err != nil {
+		log.Fatal(err)
+	}
+	_, err = producer.Send(ctx, &ProducerMessage{
+		Value: now.UnixNano(),
+	})
+	assert.Error(t, err)
+	defer producer.Close()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:                       "timestampTopic",
+		SubscriptionName:            "sub-2",
+		Schema:                      NewTimestampSchema(nil),
+		SubscriptionInitialPosition: SubscriptionPositionEarliest,
+	})
+	assert.Nil(t, err)
+
+	var res time.Time
+	msg, err := consumer.Receive(ctx)
+	assert.Nil(t, err)
+	err = msg.GetSchemaValue(&res)
+	assert.Nil(t, err)
+	assert.True(t, res.Equal(now.Truncate(time.Millisecond)))
+	defer consumer.Close()
+}
+
+func TestTimestampSchemaEncodeDecode(t *testing.T) {
+	schema := NewTimestampSchema(nil)
+	now := time.Now()
+
+	data, err := schema.Encode(&now)
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate(data))
+
+	var res time.Time
+	assert.NoError(t, schema.Decode(data, &res))
+	assert.Equal(t, now.UnixNano()/int64(time.Millisecond), res.UnixNano()/int64(time.Millisecond))
+
+	assert.Equal(t, "Timestamp", schema.GetSchemaInfo().Name)
+
+	// the times that UnixNano can not represent
+	for _, date := range []time.Time{
+		time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1600, time.June, 1, 12, 30, 15, 999999999, time.UTC),
+		time.Date(3000, time.March, 14, 1, 2, 3, 4000000, time.UTC),
+	} {
+		data, err := schema.Encode(date)
+		assert.NoError(t, err)
+		assert.NoError(t, schema.Decode(data, &res))
+		assert.True(t, date.Truncate(time.Millisecond).Equal(res), "%v decoded as %v", date, res)
+	}
+
+	_, err = schema.Encode("not a time")
+	assert.Error(t, err)
+	var nilTime *time.Time
+	_, err = schema.Encode(nilTime)
+	assert.Error(t, err)
+	var millis int64
+	assert.Error(t, schema.Decode(data, &millis))
+}
+
+func TestDateSchemaEncodeDecode(t *testing.T) {
+	schema := NewDateSchema(nil)
+	date := time.Date(2023, time.March, 14, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*3600))
+
+	data, err := schema.Encode(date)
+	assert.NoError(t, err)
+	assert.Len(t, data, 4)
+	assert.NoError(t, schema.Validate(data))
+
+	var res time.Time
+	assert.NoError(t, schema.Decode(data, &res))
+	assert.Equal(t, time.Date(2023, time.March, 14, 0, 0, 0, 0, time.UTC), res)
+
+	// the dates published by the Java client are milliseconds since the epoch
+	javaData, err := NewInt64Schema(nil).Encode(
+		time.Date(1969, time.December, 31, 12, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond))
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate(javaData))
+	assert.NoError(t, schema.Decode(javaData, &res))
+	assert.Equal(t, time.Date(1969, time.December, 31, 0, 0, 0, 0, time.UTC), res)
+
+	// the dates that UnixNano can not represent
+	for _, date := range []time.Time{
+		time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(3000, time.March, 14, 0, 0, 0, 0, time.UTC),
+	} {
+		data, err := schema.Encode(date)
+		assert.NoError(t, err)
+		assert.NoError(t, schema.Decode(data, &res))
+		assert.Equal(t, date, res)
+	}
+	assert.Equal(t, "Date", schema.GetSchemaInfo().Name)
+
+	_, err = schema.Encode(int32(1))
+	assert.Error(t, err)
+	assert.Error(t, schema.Validate([]byte{1, 2}))
+}
+
+func TestAvroSchemaWithReaderWriter(t *testing.T) {
+	writerDef := `{"type":"record","name":"Example","namespace":"test",` +
+		`"fields":[{"name":"ID","type":"int"},{"name":"Legacy","type":"string"}]}`
+	readerDef := `{"type":"record","name":"Example","namespace":"test",` +
+		`"fields":[{"name":"ID","type":"int"},{"name":"Name","type":"string","default":"unknown"}]}`
+	type legacyAvro struct {
+		ID     int
+		Legacy string
+	}
+
+	writer, err := NewAvroSchemaWithValidation(writerDef, nil)
+	require.NoError(t, err)
+	data, err := writer.Encode(&legacyAvro{ID: 1, Legacy: "dropped"})
+	require.NoError(t, err)
+
+	schema, err := NewAvroSchemaWithReaderWriter(writerDef, readerDef, nil)
+	require.NoError(t, err)
+	assert.Equal(t, AVRO, schema.GetSchemaInfo().Type)
+
+	var res testAvro
+	require.NoError(t, schema.Decode(data, &res))
+	assert.Equal(t, testAvro{ID: 1, Name: "unknown"}, res)
+
+	// the writer schema of a message is resolved from its schema version
+	schemaVersion := []byte{0, 0, 0, 0, 0, 0, 0, 1}
+	cache := newSchemaInfoCache(nil, "persistent://public/default/avro-evolution")
+	cache.add(hex.EncodeToString(schemaVersion), writer)
+	msg := &message{
+		payLoad:         data,
+		schema:          schema,
+		schemaVersion:   schemaVersion,
+		schemaInfoCache: cache,
+	}
+	res = testAvro{}
+	require.NoError(t, msg.GetSchemaValue(&res))
+	assert.Equal(t, testAvro{ID: 1, Name: "unknown"}, res)
+
+	_, err = NewAvroSchemaWithReaderWriter("invalid", readerDef, nil)
+	assert.Error(t, err)
+}
+
+func TestJSONSchemaStrict(t *testing.T) {
+	schema := NewJSONSchemaStrict(exampleSchemaDef, nil)
+	assert.Equal(t, JSON, schema.GetSchemaInfo().Type)
+
+	var res testJSON
+	assert.NoError(t, schema.Decode([]byte(`{"id":1,"name":"pulsar"}`), &res))
+	assert.Equal(t, testJSON{ID: 1, Name: "pulsar"}, res)
+
+	err := schema.Decode([]byte(`{"id":1,"name":"pulsar","extra":true}`), &res)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `"extra"`)
+
+	// the lenient schema drops the unknown fields
+	assert.NoError(t, NewJSONSchema(exampleSchemaDef, nil).Decode([]byte(`{"id":2,"extra":true}`), &res))
+	assert.Equal(t, 2, res.ID)
+
+	// the strictness of the consumer schema applies to the messages with a schema version
+	schemaVersion := []byte{0, 0, 0, 0, 0, 0, 0, 1}
+	cache := newSchemaInfoCache(nil, "persistent://public/default/json-strict")
+	cache.add(hex.EncodeToString(schemaVersion), NewJSONSchema(exampleSchemaDef, nil))
+	msg := &message{
+		payLoad:         []byte(`{"id":3,"extra":true}`),
+		schema:          schema,
+		schemaVersion:   schemaVersion,
+		schemaInfoCache: cache,
+	}
+	assert.Error(t, msg.GetSchemaValue(&res))
+}
+
+func TestKeyValueSchemaEncodeDecode(t *testing.T) {
+	for _, encoding := range []KeyValueEncodingType{KeyValueInline, KeyValueSeparated} {
+		schema := NewKeyValueSchema(NewAvroSchema(exampleSchemaDef, nil), NewJSONSchema(exampleSchemaDef, nil), encoding)
+		info := schema.GetSchemaInfo()
+		assert.Equal(t, KeyValue, info.Type)
+		assert.Equal(t, encoding.String(), info.Properties["kv.encoding.type"])
+		assert.Equal(t, "AVRO", info.Properties["key.schema.type"])
+		assert.Equal(t, "JSON", info.Properties["value.schema.type"])
+
+		key, value, err := schema.encodeSeparated(KeyValuePair{
+			Key:   testAvro{ID: 1, Name: "key"},
+			Value: testJSON{ID: 2, Name: "value"},
+		})
+		require.NoError(t, err)
+
+		data, err := schema.Encode(&KeyValuePair{Key: testAvro{ID: 1, Name: "key"}, Value: testJSON{ID: 2, Name: "value"}})
+		require.NoError(t, err)
+		assert.NoError(t, schema.Validate(data))
+		if encoding == KeyValueSeparated {
+			assert.Equal(t, value, data)
+		} else {
+			assert.Equal(t, encodeKeyValue(key, value), data)
+		}
+
+		var k testAvro
+		var v testJSON
+		msg := &message{schema: schema, payLoad: data}
+		if encoding == KeyValueSeparated {
+			msg.key = base64.StdEncoding.EncodeToString(key)
+			msg.keyB64Encoded = true
+		}
+		require.NoError(t, msg.GetSchemaValue(&KeyValuePair{Key: &k, Value: &v}))
+		assert.Equal(t, testAvro{ID: 1, Name: "key"}, k)
+		assert.Equal(t, testJSON{ID: 2, Name: "value"}, v)
+
+		// the schema registered on a topic
+		registered, err := NewSchema(KeyValue, []byte(info.Schema), info.Properties)
+		require.NoError(t, err)
+		assert.Equal(t, schema.encoding, registered.(*KeyValueSchema).encoding)
+		assert.Equal(t, info.Schema, registered.GetSchemaInfo().Schema)
+	}
+
+	schema := NewKeyValueSchema(NewInt64Schema(nil), NewStringSchema(nil), KeyValueInline)
+	data, err := schema.Encode(KeyValuePair{Value: "no key"})
+	require.NoError(t, err)
+	pair := KeyValuePair{}
+	require.NoError(t, schema.Decode(data, &pair))
+	assert.Nil(t, pair.Key)
+	assert.Equal(t, "no key", *pair.Value.(*string))
+
+	_, err = schema.Encode("not a pair")
+	assert.Error(t, err)
+	assert.Error(t, schema.Decode(data, &pair.Value))
+	assert.Error(t, schema.Validate([]byte{0, 0, 0, 9, 1}))
+}
+
+func TestKeyValueSchema(t *testing.T) {
+	client := createClient()
+	defer client.Close()
+
+	topic := newTopicName()
+	schema := NewKeyValueSchema(NewAvroSchema(exampleSchemaDef, nil), NewJSONSchema(exampleSchemaDef, nil),
+		KeyValueSeparated)
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:  topic,
+		Schema: schema,
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            "sub-1",
+		Schema:                      schema,
+		SubscriptionInitialPosition: SubscriptionPositionEarliest,
+	})
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	_, err = producer.Send(context.Background(), &ProducerMessage{
+		Value: KeyValuePair{Key: testAvro{ID: 1, Name: "key"}, Value: testJSON{ID: 2, Name: "value"}},
+	})
+	require.NoError(t, err)
+
+	msg, err := consumer.Receive(context.Background())
+	require.NoError(t, err)
+	var k testAvro
+	var v testJSON
+	require.NoError(t, msg.GetSchemaValue(&KeyValuePair{Key: &k, Value: &v}))
+	assert.Equal(t, testAvro{ID: 1, Name: "key"}, k)
+	assert.Equal(t, testJSON{ID: 2, Name: "value"}, v)
+}
+
+func TestAutoConsumeSchemaDecode(t *testing.T) {
+	schema := NewAutoConsumeSchema()
+	assert.Equal(t, SchemaType(AutoConsume), schema.GetSchemaInfo().Type)
+	_, err := schema.Encode("value")
+	assert.Error(t, err)
+
+	avroData, err := NewAvroSchema(exampleSchemaDef, nil).Encode(testAvro{ID: 1, Name: "avro"})
+	require.NoError(t, err)
+	var value interface{}
+	require.NoError(t, schema.decodeFrom(NewAvroSchema(exampleSchemaDef, nil), avroData, &value))
+	assert.Equal(t, map[string]interface{}{"ID": float64(1), "Name": "avro"}, value)
+
+	jsonData, err := NewJSONSchema(exampleSchemaDef, nil).Encode(testJSON{ID: 2, Name: "json"})
+	require.NoError(t, err)
+	require.NoError(t, schema.decodeFrom(NewJSONSchema(exampleSchemaDef, nil), jsonData, &value))
+	assert.Equal(t, map[string]interface{}{"id": float64(2), "name": "json"}, value)
+
+	require.NoError(t, schema.decodeFrom(NewStringSchema(nil), []byte("string"), &value))
+	assert.Equal(t, []byte("string"), value)
+	require.NoError(t, schema.Decode([]byte("raw"), &value))
+	assert.Equal(t, []byte("raw"), value)
+
+	var s string
+	assert.Error(t, schema.Decode([]byte("raw"), &s))
+}
+
+func TestAutoConsumeSchema(t *testing.T) {
+	client := createClient()
+	defer client.Close()
+
+	topic := newTopicName()
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:  topic,
+		Schema: NewAvroSchema(exampleSchemaDef, nil),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            "sub-1",
+		Schema:                      NewAutoConsumeSchema(),
+		SubscriptionInitialPosition: SubscriptionPositionEarliest,
+	})
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	_, err = producer.Send(context.Background(), &ProducerMessage{Value: testAvro{ID: 1, Name: "avro"}})
+	require.NoError(t, err)
+
+	msg, err := consumer.Receive(context.Background())
+	require.NoError(t, err)
+	var value interface{}
+	require.NoError(t, msg.GetSchemaValue(&value))
+	assert.Equal(t, map[string]interface{}{"ID": float64(1), "Name": "avro"}, value)
+}
+
+func TestAutoPublishSchemaEncode(t *testing.T) {
+	schema := NewAutoPublishSchema()
+	data, err := schema.Encode([]byte("encoded"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("encoded"), data)
+	_, err = schema.Encode("not bytes")
+	assert.Error(t, err)
+	var v []byte
+	assert.Error(t, schema.Decode(data, &v))
+}
+
+func TestAutoPublishSchema(t *testing.T) {
+	client := createClient()
+	defer client.Close()
+
+	sourceTopic := newTopicName()
+	targetTopic := newTopicName()
+	ctx := context.Background()
+
+	producer, err := client.CreateProducer(ProducerOptions{
+		Topic:  sourceTopic,
+		Schema: NewAvroSchema(exampleSchemaDef, nil),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+	_, err = producer.Send(ctx, &ProducerMessage{Value: testAvro{ID: 1, Name: "avro"}})
+	require.NoError(t, err)
+
+	reader, err := client.CreateReader(ReaderOptions{Topic: sourceTopic, StartMessageID: EarliestMessageID()})
+	require.NoError(t, err)
+	defer reader.Close()
+	msg, err := reader.Next(ctx)
+	require.NoError(t, err)
+
+	forwarder, err := client.CreateProducer(ProducerOptions{
+		Topic:  targetTopic,
+		Schema: NewAutoPublishSchema(),
+	})
+	require.NoError(t, err)
+	defer forwarder.Close()
+
+	consumer, err := client.Subscribe(ConsumerOptions{
+		Topic:                       targetTopic,
+		SubscriptionName:            "sub-1",
+		Schema:                      NewAvroSchema(exampleSchemaDef, nil),
+		SubscriptionInitialPosition: SubscriptionPositionEarliest,
+	})
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	_, err = forwarder.Send(ctx, &ProducerMessage{
+		Payload:       msg.Payload(),
+		SchemaVersion: msg.SchemaVersion(),
+		SchemaTopic:   msg.Topic(),
+	})
+	require.NoError(t, err)
+
+	forwarded, err := consumer.Receive(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, forwarded.SchemaVersion())
+	var value testAvro
+	require.NoError(t, forwarded.GetSchemaValue(&value))
+	assert.Equal(t, testAvro{ID: 1, Name: "avro"}, value)
+}
@@ -0,0 +1,162 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
+)
+
+// SchemaIncompatibleError is returned by Client.ValidateSchema when a schema can not be used to produce on a
+// topic because of the schema registered on it. It wraps ErrSchema.
+type SchemaIncompatibleError struct {
+	// Topic is the validated topic
+	Topic string
+	// Field is the name of the incompatible field, empty if the whole schema is incompatible
+	Field string
+	// Reason describes the incompatibility
+	Reason string
+}
+
+func (e *SchemaIncompatibleError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("schema is incompatible with the schema of topic %s: %s", e.Topic, e.Reason)
+	}
+	return fmt.Sprintf("schema is incompatible with the schema of topic %s: field %q %s", e.Topic, e.Field, e.Reason)
+}
+
+func (e *SchemaIncompatibleError) Unwrap() error {
+	return ErrSchema
+}
+
+func (c *client) ValidateSchema(topic string, schema Schema) error {
+	if schema == nil {
+		return newError(InvalidConfiguration, "schema is required")
+	}
+	tn, err := internal.ParseTopicName(topic)
+	if err != nil {
+		return err
+	}
+	registered, err := c.lookupService.GetSchema(tn.Name, nil)
+	if err != nil {
+		return err
+	}
+	if registered == nil {
+		// the first producer registers its schema
+		return nil
+	}
+	return checkSchemaCompatibility(topic, &SchemaInfo{
+		Name:       registered.GetName(),
+		Schema:     string(registered.GetSchemaData()),
+		Type:       SchemaType(registered.GetType()),
+		Properties: internal.ConvertToStringMap(registered.GetProperties()),
+	}, schema.GetSchemaInfo())
+}
+
+// checkSchemaCompatibility checks the schema can replace the registered one under the default FULL compatibility
+// strategy of the brokers. The top-level fields of the Avro and JSON record schemas are compared: a field can
+// only be added or removed if it has a default value, and its type can not change. The other schemas only need
+// to be of the same type.
+func checkSchemaCompatibility(topic string, registered, schema *SchemaInfo) error {
+	if registered.Type != schema.Type {
+		return &SchemaIncompatibleError{
+			Topic: topic,
+			Reason: fmt.Sprintf("the schema type %s does not match the registered type %s",
+				pb.Schema_Type(schema.Type), pb.Schema_Type(registered.Type)),
+		}
+	}
+	if schema.Type != AVRO && schema.Type != JSON {
+		return nil
+	}
+
+	registeredFields, err := parseRecordFields(registered.Schema)
+	if err != nil {
+		// the registered schema can not be compared, the broker is left to decide
+		return nil
+	}
+	fields, err := parseRecordFields(schema.Schema)
+	if err != nil {
+		return &SchemaIncompatibleError{Topic: topic, Reason: fmt.Sprintf("invalid schema definition: %v", err)}
+	}
+
+	for _, f := range fields {
+		old, ok := findRecordField(registeredFields, f.name)
+		if !ok {
+			if !f.hasDefault {
+				return &SchemaIncompatibleError{Topic: topic, Field: f.name, Reason: "is added without a default value"}
+			}
+			continue
+		}
+		if !bytes.Equal(old.fieldType, f.fieldType) {
+			return &SchemaIncompatibleError{
+				Topic:  topic,
+				Field:  f.name,
+				Reason: fmt.Sprintf("changes its type from %s to %s", old.fieldType, f.fieldType),
+			}
+		}
+	}
+	for _, old := range registeredFields {
+		if _, ok := findRecordField(fields, old.name); !ok && !old.hasDefault {
+			return &SchemaIncompatibleError{Topic: topic, Field: old.name, Reason: "is removed without a default value"}
+		}
+	}
+	return nil
+}
+
+type recordField struct {
+	name       string
+	fieldType  []byte
+	hasDefault bool
+}
+
+// parseRecordFields returns the top-level fields of an Avro record schema, with their compacted type definition
+func parseRecordFields(definition string) ([]recordField, error) {
+	var record struct {
+		Fields []map[string]json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(definition), &record); err != nil {
+		return nil, err
+	}
+	fields := make([]recordField, 0, len(record.Fields))
+	for _, f := range record.Fields {
+		var name string
+		if err := json.Unmarshal(f["name"], &name); err != nil {
+			return nil, fmt.Errorf("invalid field name: %w", err)
+		}
+		var fieldType bytes.Buffer
+		if err := json.Compact(&fieldType, f["type"]); err != nil {
+			return nil, fmt.Errorf("invalid type of field %q: %w", name, err)
+		}
+		_, hasDefault := f["default"]
+		fields = append(fields, recordField{name: name, fieldType: fieldType.Bytes(), hasDefault: hasDefault})
+	}
+	return fields, nil
+}
+
+func findRecordField(fields []recordField, name string) (recordField, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return recordField{}, false
+}
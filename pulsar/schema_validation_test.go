@@ -0,0 +1,88 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const validationSchemaDef = `{"type":"record","name":"Example","namespace":"test",` +
+	`"fields":[{"name":"ID","type":"int"},{"name":"Name","type":"string"}]}`
+
+func TestCheckSchemaCompatibility(t *testing.T) {
+	registered := NewAvroSchema(validationSchemaDef, nil).GetSchemaInfo()
+
+	check := func(definition string) *SchemaIncompatibleError {
+		err := checkSchemaCompatibility("my-topic", registered, NewAvroSchema(definition, nil).GetSchemaInfo())
+		if err == nil {
+			return nil
+		}
+		var incompatible *SchemaIncompatibleError
+		assert.True(t, errors.As(err, &incompatible))
+		assert.True(t, errors.Is(err, ErrSchema))
+		return incompatible
+	}
+
+	assert.Nil(t, check(validationSchemaDef))
+	assert.Nil(t, check(`{"type":"record","name":"Example","namespace":"test","fields":[{"name":"ID","type":"int"},`+
+		`{"name":"Name","type":"string"},{"name":"Age","type":"int","default":0}]}`))
+
+	err := check(`{"type":"record","name":"Example","namespace":"test","fields":[{"name":"ID","type":"int"},` +
+		`{"name":"Name","type":"string"},{"name":"Age","type":"int"}]}`)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Age", err.Field)
+	}
+
+	err = check(`{"type":"record","name":"Example","namespace":"test","fields":[{"name":"ID","type":"long"},` +
+		`{"name":"Name","type":"string"}]}`)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "ID", err.Field)
+	}
+
+	err = check(`{"type":"record","name":"Example","namespace":"test","fields":[{"name":"ID","type":"int"}]}`)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Name", err.Field)
+	}
+
+	typeErr := checkSchemaCompatibility("my-topic", registered, NewStringSchema(nil).GetSchemaInfo())
+	assert.True(t, errors.Is(typeErr, ErrSchema))
+}
+
+func TestClientValidateSchema(t *testing.T) {
+	client, err := NewClient(ClientOptions{URL: lookupURL})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	topic := newTopicName()
+	schema := NewAvroSchema(validationSchemaDef, nil)
+	assert.Nil(t, client.ValidateSchema(topic, schema))
+
+	producer, err := client.CreateProducer(ProducerOptions{Topic: topic, Schema: schema})
+	assert.Nil(t, err)
+	defer producer.Close()
+
+	assert.Nil(t, client.ValidateSchema(topic, schema))
+	err = client.ValidateSchema(topic, NewAvroSchema(`{"type":"record","name":"Example","namespace":"test",`+
+		`"fields":[{"name":"ID","type":"int"},{"name":"Name","type":"string"},{"name":"Age","type":"int"}]}`, nil))
+	var incompatible *SchemaIncompatibleError
+	assert.True(t, errors.As(err, &incompatible))
+	assert.Equal(t, "Age", incompatible.Field)
+}
@@ -19,6 +19,12 @@ package pulsar
 
 import "sync"
 
+// customKeyHasher is implemented by the TopicMetadata of the producers, to route the messages
+// with the ProducerOptions.CustomHasher
+type customKeyHasher interface {
+	keyHasher() func(string) uint32
+}
+
 func NewSinglePartitionRouter() func(*ProducerMessage, TopicMetadata) int {
 	var (
 		singlePartition *int
@@ -28,7 +34,11 @@ func NewSinglePartitionRouter() func(*ProducerMessage, TopicMetadata) int {
 		numPartitions := metadata.NumPartitions()
 		if len(message.Key) != 0 {
 			// When a key is specified, use the hash of that key
-			return int(getHashingFunction(JavaStringHash)(message.Key) % numPartitions)
+			hash := getHashingFunction(JavaStringHash)
+			if h, ok := metadata.(customKeyHasher); ok && h.keyHasher() != nil {
+				hash = h.keyHasher()
+			}
+			return int(hash(message.Key) % numPartitions)
 		}
 		once.Do(func() {
 			partition := r.R.Intn(int(numPartitions))
@@ -60,3 +60,18 @@ func TestNewSinglePartitionRouterWithKey(t *testing.T) {
 	}, numPartitions)
 	assert.Equal(t, p, p2)
 }
+
+func TestNewSinglePartitionRouterWithCustomHasher(t *testing.T) {
+	router := NewSinglePartitionRouter()
+	metadata := &producer{
+		options: &ProducerOptions{
+			CustomHasher: func(key string) uint32 {
+				return uint32(len(key))
+			},
+		},
+		numPartitions: 4,
+	}
+
+	assert.Equal(t, 2, router(&ProducerMessage{Key: "my-key"}, metadata))
+	assert.Equal(t, 3, router(&ProducerMessage{Key: "my-key-"}, metadata))
+}
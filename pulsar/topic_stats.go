@@ -0,0 +1,144 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"fmt"
+
+	"github.com/apache/pulsar-client-go/pulsar/internal"
+	pb "github.com/apache/pulsar-client-go/pulsar/internal/pulsar_proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// TopicStats holds the statistics of a topic for a subscription, see Client.TopicStats.
+type TopicStats struct {
+	// Subscription is the name of the subscription the statistics are reported for
+	Subscription string
+
+	// MsgRateOut is the rate of messages delivered to the consumer, in msg/s
+	MsgRateOut float64
+
+	// MsgThroughputOut is the throughput delivered to the consumer, in bytes/s
+	MsgThroughputOut float64
+
+	// MsgRateRedeliver is the rate of messages redelivered to the consumer, in msg/s
+	MsgRateRedeliver float64
+
+	// MsgRateExpired is the rate of messages expired on the subscription, in msg/s
+	MsgRateExpired float64
+
+	// MessageAckRate is the rate of messages acknowledged by the consumer, in msg/s
+	MessageAckRate float64
+
+	// MsgBacklog is the number of messages in the subscription backlog
+	MsgBacklog uint64
+
+	// UnackedMessages is the number of messages delivered to the consumer but not acknowledged yet
+	UnackedMessages uint64
+}
+
+func (c *client) TopicStats(topic string) (TopicStats, error) {
+	tn, err := internal.ParseTopicName(topic)
+	if err != nil {
+		return TopicStats{}, err
+	}
+
+	var consumers []*partitionConsumer
+	for _, h := range c.handlers.Values() {
+		consumers = append(consumers, topicPartitionConsumers(h, tn)...)
+		if len(consumers) > 0 {
+			break
+		}
+	}
+	if len(consumers) == 0 {
+		return TopicStats{}, newError(OperationNotSupported,
+			fmt.Sprintf("no consumer or reader on topic %s to get the stats from", topic))
+	}
+
+	stats := TopicStats{Subscription: consumers[0].options.subscription}
+	for _, pc := range consumers {
+		res, err := pc.requestConsumerStats()
+		if err != nil {
+			return TopicStats{}, err
+		}
+		stats.MsgRateOut += res.GetMsgRateOut()
+		stats.MsgThroughputOut += res.GetMsgThroughputOut()
+		stats.MsgRateRedeliver += res.GetMsgRateRedeliver()
+		stats.MsgRateExpired += res.GetMsgRateExpired()
+		stats.MessageAckRate += res.GetMessageAckRate()
+		stats.MsgBacklog += res.GetMsgBacklog()
+		stats.UnackedMessages += res.GetUnackedMessages()
+	}
+	return stats, nil
+}
+
+// topicPartitionConsumers returns the partition consumers of a client handler reading from the topic
+func topicPartitionConsumers(h internal.Closable, tn *internal.TopicName) []*partitionConsumer {
+	switch h := h.(type) {
+	case *reader:
+		return topicPartitionConsumers(h.c, tn)
+	case *consumer:
+		h.Lock()
+		defer h.Unlock()
+		var consumers []*partitionConsumer
+		for _, pc := range h.consumers {
+			if pc != nil && pc.consumesTopic(tn) {
+				consumers = append(consumers, pc)
+			}
+		}
+		return consumers
+	case *multiTopicConsumer:
+		var consumers []*partitionConsumer
+		for _, c := range h.consumers {
+			consumers = append(consumers, topicPartitionConsumers(c, tn)...)
+		}
+		return consumers
+	case *regexConsumer:
+		h.consumersLock.Lock()
+		defer h.consumersLock.Unlock()
+		var consumers []*partitionConsumer
+		for _, c := range h.consumers {
+			consumers = append(consumers, topicPartitionConsumers(c, tn)...)
+		}
+		return consumers
+	default:
+		return nil
+	}
+}
+
+func (pc *partitionConsumer) requestConsumerStats() (*pb.CommandConsumerStatsResponse, error) {
+	if state := pc.getConsumerState(); state == consumerClosed || state == consumerClosing {
+		return nil, newError(ConsumerClosed, "consumer closed")
+	}
+
+	requestID := pc.client.rpcClient.NewRequestID()
+	res, err := pc.client.rpcClient.RequestOnCnx(pc._getConn(), requestID,
+		pb.BaseCommand_CONSUMER_STATS, &pb.CommandConsumerStats{
+			RequestId:  proto.Uint64(requestID),
+			ConsumerId: proto.Uint64(pc.consumerID),
+		})
+	if err != nil {
+		pc.log.WithError(err).Error("Failed to get consumer stats")
+		return nil, err
+	}
+	stats := res.Response.ConsumerStatsResponse
+	if stats.ErrorCode != nil {
+		return nil, fmt.Errorf("failed to get consumer stats: %s: %s", stats.GetErrorCode(), stats.GetErrorMessage())
+	}
+	return stats, nil
+}
@@ -0,0 +1,125 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package tracecontext propagates the trace context of the producers to the
+// consumers through the properties of the messages.
+//
+// The trace context is encoded in the W3C Trace Context format
+// (https://www.w3.org/TR/trace-context/) by default. Users of a tracing
+// library, like OpenTelemetry, can plug its propagator with SetPropagator so
+// that the spans of their instrumentation are carried over, without the
+// client depending on that library.
+package tracecontext
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// Carrier is the storage of the trace context fields, like the properties of a message.
+type Carrier interface {
+	// Get returns the value of the field, or an empty string.
+	Get(key string) string
+
+	// Set stores the value of the field.
+	Set(key string, value string)
+
+	// Keys lists the fields of the carrier.
+	Keys() []string
+}
+
+// Propagator injects the trace context of a context into a carrier, and
+// extracts it back on the other end.
+type Propagator interface {
+	// Inject stores the trace context of ctx into the carrier.
+	Inject(ctx context.Context, carrier Carrier)
+
+	// Extract returns a copy of ctx with the trace context found in the carrier.
+	Extract(ctx context.Context, carrier Carrier) context.Context
+}
+
+// PropertiesCarrier is a Carrier backed by the properties of a message.
+type PropertiesCarrier map[string]string
+
+func (p PropertiesCarrier) Get(key string) string {
+	return p[key]
+}
+
+func (p PropertiesCarrier) Set(key string, value string) {
+	p[key] = value
+}
+
+func (p PropertiesCarrier) Keys() []string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+var (
+	propagatorMu sync.RWMutex
+	propagator   Propagator = W3CPropagator{}
+)
+
+// SetPropagator replaces the propagator used by InjectTraceContext and
+// ExtractTraceContext, W3CPropagator by default.
+func SetPropagator(p Propagator) {
+	if p == nil {
+		p = W3CPropagator{}
+	}
+	propagatorMu.Lock()
+	defer propagatorMu.Unlock()
+	propagator = p
+}
+
+// GetPropagator returns the propagator used by InjectTraceContext and ExtractTraceContext.
+func GetPropagator() Propagator {
+	propagatorMu.RLock()
+	defer propagatorMu.RUnlock()
+	return propagator
+}
+
+// InjectTraceContext stores the trace context of ctx into the properties of the message.
+//
+//nolint:revive // the message comes first, like in ExtractTraceContext
+func InjectTraceContext(msg *pulsar.ProducerMessage, ctx context.Context) {
+	if msg == nil {
+		return
+	}
+	if msg.Properties == nil {
+		msg.Properties = make(map[string]string)
+	}
+	GetPropagator().Inject(ctx, PropertiesCarrier(msg.Properties))
+}
+
+// ExtractTraceContext returns a context holding the trace context found in the
+// properties of the message.
+func ExtractTraceContext(msg pulsar.Message) context.Context {
+	ctx := context.Background()
+	if msg == nil {
+		return ctx
+	}
+	// copy the properties, the carrier must not modify the message
+	props := make(PropertiesCarrier, len(msg.Properties()))
+	for k, v := range msg.Properties() {
+		props[k] = v
+	}
+	return GetPropagator().Extract(ctx, props)
+}
@@ -0,0 +1,109 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracecontext
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceParent(t *testing.T) {
+	tc, err := ParseTraceParent(traceParent)
+	require.NoError(t, err)
+	assert.True(t, tc.Sampled())
+	assert.Equal(t, traceParent, tc.String())
+
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err := ParseTraceParent(value)
+		assert.Error(t, err, value)
+	}
+
+	// the future versions may append fields
+	_, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.NoError(t, err)
+}
+
+type mockMessage struct {
+	pulsar.Message
+	properties map[string]string
+}
+
+func (m mockMessage) Properties() map[string]string {
+	return m.properties
+}
+
+func TestInjectExtractTraceContext(t *testing.T) {
+	tc, err := ParseTraceParent(traceParent)
+	require.NoError(t, err)
+	tc.State = "vendor=value"
+
+	msg := &pulsar.ProducerMessage{}
+	InjectTraceContext(msg, ContextWithTraceContext(context.Background(), tc))
+	assert.Equal(t, traceParent, msg.Properties[TraceParentKey])
+	assert.Equal(t, "vendor=value", msg.Properties[TraceStateKey])
+
+	extracted, ok := FromContext(ExtractTraceContext(mockMessage{properties: msg.Properties}))
+	assert.True(t, ok)
+	assert.Equal(t, tc, extracted)
+
+	// nothing to propagate
+	msg = &pulsar.ProducerMessage{}
+	InjectTraceContext(msg, context.Background())
+	assert.Empty(t, msg.Properties)
+	_, ok = FromContext(ExtractTraceContext(mockMessage{properties: map[string]string{TraceParentKey: "invalid"}}))
+	assert.False(t, ok)
+}
+
+type prefixPropagator struct{}
+
+func (prefixPropagator) Inject(_ context.Context, carrier Carrier) {
+	carrier.Set("x-trace", "custom")
+}
+
+func (prefixPropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	return context.WithValue(ctx, prefixPropagator{}, carrier.Get("x-trace"))
+}
+
+func TestSetPropagator(t *testing.T) {
+	SetPropagator(prefixPropagator{})
+	defer SetPropagator(nil)
+
+	msg := &pulsar.ProducerMessage{}
+	InjectTraceContext(msg, context.Background())
+	assert.Equal(t, map[string]string{"x-trace": "custom"}, msg.Properties)
+
+	ctx := ExtractTraceContext(mockMessage{properties: msg.Properties})
+	assert.Equal(t, "custom", ctx.Value(prefixPropagator{}))
+
+	SetPropagator(nil)
+	assert.Equal(t, W3CPropagator{}, GetPropagator())
+}
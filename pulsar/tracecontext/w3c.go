@@ -0,0 +1,140 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracecontext
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// TraceParentKey is the property holding the W3C traceparent field.
+	TraceParentKey = "traceparent"
+	// TraceStateKey is the property holding the W3C tracestate field.
+	TraceStateKey = "tracestate"
+
+	traceParentVersion = "00"
+)
+
+// TraceContext identifies the span of a trace, as described by the W3C Trace Context.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+	// State is the vendor specific tracestate field, kept as is.
+	State string
+}
+
+// IsValid reports whether the trace and span ids are set.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&0x01 == 0x01
+}
+
+// String returns the traceparent field of the trace context.
+func (tc TraceContext) String() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceParentVersion,
+		hex.EncodeToString(tc.TraceID[:]), hex.EncodeToString(tc.SpanID[:]), tc.Flags)
+}
+
+// ParseTraceParent parses a W3C traceparent field.
+func ParseTraceParent(value string) (TraceContext, error) {
+	var tc TraceContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return tc, fmt.Errorf("invalid traceparent %q", value)
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 || version[0] == 0xff {
+		return tc, fmt.Errorf("invalid traceparent version %q", parts[0])
+	}
+	// the future versions may append fields, version 00 must have exactly four
+	if version[0] == 0 && len(parts) != 4 {
+		return tc, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if err := decodeHex(parts[1], tc.TraceID[:]); err != nil {
+		return tc, fmt.Errorf("invalid trace id %q", parts[1])
+	}
+	if err := decodeHex(parts[2], tc.SpanID[:]); err != nil {
+		return tc, fmt.Errorf("invalid span id %q", parts[2])
+	}
+	var flags [1]byte
+	if err := decodeHex(parts[3], flags[:]); err != nil {
+		return tc, fmt.Errorf("invalid trace flags %q", parts[3])
+	}
+	tc.Flags = flags[0]
+	if !tc.IsValid() {
+		return tc, fmt.Errorf("invalid traceparent %q, the ids must not be zero", value)
+	}
+	return tc, nil
+}
+
+func decodeHex(s string, dst []byte) error {
+	// the ids are lowercase only
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return fmt.Errorf("invalid length or case")
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of ctx holding the trace context.
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// FromContext returns the trace context held by ctx, if any.
+func FromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok && tc.IsValid()
+}
+
+// W3CPropagator propagates the TraceContext held by the contexts, see
+// ContextWithTraceContext, in the traceparent and tracestate fields.
+type W3CPropagator struct{}
+
+func (W3CPropagator) Inject(ctx context.Context, carrier Carrier) {
+	tc, ok := FromContext(ctx)
+	if !ok {
+		return
+	}
+	carrier.Set(TraceParentKey, tc.String())
+	if tc.State != "" {
+		carrier.Set(TraceStateKey, tc.State)
+	}
+}
+
+func (W3CPropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	tc, err := ParseTraceParent(carrier.Get(TraceParentKey))
+	if err != nil {
+		return ctx
+	}
+	tc.State = carrier.Get(TraceStateKey)
+	return ContextWithTraceContext(ctx, tc)
+}
@@ -0,0 +1,136 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"time"
+)
+
+// MessageOption sets a field of the messages sent by a TypedProducer.
+type MessageOption func(msg *ProducerMessage)
+
+// WithMessageKey sets the key of the message, see ProducerMessage.Key.
+func WithMessageKey(key string) MessageOption {
+	return func(msg *ProducerMessage) {
+		msg.Key = key
+	}
+}
+
+// WithMessageOrderingKey sets the ordering key of the message, see ProducerMessage.OrderingKey.
+func WithMessageOrderingKey(orderingKey string) MessageOption {
+	return func(msg *ProducerMessage) {
+		msg.OrderingKey = orderingKey
+	}
+}
+
+// WithMessageProperties sets the properties of the message, see ProducerMessage.Properties.
+func WithMessageProperties(properties map[string]string) MessageOption {
+	return func(msg *ProducerMessage) {
+		msg.Properties = properties
+	}
+}
+
+// WithMessageEventTime sets the event time of the message, see ProducerMessage.EventTime.
+func WithMessageEventTime(eventTime time.Time) MessageOption {
+	return func(msg *ProducerMessage) {
+		msg.EventTime = eventTime
+	}
+}
+
+// WithMessageDeliverAfter delays the delivery of the message, see ProducerMessage.DeliverAfter.
+func WithMessageDeliverAfter(delay time.Duration) MessageOption {
+	return func(msg *ProducerMessage) {
+		msg.DeliverAfter = delay
+	}
+}
+
+// WithMessageDeliverAt sets the delivery time of the message, see ProducerMessage.DeliverAt.
+func WithMessageDeliverAt(deliverAt time.Time) MessageOption {
+	return func(msg *ProducerMessage) {
+		msg.DeliverAt = deliverAt
+	}
+}
+
+// WithMessageTransaction sends the message within the transaction, see ProducerMessage.Transaction.
+func WithMessageTransaction(txn Transaction) MessageOption {
+	return func(msg *ProducerMessage) {
+		msg.Transaction = txn
+	}
+}
+
+// TypedProducer is a Producer encoding the values of type T with the schema of the producer. Without a schema,
+// T must be []byte and the values are sent as the payload of the messages.
+type TypedProducer[T any] struct {
+	producer Producer
+	raw      bool
+}
+
+// NewTypedProducer creates a TypedProducer from the options, which require a Schema able to encode T unless T
+// is []byte.
+func NewTypedProducer[T any](client Client, opts ProducerOptions) (*TypedProducer[T], error) {
+	var zero T
+	_, raw := interface{}(zero).([]byte)
+	if opts.Schema == nil && !raw {
+		return nil, newError(InvalidConfiguration, "Schema is required to encode the values of a TypedProducer")
+	}
+	producer, err := client.CreateProducer(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedProducer[T]{producer: producer, raw: raw && opts.Schema == nil}, nil
+}
+
+// Send sends the value in a message, blocking until it is acknowledged by the broker.
+func (p *TypedProducer[T]) Send(ctx context.Context, value T, opts ...MessageOption) (MessageID, error) {
+	return p.producer.Send(ctx, p.message(value, opts))
+}
+
+// SendAsync sends the value in a message, the callback being called once it is acknowledged by the broker.
+func (p *TypedProducer[T]) SendAsync(ctx context.Context, value T,
+	callback func(MessageID, *ProducerMessage, error), opts ...MessageOption) {
+	p.producer.SendAsync(ctx, p.message(value, opts), callback)
+}
+
+func (p *TypedProducer[T]) message(value T, opts []MessageOption) *ProducerMessage {
+	msg := &ProducerMessage{}
+	if p.raw {
+		msg.Payload = interface{}(value).([]byte)
+	} else {
+		msg.Value = value
+	}
+	for _, opt := range opts {
+		opt(msg)
+	}
+	return msg
+}
+
+// Producer returns the underlying Producer.
+func (p *TypedProducer[T]) Producer() Producer {
+	return p.producer
+}
+
+// Flush flushes all the messages buffered in the client, see Producer.Flush.
+func (p *TypedProducer[T]) Flush() error {
+	return p.producer.Flush()
+}
+
+// Close closes the underlying Producer.
+func (p *TypedProducer[T]) Close() {
+	p.producer.Close()
+}
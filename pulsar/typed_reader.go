@@ -0,0 +1,70 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pulsar
+
+import (
+	"context"
+	"fmt"
+)
+
+// TypedReader is a Reader decoding the values of the messages into T with the schema of the reader.
+type TypedReader[T any] struct {
+	reader Reader
+}
+
+// NewTypedReader creates a TypedReader from the options, which require a Schema able to decode the messages into T.
+func NewTypedReader[T any](client Client, opts ReaderOptions) (*TypedReader[T], error) {
+	if opts.Schema == nil {
+		return nil, newError(InvalidConfiguration, "Schema is required to decode the messages of a TypedReader")
+	}
+	reader, err := client.CreateReader(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedReader[T]{reader: reader}, nil
+}
+
+// Next reads the next message and decodes its value. The message is returned along with the error when its value
+// can not be decoded.
+func (r *TypedReader[T]) Next(ctx context.Context) (T, Message, error) {
+	var value T
+	msg, err := r.reader.Next(ctx)
+	if err != nil {
+		return value, nil, err
+	}
+	if err := msg.GetSchemaValue(&value); err != nil {
+		return value, msg, newError(SchemaFailure, fmt.Sprintf("failed to decode message %s into %T: %v",
+			msg.ID(), value, err))
+	}
+	return value, msg, nil
+}
+
+// HasNext checks if there is any message available to read from the current position.
+func (r *TypedReader[T]) HasNext() bool {
+	return r.reader.HasNext()
+}
+
+// Reader returns the underlying Reader.
+func (r *TypedReader[T]) Reader() Reader {
+	return r.reader
+}
+
+// Close closes the underlying Reader.
+func (r *TypedReader[T]) Close() {
+	r.reader.Close()
+}